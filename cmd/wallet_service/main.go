@@ -1,34 +1,14 @@
 package main
 
 import (
-	"github.com/amelonpie/wallet-service/internal/endpoint"
+	"flag"
+	"os"
+
 	"github.com/amelonpie/wallet-service/pkg/config"
 	"github.com/amelonpie/wallet-service/pkg/log"
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
 )
 
-func initRouter(mainLogger *logrus.Logger) {
-	// gin.SetMode(gin.ReleaseMode)
-	router := gin.Default()
-
-	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "OPTIONS"},
-		AllowHeaders:     []string{"*"},
-		ExposeHeaders:    []string{"Cache-Control", "Content-Type"},
-		AllowCredentials: true,
-	}))
-
-	endpoint.SetupRouters(router)
-
-	if err := router.Run(":" + viper.GetString("api_port")); err != nil {
-		mainLogger.Error(err)
-	}
-}
-
 func main() {
 	err := config.Initialize()
 	if err != nil {
@@ -44,6 +24,32 @@ func main() {
 		return
 	}
 
-	mainLogger.Infoln("wallet service api start running")
-	initRouter(mainLogger)
+	if args := flag.Args(); len(args) > 0 {
+		if args[0] != "migrate" {
+			mainLogger.Fatalf("unknown command %q", args[0])
+		}
+
+		if err = runMigrate(args[1:]); err != nil {
+			mainLogger.WithField("err", err).Error("migrate failed")
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if err = autoMigrate(); err != nil {
+		mainLogger.WithField("err", err).Fatalf("failed to migrate database")
+		return
+	}
+
+	if err = serve(mainLogger); err != nil {
+		mainLogger.WithField("err", err).Error("wallet service stopped with errors")
+		os.Exit(1)
+	}
+
+	mainLogger.Info("wallet service stopped")
+
+	if err = log.Close(); err != nil {
+		logrus.WithField("err", err).Error("failed to close log files")
+	}
 }
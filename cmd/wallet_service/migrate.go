@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/database"
+	"github.com/amelonpie/wallet-service/internal/migrate"
+	"github.com/spf13/viper"
+)
+
+var errMigrateUsage = errors.New("usage: wallet_service [-c config] migrate up|down [steps]|status")
+
+func newMigrator() (*migrate.Migrator, func(), error) {
+	dbConfig, err := database.NewDatabaseConfig()
+	if err != nil {
+		return nil, nil, err //nolint:wrapcheck // names the secret that failed
+	}
+
+	db, err := dbConfig.ConnectPostgre()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m, err := migrate.New(db)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	return m, func() { db.Close() }, nil
+}
+
+// runMigrate implements the migrate subcommand
+func runMigrate(args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return errMigrateUsage
+	}
+
+	m, closeDB, err := newMigrator()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		count, err := m.Up(ctx)
+		fmt.Fprintf(os.Stdout, "applied %d migration(s)\n", count)
+
+		return err
+	case "down":
+		steps := 1
+
+		if len(args) == 2 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return errMigrateUsage
+			}
+		}
+
+		count, err := m.Down(ctx, steps)
+		fmt.Fprintf(os.Stdout, "reverted %d migration(s)\n", count)
+
+		return err
+	case "status":
+		return printMigrationStatus(ctx, m)
+	default:
+		return errMigrateUsage
+	}
+}
+
+func printMigrationStatus(ctx context.Context, m *migrate.Migrator) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:mnd // column padding
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+
+	for _, s := range statuses {
+		appliedAt := "pending"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+
+		if s.Modified {
+			appliedAt += " (file modified since)"
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+	}
+
+	return w.Flush()
+}
+
+// autoMigrate applies pending migrations before serving when migrate.auto is set
+func autoMigrate() error {
+	viper.SetDefault("migrate.auto", false)
+
+	if !viper.GetBool("migrate.auto") {
+		return nil
+	}
+
+	m, closeDB, err := newMigrator()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	_, err = m.Up(context.Background())
+
+	return err
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/app"
+	"github.com/amelonpie/wallet-service/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// serve runs the API until SIGINT or SIGTERM, then shuts down in order: readiness
+// fails, streams end, requests in flight finish, background workers stop, and
+// Postgres and Redis close last
+func serve(mainLogger *logrus.Logger) error {
+	appConfig, err := app.NewConfig()
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	cfg := appConfig.Server
+
+	application, err := app.New(appConfig)
+	if err != nil {
+		return fmt.Errorf("failed to build application: %w", err)
+	}
+
+	config.Watch(func(changed *viper.Viper, name string) error {
+		if reloadErr := application.Settings.Reload(changed); reloadErr != nil {
+			mainLogger.WithFields(logrus.Fields{"err": reloadErr, "file": name}).Error("rejected config change, keeping the previous settings")
+			return reloadErr //nolint:wrapcheck // logged above, only tells Watch to keep the previous file
+		}
+
+		mainLogger.WithField("file", name).Info("config reloaded")
+
+		return nil
+	})
+
+	server := &http.Server{
+		Addr:              cfg.Addr(),
+		Handler:           application.Router,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+	}
+	// streams never finish on their own, closing them lets clients reconnect elsewhere
+	server.RegisterOnShutdown(application.Endpoint.Stream.CloseSubscribers)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	mainLogger.WithField("addr", cfg.Addr()).Infoln("wallet service api start running")
+
+	var errs []error
+
+	select {
+	case err = <-serveErr:
+		errs = append(errs, fmt.Errorf("failed to serve: %w", err))
+	case <-ctx.Done():
+		mainLogger.Info("shutting down")
+	}
+
+	// a second signal ends the process right away
+	stop()
+
+	application.Endpoint.Health.Drain()
+	time.Sleep(cfg.DrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err = server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		errs = append(errs, fmt.Errorf("failed to drain requests: %w", err))
+	}
+
+	if err = application.Stop(shutdownCtx); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,817 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/account"
+	"github.com/amelonpie/wallet-service/internal/bankimport"
+	"github.com/amelonpie/wallet-service/internal/database"
+	"github.com/amelonpie/wallet-service/internal/payout"
+	"github.com/amelonpie/wallet-service/internal/reconcile"
+	"github.com/amelonpie/wallet-service/internal/stream"
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/amelonpie/wallet-service/internal/webhook"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	errUsage           = errors.New("invalid arguments")
+	errLedgerImbalance = errors.New("ledger check found discrepancies")
+	errDiscrepancies   = errors.New("reconciliation found discrepancies")
+)
+
+// command runs a subcommand with its own arguments
+type command struct {
+	usage string
+	run   func(ctx context.Context, p printer, args []string) error
+}
+
+func commands() map[string]command {
+	return map[string]command{
+		"balance":   {"balance <user_id>", balanceCmd},
+		"history":   {"history <user_id>", historyCmd},
+		"adjust":    {"adjust -reason <text> [-actor <name>] <user_id> <amount>", adjustCmd},
+		"freeze":    {"freeze [-reason <text>] [-actor <name>] <user_id>", freezeCmd(true)},
+		"unfreeze":  {"unfreeze [-reason <text>] [-actor <name>] <user_id>", freezeCmd(false)},
+		"outbox":    {"outbox replay [-status dead|delivered] [-subscription <id>]", outboxCmd},
+		"ledger":    {"ledger check", ledgerCmd},
+		"reconcile": {"reconcile [-repair-cache]", reconcileCmd},
+		"import":    {"import [-format csv|camt054] <file>", importCmd},
+		"suspense": {
+			"suspense list [-status open|allocated] | suspense allocate [-actor <name>] <item_id> <user_id>",
+			suspenseCmd,
+		},
+		"payout": {
+			"payout list [-status <status>] [-user <user_id>] | payout batch [-o <file>] | " +
+				"payout file [-o <file>] <batch_id> | payout ack [-format pain002|nacha|csv] <file>",
+			payoutCmd,
+		},
+		"account": {"account issue | account show <user_id> | account lookup <account_number>", accountCmd},
+		"secrets": {"secrets keygen | secrets encrypt [-o <file>] <plain.yaml>", secretsCmd},
+	}
+}
+
+// backend is the one Postgres pool and Redis client a command works on, with the
+// balance cache and the notifiers of the server built on them
+type backend struct {
+	db        *sql.DB
+	cache     wallet.BalanceCache
+	notifiers []wallet.Notifier
+}
+
+// connect opens the backend, the returned func closes its connections. Balance
+// changes are queued as webhook events the server's dispatcher delivers and
+// published to the balance streams of the server instances.
+func connect() (*backend, func(), error) {
+	dbConfig, err := database.NewDatabaseConfig()
+	if err != nil {
+		return nil, nil, err //nolint:wrapcheck // names the secret that failed
+	}
+
+	db, err := dbConfig.ConnectPostgre()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+
+	closeAll := func() { database.Close() }
+
+	cacheConfig := wallet.NewCacheConfig()
+	streamConfig := stream.NewConfig()
+
+	var rdb *redis.Client
+	if cacheConfig.Backend == wallet.CacheBackendRedis || streamConfig.Backend == stream.BackendRedis {
+		rdb = dbConfig.NewRedisClient()
+	}
+
+	cache, err := wallet.NewCache(cacheConfig, rdb)
+	if err != nil {
+		closeAll()
+		return nil, nil, fmt.Errorf("failed to initialize balance cache: %w", err)
+	}
+
+	broker, err := stream.New(streamConfig, rdb)
+	if err != nil {
+		closeAll()
+		return nil, nil, fmt.Errorf("failed to initialize stream broker: %w", err)
+	}
+
+	dispatcher := webhook.NewDispatcher(webhook.NewRepository(db), webhook.NewDispatcherConfig())
+
+	return &backend{
+		db:        db,
+		cache:     cache,
+		notifiers: []wallet.Notifier{dispatcher, broker},
+	}, closeAll, nil
+}
+
+// newAdmin works on the database and the cache the service instances share
+func newAdmin() (*wallet.Admin, func(), error) {
+	b, closeAll, err := connect()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return wallet.NewAdmin(b.db, b.cache, b.notifiers...), closeAll, nil
+}
+
+func parseUserID(arg string) (int, error) {
+	userID, err := strconv.Atoi(arg)
+	if err != nil || userID < 1 {
+		return 0, fmt.Errorf("%w: user id %q", errUsage, arg)
+	}
+
+	return userID, nil
+}
+
+// defaultActor names the operator in the audit trail unless -actor is given
+func defaultActor() string {
+	return os.Getenv("USER")
+}
+
+func balanceCmd(ctx context.Context, p printer, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	userID, err := parseUserID(args[0])
+	if err != nil {
+		return err
+	}
+
+	admin, closeDB, err := newAdmin()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	w, err := admin.Wallet(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return p.print(w, []string{"USER", "BALANCE", "VERSION", "FROZEN"}, [][]string{{
+		strconv.Itoa(w.UserID), money(w.Balance), strconv.FormatInt(w.Version, 10), strconv.FormatBool(w.Frozen),
+	}})
+}
+
+func historyCmd(ctx context.Context, p printer, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	userID, err := parseUserID(args[0])
+	if err != nil {
+		return err
+	}
+
+	admin, closeDB, err := newAdmin()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	txs, err := admin.History(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(txs))
+
+	for _, t := range txs {
+		to := ""
+		if t.ToUserID != 0 {
+			to = strconv.Itoa(t.ToUserID)
+		}
+
+		rows = append(rows, []string{
+			strconv.Itoa(t.TransactionID), t.TransactionType, strconv.Itoa(t.FromUserID), to, money(t.Amount), t.Timestamp,
+		})
+	}
+
+	if txs == nil {
+		txs = []wallet.Transaction{}
+	}
+
+	return p.print(txs, []string{"ID", "TYPE", "FROM", "TO", "AMOUNT", "TIMESTAMP"}, rows)
+}
+
+func adjustCmd(ctx context.Context, p printer, args []string) error {
+	fs := flag.NewFlagSet("adjust", flag.ContinueOnError)
+	reason := fs.String("reason", "", "why the balance is corrected, required")
+	actor := fs.String("actor", defaultActor(), "operator recorded in the audit trail")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() != 2 {
+		return errUsage
+	}
+
+	userID, err := parseUserID(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	amount, err := strconv.ParseFloat(fs.Arg(1), 64)
+	if err != nil {
+		return fmt.Errorf("%w: amount %q", errUsage, fs.Arg(1))
+	}
+
+	admin, closeDB, err := newAdmin()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	adj, err := admin.Adjust(ctx, wallet.Adjustment{UserID: userID, Amount: amount, Reason: *reason, Actor: *actor})
+	if err != nil {
+		return err
+	}
+
+	return p.print(adj, []string{"ADJUSTMENT", "USER", "AMOUNT", "BALANCE", "ACTOR", "REASON"}, [][]string{{
+		strconv.Itoa(adj.AdjustmentID), strconv.Itoa(adj.UserID), money(adj.Amount), money(adj.Balance), adj.Actor, adj.Reason,
+	}})
+}
+
+func freezeCmd(frozen bool) func(ctx context.Context, p printer, args []string) error {
+	return func(ctx context.Context, p printer, args []string) error {
+		fs := flag.NewFlagSet("freeze", flag.ContinueOnError)
+		reason := fs.String("reason", "", "why the wallet is frozen or unfrozen")
+		actor := fs.String("actor", defaultActor(), "operator recorded in the audit trail")
+
+		if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+			return errUsage
+		}
+
+		userID, err := parseUserID(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		admin, closeDB, err := newAdmin()
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		freeze, err := admin.SetFrozen(ctx, wallet.Freeze{UserID: userID, Frozen: frozen, Reason: *reason, Actor: *actor})
+		if err != nil {
+			return err
+		}
+
+		w, err := admin.Wallet(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		result := struct {
+			wallet.Wallet
+			FreezeID int `json:"freeze_id"`
+		}{Wallet: w, FreezeID: freeze.FreezeID}
+
+		return p.print(result, []string{"FREEZE", "USER", "BALANCE", "FROZEN"}, [][]string{{
+			strconv.Itoa(freeze.FreezeID), strconv.Itoa(w.UserID), money(w.Balance), strconv.FormatBool(w.Frozen),
+		}})
+	}
+}
+
+// outboxCmd queues webhook deliveries again, the durable record of wallet events
+// that the dispatcher works off
+func outboxCmd(ctx context.Context, p printer, args []string) error {
+	if len(args) == 0 || args[0] != "replay" {
+		return errUsage
+	}
+
+	fs := flag.NewFlagSet("outbox replay", flag.ContinueOnError)
+	status := fs.String("status", webhook.StatusDead, "replay dead or delivered deliveries")
+	subscriptionID := fs.Int("subscription", 0, "only deliveries of this subscription, 0 for all")
+
+	if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 0 {
+		return errUsage
+	}
+
+	repo, err := webhook.InitRepository()
+	if err != nil {
+		return err
+	}
+
+	count, err := webhook.NewService(repo, webhook.NewDispatcherConfig()).ReplayDeliveries(ctx, *status, *subscriptionID)
+	if err != nil {
+		return err
+	}
+
+	result := struct {
+		Status         string `json:"status"`
+		SubscriptionID int    `json:"subscription_id,omitempty"`
+		Queued         int64  `json:"queued"`
+	}{*status, *subscriptionID, count}
+
+	return p.print(result, []string{"STATUS", "SUBSCRIPTION", "QUEUED"}, [][]string{{
+		*status, strconv.Itoa(*subscriptionID), strconv.FormatInt(count, 10),
+	}})
+}
+
+func ledgerCmd(ctx context.Context, p printer, args []string) error {
+	if len(args) != 1 || args[0] != "check" {
+		return errUsage
+	}
+
+	admin, closeDB, err := newAdmin()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	discrepancies, err := admin.CheckLedger(ctx)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(discrepancies))
+	for _, d := range discrepancies {
+		rows = append(rows, []string{strconv.Itoa(d.UserID), money(d.Balance), money(d.LedgerBalance)})
+	}
+
+	result := struct {
+		CheckedAt     time.Time                  `json:"checked_at"`
+		Discrepancies []wallet.LedgerDiscrepancy `json:"discrepancies"`
+	}{time.Now().UTC(), discrepancies}
+
+	if result.Discrepancies == nil {
+		result.Discrepancies = []wallet.LedgerDiscrepancy{}
+	}
+
+	if err = p.print(result, []string{"USER", "BALANCE", "LEDGER BALANCE"}, rows); err != nil {
+		return err
+	}
+
+	if len(discrepancies) > 0 {
+		return errLedgerImbalance
+	}
+
+	return nil
+}
+
+func reconcileCmd(ctx context.Context, p printer, args []string) error {
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	repair := fs.Bool("repair-cache", false, "invalidate stale cached balances")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return errUsage
+	}
+
+	b, closeDB, err := connect()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	reconciler := reconcile.New(wallet.NewAdmin(b.db, b.cache), b.cache, reconcile.NewConfig())
+
+	report, err := reconciler.Reconcile(ctx, *repair)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(report.Discrepancies))
+	for _, d := range report.Discrepancies {
+		rows = append(rows, []string{
+			string(d.Severity), d.Kind, strconv.Itoa(d.UserID), money(d.Expected), money(d.Actual),
+			strconv.FormatBool(d.Repaired), d.Detail,
+		})
+	}
+
+	header := []string{"SEVERITY", "KIND", "USER", "EXPECTED", "ACTUAL", "REPAIRED", "DETAIL"}
+	if err = p.print(report, header, rows); err != nil {
+		return err
+	}
+
+	if len(report.Discrepancies) > 0 {
+		return errDiscrepancies
+	}
+
+	return nil
+}
+
+// newWalletService builds a wallet service like the server's on b
+//
+//nolint:ireturn // stick to interface
+func newWalletService(b *backend) wallet.Service {
+	return wallet.NewService(wallet.NewRepository(b.db), b.cache, b.notifiers...)
+}
+
+func newImporter() (*bankimport.Importer, func(), error) {
+	b, closeAll, err := connect()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accounts, err := account.NewService(account.NewRepository(b.db), account.NewConfig())
+	if err != nil {
+		closeAll()
+		return nil, nil, err //nolint:wrapcheck // callers wrap with context
+	}
+
+	importer := bankimport.New(bankimport.NewRepository(b.db), newWalletService(b), accounts, bankimport.NewConfig())
+
+	return importer, closeAll, nil
+}
+
+func importCmd(ctx context.Context, p printer, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", string(bankimport.FormatCSV), "bank file format, csv or camt054")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return errUsage
+	}
+
+	f, err := bankimport.ParseFormat(*format)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to open bank file: %w", err)
+	}
+	defer file.Close()
+
+	importer, closeDB, err := newImporter()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	report, err := importer.Import(ctx, file, f)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(report.Results))
+
+	for _, r := range report.Results {
+		user := ""
+		if r.UserID != 0 {
+			user = strconv.Itoa(r.UserID)
+		}
+
+		rows = append(rows, []string{r.BankReference, money(r.Amount), user, r.Outcome, r.Reason})
+	}
+
+	return p.print(report, []string{"BANK REFERENCE", "AMOUNT", "USER", "OUTCOME", "REASON"}, rows)
+}
+
+// suspenseCmd works off the credits an import could not match to a wallet
+func suspenseCmd(ctx context.Context, p printer, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "list":
+		return suspenseListCmd(ctx, p, args[1:])
+	case "allocate":
+		return suspenseAllocateCmd(ctx, p, args[1:])
+	default:
+		return errUsage
+	}
+}
+
+func suspenseRows(items []bankimport.SuspenseItem) [][]string {
+	rows := make([][]string, 0, len(items))
+
+	for _, item := range items {
+		user := ""
+		if item.AllocatedUserID != 0 {
+			user = strconv.Itoa(item.AllocatedUserID)
+		}
+
+		rows = append(rows, []string{
+			strconv.Itoa(item.ItemID), item.Credit.BankReference, money(item.Credit.Amount), item.Credit.Currency,
+			item.Credit.BookedAt.Format(time.DateOnly), item.Credit.Reference, item.Status, user, item.Reason,
+		})
+	}
+
+	return rows
+}
+
+var suspenseHeader = []string{ //nolint:gochecknoglobals // read-only
+	"ITEM", "BANK REFERENCE", "AMOUNT", "CURRENCY", "BOOKED", "REFERENCE", "STATUS", "USER", "REASON",
+}
+
+func suspenseListCmd(ctx context.Context, p printer, args []string) error {
+	fs := flag.NewFlagSet("suspense list", flag.ContinueOnError)
+	status := fs.String("status", bankimport.StatusOpen, "open or allocated items, empty for all")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return errUsage
+	}
+
+	importer, closeDB, err := newImporter()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	items, err := importer.Suspense(ctx, *status)
+	if err != nil {
+		return err
+	}
+
+	if items == nil {
+		items = []bankimport.SuspenseItem{}
+	}
+
+	return p.print(items, suspenseHeader, suspenseRows(items))
+}
+
+func suspenseAllocateCmd(ctx context.Context, p printer, args []string) error {
+	fs := flag.NewFlagSet("suspense allocate", flag.ContinueOnError)
+	actor := fs.String("actor", defaultActor(), "operator recorded on the item")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() != 2 {
+		return errUsage
+	}
+
+	itemID, err := strconv.Atoi(fs.Arg(0))
+	if err != nil || itemID < 1 {
+		return fmt.Errorf("%w: item id %q", errUsage, fs.Arg(0))
+	}
+
+	userID, err := parseUserID(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	importer, closeDB, err := newImporter()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	item, err := importer.Allocate(ctx, itemID, userID, *actor)
+	if err != nil {
+		return err
+	}
+
+	return p.print(item, suspenseHeader, suspenseRows([]bankimport.SuspenseItem{item}))
+}
+
+func newPayouts() (payout.Service, func(), error) { //nolint:ireturn // stick to interface
+	b, closeAll, err := connect()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return payout.NewService(payout.NewRepository(b.db), newWalletService(b), payout.NewConfig()), closeAll, nil
+}
+
+// payoutCmd batches pending payouts into bank files and ingests what the bank sends back
+func payoutCmd(ctx context.Context, p printer, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "list":
+		return payoutListCmd(ctx, p, args[1:])
+	case "batch":
+		return payoutBatchCmd(ctx, p, args[1:])
+	case "file":
+		return payoutFileCmd(ctx, p, args[1:])
+	case "ack":
+		return payoutAckCmd(ctx, p, args[1:])
+	default:
+		return errUsage
+	}
+}
+
+func payoutRows(payouts []payout.Payout) [][]string {
+	rows := make([][]string, 0, len(payouts))
+
+	for _, po := range payouts {
+		batch := ""
+		if po.BatchID != 0 {
+			batch = strconv.Itoa(po.BatchID)
+		}
+
+		rows = append(rows, []string{
+			strconv.Itoa(po.PayoutID), strconv.Itoa(po.UserID), money(po.Amount), string(po.Scheme),
+			po.Destination.Name, po.Status, batch, po.Reference, po.ReturnReason,
+		})
+	}
+
+	return rows
+}
+
+var payoutHeader = []string{ //nolint:gochecknoglobals // read-only
+	"PAYOUT", "USER", "AMOUNT", "SCHEME", "BENEFICIARY", "STATUS", "BATCH", "REFERENCE", "RETURN REASON",
+}
+
+func payoutListCmd(ctx context.Context, p printer, args []string) error {
+	fs := flag.NewFlagSet("payout list", flag.ContinueOnError)
+	status := fs.String("status", "", "only payouts with the status")
+	user := fs.Int("user", 0, "only payouts of the user")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 || *user < 0 {
+		return errUsage
+	}
+
+	payouts, closeDB, err := newPayouts()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	list, err := payouts.List(ctx, *user, *status)
+	if err != nil {
+		return err
+	}
+
+	if list == nil {
+		list = []payout.Payout{}
+	}
+
+	return p.print(list, payoutHeader, payoutRows(list))
+}
+
+// batchFileName names the file of a batch after its id and scheme
+func batchFileName(batch payout.Batch) string {
+	ext := "xml"
+	if batch.Scheme == payout.SchemeNACHA {
+		ext = "ach"
+	}
+
+	return fmt.Sprintf("payout-batch-%d.%s", batch.BatchID, ext)
+}
+
+// writeBatchFile renders the batch into the file, or a file named after the batch
+func writeBatchFile(payouts payout.Service, batch payout.Batch, name string) error {
+	if name == "" {
+		name = batchFileName(batch)
+	}
+
+	file, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create payout file: %w", err)
+	}
+
+	if err = payouts.WriteBatch(file, batch); err != nil {
+		return errors.Join(fmt.Errorf("failed to write payout file %s: %w", name, err), file.Close())
+	}
+
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("failed to write payout file %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func payoutBatchCmd(ctx context.Context, p printer, args []string) error {
+	fs := flag.NewFlagSet("payout batch", flag.ContinueOnError)
+	out := fs.String("o", "", "payout file to write, named after the batch by default")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return errUsage
+	}
+
+	payouts, closeDB, err := newPayouts()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	batch, err := payouts.Batch(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the batch is stored already, a failed write is retried with payout file
+	if err = writeBatchFile(payouts, batch, *out); err != nil {
+		return err
+	}
+
+	return p.print(batch, payoutHeader, payoutRows(batch.Payouts))
+}
+
+func payoutFileCmd(ctx context.Context, p printer, args []string) error {
+	fs := flag.NewFlagSet("payout file", flag.ContinueOnError)
+	out := fs.String("o", "", "payout file to write, named after the batch by default")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return errUsage
+	}
+
+	batchID, err := strconv.Atoi(fs.Arg(0))
+	if err != nil || batchID < 1 {
+		return fmt.Errorf("%w: batch id %q", errUsage, fs.Arg(0))
+	}
+
+	payouts, closeDB, err := newPayouts()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	batch, err := payouts.GetBatch(ctx, batchID)
+	if err != nil {
+		return err
+	}
+
+	if err = writeBatchFile(payouts, batch, *out); err != nil {
+		return err
+	}
+
+	return p.print(batch, payoutHeader, payoutRows(batch.Payouts))
+}
+
+func payoutAckCmd(ctx context.Context, p printer, args []string) error {
+	fs := flag.NewFlagSet("payout ack", flag.ContinueOnError)
+	format := fs.String("format", string(payout.AckPain002), "acknowledgement format, pain002, nacha or csv")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return errUsage
+	}
+
+	f, err := payout.ParseAckFormat(*format)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to open acknowledgement file: %w", err)
+	}
+	defer file.Close()
+
+	payouts, closeDB, err := newPayouts()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	report, err := payouts.Ingest(ctx, file, f)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(report.Results))
+
+	for _, r := range report.Results {
+		id := ""
+		if r.PayoutID != 0 {
+			id = strconv.Itoa(r.PayoutID)
+		}
+
+		rows = append(rows, []string{r.Reference, id, r.Outcome, r.Reason})
+	}
+
+	return p.print(report, []string{"REFERENCE", "PAYOUT", "OUTCOME", "REASON"}, rows)
+}
+
+// accountCmd issues the virtual account numbers and tells whose wallet one is
+func accountCmd(ctx context.Context, p printer, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	accounts, err := account.InitService(account.NewConfig())
+	if err != nil {
+		return err
+	}
+
+	var va account.VirtualAccount
+
+	switch {
+	case args[0] == "issue" && len(args) == 1:
+		var issued int
+		if issued, err = accounts.IssueMissing(ctx); err != nil {
+			return err
+		}
+
+		return p.print(map[string]int{"issued": issued}, []string{"ISSUED"}, [][]string{{strconv.Itoa(issued)}})
+	case args[0] == "show" && len(args) == 2:
+		var userID int
+		if userID, err = parseUserID(args[1]); err != nil {
+			return err
+		}
+
+		if va, err = accounts.Issue(ctx, userID); err != nil {
+			return err
+		}
+	case args[0] == "lookup" && len(args) == 2:
+		if va, err = accounts.Lookup(ctx, args[1]); err != nil {
+			return err
+		}
+	default:
+		return errUsage
+	}
+
+	return p.print(va, []string{"ACCOUNT NUMBER", "USER", "SCHEME", "CREATED"}, [][]string{{
+		va.Number, strconv.Itoa(va.UserID), string(va.Scheme), va.CreatedAt.Format(time.RFC3339),
+	}})
+}
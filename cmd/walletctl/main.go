@@ -0,0 +1,76 @@
+// walletctl is the administrative CLI of the wallet service. It works directly on
+// the database configured for wallet_service and reads the same -c config file.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"syscall"
+
+	"github.com/amelonpie/wallet-service/pkg/config"
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/sirupsen/logrus"
+)
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "usage: walletctl [-c config] [-o table|json] <command>")
+	fmt.Fprintln(out, "\ncommands:")
+
+	cmds := commands()
+	names := make([]string, 0, len(cmds))
+
+	for name := range cmds {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintln(out, "  "+cmds[name].usage)
+	}
+
+	fmt.Fprintln(out, "\nflags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	format := flag.String("o", formatTable, "output format, table or json")
+	flag.Usage = usage
+
+	if err := config.Initialize(); err != nil {
+		logrus.WithField("err", err).Fatalf("failed to initialize config")
+	}
+
+	// adjustments and freezes are recorded in the database and logged to the service log
+	if _, err := log.Initialize(); err != nil {
+		logrus.WithField("err", err).Fatalf("failed to initialize logger")
+	}
+
+	cmd, ok := commands()[flag.Arg(0)]
+	if !ok || !slices.Contains([]string{formatTable, formatJSON}, *format) {
+		flag.Usage()
+		os.Exit(2) //nolint:mnd // usage error
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+
+	err := cmd.run(ctx, printer{format: *format, w: os.Stdout}, flag.Args()[1:])
+
+	stop()
+
+	switch {
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(os.Stderr, "%v\nusage: walletctl %s\n", err, cmd.usage)
+		os.Exit(2) //nolint:mnd // usage error
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "walletctl:", err)
+		os.Exit(1)
+	}
+}
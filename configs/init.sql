@@ -1,3 +1,5 @@
+-- Demo database for docker compose. The schema is owned by internal/migrate/migrations,
+-- keep it in sync; `wallet_service migrate up` adopts a database created by this file.
 CREATE TABLE IF NOT EXISTS users (
     user_id SERIAL PRIMARY KEY,
     username VARCHAR(255) NOT NULL,
@@ -11,7 +13,9 @@ VALUES ('tom', 'tom@example.com');
 CREATE TABLE IF NOT EXISTS wallets (
     wallet_id SERIAL PRIMARY KEY,
     user_id INT REFERENCES users(user_id) ON DELETE CASCADE,
-    balance DECIMAL(15, 2) DEFAULT 0.00
+    balance DECIMAL(15, 2) DEFAULT 0.00,
+    version BIGINT NOT NULL DEFAULT 0,
+    frozen BOOLEAN NOT NULL DEFAULT FALSE
 );
 INSERT INTO wallets (user_id, balance)
 VALUES
@@ -23,6 +27,142 @@ CREATE TABLE IF NOT EXISTS transactions (
     from_user_id INT REFERENCES users(user_id) ON DELETE CASCADE,
     to_user_id INT REFERENCES users(user_id),
     amount DECIMAL(15, 2),
-    transaction_type VARCHAR(20), -- 'deposit', 'withdrawal', 'transfer'
+    transaction_type VARCHAR(20), -- 'deposit', 'withdrawal', 'transfer', 'adjustment'
     timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
+-- opening deposits of the demo wallets, so that the ledger check balances
+INSERT INTO transactions (from_user_id, amount, transaction_type)
+VALUES
+    (1, 100.00, 'deposit'),
+    (2, 50.00, 'deposit');
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    subscription_id SERIAL PRIMARY KEY,
+    user_id INT REFERENCES users(user_id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    event_types TEXT[] NOT NULL, -- 'deposit', 'withdraw', 'transfer'
+    secret VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    delivery_id SERIAL PRIMARY KEY,
+    subscription_id INT REFERENCES webhook_subscriptions(subscription_id) ON DELETE CASCADE,
+    event_type VARCHAR(20) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- 'pending', 'delivered', 'dead'
+    attempts INT NOT NULL DEFAULT 0,
+    last_status_code INT,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS wallet_adjustments (
+    adjustment_id SERIAL PRIMARY KEY,
+    user_id INT REFERENCES users(user_id) ON DELETE CASCADE,
+    transaction_id INT REFERENCES transactions(transaction_id),
+    amount DECIMAL(15, 2) NOT NULL, -- negative debits the wallet
+    reason TEXT NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE OR REPLACE VIEW wallet_movements AS
+    SELECT transaction_id, from_user_id AS user_id,
+           CASE WHEN transaction_type IN ('deposit', 'adjustment') THEN amount ELSE -amount END AS amount,
+           timestamp
+    FROM transactions
+    UNION ALL
+    SELECT transaction_id, to_user_id AS user_id, amount, timestamp
+    FROM transactions WHERE transaction_type = 'transfer';
+CREATE INDEX IF NOT EXISTS transactions_from_user_idx ON transactions (from_user_id, timestamp);
+CREATE INDEX IF NOT EXISTS transactions_to_user_idx ON transactions (to_user_id, timestamp);
+
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+    user_id INT REFERENCES users(user_id) ON DELETE CASCADE,
+    taken_at TIMESTAMP NOT NULL,
+    balance DECIMAL(15, 2) NOT NULL,
+    PRIMARY KEY (user_id, taken_at)
+);
+
+CREATE TABLE IF NOT EXISTS statements (
+    user_id INT REFERENCES users(user_id) ON DELETE CASCADE,
+    period CHAR(7) NOT NULL, -- YYYY-MM
+    statement JSONB NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, period)
+);
+
+CREATE TABLE IF NOT EXISTS deposit_keys (
+    idempotency_key VARCHAR(255) PRIMARY KEY,
+    user_id INT REFERENCES users(user_id) ON DELETE CASCADE,
+    amount DECIMAL(15, 2) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS suspense_items (
+    item_id SERIAL PRIMARY KEY,
+    bank_reference VARCHAR(255) NOT NULL UNIQUE,
+    amount DECIMAL(15, 2) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    booked_at TIMESTAMPTZ NOT NULL,
+    debtor_name TEXT NOT NULL DEFAULT '',
+    debtor_account TEXT NOT NULL DEFAULT '',
+    reference TEXT NOT NULL DEFAULT '',
+    virtual_account TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open', -- open or allocated
+    allocated_user_id INT REFERENCES users(user_id),
+    allocated_by VARCHAR(255),
+    allocated_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS suspense_items_status_idx ON suspense_items (status, item_id);
+
+CREATE TABLE IF NOT EXISTS payout_batches (
+    batch_id SERIAL PRIMARY KEY,
+    scheme VARCHAR(10) NOT NULL, -- 'sepa' or 'nacha'
+    payout_count INT NOT NULL,
+    control_sum DECIMAL(15, 2) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS payouts (
+    payout_id SERIAL PRIMARY KEY,
+    user_id INT REFERENCES users(user_id) ON DELETE CASCADE,
+    amount DECIMAL(15, 2) NOT NULL,
+    scheme VARCHAR(10) NOT NULL,
+    beneficiary_name TEXT NOT NULL,
+    account TEXT NOT NULL, -- IBAN or account number
+    bank_code TEXT NOT NULL DEFAULT '', -- BIC or ABA routing number
+    account_type VARCHAR(10) NOT NULL DEFAULT '', -- 'checking' or 'savings' for NACHA
+    remittance TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'requested', -- requested, pending, batched, settled, returned
+    batch_id INT REFERENCES payout_batches(batch_id),
+    reference VARCHAR(35) UNIQUE, -- end-to-end id or trace number, set when batched
+    return_reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS payouts_status_idx ON payouts (status, payout_id);
+CREATE INDEX IF NOT EXISTS payouts_user_idx ON payouts (user_id, payout_id);
+
+CREATE TABLE IF NOT EXISTS virtual_accounts (
+    account_number VARCHAR(34) PRIMARY KEY,
+    user_id INT NOT NULL UNIQUE REFERENCES users(user_id) ON DELETE CASCADE,
+    scheme VARCHAR(10) NOT NULL, -- 'mod97' or 'luhn'
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS wallet_freezes (
+    freeze_id SERIAL PRIMARY KEY,
+    user_id INT REFERENCES users(user_id) ON DELETE CASCADE,
+    frozen BOOLEAN NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    actor VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS wallet_freezes_user_idx ON wallet_freezes (user_id, freeze_id);
@@ -0,0 +1,10 @@
+package account
+
+import "errors"
+
+var (
+	ErrUnknownScheme   = errors.New("unknown virtual account scheme")
+	ErrInvalidNumber   = errors.New("invalid virtual account number")
+	ErrAccountNotFound = errors.New("virtual account not found")
+	ErrNumberSpace     = errors.New("user id does not fit the virtual account number")
+)
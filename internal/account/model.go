@@ -0,0 +1,78 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Scheme is the check digit scheme of the virtual account numbers
+type Scheme string
+
+// Schemes of virtual account numbers
+const (
+	// SchemeMod97 issues IBANs with ISO 7064 mod-97 check digits
+	SchemeMod97 Scheme = "mod97"
+	// SchemeLuhn issues decimal account numbers ending with a Luhn check digit
+	SchemeLuhn Scheme = "luhn"
+)
+
+// VirtualAccount is the account number bank transfers to a wallet are sent to
+type VirtualAccount struct {
+	Number    string    `json:"account_number"`
+	UserID    int       `json:"user_id"`
+	Scheme    Scheme    `json:"scheme"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Repository stores the virtual accounts, one per wallet
+type Repository interface {
+	// Create stores the account unless the user has one, which is returned instead.
+	// wallet.ErrWalletNotFound if the user has no wallet.
+	Create(ctx context.Context, va VirtualAccount) (VirtualAccount, error)
+	GetByUser(ctx context.Context, userID int) (VirtualAccount, error)
+	GetByNumber(ctx context.Context, number string) (VirtualAccount, error)
+	// WalletsWithout lists the users whose wallet has no virtual account yet
+	WalletsWithout(ctx context.Context) ([]int, error)
+}
+
+// Service issues virtual account numbers and routes them back to wallets
+type Service interface {
+	// Issue returns the virtual account of the wallet, issuing it on first use
+	Issue(ctx context.Context, userID int) (VirtualAccount, error)
+	// IssueMissing issues a virtual account to every wallet without one
+	IssueMissing(ctx context.Context) (int, error)
+	Lookup(ctx context.Context, number string) (VirtualAccount, error)
+	// Validate checks the number against the configured scheme and returns it
+	// without spaces, in upper case
+	Validate(number string) (string, error)
+	// ResolveVirtualAccount implements bankimport.AccountResolver
+	ResolveVirtualAccount(ctx context.Context, number string) (int, error)
+	// Run issues the accounts of new wallets every interval until ctx is done
+	Run(ctx context.Context)
+}
+
+// Config of the virtual account numbers. An IBAN is the country code, check
+// digits, bank code and the user id in AccountDigits digits; a Luhn number is
+// the prefix and the user id, Length digits with the check digit.
+type Config struct {
+	Scheme        Scheme
+	Country       string
+	BankCode      string
+	AccountDigits int
+	Prefix        string
+	Length        int
+	IssueInterval time.Duration
+}
+
+type accountService struct {
+	repo   Repository
+	cfg    Config
+	logger *logrus.Entry
+}
+
+type accountRepository struct {
+	db *sql.DB
+}
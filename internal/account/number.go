@@ -0,0 +1,93 @@
+package account
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/amelonpie/wallet-service/pkg/checkdigit"
+)
+
+// ibanHeader is the country code and the check digits in front of the bank code
+const ibanHeader = 4
+
+// Normalize drops the spaces account numbers are printed with
+func Normalize(number string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(number), ""))
+}
+
+// number derives the account number from the user id, so numbers are unique
+// without a lookup and stay the same when issued again
+func (c Config) number(userID int) (string, error) {
+	if userID < 1 {
+		return "", fmt.Errorf("%w: user id %d", ErrNumberSpace, userID)
+	}
+
+	switch c.Scheme {
+	case SchemeMod97:
+		account := fmt.Sprintf("%0*d", c.AccountDigits, userID)
+		if len(account) > c.AccountDigits {
+			return "", fmt.Errorf("%w: %d in %d digits", ErrNumberSpace, userID, c.AccountDigits)
+		}
+
+		return checkdigit.IBAN(c.Country, c.BankCode+account) //nolint:wrapcheck // the config is validated
+	case SchemeLuhn:
+		digits := c.Length - len(c.Prefix) - 1
+
+		body := fmt.Sprintf("%s%0*d", c.Prefix, digits, userID)
+		if len(body) != c.Length-1 {
+			return "", fmt.Errorf("%w: %d in %d digits", ErrNumberSpace, userID, digits)
+		}
+
+		check, err := checkdigit.LuhnDigit(body)
+		if err != nil {
+			return "", err //nolint:wrapcheck // the config is validated
+		}
+
+		return body + string(check), nil
+	default:
+		return "", fmt.Errorf("%w %q", ErrUnknownScheme, c.Scheme)
+	}
+}
+
+// validate checks the normalized number is one this service issues: the layout
+// of the scheme, the bank code or prefix, and the check digits
+func (c Config) validate(number string) error {
+	var valid bool
+
+	switch c.Scheme {
+	case SchemeMod97:
+		valid = len(number) == ibanHeader+len(c.BankCode)+c.AccountDigits &&
+			strings.HasPrefix(number, c.Country) &&
+			strings.HasPrefix(number[ibanHeader:], c.BankCode) &&
+			checkdigit.ValidIBAN(number)
+	case SchemeLuhn:
+		valid = len(number) == c.Length && strings.HasPrefix(number, c.Prefix) && checkdigit.ValidLuhn(number)
+	default:
+		return fmt.Errorf("%w %q", ErrUnknownScheme, c.Scheme)
+	}
+
+	if !valid {
+		return fmt.Errorf("%w: %q is no %s account number", ErrInvalidNumber, number, c.Scheme)
+	}
+
+	return nil
+}
+
+// check rejects a config that cannot issue valid numbers
+func (c Config) check() error {
+	switch c.Scheme {
+	case SchemeMod97:
+		if _, err := checkdigit.IBAN(c.Country, c.BankCode); err != nil || c.AccountDigits < 1 {
+			return fmt.Errorf("%w: country %q, bank code %q, %d account digits",
+				ErrInvalidNumber, c.Country, c.BankCode, c.AccountDigits)
+		}
+	case SchemeLuhn:
+		if strings.Trim(c.Prefix, "0123456789") != "" || c.Length <= len(c.Prefix)+1 {
+			return fmt.Errorf("%w: prefix %q, %d digits", ErrInvalidNumber, c.Prefix, c.Length)
+		}
+	default:
+		return fmt.Errorf("%w %q", ErrUnknownScheme, c.Scheme)
+	}
+
+	return nil
+}
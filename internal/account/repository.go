@@ -0,0 +1,126 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/amelonpie/wallet-service/internal/database"
+	"github.com/amelonpie/wallet-service/internal/wallet"
+)
+
+const accountColumns = `account_number, user_id, scheme, created_at`
+
+//nolint:ireturn // stick to interface
+func InitRepository() (Repository, error) {
+	dbConfig, err := database.NewDatabaseConfig()
+	if err != nil {
+		return nil, err //nolint:wrapcheck // names the secret that failed
+	}
+
+	postgre, err := dbConfig.ConnectPostgre()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+
+	return NewRepository(postgre), nil
+}
+
+// NewRepository returns the Postgres backed repository on db.
+//
+//nolint:ireturn // stick to interface
+func NewRepository(db *sql.DB) Repository {
+	return &accountRepository{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAccount(row rowScanner) (VirtualAccount, error) {
+	var va VirtualAccount
+
+	err := row.Scan(&va.Number, &va.UserID, &va.Scheme, &va.CreatedAt)
+
+	return va, err //nolint:wrapcheck // callers wrap with context
+}
+
+// Create inserts the account if the wallet exists, the unique user id makes a
+// concurrent issue return the account the other one stored
+func (r *accountRepository) Create(ctx context.Context, va VirtualAccount) (VirtualAccount, error) {
+	query := `INSERT INTO virtual_accounts (account_number, user_id, scheme)
+              SELECT $1, $2, $3 WHERE EXISTS (SELECT 1 FROM wallets WHERE user_id = $2)
+              ON CONFLICT (user_id) DO NOTHING
+              RETURNING ` + accountColumns
+
+	created, err := scanAccount(r.db.QueryRowContext(ctx, query, va.Number, va.UserID, va.Scheme))
+	if err == nil {
+		return created, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return VirtualAccount{}, fmt.Errorf("failed to insert virtual account %s: %w", va.Number, err)
+	}
+
+	existing, err := r.GetByUser(ctx, va.UserID)
+	if errors.Is(err, ErrAccountNotFound) {
+		return VirtualAccount{}, fmt.Errorf("failed to insert virtual account %s: %w", va.Number, wallet.ErrWalletNotFound)
+	}
+
+	return existing, err
+}
+
+func (r *accountRepository) get(ctx context.Context, column string, value any) (VirtualAccount, error) {
+	query := `SELECT ` + accountColumns + ` FROM virtual_accounts WHERE ` + column + ` = $1`
+
+	va, err := scanAccount(r.db.QueryRowContext(ctx, query, value))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrAccountNotFound
+	}
+
+	if err != nil {
+		return VirtualAccount{}, fmt.Errorf("failed to query virtual account by %s %v: %w", column, value, err)
+	}
+
+	return va, nil
+}
+
+func (r *accountRepository) GetByUser(ctx context.Context, userID int) (VirtualAccount, error) {
+	return r.get(ctx, "user_id", userID)
+}
+
+func (r *accountRepository) GetByNumber(ctx context.Context, number string) (VirtualAccount, error) {
+	return r.get(ctx, "account_number", number)
+}
+
+func (r *accountRepository) WalletsWithout(ctx context.Context) ([]int, error) {
+	query := `SELECT w.user_id FROM wallets w
+              LEFT JOIN virtual_accounts va ON va.user_id = w.user_id
+              WHERE va.user_id IS NULL AND w.user_id IS NOT NULL
+              ORDER BY w.user_id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wallets: %w", err)
+	}
+	defer rows.Close()
+
+	//nolint:prealloc // row count unknown
+	var userIDs []int
+
+	for rows.Next() {
+		var userID int
+		if err = rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan wallet: %w", err)
+		}
+
+		userIDs = append(userIDs, userID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error arises during rows iteration: %w", err)
+	}
+
+	return userIDs, nil
+}
@@ -0,0 +1,59 @@
+package account
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreate_ReturnsExistingAccount(t *testing.T) {
+	// Arrange
+	db, mockSQL, err := sqlmock.New()
+	require.NoError(t, err)
+
+	repo := NewRepository(db)
+	createdAt := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+
+	mockSQL.ExpectQuery(`INSERT INTO virtual_accounts .* ON CONFLICT \(user_id\) DO NOTHING`).
+		WithArgs("DE73370400440000000007", 7, SchemeMod97).
+		WillReturnRows(sqlmock.NewRows(nil))
+	mockSQL.ExpectQuery(`SELECT .* FROM virtual_accounts WHERE user_id = \$1`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"account_number", "user_id", "scheme", "created_at"}).
+			AddRow("800000000078", 7, SchemeLuhn, createdAt))
+
+	// Act
+	va, err := repo.Create(context.Background(),
+		VirtualAccount{Number: "DE73370400440000000007", UserID: 7, Scheme: SchemeMod97})
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, "800000000078", va.Number)
+	require.Equal(t, SchemeLuhn, va.Scheme)
+	require.NoError(t, mockSQL.ExpectationsWereMet())
+}
+
+func TestCreate_WalletNotFound(t *testing.T) {
+	// Arrange
+	db, mockSQL, err := sqlmock.New()
+	require.NoError(t, err)
+
+	repo := NewRepository(db)
+
+	mockSQL.ExpectQuery(`INSERT INTO virtual_accounts`).
+		WillReturnRows(sqlmock.NewRows(nil))
+	mockSQL.ExpectQuery(`SELECT .* FROM virtual_accounts WHERE user_id = \$1`).
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows(nil))
+
+	// Act
+	_, err = repo.Create(context.Background(), VirtualAccount{Number: "x", UserID: 9, Scheme: SchemeMod97})
+
+	// Assert
+	require.ErrorIs(t, err, wallet.ErrWalletNotFound)
+	require.NoError(t, mockSQL.ExpectationsWereMet())
+}
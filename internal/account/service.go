@@ -0,0 +1,165 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/bankimport"
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/spf13/viper"
+)
+
+func NewConfig() Config {
+	viper.SetDefault("virtual_account.scheme", string(SchemeMod97))
+	viper.SetDefault("virtual_account.country", "DE")
+	viper.SetDefault("virtual_account.bank_code", "37040044")
+	viper.SetDefault("virtual_account.account_digits", 10) //nolint:mnd // German account numbers
+	viper.SetDefault("virtual_account.prefix", "")
+	viper.SetDefault("virtual_account.length", 12) //nolint:mnd // default Luhn number length
+	viper.SetDefault("virtual_account.issue_interval", "1m")
+
+	return Config{
+		Scheme:        Scheme(viper.GetString("virtual_account.scheme")),
+		Country:       strings.ToUpper(viper.GetString("virtual_account.country")),
+		BankCode:      Normalize(viper.GetString("virtual_account.bank_code")),
+		AccountDigits: viper.GetInt("virtual_account.account_digits"),
+		Prefix:        viper.GetString("virtual_account.prefix"),
+		Length:        viper.GetInt("virtual_account.length"),
+		IssueInterval: viper.GetDuration("virtual_account.issue_interval"),
+	}
+}
+
+// InitService connects the virtual accounts to PostgreSQL
+//
+//nolint:ireturn // stick to interface
+func InitService(cfg Config) (Service, error) {
+	repo, err := InitRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewService(repo, cfg)
+}
+
+// NewService fails on a config that cannot issue valid numbers
+//
+//nolint:ireturn // stick to interface
+func NewService(repo Repository, cfg Config) (Service, error) {
+	if err := cfg.check(); err != nil {
+		return nil, fmt.Errorf("invalid virtual account config: %w", err)
+	}
+
+	return &accountService{
+		repo:   repo,
+		cfg:    cfg,
+		logger: log.NewLogger("account").WithField("module", "service"),
+	}, nil
+}
+
+func (s *accountService) Issue(ctx context.Context, userID int) (VirtualAccount, error) {
+	va, err := s.repo.GetByUser(ctx, userID)
+	if err == nil {
+		return va, nil
+	}
+
+	if !errors.Is(err, ErrAccountNotFound) {
+		return VirtualAccount{}, fmt.Errorf("failed to get virtual account of user %d: %w", userID, err)
+	}
+
+	number, err := s.cfg.number(userID)
+	if err != nil {
+		return VirtualAccount{}, fmt.Errorf("failed to issue virtual account to user %d: %w", userID, err)
+	}
+
+	va, err = s.repo.Create(ctx, VirtualAccount{Number: number, UserID: userID, Scheme: s.cfg.Scheme})
+	if err != nil {
+		return VirtualAccount{}, fmt.Errorf("failed to issue virtual account to user %d: %w", userID, err)
+	}
+
+	s.logger.WithField("user_id", userID).WithField("account_number", va.Number).Info("virtual account issued")
+
+	return va, nil
+}
+
+func (s *accountService) IssueMissing(ctx context.Context) (int, error) {
+	userIDs, err := s.repo.WalletsWithout(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list wallets without virtual account: %w", err)
+	}
+
+	for i, userID := range userIDs {
+		if _, err = s.Issue(ctx, userID); err != nil {
+			return i, err
+		}
+	}
+
+	return len(userIDs), nil
+}
+
+func (s *accountService) Validate(number string) (string, error) {
+	number = Normalize(number)
+
+	if err := s.cfg.validate(number); err != nil {
+		return "", err
+	}
+
+	return number, nil
+}
+
+func (s *accountService) Lookup(ctx context.Context, number string) (VirtualAccount, error) {
+	number, err := s.Validate(number)
+	if err != nil {
+		return VirtualAccount{}, err
+	}
+
+	va, err := s.repo.GetByNumber(ctx, number)
+	if err != nil {
+		return VirtualAccount{}, fmt.Errorf("failed to look up virtual account %s: %w", number, err)
+	}
+
+	return va, nil
+}
+
+// ResolveVirtualAccount routes a bank credit, a number that fails validation is
+// unknown so the importer falls back to the payment reference
+func (s *accountService) ResolveVirtualAccount(ctx context.Context, number string) (int, error) {
+	va, err := s.Lookup(ctx, number)
+	if errors.Is(err, ErrInvalidNumber) || errors.Is(err, ErrAccountNotFound) {
+		return 0, fmt.Errorf("%w: %w", bankimport.ErrUnknownAccount, err)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return va.UserID, nil
+}
+
+// Run issues the accounts of wallets created since the last run. Wallets are
+// created outside the service, an account is also issued on its first lookup.
+func (s *accountService) Run(ctx context.Context) {
+	if s.cfg.IssueInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.IssueInterval)
+	defer ticker.Stop()
+
+	for {
+		issued, err := s.IssueMissing(ctx)
+		if err != nil {
+			s.logger.WithField("err", err).Error("failed to issue virtual accounts")
+		} else if issued > 0 {
+			s.logger.WithField("issued", issued).Info("virtual accounts issued to new wallets")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
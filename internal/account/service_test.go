@@ -0,0 +1,222 @@
+package account
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/amelonpie/wallet-service/internal/bankimport"
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepository keeps accounts in maps, wallets lists the users with a wallet
+type fakeRepository struct {
+	wallets  map[int]bool
+	byUser   map[int]VirtualAccount
+	byNumber map[string]VirtualAccount
+}
+
+func newFakeRepository(userIDs ...int) *fakeRepository {
+	repo := &fakeRepository{
+		wallets:  make(map[int]bool),
+		byUser:   make(map[int]VirtualAccount),
+		byNumber: make(map[string]VirtualAccount),
+	}
+
+	for _, userID := range userIDs {
+		repo.wallets[userID] = true
+	}
+
+	return repo
+}
+
+func (r *fakeRepository) Create(_ context.Context, va VirtualAccount) (VirtualAccount, error) {
+	if existing, ok := r.byUser[va.UserID]; ok {
+		return existing, nil
+	}
+
+	if !r.wallets[va.UserID] {
+		return VirtualAccount{}, fmt.Errorf("failed to insert virtual account: %w", wallet.ErrWalletNotFound)
+	}
+
+	r.byUser[va.UserID] = va
+	r.byNumber[va.Number] = va
+
+	return va, nil
+}
+
+func (r *fakeRepository) GetByUser(_ context.Context, userID int) (VirtualAccount, error) {
+	va, ok := r.byUser[userID]
+	if !ok {
+		return VirtualAccount{}, ErrAccountNotFound
+	}
+
+	return va, nil
+}
+
+func (r *fakeRepository) GetByNumber(_ context.Context, number string) (VirtualAccount, error) {
+	va, ok := r.byNumber[number]
+	if !ok {
+		return VirtualAccount{}, ErrAccountNotFound
+	}
+
+	return va, nil
+}
+
+func (r *fakeRepository) WalletsWithout(context.Context) ([]int, error) {
+	var userIDs []int
+
+	for userID := range r.wallets {
+		if _, ok := r.byUser[userID]; !ok {
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	sort.Ints(userIDs)
+
+	return userIDs, nil
+}
+
+func mod97Config() Config {
+	return Config{Scheme: SchemeMod97, Country: "DE", BankCode: "37040044", AccountDigits: 10}
+}
+
+func luhnConfig() Config {
+	return Config{Scheme: SchemeLuhn, Prefix: "8000", Length: 12}
+}
+
+func TestNumber(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    Config
+		userID int
+		want   string
+	}{
+		{"mod97", mod97Config(), 42, "DE98370400440000000042"},
+		{"luhn", luhnConfig(), 42, "800000000425"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			number, err := tt.cfg.number(tt.userID)
+
+			// Assert
+			require.NoError(t, err)
+			require.Equal(t, tt.want, number)
+			require.NoError(t, tt.cfg.validate(number))
+		})
+	}
+}
+
+func TestNumber_DoesNotFit(t *testing.T) {
+	cfg := luhnConfig()
+	cfg.Length = 8
+
+	_, err := cfg.number(12345)
+	require.ErrorIs(t, err, ErrNumberSpace)
+}
+
+func TestValidate(t *testing.T) {
+	svc, err := NewService(newFakeRepository(), mod97Config())
+	require.NoError(t, err)
+
+	number, err := svc.Validate("de98 3704 0044 0000 0000 42")
+	require.NoError(t, err)
+	require.Equal(t, "DE98370400440000000042", number)
+
+	for _, invalid := range []string{
+		"DE97370400440000000042", // check digits
+		"DE98370400440000000024", // transposed digits
+		"GB29NWBK60161331926819", // another bank
+		"DE8937040044053201300",  // too short
+		"800000000425",
+	} {
+		_, err = svc.Validate(invalid)
+		require.ErrorIs(t, err, ErrInvalidNumber, invalid)
+	}
+}
+
+func TestNewService_InvalidConfig(t *testing.T) {
+	for _, cfg := range []Config{
+		{Scheme: "mod11"},
+		{Scheme: SchemeMod97, Country: "D1", BankCode: "37040044", AccountDigits: 10},
+		{Scheme: SchemeLuhn, Prefix: "80A", Length: 12},
+		{Scheme: SchemeLuhn, Prefix: "8000", Length: 5},
+	} {
+		_, err := NewService(newFakeRepository(), cfg)
+		require.Error(t, err, cfg)
+	}
+}
+
+func TestIssue(t *testing.T) {
+	// Arrange
+	repo := newFakeRepository(1, 2)
+	svc, err := NewService(repo, luhnConfig())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	// Act
+	first, err := svc.Issue(ctx, 1)
+	require.NoError(t, err)
+
+	again, err := svc.Issue(ctx, 1)
+	require.NoError(t, err)
+
+	_, missingErr := svc.Issue(ctx, 3)
+
+	// Assert
+	require.Equal(t, "800000000011", first.Number)
+	require.Equal(t, first, again)
+	require.ErrorIs(t, missingErr, wallet.ErrWalletNotFound)
+}
+
+func TestIssueMissing(t *testing.T) {
+	// Arrange
+	repo := newFakeRepository(1, 2, 5)
+	svc, err := NewService(repo, mod97Config())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = svc.Issue(ctx, 2)
+	require.NoError(t, err)
+
+	// Act
+	issued, err := svc.IssueMissing(ctx)
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, 2, issued)
+	require.Len(t, repo.byUser, 3)
+
+	issued, err = svc.IssueMissing(ctx)
+	require.NoError(t, err)
+	require.Zero(t, issued)
+}
+
+func TestResolveVirtualAccount(t *testing.T) {
+	// Arrange
+	repo := newFakeRepository(7)
+	svc, err := NewService(repo, mod97Config())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	va, err := svc.Issue(ctx, 7)
+	require.NoError(t, err)
+
+	// Act
+	userID, err := svc.ResolveVirtualAccount(ctx, va.Number)
+	_, invalidErr := svc.ResolveVirtualAccount(ctx, "DE00370400440000000007")
+	_, unknownErr := svc.ResolveVirtualAccount(ctx, "DE98370400440000000042")
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, 7, userID)
+	require.ErrorIs(t, invalidErr, bankimport.ErrUnknownAccount)
+	require.ErrorIs(t, invalidErr, ErrInvalidNumber)
+	require.ErrorIs(t, unknownErr, bankimport.ErrUnknownAccount)
+	require.ErrorIs(t, unknownErr, ErrAccountNotFound)
+}
@@ -0,0 +1,232 @@
+// Package app builds the wallet service from its configuration: connections,
+// cache, repositories, services, background workers and the router, each handed
+// explicitly to what uses it.
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/amelonpie/wallet-service/internal/account"
+	"github.com/amelonpie/wallet-service/internal/database"
+	"github.com/amelonpie/wallet-service/internal/endpoint"
+	"github.com/amelonpie/wallet-service/internal/export"
+	"github.com/amelonpie/wallet-service/internal/health"
+	"github.com/amelonpie/wallet-service/internal/metrics"
+	"github.com/amelonpie/wallet-service/internal/payout"
+	"github.com/amelonpie/wallet-service/internal/reconcile"
+	"github.com/amelonpie/wallet-service/internal/stream"
+	"github.com/amelonpie/wallet-service/internal/tracing"
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/amelonpie/wallet-service/internal/webhook"
+	"github.com/amelonpie/wallet-service/pkg/config"
+	"github.com/amelonpie/wallet-service/pkg/lifecycle"
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// App is the wired application. Its workers run until Stop.
+type App struct {
+	Router   *gin.Engine
+	Endpoint *endpoint.Endpoint
+	// Settings applies reloaded settings to the loggers, CORS and endpoint limits
+	Settings *config.Reloader[Settings]
+
+	lc *lifecycle.Group
+}
+
+// New builds the application and starts its background workers. With the memory
+// repository nothing connects to Postgres, and webhooks, payouts and virtual
+// accounts, which only Postgres stores, are left out.
+func New(cfg Config) (*App, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	lc := lifecycle.New()
+	lc.OnClose("database connections", database.Close)
+
+	a, err := build(cfg, lc)
+	if err != nil {
+		// release what was started before the failure
+		_ = lc.Stop(context.Background())
+
+		return nil, err
+	}
+
+	return a, nil
+}
+
+//nolint:funlen // one step per component
+func build(cfg Config, lc *lifecycle.Group) (*App, error) {
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+
+	lc.OnClose("tracing", func() error { return shutdownTracing(context.Background()) })
+
+	db, err := openPostgres(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var rdb *redis.Client
+	if cfg.Cache.Backend == wallet.CacheBackendRedis || cfg.Stream.Backend == stream.BackendRedis {
+		rdb = cfg.Database.NewRedisClient()
+	}
+
+	repo := wallet.NewMemoryRepository(cfg.Repository.MemoryWallets)
+	if db != nil {
+		var replicas *database.ReplicaSet
+
+		if replicas, err = openReplicas(cfg, db); err != nil {
+			return nil, err
+		}
+
+		if replicas != nil {
+			lc.Go("replica lag monitor", replicas.Run)
+		}
+
+		repo = wallet.NewReplicatedRepository(db, replicas)
+	}
+
+	cache, err := wallet.NewCache(cfg.Cache, rdb)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize balance cache: %w", err)
+	}
+
+	broker, err := stream.New(cfg.Stream, rdb)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize stream broker: %w", err)
+	}
+
+	// workers stop in reverse order: the webhook dispatcher, which relays the
+	// events of the last requests, stops last
+	var hookRepo webhook.Repository
+
+	notifiers := []wallet.Notifier{broker}
+
+	if db != nil {
+		hookRepo = webhook.NewRepository(db)
+		dispatcher := webhook.NewDispatcher(hookRepo, cfg.Webhook)
+		notifiers = append([]wallet.Notifier{dispatcher}, notifiers...)
+
+		lc.Go("webhook dispatcher", dispatcher.Run)
+	}
+
+	lc.Go("stream broker", broker.Run)
+
+	svc := wallet.NewService(repo, cache, notifiers...)
+
+	ep := endpoint.New(svc)
+	ep.Stream = broker
+	ep.Websocket = cfg.Stream.Websocket
+	ep.Exporter = export.New(cfg.Export)
+	ep.Health = health.New(db, rdb, svc.CacheState, cfg.Health)
+
+	if db != nil {
+		ep.Webhooks = webhook.NewService(hookRepo, cfg.Webhook)
+		ep.Payouts = payout.NewService(payout.NewRepository(db), svc, cfg.Payout)
+
+		ep.Accounts, err = account.NewService(account.NewRepository(db), cfg.Account)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize virtual accounts: %w", err)
+		}
+
+		lc.Go("virtual account issuer", ep.Accounts.Run)
+
+		// the memory repository has nothing to reconcile and computes past
+		// balances from its full history
+		if cfg.Reconcile.Interval > 0 {
+			reconciler := reconcile.New(wallet.NewAdmin(db, cache), cache, cfg.Reconcile)
+			lc.Go("reconciler", database.Exclusive(db, reconcile.LockID, "reconciler", reconciler.Run))
+		}
+
+		if cfg.Snapshot.Interval > 0 {
+			snapshots := wallet.NewSnapshotWriter(db, cfg.Snapshot)
+			lc.Go("snapshot writer", database.Exclusive(db, wallet.SnapshotLockID, "snapshot writer", snapshots.Run))
+		}
+	}
+
+	origins := &corsOrigins{}
+	ep.AllowOrigin = origins.allow
+	router := newRouter(cfg, origins)
+	endpoint.Register(router, ep)
+
+	settings := config.NewReloader(cfg.Settings, ReadSettings)
+	settings.Subscribe(func(s Settings) {
+		log.SetLevels(s.LogLevel, s.ModuleLevels)
+		origins.set(s.CORSOrigins)
+		ep.SetLimits(s.Limits)
+	})
+
+	return &App{Router: router, Endpoint: ep, Settings: settings, lc: lc}, nil
+}
+
+// openPostgres connects unless wallets are kept in memory, then it returns nil
+func openPostgres(cfg Config) (*sql.DB, error) {
+	if cfg.Repository.Backend == wallet.RepositoryBackendMemory {
+		return nil, nil //nolint:nilnil // no database is a valid outcome
+	}
+
+	db, err := cfg.Database.ConnectPostgre()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+
+	if err = metrics.RegisterDB(db, "wallet"); err != nil {
+		return nil, fmt.Errorf("failed to register connection pool metrics: %w", err)
+	}
+
+	return db, nil
+}
+
+// openReplicas connects to the read replicas of db, it returns nil without any
+func openReplicas(cfg Config, db *sql.DB) (*database.ReplicaSet, error) {
+	if len(cfg.Database.Replicas.Addrs) == 0 {
+		return nil, nil //nolint:nilnil // reads stay on the primary
+	}
+
+	replicas, err := cfg.Database.ConnectReplicas()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the PostgreSQL replicas: %w", err)
+	}
+
+	for i, replica := range replicas {
+		if err = metrics.RegisterDB(replica, fmt.Sprintf("wallet_replica_%d", i)); err != nil {
+			return nil, fmt.Errorf("failed to register connection pool metrics: %w", err)
+		}
+	}
+
+	return database.NewReplicaSet(db, replicas, cfg.Database.Replicas), nil
+}
+
+func newRouter(cfg Config, origins *corsOrigins) *gin.Engine {
+	// gin.SetMode(gin.ReleaseMode)
+	router := gin.Default()
+
+	router.Use(cors.New(cors.Config{
+		AllowOriginFunc:  origins.allow,
+		AllowMethods:     []string{"GET", "POST", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"*"},
+		ExposeHeaders:    []string{"Cache-Control", "Content-Type", endpoint.HeaderRequestID},
+		AllowCredentials: true,
+	}))
+
+	router.Use(tracing.Middleware(cfg.AppName))
+	router.Use(endpoint.RequestID())
+	router.Use(metrics.Middleware())
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	return router
+}
+
+// Stop stops the background workers, then closes the connections. Requests
+// should be drained before.
+func (a *App) Stop(ctx context.Context) error {
+	return a.lc.Stop(ctx)
+}
@@ -0,0 +1,161 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/account"
+	"github.com/amelonpie/wallet-service/internal/database"
+	"github.com/amelonpie/wallet-service/internal/export"
+	"github.com/amelonpie/wallet-service/internal/health"
+	"github.com/amelonpie/wallet-service/internal/stream"
+	"github.com/amelonpie/wallet-service/internal/tracing"
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/amelonpie/wallet-service/internal/webhook"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+// memoryConfig runs everything in process, nothing connects to Postgres or Redis
+func memoryConfig() Config {
+	return Config{
+		AppName: "wallet-service-test",
+		Server:  ServerConfig{Port: 3000, ShutdownTimeout: time.Second},
+		Settings: Settings{
+			LogLevel:    logrus.InfoLevel,
+			CORSOrigins: []string{"*"},
+		},
+		Database: &database.Config{},
+		Repository: wallet.RepositoryConfig{
+			Backend:       wallet.RepositoryBackendMemory,
+			MemoryWallets: map[int]float64{1: 100, 2: 0},
+		},
+		Cache: wallet.CacheConfig{
+			Backend:         wallet.CacheBackendMemory,
+			MemorySize:      100,
+			BalanceTTL:      time.Minute,
+			InvalidationTTL: time.Minute,
+		},
+		Stream:  stream.Config{Backend: stream.BackendMemory, ReplaySize: 10},
+		Webhook: webhook.DispatcherConfig{},
+		Export:  export.Config{Currency: "USD", BankID: "WALLETXX"},
+		Account: account.Config{},
+		Health:  health.Config{Timeout: time.Second},
+		Tracing: tracing.Config{Exporter: tracing.ExporterNone},
+	}
+}
+
+type client struct {
+	t      *testing.T
+	router *gin.Engine
+}
+
+func (c client) do(method, url, body string) *httptest.ResponseRecorder {
+	c.t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(c.t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-ID", "e2e-"+strings.ReplaceAll(strings.Trim(url, "/"), "/", "-"))
+
+	w := httptest.NewRecorder()
+	c.router.ServeHTTP(w, req)
+
+	require.Equal(c.t, req.Header.Get("X-Request-ID"), w.Header().Get("X-Request-ID"))
+
+	return w
+}
+
+func (c client) balance(userID string) float64 {
+	c.t.Helper()
+
+	w := c.do(http.MethodGet, "/wallet/wallet/"+userID+"/balance", "")
+	require.Equal(c.t, http.StatusOK, w.Code, w.Body.String())
+
+	var body struct {
+		Balance float64 `json:"balance"`
+	}
+	require.NoError(c.t, json.Unmarshal(w.Body.Bytes(), &body))
+
+	return body.Balance
+}
+
+func TestApp_EndToEnd(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+
+	a, err := New(memoryConfig())
+	require.NoError(t, err)
+
+	api := client{t: t, router: a.Router}
+
+	// Act
+	deposit := api.do(http.MethodPost, "/wallet/1/deposit", `{"amount": 50}`)
+	withdraw := api.do(http.MethodPost, "/wallet/1/withdraw", `{"amount": 30}`)
+	transfer := api.do(http.MethodPost, "/wallet/transfer", `{"from_user_id": 1, "to_user_id": 2, "amount": 20}`)
+	overdraw := api.do(http.MethodPost, "/wallet/2/withdraw", `{"amount": 500}`)
+	history := api.do(http.MethodGet, "/wallet/wallet/1/transactions", "")
+	ready := api.do(http.MethodGet, "/readyz", "")
+	webhooks := api.do(http.MethodPost, "/webhooks", `{}`)
+
+	// Assert
+	require.Equal(t, http.StatusOK, deposit.Code, deposit.Body.String())
+	require.Equal(t, http.StatusOK, withdraw.Code, withdraw.Body.String())
+	require.Equal(t, http.StatusOK, transfer.Code, transfer.Body.String())
+	require.Equal(t, http.StatusInternalServerError, overdraw.Code)
+	require.Contains(t, overdraw.Body.String(), wallet.ErrInsufficientFunds.Error())
+
+	require.InDelta(t, 100.0, api.balance("1"), 0.001)
+	require.InDelta(t, 20.0, api.balance("2"), 0.001)
+
+	var transactions []wallet.Transaction
+	require.NoError(t, json.Unmarshal(history.Body.Bytes(), &transactions))
+	require.Len(t, transactions, 3)
+
+	require.Equal(t, http.StatusOK, ready.Code)
+	require.JSONEq(t, `{"ready":true,"checks":{"postgres":"disabled","redis":"disabled"}}`, ready.Body.String())
+
+	// webhooks are stored in Postgres only
+	require.Equal(t, http.StatusNotFound, webhooks.Code)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, a.Stop(ctx))
+}
+
+func TestApp_CORSPreflight(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+
+	a, err := New(memoryConfig())
+	require.NoError(t, err)
+
+	t.Cleanup(func() { require.NoError(t, a.Stop(context.Background())) })
+
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodDelete} {
+		req := httptest.NewRequest(http.MethodOptions, "/webhooks/1", nil)
+		req.Header.Set("Origin", "https://dashboard.example")
+		req.Header.Set("Access-Control-Request-Method", method)
+
+		w := httptest.NewRecorder()
+
+		// Act
+		a.Router.ServeHTTP(w, req)
+
+		// Assert
+		require.Equal(t, http.StatusNoContent, w.Code, method)
+		require.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), method)
+	}
+}
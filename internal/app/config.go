@@ -0,0 +1,179 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/account"
+	"github.com/amelonpie/wallet-service/internal/database"
+	"github.com/amelonpie/wallet-service/internal/export"
+	"github.com/amelonpie/wallet-service/internal/health"
+	"github.com/amelonpie/wallet-service/internal/payout"
+	"github.com/amelonpie/wallet-service/internal/reconcile"
+	"github.com/amelonpie/wallet-service/internal/stream"
+	"github.com/amelonpie/wallet-service/internal/tracing"
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/amelonpie/wallet-service/internal/webhook"
+	"github.com/spf13/viper"
+)
+
+const maxPort = 65535
+
+// Config of every part of the application
+type Config struct {
+	AppName    string
+	Server     ServerConfig
+	Settings   Settings
+	Database   *database.Config
+	Repository wallet.RepositoryConfig
+	Cache      wallet.CacheConfig
+	Stream     stream.Config
+	Webhook    webhook.DispatcherConfig
+	Reconcile  reconcile.Config
+	Snapshot   wallet.SnapshotConfig
+	Export     export.Config
+	Payout     payout.Config
+	Account    account.Config
+	Health     health.Config
+	Tracing    tracing.Config
+}
+
+// ServerConfig of the HTTP server
+type ServerConfig struct {
+	Port              int
+	ReadHeaderTimeout time.Duration
+	// DrainDelay is how long /readyz fails before the server stops accepting
+	// connections, for load balancers to take the instance out
+	DrainDelay time.Duration
+	// ShutdownTimeout bounds waiting for requests in flight and background workers
+	ShutdownTimeout time.Duration
+}
+
+// Addr is the listen address of the server
+func (c ServerConfig) Addr() string {
+	return ":" + strconv.Itoa(c.Port)
+}
+
+func NewServerConfig() ServerConfig {
+	viper.SetDefault("api_port", 3000)
+	viper.SetDefault("server.read_header_timeout", "10s")
+	viper.SetDefault("server.drain_delay", "0s")
+	viper.SetDefault("server.shutdown_timeout", "15s")
+
+	return ServerConfig{
+		Port:              viper.GetInt("api_port"),
+		ReadHeaderTimeout: viper.GetDuration("server.read_header_timeout"),
+		DrainDelay:        viper.GetDuration("server.drain_delay"),
+		ShutdownTimeout:   viper.GetDuration("server.shutdown_timeout"),
+	}
+}
+
+// NewConfig reads the configuration loaded by config.Initialize and validates it
+func NewConfig() (Config, error) {
+	repository, err := wallet.NewRepositoryConfig()
+	if err != nil {
+		return Config{}, err //nolint:wrapcheck // names the key that failed
+	}
+
+	settings, err := NewSettings()
+	if err != nil {
+		return Config{}, err
+	}
+
+	dbConfig, err := database.NewDatabaseConfig()
+	if err != nil {
+		return Config{}, err //nolint:wrapcheck // names the secret that failed
+	}
+
+	cfg := Config{
+		AppName:    viper.GetString("app_name"),
+		Server:     NewServerConfig(),
+		Settings:   settings,
+		Database:   dbConfig,
+		Repository: repository,
+		Cache:      wallet.NewCacheConfig(),
+		Stream:     stream.NewConfig(),
+		Webhook:    webhook.NewDispatcherConfig(),
+		Reconcile:  reconcile.NewConfig(),
+		Snapshot:   wallet.NewSnapshotConfig(),
+		Export:     export.NewConfig(),
+		Payout:     payout.NewConfig(),
+		Account:    account.NewConfig(),
+		Health:     health.NewConfig(),
+		Tracing:    tracing.NewConfig(),
+	}
+
+	if err = cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// Validate reports every setting the application cannot start with
+func (c Config) Validate() error {
+	errs := []error{c.Settings.Validate()}
+
+	db := c.Database
+	if db == nil {
+		db = &database.Config{}
+	}
+
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: "+format, append([]any{ErrInvalidConfig}, args...)...))
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > maxPort {
+		invalid("api_port %d is not a port", c.Server.Port)
+	}
+
+	if c.Server.ShutdownTimeout <= 0 {
+		invalid("server.shutdown_timeout must be positive")
+	}
+
+	switch c.Repository.Backend {
+	case wallet.RepositoryBackendPostgres:
+		if db.PostgreAddr == "" {
+			invalid("postgresql.address is required by the postgres repository")
+		}
+	case wallet.RepositoryBackendMemory:
+	default:
+		invalid("unknown repository.backend %q", c.Repository.Backend)
+	}
+
+	if db.Pool.MaxOpenConns < 0 || db.Pool.MaxIdleConns < 0 {
+		invalid("postgresql.pool connection counts must not be negative")
+	}
+
+	if db.Pool.MaxOpenConns > 0 && db.Pool.MaxIdleConns > db.Pool.MaxOpenConns {
+		invalid("postgresql.pool.max_idle_conns %d exceeds max_open_conns %d", db.Pool.MaxIdleConns, db.Pool.MaxOpenConns)
+	}
+
+	if len(db.Replicas.Addrs) > 0 && (db.Replicas.MaxLag <= 0 || db.Replicas.CheckInterval <= 0) {
+		invalid("postgresql.replicas.max_lag and check_interval must be positive")
+	}
+
+	if len(db.Replicas.Addrs) > 0 && c.Repository.Backend != wallet.RepositoryBackendPostgres {
+		invalid("postgresql.replicas need the postgres repository")
+	}
+
+	switch c.Cache.Backend {
+	case wallet.CacheBackendRedis, wallet.CacheBackendMemory, wallet.CacheBackendNone:
+	default:
+		invalid("unknown cache.backend %q", c.Cache.Backend)
+	}
+
+	switch c.Stream.Backend {
+	case stream.BackendRedis, stream.BackendMemory:
+	default:
+		invalid("unknown stream.backend %q", c.Stream.Backend)
+	}
+
+	if (c.Cache.Backend == wallet.CacheBackendRedis || c.Stream.Backend == stream.BackendRedis) && db.RedisAddr == "" {
+		invalid("redis.address is required by the redis cache and stream backends")
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amelonpie/wallet-service/internal/database"
+	"github.com/amelonpie/wallet-service/internal/stream"
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{
+			name:   "valid",
+			modify: func(*Config) {},
+		},
+		{
+			name:   "port out of range",
+			modify: func(c *Config) { c.Server.Port = 70000 },
+			want:   "api_port 70000 is not a port",
+		},
+		{
+			name: "postgres repository without address",
+			modify: func(c *Config) {
+				c.Repository.Backend = wallet.RepositoryBackendPostgres
+				c.Database = &database.Config{}
+			},
+			want: "postgresql.address is required",
+		},
+		{
+			name: "redis stream without address",
+			modify: func(c *Config) {
+				c.Stream.Backend = stream.BackendRedis
+				c.Database = &database.Config{}
+			},
+			want: "redis.address is required",
+		},
+		{
+			name: "more idle than open connections",
+			modify: func(c *Config) {
+				c.Database.Pool = database.PoolConfig{MaxOpenConns: 5, MaxIdleConns: 10}
+			},
+			want: "postgresql.pool.max_idle_conns 10 exceeds max_open_conns 5",
+		},
+		{
+			name:   "unknown cache backend",
+			modify: func(c *Config) { c.Cache.Backend = "memcached" },
+			want:   `unknown cache.backend "memcached"`,
+		},
+		{
+			name:   "origin without scheme",
+			modify: func(c *Config) { c.Settings.CORSOrigins = []string{"app.example.com"} },
+			want:   `"app.example.com" is neither * nor an http(s) origin`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			cfg := memoryConfig()
+			tt.modify(&cfg)
+
+			// Act
+			err := cfg.Validate()
+
+			// Assert
+			if tt.want == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, ErrInvalidConfig)
+			require.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestApp_ReloadSettings(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	t.Cleanup(viper.Reset)
+
+	a, err := New(memoryConfig())
+	require.NoError(t, err)
+
+	t.Cleanup(func() { require.NoError(t, a.Stop(context.Background())) })
+
+	deposit := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/wallet/1/deposit", strings.NewReader(`{"amount": 50}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Origin", origin)
+
+		w := httptest.NewRecorder()
+		a.Router.ServeHTTP(w, req)
+
+		return w
+	}
+
+	// Act
+	changed := viper.New()
+	changed.Set("log_level", "warn")
+	changed.Set("cors.allow_origins", []string{"https://app.example.com"})
+	changed.Set("limits.max_amount", 10)
+	accepted := a.Settings.Reload(changed)
+
+	overLimit := deposit("https://app.example.com")
+	otherOrigin := deposit("https://evil.example.com")
+
+	changed.Set("limits.max_amount", -1)
+	rejected := a.Settings.Reload(changed)
+
+	// Assert
+	require.NoError(t, accepted)
+	require.Equal(t, logrus.WarnLevel, a.Endpoint.Logger.Logger.GetLevel())
+	require.Equal(t, http.StatusBadRequest, overLimit.Code)
+	require.Contains(t, overLimit.Body.String(), "amount exceeds the limit of 10")
+	require.Equal(t, http.StatusForbidden, otherOrigin.Code)
+
+	require.ErrorIs(t, rejected, ErrInvalidConfig)
+	require.InDelta(t, 10.0, a.Settings.Current().Limits.MaxAmount, 0)
+}
@@ -0,0 +1,25 @@
+package app
+
+import (
+	"slices"
+	"sync/atomic"
+)
+
+// corsOrigins are the origins the CORS middleware allows, replaced on reload
+type corsOrigins struct {
+	origins atomic.Pointer[[]string]
+}
+
+func (o *corsOrigins) set(origins []string) {
+	origins = slices.Clone(origins)
+	o.origins.Store(&origins)
+}
+
+func (o *corsOrigins) allow(origin string) bool {
+	origins := o.origins.Load()
+	if origins == nil {
+		return false
+	}
+
+	return slices.Contains(*origins, "*") || slices.Contains(*origins, origin)
+}
@@ -0,0 +1,6 @@
+package app
+
+import "errors"
+
+// ErrInvalidConfig wraps every problem Validate finds
+var ErrInvalidConfig = errors.New("invalid configuration")
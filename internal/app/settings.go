@@ -0,0 +1,82 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/amelonpie/wallet-service/internal/endpoint"
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Settings are the part of the configuration applied while the service runs,
+// everything else is read once at start
+type Settings struct {
+	LogLevel logrus.Level
+	// ModuleLevels override LogLevel by logger name, like endpoint or wallet
+	ModuleLevels map[string]logrus.Level
+	// CORSOrigins are the allowed origins like https://app.example.com, or "*"
+	CORSOrigins []string
+	Limits      endpoint.Limits
+}
+
+// NewSettings reads the settings from the global viper and validates them
+func NewSettings() (Settings, error) {
+	return ReadSettings(viper.GetViper())
+}
+
+// ReadSettings reads the settings from v and validates them, it is called again
+// with the changed config file on every change
+func ReadSettings(v *viper.Viper) (Settings, error) {
+	v.SetDefault("log_level", "info")
+	v.SetDefault("cors.allow_origins", []string{"*"})
+	v.SetDefault("limits.max_amount", 0)
+
+	level, err := logrus.ParseLevel(v.GetString("log_level"))
+	if err != nil {
+		return Settings{}, fmt.Errorf("%w: log_level: %w", ErrInvalidConfig, err)
+	}
+
+	modules, err := log.ParseLevels(v.GetStringMapString("log_levels"))
+	if err != nil {
+		return Settings{}, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	settings := Settings{
+		LogLevel:     level,
+		ModuleLevels: modules,
+		CORSOrigins:  v.GetStringSlice("cors.allow_origins"),
+		Limits: endpoint.Limits{
+			MaxAmount: v.GetFloat64("limits.max_amount"),
+		},
+	}
+
+	if err = settings.Validate(); err != nil {
+		return Settings{}, err
+	}
+
+	return settings, nil
+}
+
+// Validate reports every invalid setting
+func (s Settings) Validate() error {
+	var errs []error
+
+	if len(s.CORSOrigins) == 0 {
+		errs = append(errs, fmt.Errorf("%w: cors.allow_origins is empty", ErrInvalidConfig))
+	}
+
+	for _, origin := range s.CORSOrigins {
+		if origin != "*" && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			errs = append(errs, fmt.Errorf("%w: cors.allow_origins: %q is neither * nor an http(s) origin", ErrInvalidConfig, origin))
+		}
+	}
+
+	if s.Limits.MaxAmount < 0 {
+		errs = append(errs, fmt.Errorf("%w: limits.max_amount must not be negative", ErrInvalidConfig))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,12 @@
+package bankimport
+
+import "errors"
+
+var (
+	ErrUnknownFormat   = errors.New("unknown bank file format")
+	ErrInvalidFile     = errors.New("invalid bank file")
+	ErrItemNotFound    = errors.New("suspense item not found")
+	ErrItemNotOpen     = errors.New("suspense item is not open")
+	ErrUnknownAccount  = errors.New("unknown virtual account")
+	ErrDuplicateCredit = errors.New("credit already imported")
+)
@@ -0,0 +1,245 @@
+package bankimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/spf13/viper"
+)
+
+// referencePattern finds the wallet a payment reference names, e.g. "WALLET-42"
+var referencePattern = regexp.MustCompile(`(?i)\bWALLET[- ]?(\d+)\b`) //nolint:gochecknoglobals // compiled once
+
+func NewConfig() Config {
+	viper.SetDefault("bank_import.currency", "USD")
+
+	return Config{
+		Currency: strings.ToUpper(viper.GetString("bank_import.currency")),
+	}
+}
+
+// Init connects the importer to the suspense queue in PostgreSQL
+func Init(wallets Depositor, accounts AccountResolver, cfg Config) (*Importer, error) {
+	repo, err := InitRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	return New(repo, wallets, accounts, cfg), nil
+}
+
+// New creates an importer, accounts may be nil when no virtual accounts are issued
+func New(repo Repository, wallets Depositor, accounts AccountResolver, cfg Config) *Importer {
+	return &Importer{
+		repo:     repo,
+		wallets:  wallets,
+		accounts: accounts,
+		cfg:      cfg,
+		logger:   log.NewLogger("bankimport").WithField("module", "importer"),
+	}
+}
+
+// DepositKey is the idempotency key of the deposit of a bank credit
+func DepositKey(bankReference string) string {
+	return "bank:" + bankReference
+}
+
+// Import deposits every credit of the file or queues it in suspense. Importing a file
+// again, or an overlapping one, deposits nothing twice. An error other than a wallet
+// refusing the deposit stops the import, the report tells how far it got.
+func (i *Importer) Import(ctx context.Context, r io.Reader, f Format) (Report, error) {
+	credits, err := Parse(r, f)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{Credits: len(credits), Results: make([]Result, 0, len(credits))}
+
+	for _, credit := range credits {
+		result, err := i.importCredit(ctx, credit)
+		if err != nil {
+			return report, fmt.Errorf("failed to import credit %s: %w", credit.BankReference, err)
+		}
+
+		switch result.Outcome {
+		case OutcomeDeposited:
+			report.Deposited++
+		case OutcomeDuplicate:
+			report.Duplicates++
+		case OutcomeSuspended:
+			report.Suspended++
+		}
+
+		report.Results = append(report.Results, result)
+	}
+
+	i.logger.WithField("format", f).WithField("credits", report.Credits).
+		WithField("deposited", report.Deposited).WithField("duplicates", report.Duplicates).
+		WithField("suspended", report.Suspended).Info("bank file imported")
+
+	return report, nil
+}
+
+func (i *Importer) importCredit(ctx context.Context, credit Credit) (Result, error) {
+	result := Result{BankReference: credit.BankReference, Amount: credit.Amount}
+
+	suspended, err := i.repo.SuspenseExists(ctx, credit.BankReference)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if suspended {
+		result.Outcome = OutcomeDuplicate
+		return result, nil
+	}
+
+	if credit.Currency != i.cfg.Currency {
+		return i.suspend(ctx, result, credit, "currency "+credit.Currency+" differs from "+i.cfg.Currency)
+	}
+
+	userID, err := i.match(ctx, credit)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if userID == 0 {
+		return i.suspend(ctx, result, credit, "no wallet matches the reference or virtual account")
+	}
+
+	result.UserID = userID
+
+	_, err = i.wallets.DepositIdempotent(ctx, userID, credit.Amount, DepositKey(credit.BankReference))
+
+	switch {
+	case err == nil:
+		result.Outcome = OutcomeDeposited
+		return result, nil
+	case errors.Is(err, wallet.ErrDuplicateDeposit):
+		result.Outcome = OutcomeDuplicate
+		return result, nil
+	case errors.Is(err, wallet.ErrWalletNotFound), errors.Is(err, wallet.ErrWalletFrozen):
+		return i.suspend(ctx, result, credit, fmt.Sprintf("wallet %d: %s", userID, rootCause(err)))
+	default:
+		return Result{}, err
+	}
+}
+
+// match returns the wallet the credit is for, 0 if nothing matches. The virtual
+// account the money was sent to wins over a reference the payer typed.
+func (i *Importer) match(ctx context.Context, credit Credit) (int, error) {
+	if credit.VirtualAccount != "" && i.accounts != nil {
+		userID, err := i.accounts.ResolveVirtualAccount(ctx, credit.VirtualAccount)
+		if err == nil {
+			return userID, nil
+		}
+
+		if !errors.Is(err, ErrUnknownAccount) {
+			return 0, fmt.Errorf("failed to resolve virtual account: %w", err)
+		}
+	}
+
+	if m := referencePattern.FindStringSubmatch(credit.Reference); m != nil {
+		if userID, err := strconv.Atoi(m[1]); err == nil {
+			return userID, nil
+		}
+	}
+
+	return 0, nil
+}
+
+func (i *Importer) suspend(ctx context.Context, result Result, credit Credit, reason string) (Result, error) {
+	result.Outcome = OutcomeSuspended
+	result.Reason = reason
+
+	_, err := i.repo.AddSuspense(ctx, credit, reason)
+	if errors.Is(err, ErrDuplicateCredit) {
+		result.Outcome = OutcomeDuplicate
+		result.Reason = ""
+
+		return result, nil
+	}
+
+	if err != nil {
+		return Result{}, err
+	}
+
+	i.logger.WithField("bank_reference", credit.BankReference).WithField("reason", reason).
+		Warn("credit queued in suspense")
+
+	return result, nil
+}
+
+// rootCause is the sentinel at the end of a wrapped error chain
+func rootCause(err error) error {
+	for {
+		next := errors.Unwrap(err)
+		if next == nil {
+			return err
+		}
+
+		err = next
+	}
+}
+
+// Suspense lists the suspense items with the status, all items for ""
+func (i *Importer) Suspense(ctx context.Context, status string) ([]SuspenseItem, error) {
+	items, err := i.repo.ListSuspense(ctx, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list suspense items: %w", err)
+	}
+
+	return items, nil
+}
+
+// Allocate deposits an open suspense item into the wallet chosen by an admin. The
+// item is claimed first, so two admins cannot allocate it to different wallets.
+// Claim and deposit commit separately: an item left allocated by an interrupted
+// allocation, or one whose reopening failed, is finished by allocating it to the
+// same wallet again. The deposit is keyed by bank reference, so it is booked once.
+func (i *Importer) Allocate(ctx context.Context, itemID, userID int, actor string) (SuspenseItem, error) {
+	item, err := i.repo.ClaimSuspense(ctx, itemID, userID, actor)
+	if errors.Is(err, ErrItemNotOpen) {
+		item, err = i.claimed(ctx, itemID, userID)
+	}
+
+	if err != nil {
+		return SuspenseItem{}, fmt.Errorf("failed to claim suspense item %d: %w", itemID, err)
+	}
+
+	_, err = i.wallets.DepositIdempotent(ctx, userID, item.Credit.Amount, DepositKey(item.Credit.BankReference))
+	if err != nil && !errors.Is(err, wallet.ErrDuplicateDeposit) {
+		if releaseErr := i.repo.ReleaseSuspense(ctx, itemID); releaseErr != nil {
+			i.logger.WithField("err", releaseErr).WithField("item_id", itemID).
+				Error("failed to reopen suspense item, allocate it to the same wallet again")
+		}
+
+		return SuspenseItem{}, fmt.Errorf("failed to allocate suspense item %d to user %d: %w", itemID, userID, err)
+	}
+
+	i.logger.WithField("item_id", itemID).WithField("user_id", userID).WithField("actor", actor).
+		WithField("amount", item.Credit.Amount).Info("suspense item allocated")
+
+	return item, nil
+}
+
+// claimed returns an item already allocated to the user, whose deposit Allocate
+// books or finds booked. An item allocated to another wallet is not open.
+func (i *Importer) claimed(ctx context.Context, itemID, userID int) (SuspenseItem, error) {
+	item, err := i.repo.GetSuspense(ctx, itemID)
+	if err != nil {
+		return SuspenseItem{}, err
+	}
+
+	if item.Status != StatusAllocated || item.AllocatedUserID != userID {
+		return SuspenseItem{}, ErrItemNotOpen
+	}
+
+	return item, nil
+}
@@ -0,0 +1,293 @@
+package bankimport
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepository is an in-process suspense queue
+type fakeRepository struct {
+	mu    sync.Mutex
+	items []SuspenseItem
+}
+
+func (r *fakeRepository) AddSuspense(_ context.Context, credit Credit, reason string) (SuspenseItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, item := range r.items {
+		if item.Credit.BankReference == credit.BankReference {
+			return SuspenseItem{}, ErrDuplicateCredit
+		}
+	}
+
+	item := SuspenseItem{ItemID: len(r.items) + 1, Credit: credit, Reason: reason, Status: StatusOpen}
+	r.items = append(r.items, item)
+
+	return item, nil
+}
+
+func (r *fakeRepository) SuspenseExists(_ context.Context, bankReference string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, item := range r.items {
+		if item.Credit.BankReference == bankReference {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *fakeRepository) ListSuspense(_ context.Context, status string) ([]SuspenseItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var items []SuspenseItem
+
+	for _, item := range r.items {
+		if status == "" || item.Status == status {
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+func (r *fakeRepository) GetSuspense(_ context.Context, itemID int) (SuspenseItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if itemID < 1 || itemID > len(r.items) {
+		return SuspenseItem{}, ErrItemNotFound
+	}
+
+	return r.items[itemID-1], nil
+}
+
+func (r *fakeRepository) ClaimSuspense(_ context.Context, itemID, userID int, actor string) (SuspenseItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if itemID < 1 || itemID > len(r.items) {
+		return SuspenseItem{}, ErrItemNotFound
+	}
+
+	item := &r.items[itemID-1]
+	if item.Status != StatusOpen {
+		return SuspenseItem{}, ErrItemNotOpen
+	}
+
+	item.Status = StatusAllocated
+	item.AllocatedUserID = userID
+	item.AllocatedBy = actor
+
+	return *item, nil
+}
+
+func (r *fakeRepository) ReleaseSuspense(_ context.Context, itemID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	item := &r.items[itemID-1]
+	item.Status = StatusOpen
+	item.AllocatedUserID = 0
+	item.AllocatedBy = ""
+
+	return nil
+}
+
+// fakeAccounts resolves virtual account numbers from a map
+type fakeAccounts map[string]int
+
+func (a fakeAccounts) ResolveVirtualAccount(_ context.Context, number string) (int, error) {
+	userID, ok := a[number]
+	if !ok {
+		return 0, ErrUnknownAccount
+	}
+
+	return userID, nil
+}
+
+func newTestImporter(accounts AccountResolver) (*Importer, *fakeRepository, wallet.Service) {
+	repo := &fakeRepository{}
+	svc := wallet.NewService(wallet.NewMemoryRepository(map[int]float64{1: 0, 2: 0, 3: 0}), wallet.NewNopCache())
+
+	return New(repo, svc, accounts, Config{Currency: "USD"}), repo, svc
+}
+
+func importFile(t *testing.T, importer *Importer, name string, f Format) Report {
+	t.Helper()
+
+	file, err := os.Open(filepath.Join("testdata", name))
+	require.NoError(t, err)
+
+	defer file.Close()
+
+	report, err := importer.Import(context.Background(), file, f)
+	require.NoError(t, err)
+
+	return report
+}
+
+func balance(t *testing.T, svc wallet.Service, userID int) float64 {
+	t.Helper()
+
+	amount, err := svc.GetBalance(context.Background(), userID)
+	require.NoError(t, err)
+
+	return amount
+}
+
+func TestImport_CSV(t *testing.T) {
+	// Arrange
+	importer, repo, svc := newTestImporter(nil)
+
+	// Act
+	report := importFile(t, importer, "credits.csv", FormatCSV)
+
+	// Assert: the references name wallets 1 and 2, the invoice matches nothing
+	require.Equal(t, 3, report.Credits)
+	require.Equal(t, 2, report.Deposited)
+	require.Equal(t, 1, report.Suspended)
+	require.Equal(t, []Result{
+		{BankReference: "BR-1001", Amount: 150, UserID: 1, Outcome: OutcomeDeposited},
+		{BankReference: "BR-1003", Amount: 75.5, UserID: 2, Outcome: OutcomeDeposited},
+		{
+			BankReference: "BR-1004", Amount: 12, Outcome: OutcomeSuspended,
+			Reason: "no wallet matches the reference or virtual account",
+		},
+	}, report.Results)
+	require.InDelta(t, 150, balance(t, svc, 1), 0.001)
+	require.InDelta(t, 75.5, balance(t, svc, 2), 0.001)
+	require.Len(t, repo.items, 1)
+}
+
+func TestImport_Twice(t *testing.T) {
+	// Arrange
+	importer, repo, svc := newTestImporter(nil)
+	importFile(t, importer, "credits.csv", FormatCSV)
+
+	// Act
+	report := importFile(t, importer, "credits.csv", FormatCSV)
+
+	// Assert: nothing is deposited or queued twice
+	require.Equal(t, 3, report.Duplicates)
+	require.Zero(t, report.Deposited)
+	require.Zero(t, report.Suspended)
+	require.InDelta(t, 150, balance(t, svc, 1), 0.001)
+	require.Len(t, repo.items, 1)
+}
+
+func TestImport_Camt054(t *testing.T) {
+	// Arrange
+	importer, repo, svc := newTestImporter(fakeAccounts{"VA-0003": 3})
+
+	// Act
+	report := importFile(t, importer, "credits.camt054.xml", FormatCamt054)
+
+	// Assert: the virtual account routes the first credit, the collection account
+	// resolves to no wallet so the reference routes the second
+	require.Equal(t, 2, report.Deposited)
+	require.Equal(t, 1, report.Suspended)
+	require.InDelta(t, 120, balance(t, svc, 3), 0.001)
+	require.InDelta(t, 80, balance(t, svc, 1), 0.001)
+	require.Equal(t, "SVC-E4", repo.items[0].Credit.BankReference)
+	require.Equal(t, "currency EUR differs from USD", repo.items[0].Reason)
+}
+
+func TestImport_UnknownWalletSuspended(t *testing.T) {
+	// Arrange
+	importer, repo, _ := newTestImporter(nil)
+	file := "bank_reference,booking_date,amount,currency,reference\nBR-9,2026-09-01,10,USD,WALLET-99\n"
+
+	// Act
+	report, err := importer.Import(context.Background(), strings.NewReader(file), FormatCSV)
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, 1, report.Suspended)
+	require.Equal(t, 99, report.Results[0].UserID)
+	require.Contains(t, repo.items[0].Reason, wallet.ErrWalletNotFound.Error())
+}
+
+func TestAllocate(t *testing.T) {
+	// Arrange
+	importer, _, svc := newTestImporter(nil)
+	importFile(t, importer, "credits.csv", FormatCSV)
+
+	// Act
+	item, err := importer.Allocate(context.Background(), 1, 3, "ops")
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, StatusAllocated, item.Status)
+	require.Equal(t, "ops", item.AllocatedBy)
+	require.InDelta(t, 12, balance(t, svc, 3), 0.001)
+
+	open, err := importer.Suspense(context.Background(), StatusOpen)
+	require.NoError(t, err)
+	require.Empty(t, open)
+
+	// Act: a second allocation of the item is refused
+	_, err = importer.Allocate(context.Background(), 1, 2, "ops")
+
+	// Assert
+	require.ErrorIs(t, err, ErrItemNotOpen)
+	require.InDelta(t, 75.5, balance(t, svc, 2), 0.001)
+}
+
+func TestAllocate_FailedDepositReopens(t *testing.T) {
+	// Arrange
+	importer, repo, _ := newTestImporter(nil)
+	importFile(t, importer, "credits.csv", FormatCSV)
+
+	// Act
+	_, err := importer.Allocate(context.Background(), 1, 99, "ops")
+
+	// Assert
+	require.ErrorIs(t, err, wallet.ErrWalletNotFound)
+	require.Equal(t, StatusOpen, repo.items[0].Status)
+}
+
+func TestAllocate_FinishesInterruptedAllocation(t *testing.T) {
+	// Arrange: an allocation stopped after the claim, before the deposit
+	importer, repo, svc := newTestImporter(nil)
+	importFile(t, importer, "credits.csv", FormatCSV)
+
+	_, err := repo.ClaimSuspense(context.Background(), 1, 3, "ops")
+	require.NoError(t, err)
+
+	// Act
+	item, err := importer.Allocate(context.Background(), 1, 3, "ops")
+	require.NoError(t, err)
+
+	_, again := importer.Allocate(context.Background(), 1, 3, "ops")
+	_, other := importer.Allocate(context.Background(), 1, 2, "ops")
+
+	// Assert: the deposit is booked once, the item stays with its wallet
+	require.Equal(t, StatusAllocated, item.Status)
+	require.NoError(t, again)
+	require.ErrorIs(t, other, ErrItemNotOpen)
+	require.InDelta(t, 12, balance(t, svc, 3), 0.001)
+	require.InDelta(t, 75.5, balance(t, svc, 2), 0.001)
+}
+
+func TestAllocate_NotFound(t *testing.T) {
+	// Arrange
+	importer, _, _ := newTestImporter(nil)
+
+	// Act
+	_, err := importer.Allocate(context.Background(), 7, 1, "ops")
+
+	// Assert
+	require.ErrorIs(t, err, ErrItemNotFound)
+}
@@ -0,0 +1,119 @@
+package bankimport
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Format is a bank file format the importer reads
+type Format string
+
+// Supported bank file formats
+const (
+	FormatCSV     Format = "csv"
+	FormatCamt054 Format = "camt054"
+)
+
+// Suspense item states. Items are open until an admin allocates them to a wallet.
+const (
+	StatusOpen      = "open"
+	StatusAllocated = "allocated"
+)
+
+// Outcomes of an imported credit
+const (
+	OutcomeDeposited = "deposited"
+	OutcomeDuplicate = "duplicate"
+	OutcomeSuspended = "suspended"
+)
+
+// Credit is an incoming bank transfer read from a bank file. BankReference is
+// unique per transfer at the bank and keys the deposit.
+type Credit struct {
+	BankReference  string    `json:"bank_reference"`
+	Amount         float64   `json:"amount"`
+	Currency       string    `json:"currency"`
+	BookedAt       time.Time `json:"booked_at"`
+	DebtorName     string    `json:"debtor_name,omitempty"`
+	DebtorAccount  string    `json:"debtor_account,omitempty"`
+	Reference      string    `json:"reference,omitempty"`
+	VirtualAccount string    `json:"virtual_account,omitempty"`
+}
+
+// SuspenseItem is a credit that could not be deposited automatically
+type SuspenseItem struct {
+	ItemID          int        `json:"item_id"`
+	Credit          Credit     `json:"credit"`
+	Reason          string     `json:"reason"`
+	Status          string     `json:"status"`
+	AllocatedUserID int        `json:"allocated_user_id,omitempty"`
+	AllocatedBy     string     `json:"allocated_by,omitempty"`
+	AllocatedAt     *time.Time `json:"allocated_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// Result is what happened to one credit of a file
+type Result struct {
+	BankReference string  `json:"bank_reference"`
+	Amount        float64 `json:"amount"`
+	UserID        int     `json:"user_id,omitempty"`
+	Outcome       string  `json:"outcome"`
+	Reason        string  `json:"reason,omitempty"`
+}
+
+// Report summarises an import, debits in the file are skipped
+type Report struct {
+	Credits    int      `json:"credits"`
+	Deposited  int      `json:"deposited"`
+	Duplicates int      `json:"duplicates"`
+	Suspended  int      `json:"suspended"`
+	Results    []Result `json:"results"`
+}
+
+// Repository stores the suspense queue
+type Repository interface {
+	// AddSuspense queues the credit, ErrDuplicateCredit if its bank reference is queued already
+	AddSuspense(ctx context.Context, credit Credit, reason string) (SuspenseItem, error)
+	// SuspenseExists tells whether a credit with the bank reference was queued
+	SuspenseExists(ctx context.Context, bankReference string) (bool, error)
+	ListSuspense(ctx context.Context, status string) ([]SuspenseItem, error)
+	// GetSuspense returns the item, ErrItemNotFound if there is none
+	GetSuspense(ctx context.Context, itemID int) (SuspenseItem, error)
+	// ClaimSuspense marks an open item allocated to the user, ErrItemNotOpen otherwise
+	ClaimSuspense(ctx context.Context, itemID, userID int, actor string) (SuspenseItem, error)
+	// ReleaseSuspense opens a claimed item again after its deposit failed
+	ReleaseSuspense(ctx context.Context, itemID int) error
+}
+
+// Depositor books deposits once per key, wallet.Service is one
+type Depositor interface {
+	DepositIdempotent(ctx context.Context, userID int, amount float64, key string) (float64, error)
+}
+
+// AccountResolver finds the wallet a virtual account number belongs to,
+// ErrUnknownAccount if there is none
+type AccountResolver interface {
+	ResolveVirtualAccount(ctx context.Context, number string) (int, error)
+}
+
+// Config of the importer
+type Config struct {
+	// Currency of the wallets, credits in other currencies are suspended
+	Currency string
+}
+
+// Importer deposits the credits of bank files into the matching wallets
+type Importer struct {
+	repo     Repository
+	wallets  Depositor
+	accounts AccountResolver
+	cfg      Config
+	logger   *logrus.Entry
+}
+
+type bankImportRepository struct {
+	db *sql.DB
+}
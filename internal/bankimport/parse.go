@@ -0,0 +1,325 @@
+package bankimport
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvColumns are the columns of the CSV bank file, the first four are required
+//
+//nolint:gochecknoglobals // read-only
+var csvColumns = []string{
+	"bank_reference", "booking_date", "amount", "currency",
+	"debtor_name", "debtor_account", "reference", "virtual_account",
+}
+
+const requiredCSVColumns = 4
+
+// ParseFormat accepts the formats by name
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(name); f {
+	case FormatCSV, FormatCamt054:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w %q", ErrUnknownFormat, name)
+	}
+}
+
+// Parse reads the credits of a bank file, debits and pending entries are left out
+func Parse(r io.Reader, f Format) ([]Credit, error) {
+	switch f {
+	case FormatCSV:
+		return parseCSV(r)
+	case FormatCamt054:
+		return parseCamt054(r)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, f)
+	}
+}
+
+// parseDate accepts a date, which is booked at midnight UTC, or an RFC 3339 time
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: booking date %q", ErrInvalidFile, value)
+	}
+
+	return t.UTC(), nil
+}
+
+func parseAmount(value string) (float64, error) {
+	amount, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: amount %q", ErrInvalidFile, value)
+	}
+
+	return amount, nil
+}
+
+// parseCSV reads a CSV file with a header naming csvColumns in any order, negative
+// amounts are debits
+func parseCSV(r io.Reader) ([]Credit, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read header: %w", ErrInvalidFile, err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, name := range csvColumns[:requiredCSVColumns] {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("%w: missing column %q", ErrInvalidFile, name)
+		}
+	}
+
+	column := func(record []string, name string) string {
+		i, ok := index[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+
+		return strings.TrimSpace(record[i])
+	}
+
+	var credits []Credit
+
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidFile, line, err)
+		}
+
+		amount, err := parseAmount(column(record, "amount"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if amount <= 0 {
+			continue
+		}
+
+		bookedAt, err := parseDate(column(record, "booking_date"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		credit := Credit{
+			BankReference:  column(record, "bank_reference"),
+			Amount:         amount,
+			Currency:       strings.ToUpper(column(record, "currency")),
+			BookedAt:       bookedAt,
+			DebtorName:     column(record, "debtor_name"),
+			DebtorAccount:  column(record, "debtor_account"),
+			Reference:      column(record, "reference"),
+			VirtualAccount: column(record, "virtual_account"),
+		}
+
+		if credit.BankReference == "" {
+			return nil, fmt.Errorf("%w: line %d: empty bank_reference", ErrInvalidFile, line)
+		}
+
+		credits = append(credits, credit)
+	}
+
+	return credits, nil
+}
+
+// camt.054 BankToCustomerDebitCreditNotification, elements are matched by local
+// name so versions 02 to 08 are read alike
+type camt054Document struct {
+	Notifications []struct {
+		Account camt054Account `xml:"Acct>Id"`
+		Entries []camt054Entry `xml:"Ntry"`
+	} `xml:"BkToCstmrDbtCdtNtfctn>Ntfctn"`
+}
+
+type camt054Account struct {
+	IBAN  string `xml:"IBAN"`
+	Other string `xml:"Othr>Id"`
+}
+
+func (a camt054Account) number() string {
+	if a.IBAN != "" {
+		return a.IBAN
+	}
+
+	return a.Other
+}
+
+type camt054Amount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camt054Entry struct {
+	Reference string        `xml:"NtryRef"`
+	Amount    camt054Amount `xml:"Amt"`
+	Indicator string        `xml:"CdtDbtInd"`
+	// Sts is a code up to version 04 and a choice with Cd from version 05
+	Status struct {
+		Code string `xml:"Cd"`
+		Text string `xml:",chardata"`
+	} `xml:"Sts"`
+	BookingDate struct {
+		Date     string `xml:"Dt"`
+		DateTime string `xml:"DtTm"`
+	} `xml:"BookgDt"`
+	ServicerRef  string      `xml:"AcctSvcrRef"`
+	Transactions []camt054Tx `xml:"NtryDtls>TxDtls"`
+}
+
+type camt054Tx struct {
+	ServicerRef     string         `xml:"Refs>AcctSvcrRef"`
+	Amount          *camt054Amount `xml:"Amt"`
+	Indicator       string         `xml:"CdtDbtInd"`
+	DebtorName      string         `xml:"RltdPties>Dbtr>Nm"`
+	DebtorPartyName string         `xml:"RltdPties>Dbtr>Pty>Nm"`
+	DebtorAccount   camt054Account `xml:"RltdPties>DbtrAcct>Id"`
+	CreditorAccount camt054Account `xml:"RltdPties>CdtrAcct>Id"`
+	Unstructured    []string       `xml:"RmtInf>Ustrd"`
+	CreditorRef     string         `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+}
+
+func (e camt054Entry) booked() bool {
+	status := e.Status.Code
+	if status == "" {
+		status = strings.TrimSpace(e.Status.Text)
+	}
+
+	return status == "BOOK"
+}
+
+// parseCamt054 reads the booked credit transactions of every notification. An entry
+// without transaction details is one credit.
+func parseCamt054(r io.Reader) ([]Credit, error) {
+	var doc camt054Document
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+
+	var credits []Credit
+
+	for _, n := range doc.Notifications {
+		for _, entry := range n.Entries {
+			if !entry.booked() {
+				continue
+			}
+
+			txs := entry.Transactions
+			if len(txs) == 0 {
+				txs = []camt054Tx{{}}
+			}
+
+			for i, tx := range txs {
+				credit, ok, err := camt054Credit(entry, tx, i, len(txs), n.Account.number())
+				if err != nil {
+					return nil, err
+				}
+
+				if ok {
+					credits = append(credits, credit)
+				}
+			}
+		}
+	}
+
+	return credits, nil
+}
+
+// camt054Credit maps a transaction of an entry, ok is false for a debit
+func camt054Credit(entry camt054Entry, tx camt054Tx, index, count int, account string) (Credit, bool, error) {
+	indicator := tx.Indicator
+	if indicator == "" {
+		indicator = entry.Indicator
+	}
+
+	if indicator != "CRDT" {
+		return Credit{}, false, nil
+	}
+
+	amount := entry.Amount
+	if tx.Amount != nil {
+		amount = *tx.Amount
+	}
+
+	value, err := parseAmount(amount.Value)
+	if err != nil {
+		return Credit{}, false, err
+	}
+
+	// camt.054 amounts are unsigned, the indicator gives the direction
+	if value <= 0 {
+		return Credit{}, false, fmt.Errorf("%w: credit of non-positive amount %s", ErrInvalidFile, amount.Value)
+	}
+
+	booked := entry.BookingDate.DateTime
+	if booked == "" {
+		booked = entry.BookingDate.Date
+	}
+
+	bookedAt, err := parseDate(booked)
+	if err != nil {
+		return Credit{}, false, err
+	}
+
+	credit := Credit{
+		BankReference:  tx.ServicerRef,
+		Amount:         value,
+		Currency:       strings.ToUpper(strings.TrimSpace(amount.Currency)),
+		BookedAt:       bookedAt,
+		DebtorName:     tx.DebtorName,
+		DebtorAccount:  tx.DebtorAccount.number(),
+		Reference:      tx.CreditorRef,
+		VirtualAccount: tx.CreditorAccount.number(),
+	}
+
+	if credit.BankReference == "" {
+		// the entry reference is unique, its transactions are numbered
+		credit.BankReference = entry.ServicerRef
+		if credit.BankReference == "" {
+			credit.BankReference = entry.Reference
+		}
+
+		if count > 1 {
+			credit.BankReference += "/" + strconv.Itoa(index+1)
+		}
+	}
+
+	if credit.BankReference == "" {
+		return Credit{}, false, fmt.Errorf("%w: credit of %s without reference", ErrInvalidFile, amount.Value)
+	}
+
+	if credit.DebtorName == "" {
+		credit.DebtorName = tx.DebtorPartyName
+	}
+
+	if credit.Reference == "" {
+		credit.Reference = strings.Join(tx.Unstructured, " ")
+	}
+
+	if credit.VirtualAccount == "" {
+		credit.VirtualAccount = account
+	}
+
+	return credit, true, nil
+}
@@ -0,0 +1,129 @@
+package bankimport
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func parseFile(t *testing.T, name string, f Format) []Credit {
+	t.Helper()
+
+	file, err := os.Open(filepath.Join("testdata", name))
+	require.NoError(t, err)
+
+	defer file.Close()
+
+	credits, err := Parse(file, f)
+	require.NoError(t, err)
+
+	return credits
+}
+
+func TestParse_CSV(t *testing.T) {
+	// Act
+	credits := parseFile(t, "credits.csv", FormatCSV)
+
+	// Assert: the debit is skipped
+	require.Equal(t, []Credit{
+		{
+			BankReference: "BR-1001", Amount: 150, Currency: "USD", BookedAt: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+			DebtorName: "Ada Lovelace", Reference: "Top up WALLET-1",
+		},
+		{
+			BankReference: "BR-1003", Amount: 75.5, Currency: "USD", BookedAt: time.Date(2026, 9, 2, 8, 30, 0, 0, time.UTC),
+			DebtorName: "Alan Turing", Reference: "wallet 2 savings",
+		},
+		{
+			BankReference: "BR-1004", Amount: 12, Currency: "USD", BookedAt: time.Date(2026, 9, 2, 0, 0, 0, 0, time.UTC),
+			DebtorName: "Unknown payer", Reference: "invoice 7781",
+		},
+	}, credits)
+}
+
+func TestParse_CSVInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{name: "missing column", file: "bank_reference,amount,currency\nBR-1,10,USD\n"},
+		{name: "bad amount", file: "bank_reference,booking_date,amount,currency\nBR-1,2026-09-01,ten,USD\n"},
+		{name: "bad date", file: "bank_reference,booking_date,amount,currency\nBR-1,01/09/2026,10,USD\n"},
+		{name: "empty reference", file: "bank_reference,booking_date,amount,currency\n,2026-09-01,10,USD\n"},
+		{name: "empty file", file: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			_, err := Parse(strings.NewReader(tt.file), FormatCSV)
+
+			// Assert
+			require.ErrorIs(t, err, ErrInvalidFile)
+		})
+	}
+}
+
+func TestParse_Camt054(t *testing.T) {
+	// Act
+	credits := parseFile(t, "credits.camt054.xml", FormatCamt054)
+
+	// Assert: the debit and the pending entry are skipped
+	require.Equal(t, []Credit{
+		{
+			BankReference: "SVC-T1", Amount: 120, Currency: "USD", BookedAt: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+			DebtorName: "Grace Hopper", DebtorAccount: "GB33BUKB20201555555555", Reference: "Monthly top up",
+			VirtualAccount: "VA-0003",
+		},
+		{
+			BankReference: "SVC-E1/2", Amount: 80, Currency: "USD", BookedAt: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+			Reference: "WALLET-1", VirtualAccount: "DE89370400440532013000",
+		},
+		{
+			BankReference: "SVC-E4", Amount: 10, Currency: "EUR", BookedAt: time.Date(2026, 9, 1, 9, 15, 0, 0, time.UTC),
+			VirtualAccount: "DE89370400440532013000",
+		},
+	}, credits)
+}
+
+// camt054Notification is a notification of one booked entry of amount in currency
+func camt054Notification(currency, amount string) string {
+	return `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.054.001.08"><BkToCstmrDbtCdtNtfctn><Ntfctn>
+<Ntry><Amt Ccy="` + currency + `">` + amount + `</Amt><CdtDbtInd>CRDT</CdtDbtInd><Sts><Cd>BOOK</Cd></Sts>
+<BookgDt><Dt>2026-09-01</Dt></BookgDt><AcctSvcrRef>SVC-X1</AcctSvcrRef></Ntry>
+</Ntfctn></BkToCstmrDbtCdtNtfctn></Document>`
+}
+
+func TestParse_Camt054Invalid(t *testing.T) {
+	for _, amount := range []string{"-50.00", "0.00"} {
+		t.Run(amount, func(t *testing.T) {
+			// Act
+			_, err := Parse(strings.NewReader(camt054Notification("USD", amount)), FormatCamt054)
+
+			// Assert: a credit never takes money out of a wallet
+			require.ErrorIs(t, err, ErrInvalidFile)
+		})
+	}
+}
+
+func TestParse_Camt054Currency(t *testing.T) {
+	// Act
+	credits, err := Parse(strings.NewReader(camt054Notification(" usd", "25.00")), FormatCamt054)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, credits, 1)
+	require.Equal(t, "USD", credits[0].Currency)
+}
+
+func TestParse_UnknownFormat(t *testing.T) {
+	// Act
+	_, err := ParseFormat("mt940")
+
+	// Assert
+	require.ErrorIs(t, err, ErrUnknownFormat)
+}
@@ -0,0 +1,195 @@
+package bankimport
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/amelonpie/wallet-service/internal/database"
+)
+
+const suspenseColumns = `item_id, bank_reference, amount, currency, booked_at, debtor_name, debtor_account,
+    reference, virtual_account, reason, status, COALESCE(allocated_user_id, 0), COALESCE(allocated_by, ''),
+    allocated_at, created_at`
+
+//nolint:ireturn // stick to interface
+func InitRepository() (Repository, error) {
+	dbConfig, err := database.NewDatabaseConfig()
+	if err != nil {
+		return nil, err //nolint:wrapcheck // names the secret that failed
+	}
+
+	postgre, err := dbConfig.ConnectPostgre()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+
+	return NewRepository(postgre), nil
+}
+
+// NewRepository returns the Postgres backed repository on db.
+//
+//nolint:ireturn // stick to interface
+func NewRepository(db *sql.DB) Repository {
+	return &bankImportRepository{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSuspenseItem(row rowScanner) (SuspenseItem, error) {
+	var item SuspenseItem
+
+	err := row.Scan(
+		&item.ItemID,
+		&item.Credit.BankReference,
+		&item.Credit.Amount,
+		&item.Credit.Currency,
+		&item.Credit.BookedAt,
+		&item.Credit.DebtorName,
+		&item.Credit.DebtorAccount,
+		&item.Credit.Reference,
+		&item.Credit.VirtualAccount,
+		&item.Reason,
+		&item.Status,
+		&item.AllocatedUserID,
+		&item.AllocatedBy,
+		&item.AllocatedAt,
+		&item.CreatedAt,
+	)
+
+	return item, err //nolint:wrapcheck // callers wrap with context
+}
+
+// AddSuspense inserts the credit unless its bank reference is queued already
+func (r *bankImportRepository) AddSuspense(ctx context.Context, credit Credit, reason string) (SuspenseItem, error) {
+	query := `INSERT INTO suspense_items (bank_reference, amount, currency, booked_at, debtor_name, debtor_account,
+              reference, virtual_account, reason)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+              ON CONFLICT (bank_reference) DO NOTHING
+              RETURNING ` + suspenseColumns
+
+	item, err := scanSuspenseItem(r.db.QueryRowContext(ctx, query,
+		credit.BankReference, credit.Amount, credit.Currency, credit.BookedAt, credit.DebtorName,
+		credit.DebtorAccount, credit.Reference, credit.VirtualAccount, reason,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrDuplicateCredit
+	}
+
+	if err != nil {
+		return SuspenseItem{}, fmt.Errorf("failed to insert suspense item %s: %w", credit.BankReference, err)
+	}
+
+	return item, nil
+}
+
+// SuspenseExists tells whether the bank reference is in the suspense queue, open or not
+func (r *bankImportRepository) SuspenseExists(ctx context.Context, bankReference string) (bool, error) {
+	var exists bool
+
+	query := `SELECT EXISTS (SELECT 1 FROM suspense_items WHERE bank_reference = $1)`
+
+	if err := r.db.QueryRowContext(ctx, query, bankReference).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to query suspense item %s: %w", bankReference, err)
+	}
+
+	return exists, nil
+}
+
+// ListSuspense returns the items with the status oldest first, every item for ""
+func (r *bankImportRepository) ListSuspense(ctx context.Context, status string) ([]SuspenseItem, error) {
+	query := `SELECT ` + suspenseColumns + ` FROM suspense_items
+              WHERE $1 = '' OR status = $1
+              ORDER BY item_id`
+
+	rows, err := r.db.QueryContext(ctx, query, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query suspense items: %w", err)
+	}
+	defer rows.Close()
+
+	//nolint:prealloc // row count unknown
+	var items []SuspenseItem
+
+	for rows.Next() {
+		item, err := scanSuspenseItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan suspense item: %w", err)
+		}
+
+		items = append(items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error arises during rows iteration: %w", err)
+	}
+
+	return items, nil
+}
+
+// GetSuspense returns the item whatever its status
+func (r *bankImportRepository) GetSuspense(ctx context.Context, itemID int) (SuspenseItem, error) {
+	query := `SELECT ` + suspenseColumns + ` FROM suspense_items WHERE item_id = $1`
+
+	item, err := scanSuspenseItem(r.db.QueryRowContext(ctx, query, itemID))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrItemNotFound
+	}
+
+	if err != nil {
+		return SuspenseItem{}, fmt.Errorf("failed to get suspense item %d: %w", itemID, err)
+	}
+
+	return item, nil
+}
+
+// ClaimSuspense allocates an open item, the status check makes concurrent claims exclusive
+func (r *bankImportRepository) ClaimSuspense(ctx context.Context, itemID, userID int, actor string) (SuspenseItem, error) {
+	query := `UPDATE suspense_items
+              SET status = $4, allocated_user_id = $2, allocated_by = $3, allocated_at = CURRENT_TIMESTAMP
+              WHERE item_id = $1 AND status = $5
+              RETURNING ` + suspenseColumns
+
+	item, err := scanSuspenseItem(r.db.QueryRowContext(ctx, query, itemID, userID, actor, StatusAllocated, StatusOpen))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = r.notOpenError(ctx, itemID)
+	}
+
+	if err != nil {
+		return SuspenseItem{}, fmt.Errorf("failed to claim suspense item %d: %w", itemID, err)
+	}
+
+	return item, nil
+}
+
+// notOpenError tells why a claim matched no row
+func (r *bankImportRepository) notOpenError(ctx context.Context, itemID int) error {
+	var exists bool
+
+	query := `SELECT EXISTS (SELECT 1 FROM suspense_items WHERE item_id = $1)`
+	if err := r.db.QueryRowContext(ctx, query, itemID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to query suspense item %d: %w", itemID, err)
+	}
+
+	if !exists {
+		return ErrItemNotFound
+	}
+
+	return ErrItemNotOpen
+}
+
+// ReleaseSuspense reopens an allocated item
+func (r *bankImportRepository) ReleaseSuspense(ctx context.Context, itemID int) error {
+	query := `UPDATE suspense_items
+              SET status = $2, allocated_user_id = NULL, allocated_by = NULL, allocated_at = NULL
+              WHERE item_id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, itemID, StatusOpen); err != nil {
+		return fmt.Errorf("failed to reopen suspense item %d: %w", itemID, err)
+	}
+
+	return nil
+}
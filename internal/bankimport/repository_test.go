@@ -0,0 +1,50 @@
+package bankimport
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimSuspense_NotOpen(t *testing.T) {
+	// Arrange
+	db, mockSQL, err := sqlmock.New()
+	require.NoError(t, err)
+
+	repo := NewRepository(db)
+
+	mockSQL.ExpectQuery(`UPDATE suspense_items`).
+		WithArgs(4, 2, "ops", StatusAllocated, StatusOpen).
+		WillReturnRows(sqlmock.NewRows(nil))
+	mockSQL.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM suspense_items WHERE item_id = \$1\)`).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	// Act
+	_, err = repo.ClaimSuspense(context.Background(), 4, 2, "ops")
+
+	// Assert
+	require.ErrorIs(t, err, ErrItemNotOpen)
+	require.NoError(t, mockSQL.ExpectationsWereMet())
+}
+
+func TestAddSuspense_Duplicate(t *testing.T) {
+	// Arrange
+	db, mockSQL, err := sqlmock.New()
+	require.NoError(t, err)
+
+	repo := NewRepository(db)
+	credit := Credit{BankReference: "BR-1", Amount: 10, Currency: "USD"}
+
+	mockSQL.ExpectQuery(`INSERT INTO suspense_items .* ON CONFLICT \(bank_reference\) DO NOTHING`).
+		WillReturnRows(sqlmock.NewRows(nil))
+
+	// Act
+	_, err = repo.AddSuspense(context.Background(), credit, "unmatched")
+
+	// Assert
+	require.ErrorIs(t, err, ErrDuplicateCredit)
+	require.NoError(t, mockSQL.ExpectationsWereMet())
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// opened holds every pool and client created by this package, for Close
+var opened struct { //nolint:gochecknoglobals // the connections live as long as the process
+	mu      sync.Mutex
+	closers []io.Closer
+}
+
+func track(c io.Closer) {
+	opened.mu.Lock()
+	defer opened.mu.Unlock()
+
+	opened.closers = append(opened.closers, c)
+}
+
+// Close closes every Postgres pool and Redis client opened so far. It is called
+// once on shutdown, after everything using them stopped.
+func Close() error {
+	opened.mu.Lock()
+	closers := opened.closers
+	opened.closers = nil
+	opened.mu.Unlock()
+
+	var errs []error
+
+	for _, c := range closers {
+		if err := c.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close connection: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
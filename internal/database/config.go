@@ -1,25 +1,151 @@
 package database
 
 import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
 	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/amelonpie/wallet-service/pkg/secret"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
+const (
+	keyPostgresPassword = "postgresql.password"
+	keyRedisPassword    = "redis.password"
+)
+
 type Config struct {
-	PostgreAddr string
+	// PostgreAddr is the connection URL including the password
+	PostgreAddr secret.String
+	Pool        PoolConfig
+	Replicas    ReplicaConfig
 	RedisAddr   string
-	RedisPwd    string
+	RedisPwd    secret.String
 	RedisDB     int
 	logger      *logrus.Entry
 }
 
-func NewDatabaseConfig() *Config {
+// PoolConfig of every Postgres connection pool, zero values keep the database/sql defaults
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// ReplicaConfig of the read replicas, reads stay on the primary without Addrs
+type ReplicaConfig struct {
+	// Addrs are the connection URLs including the password
+	Addrs []secret.String
+	// MaxLag is how far a replica may be behind the primary and still serve reads
+	MaxLag time.Duration
+	// CheckInterval is how often the lag of the replicas is measured
+	CheckInterval time.Duration
+}
+
+// NewDatabaseConfig reads the addresses from the config and the passwords from
+// the secret providers. A password left in the config file, inside
+// postgresql.address or as redis.password, is still used but redacted from the logs.
+func NewDatabaseConfig() (*Config, error) {
+	logger := log.NewLogger("database").WithField("module", "database")
+
+	postgresPwd, err := lookupPassword(keyPostgresPassword, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	postgreAddr, err := postgresAddress(viper.GetString("postgresql.address"), postgresPwd)
+	if err != nil {
+		return nil, err
+	}
+
+	viper.SetDefault("postgresql.pool.max_open_conns", 25)
+	viper.SetDefault("postgresql.pool.max_idle_conns", 10)
+	viper.SetDefault("postgresql.pool.conn_max_lifetime", "30m")
+	viper.SetDefault("postgresql.pool.conn_max_idle_time", "5m")
+	viper.SetDefault("postgresql.replicas.max_lag", "2s")
+	viper.SetDefault("postgresql.replicas.check_interval", "1s")
+
+	// replicas share postgresql.password with the primary
+	replicaAddrs := viper.GetStringSlice("postgresql.replicas.addresses")
+	replicas := make([]secret.String, 0, len(replicaAddrs))
+
+	for _, addr := range replicaAddrs {
+		replica, err := postgresAddress(addr, postgresPwd)
+		if err != nil {
+			return nil, err
+		}
+
+		replicas = append(replicas, replica)
+	}
+
+	redisPwd, err := lookupPassword(keyRedisPassword, logger)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
-		PostgreAddr: viper.GetString("postgresql.address"),
-		RedisAddr:   viper.GetString("redis.address"),
-		RedisPwd:    viper.GetString("redis.password"),
-		RedisDB:     viper.GetInt("redis.db"),
-		logger:      log.NewLogger("database").WithField("module", "database"),
+		PostgreAddr: postgreAddr,
+		Pool: PoolConfig{
+			MaxOpenConns:    viper.GetInt("postgresql.pool.max_open_conns"),
+			MaxIdleConns:    viper.GetInt("postgresql.pool.max_idle_conns"),
+			ConnMaxLifetime: viper.GetDuration("postgresql.pool.conn_max_lifetime"),
+			ConnMaxIdleTime: viper.GetDuration("postgresql.pool.conn_max_idle_time"),
+		},
+		Replicas: ReplicaConfig{
+			Addrs:         replicas,
+			MaxLag:        viper.GetDuration("postgresql.replicas.max_lag"),
+			CheckInterval: viper.GetDuration("postgresql.replicas.check_interval"),
+		},
+		RedisAddr: viper.GetString("redis.address"),
+		RedisPwd:  redisPwd,
+		RedisDB:   viper.GetInt("redis.db"),
+		logger:    logger,
+	}, nil
+}
+
+func lookupPassword(key string, logger *logrus.Entry) (secret.String, error) {
+	value, ok, err := secret.Lookup(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up %s: %w", key, err)
+	}
+
+	if ok {
+		return value, nil
+	}
+
+	plain := viper.GetString(key)
+	if plain != "" {
+		log.Redact(plain)
+		logger.WithField("key", key).Warn("password read from the config file, provide it as a secret instead")
+	}
+
+	return secret.String(plain), nil
+}
+
+// postgresAddress sets password in the URL address, a password in the address
+// itself is kept unless a secret overrides it
+func postgresAddress(address string, password secret.String) (secret.String, error) {
+	if address == "" || !strings.Contains(address, "://") {
+		// empty, or a keyword/value connection string, which carries its own password=
+		return secret.String(address), nil
 	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", ErrInvalidPostgresAddress
+	}
+
+	if embedded, ok := u.User.Password(); ok {
+		log.Redact(embedded)
+	}
+
+	if password != "" {
+		u.User = url.UserPassword(u.User.Username(), password.Reveal())
+	}
+
+	return secret.String(u.String()), nil
 }
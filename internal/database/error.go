@@ -0,0 +1,9 @@
+package database
+
+import "errors"
+
+// ErrInvalidPostgresAddress does not repeat the address, which may hold a password
+var ErrInvalidPostgresAddress = errors.New("postgresql.address is not a valid URL")
+
+// ErrReplicaNotStarted is reported for a replica that has not replayed anything yet
+var ErrReplicaNotStarted = errors.New("replica has not replayed any transaction yet")
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/sirupsen/logrus"
+)
+
+// lockRetry is how often an instance without the lock tries to take it, and how
+// often the holder checks that its connection, and so the lock, is still alive
+const lockRetry = 30 * time.Second
+
+// Exclusive returns a worker that runs run on one instance at a time. The instance
+// holding the session-level advisory lock key runs it, on a connection kept for
+// that; the others try again every lockRetry and take over when the holder stops
+// or loses its connection.
+func Exclusive(db *sql.DB, key int64, name string, run func(ctx context.Context)) func(ctx context.Context) {
+	logger := log.NewLogger("database").WithField("module", "lock").WithFields(logrus.Fields{
+		"worker": name,
+		"lock":   key,
+	})
+
+	return func(ctx context.Context) {
+		for {
+			held, err := runLocked(ctx, db, key, logger, run)
+
+			switch {
+			case err != nil:
+				logger.WithField("err", err).Warn("failed to take the worker lock")
+			case !held:
+				logger.Debug("worker runs on another instance")
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(lockRetry):
+			}
+		}
+	}
+}
+
+// runLocked runs run until ctx is done or the connection holding key drops, and
+// reports whether it got the lock
+func runLocked(
+	ctx context.Context,
+	db *sql.DB,
+	key int64,
+	logger *logrus.Entry,
+	run func(ctx context.Context),
+) (bool, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	var held bool
+	if err = conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&held); err != nil {
+		return false, fmt.Errorf("failed to try lock: %w", err)
+	}
+
+	if !held {
+		return false, nil
+	}
+
+	logger.Info("worker lock taken, running here")
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		run(runCtx)
+	}()
+
+	ticker := time.NewTicker(lockRetry)
+	defer ticker.Stop()
+
+	for alive := true; alive; {
+		select {
+		case <-done:
+			alive = false
+		case <-ticker.C:
+			if err = conn.PingContext(runCtx); err != nil && runCtx.Err() == nil {
+				logger.WithField("err", err).Warn("lost the worker lock connection, stopping")
+				cancel()
+			}
+		}
+	}
+
+	// released with a fresh context, ctx is done when shutting down
+	if _, err = conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, key); err != nil {
+		logger.WithField("err", err).Warn("failed to release the worker lock")
+	}
+
+	return true, nil
+}
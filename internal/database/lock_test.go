@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExclusive_RunsWhileHoldingLock(t *testing.T) {
+	// Arrange
+	db, mockSQL, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	mockSQL.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\)`).
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+	mockSQL.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).
+		WithArgs(42).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+
+	worker := Exclusive(db, 42, "test", func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+	})
+
+	done := make(chan struct{})
+
+	// Act
+	go func() {
+		defer close(done)
+		worker(ctx)
+	}()
+
+	<-started
+	cancel()
+	<-done
+
+	// Assert
+	require.NoError(t, mockSQL.ExpectationsWereMet())
+}
+
+func TestExclusive_SkipsWhenLockHeldElsewhere(t *testing.T) {
+	// Arrange
+	db, mockSQL, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	mockSQL.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\)`).
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(false))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	ran := false
+
+	// Act
+	Exclusive(db, 42, "test", func(context.Context) { ran = true })(ctx)
+
+	// Assert
+	require.False(t, ran, "expected the worker to run on the lock holder only")
+	require.NoError(t, mockSQL.ExpectationsWereMet())
+}
@@ -0,0 +1,14 @@
+package database
+
+import (
+	"github.com/amelonpie/wallet-service/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+//nolint:gochecknoglobals // registered once
+var replicaLagSeconds = promauto.With(metrics.Registry).NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: metrics.Namespace,
+	Name:      "db_replica_lag_seconds",
+	Help:      "Replication lag of the read replicas at the last check.",
+}, []string{"replica"})
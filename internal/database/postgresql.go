@@ -4,22 +4,83 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/XSAM/otelsql"
+	"github.com/amelonpie/wallet-service/pkg/secret"
 	_ "github.com/lib/pq" // Postgres driver
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
 // Connect connects to the Postgres database and returns a *sql.DB instance.
+// Every statement is traced as a span of the context it runs with.
 func (cfg *Config) ConnectPostgre() (*sql.DB, error) {
-	db, err := sql.Open("postgres", cfg.PostgreAddr)
+	db, err := cfg.openPostgres(cfg.PostgreAddr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open Postgres DB: %w", err)
+		return nil, err
 	}
 
 	// Test the connection
 	if err := db.Ping(); err != nil {
+		db.Close()
+
 		return nil, fmt.Errorf("failed to ping Postgres DB: %w", err)
 	}
 
+	track(db)
+
 	cfg.logger.Info("Connected to PostgreSQL successfully")
 
 	return db, nil
 }
+
+// ConnectReplicas opens a pool per read replica without testing the connection:
+// an unreachable replica does not prevent the start, reads go to the primary
+// until its lag is measured. The pools are closed by Close.
+func (cfg *Config) ConnectReplicas() ([]*sql.DB, error) {
+	replicas := make([]*sql.DB, 0, len(cfg.Replicas.Addrs))
+
+	for i, addr := range cfg.Replicas.Addrs {
+		db, err := cfg.openPostgres(addr)
+		if err != nil {
+			return nil, fmt.Errorf("replica %d: %w", i, err)
+		}
+
+		track(db)
+		replicas = append(replicas, db)
+	}
+
+	return replicas, nil
+}
+
+func (cfg *Config) openPostgres(addr secret.String) (*sql.DB, error) {
+	db, err := otelsql.Open("postgres", addr.Reveal(),
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true, OmitRows: true}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Postgres DB: %w", err)
+	}
+
+	cfg.Pool.apply(db)
+
+	return db, nil
+}
+
+// apply sets the limits that are not zero, which would mean none or unlimited to
+// database/sql and not its default
+func (p PoolConfig) apply(db *sql.DB) {
+	if p.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(p.MaxOpenConns)
+	}
+
+	if p.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(p.MaxIdleConns)
+	}
+
+	if p.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(p.ConnMaxLifetime)
+	}
+
+	if p.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+	}
+}
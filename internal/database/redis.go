@@ -4,16 +4,32 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 )
 
-func (cfg *Config) ConnectRedis() (*redis.Client, error) {
+// NewRedisClient creates a client without testing the connection,
+// it connects lazily and reconnects on its own. Every command is traced.
+// The client is closed by Close.
+func (cfg *Config) NewRedisClient() *redis.Client {
 	rdb := redis.NewClient(&redis.Options{
 		Addr:     cfg.RedisAddr,
-		Password: cfg.RedisPwd,
+		Password: cfg.RedisPwd.Reveal(),
 		DB:       cfg.RedisDB, // 0 = default DB
 	})
 
+	if err := redisotel.InstrumentTracing(rdb); err != nil {
+		cfg.logger.WithField("err", err).Warn("failed to trace Redis commands")
+	}
+
+	track(rdb)
+
+	return rdb
+}
+
+func (cfg *Config) ConnectRedis() (*redis.Client, error) {
+	rdb := cfg.NewRedisClient()
+
 	// Test the connection
 	ctx := context.Background()
 
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/sirupsen/logrus"
+)
+
+// lagQuery returns how far a replica is behind: 0 once it replayed everything it
+// received, otherwise the age of the last replayed transaction. A server that is
+// not in recovery is the primary itself, NULL means nothing was replayed yet.
+const lagQuery = `
+    SELECT CASE
+        WHEN NOT pg_is_in_recovery() THEN 0
+        WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+        ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+    END`
+
+type replica struct {
+	name string
+	db   *sql.DB
+	// healthy replicas answered the last lag check within MaxLag
+	healthy atomic.Bool
+}
+
+// ReplicaSet routes reads that tolerate a bounded lag to the read replicas, and
+// to the primary while none is caught up. Writes and reads inside transactions
+// always use the primary.
+type ReplicaSet struct {
+	primary  *sql.DB
+	replicas []*replica
+	cfg      ReplicaConfig
+	next     atomic.Uint64
+	logger   *logrus.Entry
+}
+
+// NewReplicaSet starts with every replica considered behind, until Check or Run
+// measured its lag
+func NewReplicaSet(primary *sql.DB, replicas []*sql.DB, cfg ReplicaConfig) *ReplicaSet {
+	rs := &ReplicaSet{
+		primary: primary,
+		cfg:     cfg,
+		logger:  log.NewLogger("database").WithField("module", "replicas"),
+	}
+
+	for i, db := range replicas {
+		rs.replicas = append(rs.replicas, &replica{name: "replica_" + strconv.Itoa(i), db: db})
+	}
+
+	return rs
+}
+
+// Primary returns the primary database
+func (rs *ReplicaSet) Primary() *sql.DB {
+	return rs.primary
+}
+
+// Reader returns the next caught up replica in turn, or the primary if none is
+func (rs *ReplicaSet) Reader() *sql.DB {
+	n := len(rs.replicas)
+	start := rs.next.Add(1)
+
+	for i := range n {
+		r := rs.replicas[(start+uint64(i))%uint64(n)] //nolint:gosec // n is a small positive count
+		if r.healthy.Load() {
+			return r.db
+		}
+	}
+
+	return rs.primary
+}
+
+// Run checks the lag of the replicas every CheckInterval until ctx is done
+func (rs *ReplicaSet) Run(ctx context.Context) {
+	ticker := time.NewTicker(rs.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		rs.Check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check measures the lag of every replica once. A replica that does not answer
+// within CheckInterval, or is more than MaxLag behind, serves no reads until the
+// next check finds it caught up.
+func (rs *ReplicaSet) Check(ctx context.Context) {
+	for _, r := range rs.replicas {
+		lag, err := rs.lag(ctx, r.db)
+		healthy := err == nil && lag <= rs.cfg.MaxLag
+
+		if err == nil {
+			replicaLagSeconds.WithLabelValues(r.name).Set(lag.Seconds())
+		}
+
+		if r.healthy.Swap(healthy) == healthy {
+			continue
+		}
+
+		logger := rs.logger.WithFields(logrus.Fields{"replica": r.name, "lag": lag.String(), "max_lag": rs.cfg.MaxLag.String()})
+		switch {
+		case healthy:
+			logger.Info("replica caught up, serving reads")
+		case err != nil:
+			logger.WithField("err", err).Warn("replica unavailable, reading from the primary")
+		default:
+			logger.Warn("replica lagging behind, reading from the primary")
+		}
+	}
+}
+
+func (rs *ReplicaSet) lag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, rs.cfg.CheckInterval)
+	defer cancel()
+
+	var seconds sql.NullFloat64
+	if err := db.QueryRowContext(ctx, lagQuery).Scan(&seconds); err != nil {
+		return 0, fmt.Errorf("failed to query replication lag: %w", err)
+	}
+
+	if !seconds.Valid {
+		return 0, ErrReplicaNotStarted
+	}
+
+	return time.Duration(seconds.Float64 * float64(time.Second)), nil
+}
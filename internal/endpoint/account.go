@@ -0,0 +1,77 @@
+package endpoint
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/amelonpie/wallet-service/internal/account"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func addAccountRoutes(wallet *gin.RouterGroup, ep *Endpoint) {
+	wallet.GET("/wallet/:user_id/account", ep.walletAccountHandler)
+}
+
+func addAccountLookupRoutes(accounts *gin.RouterGroup, ep *Endpoint) {
+	accounts.GET("/:account_number", ep.lookupAccountHandler)
+}
+
+// accountErrorStatus maps virtual account errors to the HTTP status returned to the caller
+func accountErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, account.ErrInvalidNumber):
+		return http.StatusBadRequest
+	case errors.Is(err, account.ErrAccountNotFound):
+		return http.StatusNotFound
+	default:
+		return walletErrorStatus(err)
+	}
+}
+
+// walletAccountHandler returns the virtual account bank transfers to the wallet
+// are sent to, issuing it if the wallet has none yet
+func (ep *Endpoint) walletAccountHandler(c *gin.Context) {
+	endpointLogger := ep.logger(c)
+
+	userID, ok := intParam(c, "user_id", endpointLogger)
+	if !ok {
+		return
+	}
+
+	va, err := ep.Accounts.Issue(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(accountErrorStatus(err), gin.H{"error": err.Error()})
+		endpointLogger.WithFields(logrus.Fields{
+			"err":     err,
+			"user_id": userID,
+		}).Error("failed to get virtual account")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, va)
+}
+
+// lookupAccountHandler tells support which wallet a virtual account number belongs to
+func (ep *Endpoint) lookupAccountHandler(c *gin.Context) {
+	endpointLogger := ep.logger(c)
+
+	number := c.Param("account_number")
+	va, err := ep.Accounts.Lookup(c.Request.Context(), number)
+	if err != nil {
+		c.JSON(accountErrorStatus(err), gin.H{"error": err.Error()})
+		endpointLogger.WithFields(logrus.Fields{
+			"err":            err,
+			"account_number": number,
+		}).Error("failed to look up virtual account")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, va)
+	endpointLogger.WithFields(logrus.Fields{
+		"account_number": va.Number,
+		"user_id":        va.UserID,
+	}).Info("successful virtual account lookup")
+}
@@ -0,0 +1,160 @@
+package endpoint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amelonpie/wallet-service/internal/account"
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+type mockAccountService struct {
+	IssueFunc  func(ctx context.Context, userID int) (account.VirtualAccount, error)
+	LookupFunc func(ctx context.Context, number string) (account.VirtualAccount, error)
+}
+
+func (m *mockAccountService) Issue(ctx context.Context, userID int) (account.VirtualAccount, error) {
+	return m.IssueFunc(ctx, userID)
+}
+func (m *mockAccountService) IssueMissing(context.Context) (int, error) { return 0, nil }
+func (m *mockAccountService) Lookup(ctx context.Context, number string) (account.VirtualAccount, error) {
+	return m.LookupFunc(ctx, number)
+}
+func (m *mockAccountService) Validate(number string) (string, error) { return number, nil }
+func (m *mockAccountService) ResolveVirtualAccount(context.Context, string) (int, error) {
+	return 0, nil
+}
+func (m *mockAccountService) Run(context.Context) {}
+
+const testAccountNumber = "DE98370400440000000042"
+
+func newMockAccounts() *mockAccountService {
+	return &mockAccountService{
+		IssueFunc: func(_ context.Context, userID int) (account.VirtualAccount, error) {
+			if userID != 42 {
+				return account.VirtualAccount{}, fmt.Errorf("failed to issue: %w", wallet.ErrWalletNotFound)
+			}
+
+			return account.VirtualAccount{Number: testAccountNumber, UserID: 42, Scheme: account.SchemeMod97}, nil
+		},
+		LookupFunc: func(_ context.Context, number string) (account.VirtualAccount, error) {
+			switch account.Normalize(number) {
+			case testAccountNumber:
+				return account.VirtualAccount{Number: number, UserID: 42, Scheme: account.SchemeMod97}, nil
+			case "DE98370400440000000043":
+				return account.VirtualAccount{}, fmt.Errorf("failed to look up: %w", account.ErrAccountNotFound)
+			default:
+				return account.VirtualAccount{}, fmt.Errorf("%w: %q", account.ErrInvalidNumber, number)
+			}
+		},
+	}
+}
+
+func TestAccountHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	ep := New(&mockWalletService{})
+	ep.Accounts = newMockAccounts()
+	addAccountRoutes(router.Group("/wallet"), ep)
+	addAccountLookupRoutes(router.Group("/accounts"), ep)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		return w
+	}
+
+	t.Run("wallet account", func(t *testing.T) {
+		w := get("/wallet/wallet/42/account")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var va account.VirtualAccount
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &va))
+		require.Equal(t, testAccountNumber, va.Number)
+	})
+
+	t.Run("wallet not found", func(t *testing.T) {
+		w := get("/wallet/wallet/7/account")
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("lookup", func(t *testing.T) {
+		w := get("/accounts/" + testAccountNumber)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), `"user_id":42`)
+	})
+
+	t.Run("lookup unknown", func(t *testing.T) {
+		w := get("/accounts/DE98370400440000000043")
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("lookup invalid check digits", func(t *testing.T) {
+		w := get("/accounts/DE97370400440000000042")
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestTransferHandler_ToAccount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	var toUser int
+
+	mockSvc := &mockWalletService{
+		TransferFunc: func(_ context.Context, _, toUserID int, _ float64) (float64, float64, error) {
+			toUser = toUserID
+			return 50.0, 150.0, nil
+		},
+	}
+
+	ep := New(mockSvc)
+	ep.Accounts = newMockAccounts()
+
+	router.POST("/transfer", ep.transferHandler)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/transfer", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		return w
+	}
+
+	t.Run("to account", func(t *testing.T) {
+		w := post(`{"from_user_id": 1, "to_account": "DE98 3704 0044 0000 0000 42", "amount": 20}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, 42, toUser)
+	})
+
+	t.Run("invalid check digits", func(t *testing.T) {
+		w := post(`{"from_user_id": 1, "to_account": "DE97370400440000000042", "amount": 20}`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("unknown account", func(t *testing.T) {
+		w := post(`{"from_user_id": 1, "to_account": "DE98370400440000000043", "amount": 20}`)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("account of another user", func(t *testing.T) {
+		w := post(`{"from_user_id": 1, "to_user_id": 2, "to_account": "` + testAccountNumber + `", "amount": 20}`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("no receiver", func(t *testing.T) {
+		w := post(`{"from_user_id": 1, "amount": 20}`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
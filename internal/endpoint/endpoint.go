@@ -1,64 +1,87 @@
 package endpoint
 
 import (
-	"errors"
+	"fmt"
 	"net/http"
+	"sync/atomic"
 
+	"github.com/amelonpie/wallet-service/internal/account"
+	"github.com/amelonpie/wallet-service/internal/export"
+	"github.com/amelonpie/wallet-service/internal/health"
+	"github.com/amelonpie/wallet-service/internal/payout"
+	"github.com/amelonpie/wallet-service/internal/stream"
 	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/amelonpie/wallet-service/internal/webhook"
 	"github.com/amelonpie/wallet-service/pkg/log"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
+// Endpoint holds what the handlers depend on, they are its methods
 type Endpoint struct {
-	Logger *logrus.Entry
-	Svc    *wallet.Service
+	Logger   *logrus.Entry
+	Svc      wallet.Service
+	Webhooks webhook.Service
+	Stream   *stream.Broker
+	// Websocket registers the WebSocket stream next to the SSE one
+	Websocket bool
+	Exporter  *export.Exporter
+	Payouts   payout.Service
+	Accounts  account.Service
+	Health    *health.Checker
+	// AllowOrigin tells whether a cross-origin WebSocket stream may be opened
+	// from origin, when nil only same-origin streams are
+	AllowOrigin func(origin string) bool
+
+	limits atomic.Pointer[Limits]
+}
+
+// Limits bound the amount of a single deposit, withdrawal, transfer or payout.
+// Zero means no limit.
+type Limits struct {
+	MaxAmount float64
 }
 
-func newEndpoint(svc wallet.Service) *Endpoint {
+// New creates an endpoint for svc, the optional services are set on the result
+func New(svc wallet.Service) *Endpoint {
 	logger := log.NewLogger("endpoint").WithField("module", "endpoint")
 
 	return &Endpoint{
 		Logger: logger,
-		Svc:    &svc,
+		Svc:    svc,
 	}
 }
 
-func epInstance(c *gin.Context) (*Endpoint, error) {
-	epAny, exists := c.Get("endpoint")
-	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Endpoint not found in context"})
-		//nolint:err113 // no need to define error class
-		return nil, errors.New("endpoint not found")
-	}
-
-	ep, ok := epAny.(*Endpoint)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cast Endpoint"})
-		//nolint:err113 // no need to define error class
-		return nil, errors.New("failed to cast endpoint")
-	}
+// logger returns the endpoint logger with the request id, user id and trace of c
+func (ep *Endpoint) logger(c *gin.Context) *logrus.Entry {
+	return log.FromContext(c.Request.Context(), ep.Logger)
+}
 
-	return ep, nil
+// SetLimits applies limits to the requests from now on, it is safe while serving
+func (ep *Endpoint) SetLimits(limits Limits) {
+	ep.limits.Store(&limits)
 }
 
-func epLogger(c *gin.Context) (*logrus.Entry, error) {
-	ep, err := epInstance(c)
-	if err != nil {
-		logrus.Fatal("fail to get endpoint")
+// withinLimits responds and returns false when amount is not positive or exceeds
+// the limits
+func (ep *Endpoint) withinLimits(c *gin.Context, amount float64, endpointLogger *logrus.Entry) bool {
+	if amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount must be positive"})
+		endpointLogger.WithField("amount", amount).Warn("amount is not positive")
 
-		return nil, err
+		return false
 	}
 
-	return ep.Logger, nil
-}
-
-func epSvc(c *gin.Context) (*wallet.Service, error) {
-	ep, err := epInstance(c)
-	if err != nil {
-		logrus.Fatal("fail to get endpoint")
-		return nil, err
+	limits := ep.limits.Load()
+	if limits == nil || limits.MaxAmount == 0 || amount <= limits.MaxAmount {
+		return true
 	}
 
-	return ep.Svc, nil
+	c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("amount exceeds the limit of %g", limits.MaxAmount)})
+	endpointLogger.WithFields(logrus.Fields{
+		"amount":     amount,
+		"max_amount": limits.MaxAmount,
+	}).Warn("amount exceeds the limit")
+
+	return false
 }
@@ -0,0 +1,66 @@
+package endpoint
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/export"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func addExportRoutes(wallet *gin.RouterGroup, ep *Endpoint) {
+	wallet.GET("/:user_id/transactions/export", ep.exportHandler)
+}
+
+// exportHandler writes the transaction history in the format of ?format=ofx|camt053|csv
+// straight to the response
+func (ep *Endpoint) exportHandler(c *gin.Context) {
+	endpointLogger := ep.logger(c)
+
+	userID, ok := intParam(c, "user_id", endpointLogger)
+	if !ok {
+		return
+	}
+
+	format, err := export.ParseFormat(c.Query("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		endpointLogger.WithField("err", err).Error("invalid export format")
+
+		return
+	}
+
+	account, err := export.Load(c.Request.Context(), ep.Svc, userID, time.Now())
+	if err != nil {
+		c.JSON(walletErrorStatus(err), gin.H{"error": err.Error()})
+		endpointLogger.WithFields(logrus.Fields{
+			"err":     err,
+			"user_id": userID,
+		}).Error("failed to load transactions for export")
+
+		return
+	}
+
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="transactions-%d.%s"`, userID, format.Extension()))
+	c.Status(http.StatusOK)
+
+	// the status is sent with the first write, a failure can only be logged
+	if err = ep.Exporter.Write(c.Writer, format, account); err != nil {
+		endpointLogger.WithFields(logrus.Fields{
+			"err":     err,
+			"user_id": userID,
+		}).Error("failed to write export")
+
+		return
+	}
+
+	endpointLogger.WithFields(logrus.Fields{
+		"user_id":      userID,
+		"format":       format,
+		"transactions": len(account.Entries),
+	}).Info("successful export of transactions")
+}
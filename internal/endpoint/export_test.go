@@ -0,0 +1,81 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/export"
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	mockSvc := &mockWalletService{
+		GetTransactionsAsOfFunc: func(_ context.Context, userID int, _ time.Time) ([]wallet.Transaction, error) {
+			if userID == 404 {
+				return nil, fmt.Errorf("failed: %w", wallet.ErrWalletNotFound)
+			}
+
+			return []wallet.Transaction{
+				{TransactionID: 2, FromUserID: userID, ToUserID: 2, Amount: 30, TransactionType: "transfer", Timestamp: "2025-02-03T10:00:00Z"},
+				{TransactionID: 1, FromUserID: userID, Amount: 50, TransactionType: "deposit", Timestamp: "2025-02-03T09:30:00Z"},
+			}, nil
+		},
+		GetBalanceAsOfFunc: func(_ context.Context, _ int, _ time.Time) (float64, error) {
+			return 120, nil
+		},
+	}
+	ep := New(mockSvc)
+	ep.Exporter = export.New(export.Config{Currency: "EUR", BankID: "WALLETXX"})
+	addExportRoutes(router.Group("/wallet"), ep)
+
+	t.Run("ofx", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/wallet/1/transactions/export?format=ofx", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "application/x-ofx", w.Header().Get("Content-Type"))
+		require.Contains(t, w.Body.String(), "<TRNAMT>-30.00</TRNAMT>")
+		require.Contains(t, w.Body.String(), "<BALAMT>120.00</BALAMT>")
+	})
+
+	t.Run("camt053", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/wallet/1/transactions/export?format=camt053", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, `attachment; filename="transactions-1.xml"`, w.Header().Get("Content-Disposition"))
+		require.Contains(t, w.Body.String(), `<Amt Ccy="EUR">100.00</Amt>`, "opening balance")
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/wallet/1/transactions/export?format=csv", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Len(t, strings.Split(strings.TrimSpace(w.Body.String()), "\n"), 3)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/wallet/1/transactions/export?format=qif", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("unknown wallet", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/wallet/404/transactions/export?format=csv", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
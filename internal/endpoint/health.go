@@ -0,0 +1,53 @@
+package endpoint
+
+import (
+	"net/http"
+
+	"github.com/amelonpie/wallet-service/pkg/breaker"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	healthOK       = "ok"
+	healthDegraded = "degraded"
+)
+
+func addHealthRoutes(router *gin.Engine, ep *Endpoint) {
+	router.GET("/healthz", ep.healthHandler)
+	router.GET("/readyz", ep.readyHandler)
+	router.GET("/status", ep.statusHandler)
+}
+
+// healthHandler is the liveness check: it answers as long as the process serves
+// requests, without touching Postgres or Redis. It reports degraded while balances
+// bypass the cache. The service still works from Postgres alone, so degraded is
+// not an error status.
+func (ep *Endpoint) healthHandler(c *gin.Context) {
+	cacheState := ep.Svc.CacheState()
+
+	status := healthOK
+	if cacheState != breaker.Closed {
+		status = healthDegraded
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": status,
+		"cache":  cacheState,
+	})
+}
+
+// readyHandler answers 503 while Postgres cannot be reached or the instance is
+// draining, so no new requests are routed to it
+func (ep *Endpoint) readyHandler(c *gin.Context) {
+	readiness := ep.Health.Ready(c.Request.Context())
+	if !readiness.Ready {
+		c.JSON(http.StatusServiceUnavailable, readiness)
+		return
+	}
+
+	c.JSON(http.StatusOK, readiness)
+}
+
+func (ep *Endpoint) statusHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, ep.Health.Status(c.Request.Context()))
+}
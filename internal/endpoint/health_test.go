@@ -0,0 +1,96 @@
+package endpoint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/amelonpie/wallet-service/internal/health"
+	"github.com/amelonpie/wallet-service/pkg/breaker"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		state    breaker.State
+		expected string
+	}{
+		{"cache healthy", breaker.Closed, `{"status":"ok","cache":"closed"}`},
+		{"cache open", breaker.Open, `{"status":"degraded","cache":"open"}`},
+		{"cache probing", breaker.HalfOpen, `{"status":"degraded","cache":"half-open"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			addHealthRoutes(router, New(&mockWalletService{
+				CacheStateFunc: func() breaker.State { return tt.state },
+			}))
+
+			req, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			require.JSONEq(t, tt.expected, w.Body.String())
+		})
+	}
+}
+
+func TestReadyAndStatusHandlers(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+
+	db, mockSQL, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	checker := health.New(db, nil, func() breaker.State { return breaker.Open }, health.Config{Timeout: time.Second})
+
+	ep := New(&mockWalletService{})
+	ep.Health = checker
+
+	router := gin.New()
+	addHealthRoutes(router, ep)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		return w
+	}
+
+	mockSQL.ExpectPing()
+	mockSQL.ExpectPing()
+	mockSQL.ExpectQuery(`SELECT to_regclass`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mockSQL.ExpectQuery(`FROM schema_migrations`).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(9))
+	mockSQL.ExpectQuery(`FROM webhook_deliveries`).WillReturnRows(sqlmock.NewRows([]string{"count", "lag"}).AddRow(0, 0))
+	mockSQL.ExpectPing()
+
+	// Act
+	ready := get("/readyz")
+	status := get("/status")
+
+	checker.Drain()
+	draining := get("/readyz")
+
+	// Assert
+	require.Equal(t, http.StatusOK, ready.Code)
+	require.JSONEq(t, `{"ready":true,"checks":{"postgres":"up","redis":"disabled"}}`, ready.Body.String())
+
+	require.Equal(t, http.StatusOK, status.Code)
+	require.Contains(t, status.Body.String(), `"migration_version":9`)
+	require.Contains(t, status.Body.String(), `"cache":"open"`)
+
+	require.Equal(t, http.StatusServiceUnavailable, draining.Code)
+	require.Contains(t, draining.Body.String(), `"draining":true`)
+	require.NoError(t, mockSQL.ExpectationsWereMet())
+}
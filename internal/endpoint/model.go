@@ -1,5 +1,7 @@
 package endpoint
 
+import "github.com/amelonpie/wallet-service/internal/payout"
+
 // Request structures for JSON body binding
 type DepositRequest struct {
 	Amount float64 `json:"amount" binding:"required"`
@@ -9,8 +11,23 @@ type WithdrawRequest struct {
 	Amount float64 `json:"amount" binding:"required"`
 }
 
+// TransferRequest names the receiving wallet by user id or by its virtual account number
 type TransferRequest struct {
 	FromUserID int     `json:"from_user_id" binding:"required"`
-	ToUserID   int     `json:"to_user_id" binding:"required"`
+	ToUserID   int     `json:"to_user_id" binding:"required_without=ToAccount"`
+	ToAccount  string  `json:"to_account,omitempty"`
 	Amount     float64 `json:"amount" binding:"required"`
 }
+
+type CreatePayoutRequest struct {
+	Amount      float64            `json:"amount" binding:"required"`
+	Destination payout.Destination `json:"destination" binding:"required"`
+	Remittance  string             `json:"remittance"`
+}
+
+type CreateWebhookRequest struct {
+	UserID     int      `json:"user_id" binding:"required"`
+	URL        string   `json:"url" binding:"required"`
+	EventTypes []string `json:"event_types" binding:"required"`
+	Secret     string   `json:"secret"`
+}
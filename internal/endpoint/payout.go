@@ -0,0 +1,107 @@
+package endpoint
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/amelonpie/wallet-service/internal/payout"
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func addPayoutRoutes(wallet *gin.RouterGroup, ep *Endpoint) {
+	wallet.POST("/wallet/:user_id/payouts", ep.createPayoutHandler)
+	wallet.GET("/wallet/:user_id/payouts", ep.listPayoutsHandler)
+}
+
+// payoutErrorStatus maps payout errors to the HTTP status returned to the caller
+func payoutErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, payout.ErrInvalidDestination), errors.Is(err, payout.ErrInvalidAmount):
+		return http.StatusBadRequest
+	case errors.Is(err, wallet.ErrInsufficientFunds), errors.Is(err, wallet.ErrWalletFrozen):
+		return http.StatusConflict
+	default:
+		return walletErrorStatus(err)
+	}
+}
+
+// createPayoutHandler withdraws to a bank account, the payout goes out with the
+// next payout file
+func (ep *Endpoint) createPayoutHandler(c *gin.Context) {
+	endpointLogger := ep.logger(c)
+
+	userID, ok := intParam(c, "user_id", endpointLogger)
+	if !ok {
+		return
+	}
+
+	var req CreatePayoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		endpointLogger.WithField("err", err).Error("invalid request body")
+
+		return
+	}
+
+	if !ep.withinLimits(c, req.Amount, endpointLogger) {
+		return
+	}
+
+	p, err := ep.Payouts.Request(c.Request.Context(), userID, req.Amount, req.Destination, req.Remittance)
+	if err != nil {
+		c.JSON(payoutErrorStatus(err), gin.H{"error": err.Error()})
+		endpointLogger.WithFields(logrus.Fields{
+			"err":     err,
+			"user_id": userID,
+			"amount":  req.Amount,
+		}).Error("failed to request payout")
+
+		return
+	}
+
+	// a requested payout is not debited yet, the next batch run completes it
+	status := http.StatusCreated
+	if p.Status == payout.StatusRequested {
+		status = http.StatusAccepted
+	}
+
+	c.JSON(status, p)
+	endpointLogger.WithFields(logrus.Fields{
+		"user_id":   userID,
+		"payout_id": p.PayoutID,
+		"amount":    p.Amount,
+	}).Info("successful payout request")
+}
+
+// listPayoutsHandler lists the payouts of the user, ?status= filters by state
+func (ep *Endpoint) listPayoutsHandler(c *gin.Context) {
+	endpointLogger := ep.logger(c)
+
+	userID, ok := intParam(c, "user_id", endpointLogger)
+	if !ok {
+		return
+	}
+
+	list, err := ep.Payouts.List(c.Request.Context(), userID, c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		endpointLogger.WithFields(logrus.Fields{
+			"err":     err,
+			"user_id": userID,
+		}).Error("failed to list ep.Payouts")
+
+		return
+	}
+
+	if list == nil {
+		list = []payout.Payout{}
+	}
+
+	c.JSON(http.StatusOK, list)
+	endpointLogger.WithFields(logrus.Fields{
+		"user_id":    userID,
+		"ep.Payouts": len(list),
+	}).Info("successful list of ep.Payouts")
+}
@@ -0,0 +1,131 @@
+package endpoint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amelonpie/wallet-service/internal/payout"
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+type mockPayoutService struct {
+	RequestFunc func(ctx context.Context, userID int, amount float64, dest payout.Destination, remittance string) (payout.Payout, error)
+	ListFunc    func(ctx context.Context, userID int, status string) ([]payout.Payout, error)
+}
+
+func (m *mockPayoutService) Request(
+	ctx context.Context, userID int, amount float64, dest payout.Destination, remittance string,
+) (payout.Payout, error) {
+	return m.RequestFunc(ctx, userID, amount, dest, remittance)
+}
+func (m *mockPayoutService) List(ctx context.Context, userID int, status string) ([]payout.Payout, error) {
+	return m.ListFunc(ctx, userID, status)
+}
+func (m *mockPayoutService) Batch(context.Context) (payout.Batch, error) { return payout.Batch{}, nil }
+func (m *mockPayoutService) GetBatch(context.Context, int) (payout.Batch, error) {
+	return payout.Batch{}, nil
+}
+func (m *mockPayoutService) WriteBatch(io.Writer, payout.Batch) error { return nil }
+func (m *mockPayoutService) Ingest(context.Context, io.Reader, payout.AckFormat) (payout.AckReport, error) {
+	return payout.AckReport{}, nil
+}
+
+func TestPayoutHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	mockPayouts := &mockPayoutService{
+		RequestFunc: func(_ context.Context, userID int, amount float64, dest payout.Destination, _ string) (payout.Payout, error) {
+			switch {
+			case dest.IBAN == "":
+				return payout.Payout{}, fmt.Errorf("%w: iban", payout.ErrInvalidDestination)
+			case amount > 100:
+				return payout.Payout{}, fmt.Errorf("failed to withdraw payout 2: %w", wallet.ErrInsufficientFunds)
+			case amount == 77:
+				return payout.Payout{PayoutID: 3, UserID: userID, Amount: amount, Destination: dest, Status: payout.StatusRequested}, nil
+			}
+
+			return payout.Payout{PayoutID: 1, UserID: userID, Amount: amount, Destination: dest, Status: payout.StatusPending}, nil
+		},
+		ListFunc: func(_ context.Context, userID int, status string) ([]payout.Payout, error) {
+			if status != "" {
+				return nil, nil
+			}
+
+			return []payout.Payout{{PayoutID: 1, UserID: userID, Status: payout.StatusBatched}}, nil
+		},
+	}
+	ep := New(&mockWalletService{})
+	ep.Payouts = mockPayouts
+	addPayoutRoutes(router.Group("/wallet"), ep)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/wallet/wallet/1/payouts", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		return w
+	}
+
+	t.Run("request", func(t *testing.T) {
+		w := post(`{"amount": 50, "destination": {"name": "Ada Lovelace", "iban": "GB29NWBK60161331926819"}}`)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var p payout.Payout
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+		require.Equal(t, payout.StatusPending, p.Status)
+		require.Equal(t, "GB29NWBK60161331926819", p.Destination.IBAN)
+	})
+
+	t.Run("request left for the batch run", func(t *testing.T) {
+		w := post(`{"amount": 77, "destination": {"name": "Ada Lovelace", "iban": "GB29NWBK60161331926819"}}`)
+		require.Equal(t, http.StatusAccepted, w.Code)
+		require.Contains(t, w.Body.String(), `"payout_id":3`)
+	})
+
+	t.Run("invalid destination", func(t *testing.T) {
+		w := post(`{"amount": 50, "destination": {"name": "Ada Lovelace"}}`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("insufficient funds", func(t *testing.T) {
+		w := post(`{"amount": 500, "destination": {"name": "Ada Lovelace", "iban": "GB29NWBK60161331926819"}}`)
+		require.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("negative amount", func(t *testing.T) {
+		w := post(`{"amount": -50, "destination": {"name": "Ada Lovelace", "iban": "GB29NWBK60161331926819"}}`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Contains(t, w.Body.String(), "amount must be positive")
+	})
+
+	t.Run("missing amount", func(t *testing.T) {
+		w := post(`{"destination": {"name": "Ada Lovelace", "iban": "GB29NWBK60161331926819"}}`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/wallet/wallet/1/payouts", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), `"status":"batched"`)
+	})
+
+	t.Run("list empty", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/wallet/wallet/1/payouts?status=returned", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "[]", w.Body.String())
+	})
+}
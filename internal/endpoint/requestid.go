@@ -0,0 +1,50 @@
+package endpoint
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+	"strconv"
+
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const HeaderRequestID = "X-Request-ID"
+
+// ids of callers are only taken over when they cannot break a log line
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`) //nolint:gochecknoglobals // compiled once
+
+// RequestID takes over the X-Request-ID of the caller or assigns a new one,
+// returns it in the response and puts it, with the user id of the path, into the
+// request context so every log line of the request carries them
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(HeaderRequestID)
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+
+		c.Header(HeaderRequestID, requestID)
+
+		ctx := log.WithRequestID(c.Request.Context(), requestID)
+		if userID, err := strconv.Atoi(c.Param("user_id")); err == nil {
+			ctx = log.WithUserID(ctx, userID)
+		}
+
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String(log.FieldRequestID, requestID))
+
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16) //nolint:mnd // 128 bit
+	_, _ = rand.Read(buf)   // never fails, see crypto/rand.Read
+
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,61 @@
+package endpoint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID())
+
+	var fields logrus.Fields
+
+	router.GET("/wallet/:user_id/balance", func(c *gin.Context) {
+		fields = log.FromContext(c.Request.Context(), logrus.NewEntry(logrus.New())).Data
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"propagated", "3f2a9c1e-req", true},
+		{"missing", "", false},
+		{"invalid", "bad id\nforged=1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/wallet/7/balance", nil)
+			if tt.header != "" {
+				req.Header.Set(HeaderRequestID, tt.header)
+			}
+
+			w := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(w, req)
+
+			// Assert
+			requestID := w.Header().Get(HeaderRequestID)
+			if tt.keep {
+				require.Equal(t, tt.header, requestID)
+			} else {
+				require.Len(t, requestID, 32)
+			}
+
+			require.Equal(t, requestID, fields[log.FieldRequestID])
+			require.Equal(t, 7, fields[log.FieldUserID])
+		})
+	}
+}
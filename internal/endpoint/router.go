@@ -1,39 +1,36 @@
 package endpoint
 
 import (
-	"github.com/amelonpie/wallet-service/internal/wallet"
 	"github.com/gin-gonic/gin"
-	"github.com/sirupsen/logrus"
 )
 
-func SetupRouters(router *gin.Engine) {
-	var repo wallet.Repository
-
-	var err error
+// Register adds the routes of ep to router. Webhooks, payouts and virtual
+// accounts are stored in Postgres, their routes are left out when ep has none.
+func Register(router *gin.Engine, ep *Endpoint) {
+	wallet := router.Group("/wallet")
+	{
+		addTransactionRoutes(wallet, ep)
+		addViewRoutes(wallet, ep)
+		addStreamRoutes(wallet, ep)
+		addStatementRoutes(wallet, ep)
+		addExportRoutes(wallet, ep)
 
-	repo, err = wallet.InitRepository()
-	if err != nil {
-		msg := "failed to initialize wallet repository"
-		logrus.Fatalf("%s: %v", msg, err)
+		if ep.Payouts != nil {
+			addPayoutRoutes(wallet, ep)
+		}
 
-		panic(err)
+		if ep.Accounts != nil {
+			addAccountRoutes(wallet, ep)
+		}
 	}
 
-	var svc wallet.Service
-	svc, err = wallet.InitService(repo)
-
-	if err != nil {
-		msg := "failed to initialize wallet service"
-		logrus.Fatalf("%s: %v", msg, err)
-
-		panic(err)
+	if ep.Accounts != nil {
+		addAccountLookupRoutes(router.Group("/accounts"), ep)
 	}
 
-	ep := newEndpoint(svc)
-
-	wallet := router.Group("/wallet")
-	{
-		addTransactionRoutes(wallet, ep)
-		addViewRoutes(wallet, ep)
+	if ep.Webhooks != nil {
+		addWebhookRoutes(router.Group("/webhooks"), ep)
 	}
+
+	addHealthRoutes(router, ep)
 }
@@ -0,0 +1,86 @@
+package endpoint
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/amelonpie/wallet-service/internal/statement"
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func addStatementRoutes(wallet *gin.RouterGroup, ep *Endpoint) {
+	wallet.GET("/:user_id/statements/:period", ep.statementHandler)
+}
+
+// walletErrorStatus maps wallet errors to the HTTP status returned to the caller
+func walletErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, wallet.ErrWalletNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, wallet.ErrInvalidPeriod):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// statementHandler renders the statement of a month, ?format= selects json (default),
+// csv or text
+func (ep *Endpoint) statementHandler(c *gin.Context) {
+	endpointLogger := ep.logger(c)
+
+	userID, ok := intParam(c, "user_id", endpointLogger)
+	if !ok {
+		return
+	}
+
+	period, err := wallet.ParsePeriod(c.Param("period"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		endpointLogger.WithField("err", err).Error("invalid period")
+
+		return
+	}
+
+	format, err := statement.ParseFormat(c.Query("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		endpointLogger.WithField("err", err).Error("invalid statement format")
+
+		return
+	}
+
+	st, err := ep.Svc.GetStatement(c.Request.Context(), userID, period)
+	if err != nil {
+		c.JSON(walletErrorStatus(err), gin.H{"error": err.Error()})
+		endpointLogger.WithFields(logrus.Fields{
+			"err":     err,
+			"user_id": userID,
+			"period":  period.String(),
+		}).Error("failed to get statement")
+
+		return
+	}
+
+	var buf bytes.Buffer
+	if err = statement.Render(&buf, format, st); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server internal error"})
+		endpointLogger.WithField("err", err).Error("failed to render statement")
+
+		return
+	}
+
+	c.Header("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="statement-%d-%s.%s"`, userID, st.Period, format.Extension()))
+	c.Data(http.StatusOK, format.ContentType(), buf.Bytes())
+	endpointLogger.WithFields(logrus.Fields{
+		"user_id": userID,
+		"period":  st.Period,
+		"format":  format,
+		"final":   st.Final,
+	}).Info("successful get statement")
+}
@@ -0,0 +1,91 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatementHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	mockSvc := &mockWalletService{
+		GetStatementFunc: func(_ context.Context, userID int, period wallet.Period) (wallet.Statement, error) {
+			if userID == 404 {
+				return wallet.Statement{}, fmt.Errorf("failed: %w", wallet.ErrWalletNotFound)
+			}
+
+			return wallet.Statement{
+				UserID:         userID,
+				Period:         period.String(),
+				From:           period.Start(),
+				To:             period.End(),
+				OpeningBalance: 100,
+				ClosingBalance: 150,
+				Entries: []wallet.StatementEntry{
+					{TransactionID: 1, Timestamp: period.Start().Add(time.Hour), Type: "deposit", Amount: 50, Balance: 150},
+				},
+				Totals: []wallet.StatementTotal{{Type: "deposit", Count: 1, Credits: 50}},
+				Final:  true,
+			}, nil
+		},
+	}
+	addStatementRoutes(router.Group("/wallet"), New(mockSvc))
+
+	t.Run("json by default", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/wallet/1/statements/2025-02", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Header().Get("Content-Type"), "application/json")
+		require.Contains(t, w.Body.String(), `"closing_balance": 150`)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/wallet/1/statements/2025-02?format=csv", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+		require.Equal(t, `attachment; filename="statement-1-2025-02.csv"`, w.Header().Get("Content-Disposition"))
+		require.True(t, strings.HasPrefix(w.Body.String(), "record,timestamp,"))
+	})
+
+	t.Run("text", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/wallet/1/statements/2025-02?format=text", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), "Period:     2025-02 (2025-02-01 to 2025-02-28)")
+	})
+
+	t.Run("invalid period", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/wallet/1/statements/february", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/wallet/1/statements/2025-02?format=pdf", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("unknown wallet", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/wallet/404/statements/2025-02", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,303 @@
+package endpoint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	streamHeartbeat  = 15 * time.Second
+	wsWriteTimeout   = 10 * time.Second
+	balanceEventType = "balance"
+)
+
+// streamFrame is one event of a stream, written as SSE event or WebSocket JSON message
+type streamFrame struct {
+	ID   int64  `json:"id,omitempty"`
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+func addStreamRoutes(wallet *gin.RouterGroup, ep *Endpoint) {
+	wallet.GET("/:user_id/stream", ep.streamHandler)
+
+	if ep.Websocket {
+		wallet.GET("/:user_id/stream/ws", ep.websocketStreamHandler)
+	}
+}
+
+// lastEventID reads the resume point. ok is false for a fresh connection.
+func lastEventID(c *gin.Context) (int64, bool) {
+	raw := c.GetHeader("Last-Event-ID")
+	if raw == "" {
+		raw = c.Query("last_event_id")
+	}
+
+	if raw == "" {
+		return 0, false
+	}
+
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id < 0 {
+		return 0, false
+	}
+
+	return id, true
+}
+
+// balanceFrame returns the current balance as the first frame of a stream
+func (ep *Endpoint) balanceFrame(ctx context.Context, userID int) (streamFrame, error) {
+	balance, err := ep.Svc.GetBalance(ctx, userID)
+	if err != nil {
+		return streamFrame{}, fmt.Errorf("failed to get balance for user %d: %w", userID, err)
+	}
+
+	return streamFrame{Type: balanceEventType, Data: gin.H{"user_id": userID, "balance": balance}}, nil
+}
+
+// backlog returns what a new stream starts with and the id after which events
+// are streamed: the current balance for a fresh connection, or the buffered
+// events after the resume point. When the buffer no longer covers the resume
+// point the client gets the current balance too, and events after the last id
+// assigned before reading it.
+func (ep *Endpoint) backlog(ctx context.Context, c *gin.Context, userID int) ([]streamFrame, int64, error) {
+	afterID, resume := lastEventID(c)
+	if !resume {
+		frame, err := ep.balanceFrame(ctx, userID)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		return []streamFrame{frame}, 0, nil
+	}
+
+	replay, err := ep.Stream.Replay(ctx, userID, afterID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !replay.Complete(afterID) {
+		log.FromContext(ctx, ep.Logger).WithFields(logrus.Fields{
+			"user_id":       userID,
+			"last_event_id": afterID,
+			"last_id":       replay.LastID,
+		}).Info("events after last_event_id are gone, resending the balance")
+
+		frame, err := ep.balanceFrame(ctx, userID)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		return []streamFrame{frame}, replay.LastID, nil
+	}
+
+	frames := make([]streamFrame, 0, len(replay.Messages))
+	for _, msg := range replay.Messages {
+		frames = append(frames, streamFrame{ID: msg.ID, Type: msg.Event.Type, Data: msg.Event})
+		afterID = msg.ID
+	}
+
+	return frames, afterID, nil
+}
+
+func writeSSE(w io.Writer, frame streamFrame) error {
+	data, err := json.Marshal(frame.Data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stream event: %w", err)
+	}
+
+	if frame.ID != 0 {
+		if _, err = fmt.Fprintf(w, "id: %d\n", frame.ID); err != nil {
+			return fmt.Errorf("failed to write stream event: %w", err)
+		}
+	}
+
+	if _, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", frame.Type, data); err != nil {
+		return fmt.Errorf("failed to write stream event: %w", err)
+	}
+
+	return nil
+}
+
+func (ep *Endpoint) streamHandler(c *gin.Context) {
+	endpointLogger := ep.logger(c)
+
+	userID, ok := intParam(c, "user_id", endpointLogger)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	// subscribe before reading the backlog so nothing committed in between is lost
+	msgs, cancel := ep.Stream.Subscribe(userID)
+	defer cancel()
+
+	frames, lastID, err := ep.backlog(ctx, c, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		endpointLogger.WithFields(logrus.Fields{
+			"err":     err,
+			"user_id": userID,
+		}).Error("failed to start stream")
+
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	for _, frame := range frames {
+		if err = writeSSE(c.Writer, frame); err != nil {
+			return
+		}
+	}
+
+	c.Writer.Flush()
+	endpointLogger.WithField("user_id", userID).Info("stream connected")
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err = io.WriteString(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+		case msg, open := <-msgs:
+			if !open {
+				// fell behind, the client resumes with Last-Event-ID
+				return
+			}
+
+			if msg.ID <= lastID {
+				continue
+			}
+
+			if err = writeSSE(c.Writer, streamFrame{ID: msg.ID, Type: msg.Event.Type, Data: msg.Event}); err != nil {
+				return
+			}
+
+			lastID = msg.ID
+		}
+
+		c.Writer.Flush()
+	}
+}
+
+// checkOrigin accepts WebSocket upgrades from clients that send no Origin, from
+// the same origin and from the origins AllowOrigin allows
+func (ep *Endpoint) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	return ep.AllowOrigin != nil && ep.AllowOrigin(origin)
+}
+
+func (ep *Endpoint) websocketStreamHandler(c *gin.Context) {
+	endpointLogger := ep.logger(c)
+
+	userID, ok := intParam(c, "user_id", endpointLogger)
+	if !ok {
+		return
+	}
+
+	ctx, stop := context.WithCancel(c.Request.Context())
+	defer stop()
+
+	msgs, cancel := ep.Stream.Subscribe(userID)
+	defer cancel()
+
+	frames, lastID, err := ep.backlog(ctx, c, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// CORS does not apply to WebSocket upgrades, so the origin is checked here
+	upgrader := websocket.Upgrader{CheckOrigin: ep.checkOrigin}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		endpointLogger.WithField("err", err).Error("failed to upgrade stream to websocket")
+		return
+	}
+	defer conn.Close()
+
+	// the client sends nothing; reading detects when it goes away
+	go func() {
+		defer stop()
+
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	write := func(frame streamFrame) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		return conn.WriteJSON(frame)
+	}
+
+	for _, frame := range frames {
+		if err = write(frame); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout))
+		case msg, open := <-msgs:
+			if !open {
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "resume with last_event_id"),
+					time.Now().Add(wsWriteTimeout))
+
+				return
+			}
+
+			if msg.ID <= lastID {
+				continue
+			}
+
+			err = write(streamFrame{ID: msg.ID, Type: msg.Event.Type, Data: msg.Event})
+			lastID = msg.ID
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
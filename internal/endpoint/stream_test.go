@@ -0,0 +1,237 @@
+package endpoint
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/stream"
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// readSSE reads events from the stream until n events were read
+func readSSE(t *testing.T, scanner *bufio.Scanner, n int) []map[string]string {
+	t.Helper()
+
+	var (
+		events  []map[string]string
+		current = map[string]string{}
+	)
+
+	for len(events) < n && scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			events = append(events, current)
+			current = map[string]string{}
+
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ": ")
+		current[field] = value
+	}
+
+	require.Len(t, events, n)
+
+	return events
+}
+
+func setupStreamServer(t *testing.T) (*httptest.Server, *stream.Broker) {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	broker := stream.NewBroker(stream.NewMemoryBackend(10))
+	ep := New(&mockWalletService{
+		GetBalanceFunc: func(_ context.Context, _ int) (float64, error) {
+			return 42, nil
+		},
+	})
+	ep.Stream = broker
+	addStreamRoutes(router.Group("/wallet"), ep)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return server, broker
+}
+
+func TestStreamHandler_SendsBalanceOnConnect(t *testing.T) {
+	server, _ := setupStreamServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/wallet/1/stream", nil)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := readSSE(t, bufio.NewScanner(resp.Body), 1)
+	require.Equal(t, "balance", events[0]["event"])
+	require.JSONEq(t, `{"user_id":1,"balance":42}`, events[0]["data"])
+}
+
+func TestStreamHandler_ResumesFromLastEventID(t *testing.T) {
+	server, broker := setupStreamServer(t)
+
+	for _, amount := range []float64{10, 20, 30} {
+		require.NoError(t, broker.Notify(context.Background(), wallet.Event{Type: wallet.EventDeposit, UserID: 1, Amount: amount}))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/wallet/1/stream", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	events := readSSE(t, bufio.NewScanner(resp.Body), 2)
+	require.Equal(t, "2", events[0]["id"])
+	require.Equal(t, "3", events[1]["id"])
+	require.Equal(t, wallet.EventDeposit, events[1]["event"])
+}
+
+// notifyUntilDone publishes deposits of user 1 until ctx is done, so that one
+// reaches the stream however long the broker takes to start listening
+func notifyUntilDone(ctx context.Context, broker *stream.Broker) {
+	go broker.Run(ctx)
+
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_ = broker.Notify(ctx, wallet.Event{Type: wallet.EventDeposit, UserID: 1, Amount: 1})
+			}
+		}
+	}()
+}
+
+func TestStreamHandler_ResendsBalanceWhenReplayIncomplete(t *testing.T) {
+	tests := []struct {
+		name        string
+		published   int
+		lastEventID string
+	}{
+		{"events trimmed from the buffer", 15, "2"},
+		{"ids reset", 0, "99"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			server, broker := setupStreamServer(t)
+
+			for range tt.published {
+				require.NoError(t, broker.Notify(context.Background(), wallet.Event{Type: wallet.EventDeposit, UserID: 1, Amount: 1}))
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/wallet/1/stream", nil)
+			req.Header.Set("Last-Event-ID", tt.lastEventID)
+
+			// Act
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+
+			defer resp.Body.Close()
+
+			notifyUntilDone(ctx, broker)
+
+			// Assert
+			events := readSSE(t, bufio.NewScanner(resp.Body), 2)
+			require.Equal(t, "balance", events[0]["event"])
+			require.Empty(t, events[0]["id"])
+			require.JSONEq(t, `{"user_id":1,"balance":42}`, events[0]["data"])
+
+			// live events continue after the last id assigned before the balance
+			require.Equal(t, wallet.EventDeposit, events[1]["event"])
+			id, err := strconv.Atoi(events[1]["id"])
+			require.NoError(t, err)
+			require.Greater(t, id, tt.published)
+		})
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	ep := New(&mockWalletService{})
+	ep.AllowOrigin = func(origin string) bool { return origin == "https://app.example" }
+
+	tests := []struct {
+		name     string
+		origin   string
+		expected bool
+	}{
+		{"no origin", "", true},
+		{"same origin", "https://wallet.example", true},
+		{"allowed origin", "https://app.example", true},
+		{"other origin", "https://evil.example", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "https://wallet.example/wallet/1/stream/ws", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+
+			require.Equal(t, tt.expected, ep.checkOrigin(req))
+		})
+	}
+
+	ep.AllowOrigin = nil
+	req := httptest.NewRequest(http.MethodGet, "https://wallet.example/wallet/1/stream/ws", nil)
+	req.Header.Set("Origin", "https://app.example")
+	require.False(t, ep.checkOrigin(req), "only same origin without AllowOrigin")
+}
+
+func TestStreamHandler_InvalidUserID(t *testing.T) {
+	server, _ := setupStreamServer(t)
+
+	resp, err := http.Get(server.URL + "/wallet/abc/stream")
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAddStreamRoutes_Websocket(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for _, enabled := range []bool{false, true} {
+		router := gin.New()
+		ep := New(&mockWalletService{})
+		ep.Websocket = enabled
+		addStreamRoutes(router.Group("/wallet"), ep)
+
+		registered := false
+		for _, route := range router.Routes() {
+			registered = registered || route.Path == "/wallet/:user_id/stream/ws"
+		}
+
+		require.Equal(t, enabled, registered)
+	}
+}
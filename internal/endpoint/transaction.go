@@ -5,7 +5,6 @@ import (
 	"net/http"
 	"strconv"
 
-	"github.com/amelonpie/wallet-service/internal/wallet"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
@@ -28,41 +27,22 @@ import (
 */
 
 func addTransactionRoutes(wallet *gin.RouterGroup, ep *Endpoint) {
-	wallet.POST("/:user_id/deposit", func(c *gin.Context) {
-		c.Set("endpoint", ep)
-		depositHandler(c)
-	})
-	wallet.POST("/:user_id/withdraw", func(c *gin.Context) {
-		c.Set("endpoint", ep)
-		withdrawHandler(c)
-	})
-	wallet.POST("/transfer", func(c *gin.Context) {
-		c.Set("endpoint", ep)
-		transferHandler(c)
-	})
+	wallet.POST("/:user_id/deposit", ep.depositHandler)
+	wallet.POST("/:user_id/withdraw", ep.withdrawHandler)
+	wallet.POST("/transfer", ep.transferHandler)
 }
 
-func depositHandler(c *gin.Context) {
-	handleTransactionRequest(c, "deposit")
+func (ep *Endpoint) depositHandler(c *gin.Context) {
+	ep.handleTransactionRequest(c, "deposit")
 }
 
-func withdrawHandler(c *gin.Context) {
-	handleTransactionRequest(c, "withdraw")
+func (ep *Endpoint) withdrawHandler(c *gin.Context) {
+	ep.handleTransactionRequest(c, "withdraw")
 }
 
 //nolint:funlen
-func handleTransactionRequest(c *gin.Context, requestType string) {
-	endpointLogger, err := epLogger(c)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "server internal error"})
-		reqBody, _ := c.GetRawData()
-		endpointLogger.WithFields(logrus.Fields{
-			"err":          "server internal error",
-			"request_body": reqBody,
-		}).Error("failed to get logger")
-
-		return
-	}
+func (ep *Endpoint) handleTransactionRequest(c *gin.Context, requestType string) {
+	endpointLogger := ep.logger(c)
 
 	// Parse user_id from path
 	userIDParam := c.Param("user_id")
@@ -83,17 +63,13 @@ func handleTransactionRequest(c *gin.Context, requestType string) {
 
 	var serviceMethod func(context.Context, int, float64) (float64, error)
 
-	var svc *wallet.Service
-
-	svc, _ = epSvc(c)
-
 	switch requestType {
 	case "deposit":
 		req = &DepositRequest{}
-		serviceMethod = (*svc).Deposit
+		serviceMethod = ep.Svc.Deposit
 	case "withdraw":
 		req = &WithdrawRequest{}
-		serviceMethod = (*svc).Withdraw
+		serviceMethod = ep.Svc.Withdraw
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request type"})
 		reqBody, _ := c.GetRawData()
@@ -149,7 +125,11 @@ func handleTransactionRequest(c *gin.Context, requestType string) {
 		amount = withdrawReq.Amount
 	}
 
-	newBalance, err := serviceMethod(context.Background(), userID, amount)
+	if !ep.withinLimits(c, amount, endpointLogger) {
+		return
+	}
+
+	newBalance, err := serviceMethod(c.Request.Context(), userID, amount)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -174,21 +154,11 @@ func handleTransactionRequest(c *gin.Context, requestType string) {
 	}).Infof("successful %s", requestType)
 }
 
-func transferHandler(c *gin.Context) {
-	endpointLogger, err := epLogger(c)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "server internal error"})
-		reqBody, _ := c.GetRawData()
-		endpointLogger.WithFields(logrus.Fields{
-			"err":          "server internal error",
-			"request_body": reqBody,
-		}).Error("failed to get logger")
-
-		return
-	}
+func (ep *Endpoint) transferHandler(c *gin.Context) {
+	endpointLogger := ep.logger(c)
 
 	var req TransferRequest
-	if err = c.ShouldBindJSON(&req); err != nil {
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
 		reqBody, _ := c.GetRawData()
 		endpointLogger.WithFields(logrus.Fields{
@@ -199,10 +169,16 @@ func transferHandler(c *gin.Context) {
 		return
 	}
 
-	svc, _ := epSvc(c)
+	if !ep.withinLimits(c, req.Amount, endpointLogger) {
+		return
+	}
 
-	newFromBalance, newToBalance, err := (*svc).Transfer(
-		context.Background(),
+	if req.ToAccount != "" && !ep.resolveToAccount(c, &req, endpointLogger) {
+		return
+	}
+
+	newFromBalance, newToBalance, err := ep.Svc.Transfer(
+		c.Request.Context(),
 		req.FromUserID,
 		req.ToUserID,
 		req.Amount,
@@ -224,3 +200,37 @@ func transferHandler(c *gin.Context) {
 		"new_to_balance":   newToBalance,
 	}).Infof("successful transfer")
 }
+
+// resolveToAccount sets the receiving user of a transfer to the owner of its virtual
+// account, it responds and returns false for an invalid or unknown number
+func (ep *Endpoint) resolveToAccount(c *gin.Context, req *TransferRequest, endpointLogger *logrus.Entry) bool {
+	if ep.Accounts == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "virtual accounts are not available"})
+		return false
+	}
+
+	va, err := ep.Accounts.Lookup(c.Request.Context(), req.ToAccount)
+	if err != nil {
+		c.JSON(accountErrorStatus(err), gin.H{"error": err.Error()})
+		endpointLogger.WithFields(logrus.Fields{
+			"err":        err,
+			"to_account": req.ToAccount,
+		}).Error("failed to resolve to_account")
+
+		return false
+	}
+
+	if req.ToUserID != 0 && req.ToUserID != va.UserID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to_user_id does not own to_account"})
+		endpointLogger.WithFields(logrus.Fields{
+			"to_user_id": req.ToUserID,
+			"to_account": req.ToAccount,
+		}).Error("to_user_id does not own to_account")
+
+		return false
+	}
+
+	req.ToUserID = va.UserID
+
+	return true
+}
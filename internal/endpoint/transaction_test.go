@@ -5,41 +5,68 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"go.uber.org/goleak"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/amelonpie/wallet-service/pkg/breaker"
 	"github.com/gin-gonic/gin"
 )
 
-// func TestMain(m *testing.M) {
-// 	goleak.VerifyTestMain(m)
-// }
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
 
 type mockWalletService struct {
 	DepositFunc               func(ctx context.Context, userID int, amount float64) (float64, error)
+	DepositIdempotentFunc     func(ctx context.Context, userID int, amount float64, key string) (float64, error)
 	WithdrawFunc              func(ctx context.Context, userID int, amount float64) (float64, error)
+	WithdrawIdempotentFunc    func(ctx context.Context, userID int, amount float64, key string) (float64, error)
 	TransferFunc              func(ctx context.Context, fromUserID, toUserID int, amount float64) (float64, float64, error)
 	GetBalanceFunc            func(ctx context.Context, userID int) (float64, error)
+	GetBalanceAsOfFunc        func(ctx context.Context, userID int, asOf time.Time) (float64, error)
 	GetTransactionHistoryFunc func(ctx context.Context, userID int) ([]wallet.Transaction, error)
+	GetTransactionsAsOfFunc   func(ctx context.Context, userID int, asOf time.Time) ([]wallet.Transaction, error)
+	GetStatementFunc          func(ctx context.Context, userID int, period wallet.Period) (wallet.Statement, error)
+	CacheStateFunc            func() breaker.State
 }
 
 func (m *mockWalletService) Deposit(ctx context.Context, userID int, amount float64) (float64, error) {
 	return m.DepositFunc(ctx, userID, amount)
 }
+func (m *mockWalletService) DepositIdempotent(ctx context.Context, userID int, amount float64, key string) (float64, error) {
+	return m.DepositIdempotentFunc(ctx, userID, amount, key)
+}
 func (m *mockWalletService) Withdraw(ctx context.Context, userID int, amount float64) (float64, error) {
 	return m.WithdrawFunc(ctx, userID, amount)
 }
+func (m *mockWalletService) WithdrawIdempotent(ctx context.Context, userID int, amount float64, key string) (float64, error) {
+	return m.WithdrawIdempotentFunc(ctx, userID, amount, key)
+}
 func (m *mockWalletService) Transfer(ctx context.Context, fromUserID, toUserID int, amount float64) (float64, float64, error) {
 	return m.TransferFunc(ctx, fromUserID, toUserID, amount)
 }
 func (m *mockWalletService) GetBalance(ctx context.Context, userID int) (float64, error) {
 	return m.GetBalanceFunc(ctx, userID)
 }
+func (m *mockWalletService) GetBalanceAsOf(ctx context.Context, userID int, asOf time.Time) (float64, error) {
+	return m.GetBalanceAsOfFunc(ctx, userID, asOf)
+}
 func (m *mockWalletService) GetTransactionHistory(ctx context.Context, userID int) ([]wallet.Transaction, error) {
 	return m.GetTransactionHistoryFunc(ctx, userID)
 }
+func (m *mockWalletService) GetTransactionsAsOf(ctx context.Context, userID int, asOf time.Time) ([]wallet.Transaction, error) {
+	return m.GetTransactionsAsOfFunc(ctx, userID, asOf)
+}
+func (m *mockWalletService) GetStatement(ctx context.Context, userID int, period wallet.Period) (wallet.Statement, error) {
+	return m.GetStatementFunc(ctx, userID, period)
+}
+func (m *mockWalletService) CacheState() breaker.State {
+	return m.CacheStateFunc()
+}
 
 var _ wallet.Service = (*mockWalletService)(nil)
 
@@ -57,13 +84,10 @@ func TestDepositHandler(t *testing.T) {
 		},
 	}
 
-	ep := newEndpoint(mockSvc)
+	ep := New(mockSvc)
 
 	rg := router.Group("/wallet")
-	rg.POST("/:user_id/deposit", func(c *gin.Context) {
-		c.Set("endpoint", ep)
-		depositHandler(c)
-	})
+	rg.POST("/:user_id/deposit", ep.depositHandler)
 
 	t.Run("valid deposit", func(t *testing.T) {
 		body, _ := json.Marshal(DepositRequest{Amount: 50.0})
@@ -135,12 +159,9 @@ func TestWithdrawHandler(t *testing.T) {
 		},
 	}
 
-	ep := newEndpoint(mockSvc)
+	ep := New(mockSvc)
 	rg := router.Group("/wallet")
-	rg.POST("/:user_id/withdraw", func(c *gin.Context) {
-		c.Set("endpoint", ep)
-		withdrawHandler(c)
-	})
+	rg.POST("/:user_id/withdraw", ep.withdrawHandler)
 
 	t.Run("valid withdraw", func(t *testing.T) {
 		body, _ := json.Marshal(WithdrawRequest{Amount: 30.0})
@@ -201,11 +222,10 @@ func TestHandleTransactionRequest_InvalidRequestType(t *testing.T) {
 	router := gin.Default()
 
 	mockSvc := &mockWalletService{}
-	ep := newEndpoint(mockSvc)
+	ep := New(mockSvc)
 
 	router.POST("/transaction/:user_id", func(c *gin.Context) {
-		c.Set("endpoint", ep)
-		handleTransactionRequest(c, "other")
+		ep.handleTransactionRequest(c, "other")
 	})
 
 	// Act
@@ -246,12 +266,9 @@ func TestTransferHandler(t *testing.T) {
 		},
 	}
 
-	ep := newEndpoint(mockSvc)
+	ep := New(mockSvc)
 
-	router.POST("/transfer", func(c *gin.Context) {
-		c.Set("endpoint", ep)
-		transferHandler(c)
-	})
+	router.POST("/transfer", ep.transferHandler)
 
 	t.Run("valid transfer", func(t *testing.T) {
 		body, _ := json.Marshal(TransferRequest{FromUserID: 1, ToUserID: 2, Amount: 20.0})
@@ -292,3 +309,119 @@ func TestTransferHandler(t *testing.T) {
 		}
 	})
 }
+
+func TestTransactionHandlers_Limits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	calls := 0
+	mockSvc := &mockWalletService{
+		DepositFunc: func(_ context.Context, _ int, amount float64) (float64, error) {
+			calls++
+			return amount, nil
+		},
+		TransferFunc: func(_ context.Context, _, _ int, amount float64) (float64, float64, error) {
+			calls++
+			return 0, amount, nil
+		},
+	}
+
+	ep := New(mockSvc)
+	ep.SetLimits(Limits{MaxAmount: 100})
+
+	rg := router.Group("/wallet")
+	rg.POST("/:user_id/deposit", ep.depositHandler)
+	rg.POST("/transfer", ep.transferHandler)
+
+	post := func(url string, payload any) int {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		return w.Code
+	}
+
+	if code := post("/wallet/1/deposit", DepositRequest{Amount: 100}); code != http.StatusOK {
+		t.Fatalf("expected a deposit at the limit to pass, got %v", code)
+	}
+
+	if code := post("/wallet/1/deposit", DepositRequest{Amount: 100.5}); code != http.StatusBadRequest {
+		t.Fatalf("expected %v for a deposit over the limit, got %v", http.StatusBadRequest, code)
+	}
+
+	if code := post("/wallet/transfer", TransferRequest{FromUserID: 1, ToUserID: 2, Amount: 500}); code != http.StatusBadRequest {
+		t.Fatalf("expected %v for a transfer over the limit, got %v", http.StatusBadRequest, code)
+	}
+
+	// limits are swapped while serving
+	ep.SetLimits(Limits{})
+
+	if code := post("/wallet/transfer", TransferRequest{FromUserID: 1, ToUserID: 2, Amount: 500}); code != http.StatusOK {
+		t.Fatalf("expected no limit after clearing it, got %v", code)
+	}
+
+	if calls != 2 {
+		t.Fatalf("expected requests over the limit not to reach the service, got %d calls", calls)
+	}
+}
+
+func TestTransactionHandlers_NonPositiveAmount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	calls := 0
+	mockSvc := &mockWalletService{
+		DepositFunc: func(_ context.Context, _ int, amount float64) (float64, error) {
+			calls++
+			return amount, nil
+		},
+		WithdrawFunc: func(_ context.Context, _ int, amount float64) (float64, error) {
+			calls++
+			return amount, nil
+		},
+		TransferFunc: func(_ context.Context, _, _ int, amount float64) (float64, float64, error) {
+			calls++
+			return 0, amount, nil
+		},
+	}
+
+	// no limit configured, the amount is checked all the same
+	ep := New(mockSvc)
+
+	rg := router.Group("/wallet")
+	rg.POST("/:user_id/deposit", ep.depositHandler)
+	rg.POST("/:user_id/withdraw", ep.withdrawHandler)
+	rg.POST("/transfer", ep.transferHandler)
+
+	tests := []struct {
+		name    string
+		url     string
+		payload any
+	}{
+		{"negative deposit", "/wallet/1/deposit", DepositRequest{Amount: -50}},
+		{"negative withdraw", "/wallet/1/withdraw", WithdrawRequest{Amount: -50}},
+		{"negative transfer", "/wallet/transfer", TransferRequest{FromUserID: 1, ToUserID: 2, Amount: -10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.payload)
+			req, _ := http.NewRequest(http.MethodPost, tt.url, bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected %v, got %v", http.StatusBadRequest, w.Code)
+			}
+		})
+	}
+
+	if calls != 0 {
+		t.Fatalf("expected non-positive amounts not to reach the service, got %d calls", calls)
+	}
+}
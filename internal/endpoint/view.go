@@ -1,37 +1,21 @@
 package endpoint
 
 import (
-	"context"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
 func addViewRoutes(wallet *gin.RouterGroup, ep *Endpoint) {
-	wallet.GET("/wallet/:user_id/balance", func(c *gin.Context) {
-		c.Set("endpoint", ep)
-		balanceHandler(c)
-	})
-	wallet.GET("/wallet/:user_id/transactions", func(c *gin.Context) {
-		c.Set("endpoint", ep)
-		transactionsHandler(c)
-	})
+	wallet.GET("/wallet/:user_id/balance", ep.balanceHandler)
+	wallet.GET("/wallet/:user_id/transactions", ep.transactionsHandler)
 }
 
-func balanceHandler(c *gin.Context) {
-	endpointLogger, err := epLogger(c)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "server internal error"})
-		reqBody, _ := c.GetRawData()
-		endpointLogger.WithFields(logrus.Fields{
-			"err":          "server internal error",
-			"request_body": reqBody,
-		}).Error("failed to get logger")
-
-		return
-	}
+func (ep *Endpoint) balanceHandler(c *gin.Context) {
+	endpointLogger := ep.logger(c)
 
 	userIDParam := c.Param("user_id")
 	userID, err := strconv.Atoi(userIDParam)
@@ -46,9 +30,12 @@ func balanceHandler(c *gin.Context) {
 		return
 	}
 
-	svc, _ := epSvc(c)
+	if asOfParam, ok := c.GetQuery("as_of"); ok {
+		ep.balanceAsOfHandler(c, endpointLogger, userID, asOfParam)
+		return
+	}
 
-	balance, err := (*svc).GetBalance(context.Background(), userID)
+	balance, err := ep.Svc.GetBalance(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		endpointLogger.WithFields(logrus.Fields{
@@ -68,19 +55,45 @@ func balanceHandler(c *gin.Context) {
 	}).Info("successful get balance")
 }
 
-func transactionsHandler(c *gin.Context) {
-	endpointLogger, err := epLogger(c)
+// balanceAsOfHandler answers the balance at the RFC3339 time of the as_of parameter
+func (ep *Endpoint) balanceAsOfHandler(c *gin.Context, endpointLogger *logrus.Entry, userID int, asOfParam string) {
+	asOf, err := time.Parse(time.RFC3339, asOfParam)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "server internal error"})
-		reqBody, _ := c.GetRawData()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid as_of, expected RFC3339"})
 		endpointLogger.WithFields(logrus.Fields{
-			"err":          "server internal error",
-			"request_body": reqBody,
-		}).Error("failed to get logger")
+			"err":   err,
+			"as_of": asOfParam,
+		}).Error("invalid as_of")
 
 		return
 	}
 
+	balance, err := ep.Svc.GetBalanceAsOf(c.Request.Context(), userID, asOf)
+	if err != nil {
+		c.JSON(walletErrorStatus(err), gin.H{"error": err.Error()})
+		endpointLogger.WithFields(logrus.Fields{
+			"err":     err,
+			"user_id": userID,
+			"as_of":   asOfParam,
+		}).Errorf("failed to get balance as of")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"balance": balance,
+		"as_of":   asOf.UTC(),
+	})
+	endpointLogger.WithFields(logrus.Fields{
+		"user_id": userID,
+		"balance": balance,
+		"as_of":   asOfParam,
+	}).Info("successful get balance as of")
+}
+
+func (ep *Endpoint) transactionsHandler(c *gin.Context) {
+	endpointLogger := ep.logger(c)
+
 	userIDParam := c.Param("user_id")
 	userID, err := strconv.Atoi(userIDParam)
 
@@ -94,9 +107,7 @@ func transactionsHandler(c *gin.Context) {
 		return
 	}
 
-	svc, _ := epSvc(c)
-
-	history, err := (*svc).GetTransactionHistory(context.Background(), userID)
+	history, err := ep.Svc.GetTransactionHistory(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/amelonpie/wallet-service/internal/wallet"
 	"github.com/gin-gonic/gin"
@@ -23,13 +24,19 @@ func TestBalanceHandler(t *testing.T) {
 			}
 			return 999.99, nil
 		},
+		GetBalanceAsOfFunc: func(_ context.Context, _ int, asOf time.Time) (float64, error) {
+			if asOf.Before(time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)) {
+				return 0, errors.New("connection refused")
+			}
+			if asOf.Before(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)) {
+				return 0, wallet.ErrWalletNotFound
+			}
+			return 42.5, nil
+		},
 	}
-	ep := newEndpoint(mockSvc)
+	ep := New(mockSvc)
 	rg := router.Group("/wallet")
-	rg.GET("/wallet/:user_id/balance", func(c *gin.Context) {
-		c.Set("endpoint", ep)
-		balanceHandler(c)
-	})
+	rg.GET("/wallet/:user_id/balance", ep.balanceHandler)
 
 	t.Run("valid balance request", func(t *testing.T) {
 		req, _ := http.NewRequest(http.MethodGet, "/wallet/wallet/123/balance", nil)
@@ -51,6 +58,35 @@ func TestBalanceHandler(t *testing.T) {
 		router.ServeHTTP(w, req)
 		require.Equal(t, http.StatusInternalServerError, w.Code)
 	})
+
+	t.Run("balance as of", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/wallet/wallet/123/balance?as_of=2025-02-24T23:59:59%2B08:00", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.JSONEq(t, `{"balance":42.5,"as_of":"2025-02-24T15:59:59Z"}`, w.Body.String())
+	})
+
+	t.Run("invalid as_of", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/wallet/wallet/123/balance?as_of=yesterday", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("as_of unknown wallet", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/wallet/wallet/123/balance?as_of=1999-01-01T00:00:00Z", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("as_of service error", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/wallet/wallet/123/balance?as_of=1980-01-01T00:00:00Z", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+	})
 }
 
 func TestTransactionsHandler(t *testing.T) {
@@ -68,12 +104,9 @@ func TestTransactionsHandler(t *testing.T) {
 			}, nil
 		},
 	}
-	ep := newEndpoint(mockSvc)
+	ep := New(mockSvc)
 	rg := router.Group("/wallet")
-	rg.GET("/wallet/:user_id/transactions", func(c *gin.Context) {
-		c.Set("endpoint", ep)
-		transactionsHandler(c)
-	})
+	rg.GET("/wallet/:user_id/transactions", ep.transactionsHandler)
 
 	t.Run("valid user_id", func(t *testing.T) {
 		req, _ := http.NewRequest(http.MethodGet, "/wallet/wallet/123/transactions", nil)
@@ -0,0 +1,167 @@
+package endpoint
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/amelonpie/wallet-service/internal/webhook"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func addWebhookRoutes(webhooks *gin.RouterGroup, ep *Endpoint) {
+	webhooks.POST("", ep.createWebhookHandler)
+	webhooks.GET("/:subscription_id", ep.getWebhookHandler)
+	webhooks.DELETE("/:subscription_id", ep.deleteWebhookHandler)
+	webhooks.GET("/:subscription_id/deliveries", ep.webhookDeliveriesHandler)
+	webhooks.POST("/:subscription_id/deliveries/:delivery_id/redeliver", ep.redeliverWebhookHandler)
+}
+
+// webhookErrorStatus maps webhook errors to the HTTP status returned to the caller
+func webhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, webhook.ErrSubscriptionNotFound), errors.Is(err, webhook.ErrDeliveryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, webhook.ErrInvalidURL), errors.Is(err, webhook.ErrInternalAddress),
+		errors.Is(err, webhook.ErrInvalidEventType):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func intParam(c *gin.Context, name string, logger *logrus.Entry) (int, bool) {
+	param := c.Param(name)
+
+	value, err := strconv.Atoi(param)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		logger.WithFields(logrus.Fields{
+			"err":           err,
+			name + "_param": param,
+		}).Error("invalid " + name)
+
+		return 0, false
+	}
+
+	return value, true
+}
+
+func (ep *Endpoint) createWebhookHandler(c *gin.Context) {
+	endpointLogger := ep.logger(c)
+
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		endpointLogger.WithField("err", err).Error("invalid request body")
+
+		return
+	}
+
+	sub, err := ep.Webhooks.CreateSubscription(c.Request.Context(), req.UserID, req.URL, req.EventTypes, req.Secret)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		endpointLogger.WithFields(logrus.Fields{
+			"err":     err,
+			"user_id": req.UserID,
+		}).Error("failed to create webhook subscription")
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, sub)
+	endpointLogger.WithFields(logrus.Fields{
+		"user_id":         req.UserID,
+		"subscription_id": sub.SubscriptionID,
+	}).Info("successful create webhook subscription")
+}
+
+func (ep *Endpoint) getWebhookHandler(c *gin.Context) {
+	endpointLogger := ep.logger(c)
+
+	subscriptionID, ok := intParam(c, "subscription_id", endpointLogger)
+	if !ok {
+		return
+	}
+
+	sub, err := ep.Webhooks.GetSubscription(c.Request.Context(), subscriptionID)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+func (ep *Endpoint) deleteWebhookHandler(c *gin.Context) {
+	endpointLogger := ep.logger(c)
+
+	subscriptionID, ok := intParam(c, "subscription_id", endpointLogger)
+	if !ok {
+		return
+	}
+
+	if err := ep.Webhooks.DeleteSubscription(c.Request.Context(), subscriptionID); err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+	endpointLogger.WithField("subscription_id", subscriptionID).Info("successful delete webhook subscription")
+}
+
+func (ep *Endpoint) webhookDeliveriesHandler(c *gin.Context) {
+	endpointLogger := ep.logger(c)
+
+	subscriptionID, ok := intParam(c, "subscription_id", endpointLogger)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil || limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	deliveries, err := ep.Webhooks.ListDeliveries(c.Request.Context(), subscriptionID, limit)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+func (ep *Endpoint) redeliverWebhookHandler(c *gin.Context) {
+	endpointLogger := ep.logger(c)
+
+	subscriptionID, ok := intParam(c, "subscription_id", endpointLogger)
+	if !ok {
+		return
+	}
+
+	deliveryID, ok := intParam(c, "delivery_id", endpointLogger)
+	if !ok {
+		return
+	}
+
+	delivery, err := ep.Webhooks.Redeliver(c.Request.Context(), subscriptionID, deliveryID)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		endpointLogger.WithFields(logrus.Fields{
+			"err":             err,
+			"subscription_id": subscriptionID,
+			"delivery_id":     deliveryID,
+		}).Error("failed to redeliver webhook")
+
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+	endpointLogger.WithFields(logrus.Fields{
+		"subscription_id": subscriptionID,
+		"delivery_id":     deliveryID,
+	}).Info("successful queue webhook redelivery")
+}
@@ -0,0 +1,166 @@
+package endpoint
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amelonpie/wallet-service/internal/webhook"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+type mockWebhookService struct {
+	CreateSubscriptionFunc func(ctx context.Context, userID int, url string, eventTypes []string, secret string) (webhook.Subscription, error)
+	GetSubscriptionFunc    func(ctx context.Context, subscriptionID int) (webhook.Subscription, error)
+	DeleteSubscriptionFunc func(ctx context.Context, subscriptionID int) error
+	ListDeliveriesFunc     func(ctx context.Context, subscriptionID int, limit int) ([]webhook.Delivery, error)
+	RedeliverFunc          func(ctx context.Context, subscriptionID, deliveryID int) (webhook.Delivery, error)
+	ReplayDeliveriesFunc   func(ctx context.Context, status string, subscriptionID int) (int64, error)
+}
+
+func (m *mockWebhookService) CreateSubscription(
+	ctx context.Context, userID int, url string, eventTypes []string, secret string,
+) (webhook.Subscription, error) {
+	return m.CreateSubscriptionFunc(ctx, userID, url, eventTypes, secret)
+}
+func (m *mockWebhookService) GetSubscription(ctx context.Context, subscriptionID int) (webhook.Subscription, error) {
+	return m.GetSubscriptionFunc(ctx, subscriptionID)
+}
+func (m *mockWebhookService) DeleteSubscription(ctx context.Context, subscriptionID int) error {
+	return m.DeleteSubscriptionFunc(ctx, subscriptionID)
+}
+func (m *mockWebhookService) ListDeliveries(ctx context.Context, subscriptionID int, limit int) ([]webhook.Delivery, error) {
+	return m.ListDeliveriesFunc(ctx, subscriptionID, limit)
+}
+func (m *mockWebhookService) Redeliver(ctx context.Context, subscriptionID, deliveryID int) (webhook.Delivery, error) {
+	return m.RedeliverFunc(ctx, subscriptionID, deliveryID)
+}
+func (m *mockWebhookService) ReplayDeliveries(ctx context.Context, status string, subscriptionID int) (int64, error) {
+	return m.ReplayDeliveriesFunc(ctx, status, subscriptionID)
+}
+
+var _ webhook.Service = (*mockWebhookService)(nil)
+
+func setupWebhookRouter(hooks webhook.Service) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	ep := New(&mockWalletService{})
+	ep.Webhooks = hooks
+	addWebhookRoutes(router.Group("/webhooks"), ep)
+
+	return router
+}
+
+func TestCreateWebhookHandler(t *testing.T) {
+	router := setupWebhookRouter(&mockWebhookService{
+		CreateSubscriptionFunc: func(_ context.Context, userID int, url string, eventTypes []string, _ string) (webhook.Subscription, error) {
+			if url == "bad" {
+				return webhook.Subscription{}, webhook.ErrInvalidURL
+			}
+
+			return webhook.Subscription{SubscriptionID: 7, UserID: userID, URL: url, EventTypes: eventTypes, Secret: "generated"}, nil
+		},
+	})
+
+	t.Run("valid subscription", func(t *testing.T) {
+		body, _ := json.Marshal(CreateWebhookRequest{UserID: 1, URL: "https://merchant.example/hook", EventTypes: []string{"deposit"}})
+		req, _ := http.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var sub webhook.Subscription
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &sub))
+		require.Equal(t, "generated", sub.Secret)
+	})
+
+	t.Run("invalid url", func(t *testing.T) {
+		body, _ := json.Marshal(CreateWebhookRequest{UserID: 1, URL: "bad", EventTypes: []string{"deposit"}})
+		req, _ := http.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("missing fields", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "/webhooks", bytes.NewBufferString(`{"user_id": 1}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestWebhookDeliveriesHandler(t *testing.T) {
+	router := setupWebhookRouter(&mockWebhookService{
+		ListDeliveriesFunc: func(_ context.Context, subscriptionID int, _ int) ([]webhook.Delivery, error) {
+			if subscriptionID != 7 {
+				return nil, webhook.ErrSubscriptionNotFound
+			}
+
+			return []webhook.Delivery{{DeliveryID: 1, SubscriptionID: 7, Status: webhook.StatusDead}}, nil
+		},
+	})
+
+	t.Run("existing subscription", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/webhooks/7/deliveries", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("unknown subscription", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/webhooks/8/deliveries", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("invalid subscription_id", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/webhooks/abc/deliveries", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestRedeliverWebhookHandler(t *testing.T) {
+	router := setupWebhookRouter(&mockWebhookService{
+		RedeliverFunc: func(_ context.Context, subscriptionID, deliveryID int) (webhook.Delivery, error) {
+			if deliveryID != 1 {
+				return webhook.Delivery{}, webhook.ErrDeliveryNotFound
+			}
+
+			return webhook.Delivery{DeliveryID: deliveryID, SubscriptionID: subscriptionID, Status: webhook.StatusPending}, nil
+		},
+	})
+
+	t.Run("dead delivery", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "/webhooks/7/deliveries/1/redeliver", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusAccepted, w.Code)
+	})
+
+	t.Run("unknown delivery", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "/webhooks/7/deliveries/2/redeliver", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,246 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// camt053Namespace is the BankToCustomerStatement version written
+const camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.08"
+
+// Credit/debit indicators and codes of ISO 20022
+const (
+	camtCredit         = "CRDT"
+	camtDebit          = "DBIT"
+	camtOpeningBooked  = "OPBD"
+	camtClosingBooked  = "CLBD"
+	camtStatusBooked   = "BOOK"
+	camtDateTimeLayout = "2006-01-02T15:04:05Z"
+)
+
+type camtDocument struct {
+	XMLName   xml.Name `xml:"Document"`
+	Namespace string   `xml:"xmlns,attr"`
+	Statement struct {
+		Header struct {
+			MsgID   string `xml:"MsgId"`
+			Created string `xml:"CreDtTm"`
+		} `xml:"GrpHdr"`
+		Stmt camtStatement `xml:"Stmt"`
+	} `xml:"BkToCstmrStmt"`
+}
+
+type camtStatement struct {
+	ID       string `xml:"Id"`
+	Created  string `xml:"CreDtTm"`
+	FromTo   camtFromTo
+	Account  camtAccount   `xml:"Acct"`
+	Balances []camtBalance `xml:"Bal"`
+	Summary  struct {
+		Entries struct {
+			Count  int        `xml:"NbOfNtries"`
+			Sum    string     `xml:"Sum"`
+			NetSum camtNetSum `xml:"TtlNetNtry"`
+		} `xml:"TtlNtries"`
+	} `xml:"TxsSummry"`
+	Entries []camtEntry `xml:"Ntry"`
+}
+
+type camtFromTo struct {
+	XMLName xml.Name `xml:"FrToDt"`
+	From    string   `xml:"FrDtTm"`
+	To      string   `xml:"ToDtTm"`
+}
+
+type camtAccount struct {
+	ID       camtAccountID `xml:"Id"`
+	Currency string        `xml:"Ccy,omitempty"`
+	Servicer *camtServicer `xml:"Svcr,omitempty"`
+}
+
+type camtServicer struct {
+	BIC string `xml:"FinInstnId>BICFI"`
+}
+
+type camtAccountID struct {
+	Other string `xml:"Othr>Id"`
+}
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camtNetSum struct {
+	Amount    string `xml:"Amt"`
+	Indicator string `xml:"CdtDbtInd"`
+}
+
+type camtBalance struct {
+	Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    camtAmount `xml:"Amt"`
+	Indicator string     `xml:"CdtDbtInd"`
+	Date      string     `xml:"Dt>DtTm"`
+}
+
+type camtEntry struct {
+	Reference    string     `xml:"NtryRef"`
+	Amount       camtAmount `xml:"Amt"`
+	Indicator    string     `xml:"CdtDbtInd"`
+	Status       string     `xml:"Sts>Cd"`
+	BookingDate  string     `xml:"BookgDt>DtTm"`
+	ValueDate    string     `xml:"ValDt>DtTm"`
+	ServicerRef  string     `xml:"AcctSvcrRef"`
+	TxCode       string     `xml:"BkTxCd>Prtry>Cd"`
+	Transactions []camtTx   `xml:"NtryDtls>TxDtls"`
+}
+
+type camtTx struct {
+	ServicerRef    string       `xml:"Refs>AcctSvcrRef"`
+	Amount         camtAmount   `xml:"Amt"`
+	Indicator      string       `xml:"CdtDbtInd"`
+	RelatedParties *camtParties `xml:"RltdPties,omitempty"`
+	Remittance     string       `xml:"RmtInf>Ustrd"`
+}
+
+// camtParties names the other wallet of a transfer, the debtor of a credit and
+// the creditor of a debit
+type camtParties struct {
+	Debtor          *camtParty     `xml:"Dbtr,omitempty"`
+	DebtorAccount   *camtAccountID `xml:"DbtrAcct>Id,omitempty"`
+	Creditor        *camtParty     `xml:"Cdtr,omitempty"`
+	CreditorAccount *camtAccountID `xml:"CdtrAcct>Id,omitempty"`
+}
+
+type camtParty struct {
+	Name string `xml:"Pty>Nm"`
+}
+
+func camtTime(t time.Time) string {
+	return t.UTC().Format(camtDateTimeLayout)
+}
+
+// indicator splits a signed amount into the unsigned amount and its indicator
+func indicator(amount float64) (string, string) {
+	if amount < 0 {
+		return money(-amount), camtDebit
+	}
+
+	return money(amount), camtCredit
+}
+
+func (e *Exporter) camtEntry(entry Entry) camtEntry {
+	amount, ind := indicator(entry.Amount)
+	ref := strconv.Itoa(entry.TransactionID)
+
+	tx := camtTx{
+		ServicerRef: ref,
+		Amount:      camtAmount{Currency: e.cfg.Currency, Value: amount},
+		Indicator:   ind,
+		Remittance:  memo(entry),
+	}
+
+	if entry.CounterpartyID != 0 {
+		party := &camtParty{Name: "User " + strconv.Itoa(entry.CounterpartyID)}
+		account := &camtAccountID{Other: strconv.Itoa(entry.CounterpartyID)}
+
+		if ind == camtCredit {
+			tx.RelatedParties = &camtParties{Debtor: party, DebtorAccount: account}
+		} else {
+			tx.RelatedParties = &camtParties{Creditor: party, CreditorAccount: account}
+		}
+	}
+
+	return camtEntry{
+		Reference:    ref,
+		Amount:       tx.Amount,
+		Indicator:    ind,
+		Status:       camtStatusBooked,
+		BookingDate:  camtTime(entry.BookedAt),
+		ValueDate:    camtTime(entry.BookedAt),
+		ServicerRef:  ref,
+		TxCode:       entry.Type,
+		Transactions: []camtTx{tx},
+	}
+}
+
+func (e *Exporter) writeCamt053(w io.Writer, a Account) error {
+	var doc camtDocument
+
+	id := fmt.Sprintf("%d-%s", a.UserID, a.AsOf.UTC().Format("20060102150405"))
+
+	doc.Namespace = camt053Namespace
+	doc.Statement.Header.MsgID = "STMT-" + id
+	doc.Statement.Header.Created = camtTime(a.AsOf)
+
+	from := a.AsOf
+	if len(a.Entries) > 0 {
+		from = a.Entries[0].BookedAt
+	}
+
+	stmt := &doc.Statement.Stmt
+	stmt.ID = id
+	stmt.Created = camtTime(a.AsOf)
+	stmt.FromTo = camtFromTo{From: camtTime(from), To: camtTime(a.AsOf)}
+	stmt.Account = camtAccount{ID: camtAccountID{Other: strconv.Itoa(a.UserID)}, Currency: e.cfg.Currency}
+
+	if e.cfg.BankID != "" {
+		stmt.Account.Servicer = &camtServicer{BIC: e.cfg.BankID}
+	}
+
+	opening, openingInd := indicator(a.Opening)
+	closing, closingInd := indicator(a.Closing)
+	stmt.Balances = []camtBalance{
+		{
+			Code:      camtOpeningBooked,
+			Amount:    camtAmount{Currency: e.cfg.Currency, Value: opening},
+			Indicator: openingInd,
+			Date:      camtTime(from),
+		},
+		{
+			Code:      camtClosingBooked,
+			Amount:    camtAmount{Currency: e.cfg.Currency, Value: closing},
+			Indicator: closingInd,
+			Date:      camtTime(a.AsOf),
+		},
+	}
+
+	var sum float64
+
+	stmt.Entries = make([]camtEntry, 0, len(a.Entries))
+
+	for _, entry := range a.Entries {
+		if entry.Amount < 0 {
+			sum -= entry.Amount
+		} else {
+			sum += entry.Amount
+		}
+
+		stmt.Entries = append(stmt.Entries, e.camtEntry(entry))
+	}
+
+	net, netInd := indicator(roundCents(a.Closing - a.Opening))
+	stmt.Summary.Entries.Count = len(a.Entries)
+	stmt.Summary.Entries.Sum = money(roundCents(sum))
+	stmt.Summary.Entries.NetSum = camtNetSum{Amount: net, Indicator: netInd}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode document: %w", err)
+	}
+
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("failed to write document: %w", err)
+	}
+
+	return nil
+}
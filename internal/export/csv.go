@@ -0,0 +1,43 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// writeCSV writes one row per entry with the signed amount, flushing as it goes
+func (e *Exporter) writeCSV(w io.Writer, a Account) error {
+	cw := csv.NewWriter(w)
+
+	header := []string{"transaction_id", "booking_date", "type", "amount", "currency", "counterparty_id", "description"}
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+
+	for _, entry := range a.Entries {
+		counterparty := ""
+		if entry.CounterpartyID != 0 {
+			counterparty = strconv.Itoa(entry.CounterpartyID)
+		}
+
+		row := []string{
+			strconv.Itoa(entry.TransactionID), entry.BookedAt.Format(time.RFC3339Nano), entry.Type,
+			money(entry.Amount), e.cfg.Currency, counterparty, memo(entry),
+		}
+
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("failed to write transaction %d: %w", entry.TransactionID, err)
+		}
+	}
+
+	cw.Flush()
+
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to flush: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,5 @@
+package export
+
+import "errors"
+
+var ErrUnknownFormat = errors.New("unknown export format")
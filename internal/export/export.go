@@ -0,0 +1,144 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/spf13/viper"
+)
+
+func NewConfig() Config {
+	viper.SetDefault("export.currency", "USD")
+	viper.SetDefault("export.bank_id", "WALLETXX")
+
+	return Config{
+		Currency: viper.GetString("export.currency"),
+		BankID:   viper.GetString("export.bank_id"),
+	}
+}
+
+func New(cfg Config) *Exporter {
+	return &Exporter{cfg: cfg}
+}
+
+// ParseFormat accepts the formats by name
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(name); f {
+	case FormatOFX, FormatCamt053, FormatCSV:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w %q", ErrUnknownFormat, name)
+	}
+}
+
+// ContentType is the media type of the export
+func (f Format) ContentType() string {
+	switch f {
+	case FormatOFX:
+		return "application/x-ofx"
+	case FormatCamt053:
+		return "application/xml"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// Extension is the file name extension of the export
+func (f Format) Extension() string {
+	if f == FormatCamt053 {
+		return "xml"
+	}
+
+	return string(f)
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func money(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// NewAccount maps the history of a wallet, newest first as the wallet service returns
+// it, to entries up to asOf. balance is the balance at asOf, the opening balance is
+// derived from it.
+func NewAccount(userID int, history []wallet.Transaction, balance float64, asOf time.Time) (Account, error) {
+	account := Account{
+		UserID:  userID,
+		AsOf:    asOf.UTC(),
+		Opening: balance,
+		Closing: balance,
+		Entries: make([]Entry, 0, len(history)),
+	}
+
+	for i := len(history) - 1; i >= 0; i-- {
+		t := history[i]
+
+		bookedAt, err := time.Parse(time.RFC3339Nano, t.Timestamp)
+		if err != nil {
+			return Account{}, fmt.Errorf("failed to parse timestamp of transaction %d: %w", t.TransactionID, err)
+		}
+
+		if bookedAt.After(asOf) {
+			continue
+		}
+
+		entry := Entry{
+			TransactionID:  t.TransactionID,
+			BookedAt:       bookedAt.UTC(),
+			Type:           t.TransactionType,
+			Amount:         t.SignedAmount(userID),
+			CounterpartyID: t.Counterparty(userID),
+		}
+
+		account.Opening = roundCents(account.Opening - entry.Amount)
+		account.Entries = append(account.Entries, entry)
+	}
+
+	return account, nil
+}
+
+// Load reads the history of a wallet and its balance at asOf from the wallet service.
+// Both are read from the primary, a history from a lagging replica would give a
+// wrong opening balance.
+func Load(ctx context.Context, svc wallet.Service, userID int, asOf time.Time) (Account, error) {
+	history, err := svc.GetTransactionsAsOf(ctx, userID, asOf)
+	if err != nil {
+		return Account{}, fmt.Errorf("failed to load history of user %d: %w", userID, err)
+	}
+
+	balance, err := svc.GetBalanceAsOf(ctx, userID, asOf)
+	if err != nil {
+		return Account{}, fmt.Errorf("failed to load balance of user %d: %w", userID, err)
+	}
+
+	return NewAccount(userID, history, balance, asOf)
+}
+
+// Write encodes the account in the format directly to w
+func (e *Exporter) Write(w io.Writer, f Format, a Account) error {
+	var err error
+
+	switch f {
+	case FormatOFX:
+		err = e.writeOFX(w, a)
+	case FormatCamt053:
+		err = e.writeCamt053(w, a)
+	case FormatCSV:
+		err = e.writeCSV(w, a)
+	default:
+		return fmt.Errorf("%w %q", ErrUnknownFormat, f)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to write %s export of user %d: %w", f, a.UserID, err)
+	}
+
+	return nil
+}
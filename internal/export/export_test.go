@@ -0,0 +1,111 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+// history of user 1 newest first, as the wallet service returns it
+func history() []wallet.Transaction {
+	return []wallet.Transaction{
+		{TransactionID: 6, FromUserID: 1, Amount: 99, TransactionType: "deposit", Timestamp: "2025-03-01T00:00:00.000001Z"},
+		{TransactionID: 5, FromUserID: 1, Amount: -2.5, TransactionType: "adjustment", Timestamp: "2025-02-20T08:00:00Z"},
+		{TransactionID: 4, FromUserID: 1, Amount: 10, TransactionType: "withdraw", Timestamp: "2025-02-14T12:00:00.5Z"},
+		{TransactionID: 3, FromUserID: 2, ToUserID: 1, Amount: 5.5, TransactionType: "transfer", Timestamp: "2025-02-10T18:05:12Z"},
+		{TransactionID: 2, FromUserID: 1, ToUserID: 2, Amount: 30, TransactionType: "transfer", Timestamp: "2025-02-03T10:00:00Z"},
+		{TransactionID: 1, FromUserID: 1, Amount: 50, TransactionType: "deposit", Timestamp: "2025-02-03T09:30:00Z"},
+	}
+}
+
+func account(t *testing.T) Account {
+	t.Helper()
+
+	a, err := NewAccount(1, history(), 200, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	return a
+}
+
+func TestNewAccount(t *testing.T) {
+	// Act
+	a := account(t)
+
+	// Assert: the deposit after asOf is left out, the rest is oldest first
+	require.Len(t, a.Entries, 5)
+	require.Equal(t, 1, a.Entries[0].TransactionID)
+	require.InDelta(t, 187, a.Opening, 0.001)
+	require.InDelta(t, 200, a.Closing, 0.001)
+	require.Equal(t, Entry{
+		TransactionID: 2, BookedAt: time.Date(2025, 2, 3, 10, 0, 0, 0, time.UTC), Type: "transfer", Amount: -30, CounterpartyID: 2,
+	}, a.Entries[1])
+	require.InDelta(t, 5.5, a.Entries[2].Amount, 0.001)
+	require.InDelta(t, -10, a.Entries[3].Amount, 0.001)
+	require.InDelta(t, -2.5, a.Entries[4].Amount, 0.001)
+}
+
+func TestWrite_Fixtures(t *testing.T) {
+	exporter := New(Config{Currency: "EUR", BankID: "WALLETXX"})
+
+	for _, f := range []Format{FormatOFX, FormatCamt053, FormatCSV} {
+		t.Run(string(f), func(t *testing.T) {
+			// Arrange
+			expected, err := os.ReadFile(filepath.Join("testdata", "history."+string(f)))
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+
+			// Act
+			err = exporter.Write(&buf, f, account(t))
+
+			// Assert
+			require.NoError(t, err)
+			require.Equal(t, string(expected), buf.String())
+		})
+	}
+}
+
+func TestWrite_Camt053Balances(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	require.NoError(t, New(Config{Currency: "EUR"}).Write(&buf, FormatCamt053, account(t)))
+
+	var doc struct {
+		Balances []struct {
+			Code      string `xml:"Tp>CdOrPrtry>Cd"`
+			Amount    string `xml:"Amt"`
+			Indicator string `xml:"CdtDbtInd"`
+		} `xml:"BkToCstmrStmt>Stmt>Bal"`
+		Entries []struct {
+			Indicator string `xml:"CdtDbtInd"`
+		} `xml:"BkToCstmrStmt>Stmt>Ntry"`
+	}
+
+	// Act
+	err := xml.Unmarshal(buf.Bytes(), &doc)
+
+	// Assert: every entry moves the opening balance towards the closing balance
+	require.NoError(t, err)
+	require.Len(t, doc.Balances, 2)
+	require.Equal(t, "OPBD", doc.Balances[0].Code)
+	require.Equal(t, "187.00", doc.Balances[0].Amount)
+	require.Equal(t, "CLBD", doc.Balances[1].Code)
+	require.Equal(t, "200.00", doc.Balances[1].Amount)
+	require.Len(t, doc.Entries, 5)
+	require.Equal(t, "DBIT", doc.Entries[1].Indicator)
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("camt053")
+	require.NoError(t, err)
+	require.Equal(t, FormatCamt053, f)
+
+	_, err = ParseFormat("qif")
+	require.ErrorIs(t, err, ErrUnknownFormat)
+}
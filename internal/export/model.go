@@ -0,0 +1,46 @@
+package export
+
+import "time"
+
+// Format is a standard the transaction history is exported in
+type Format string
+
+// Supported export formats
+const (
+	FormatOFX     Format = "ofx"
+	FormatCamt053 Format = "camt053"
+	FormatCSV     Format = "csv"
+)
+
+// Config describes the wallets to accounting tools
+type Config struct {
+	// Currency of all wallets, ISO 4217
+	Currency string
+	// BankID identifies the wallet service as account servicer, a BIC in camt.053
+	BankID string
+}
+
+// Entry is a booked transaction from the point of view of one wallet. Amount is
+// negative when money left the wallet.
+type Entry struct {
+	TransactionID  int
+	BookedAt       time.Time
+	Type           string
+	Amount         float64
+	CounterpartyID int
+}
+
+// Account is the exported history of a wallet up to AsOf, oldest entry first.
+// Closing is the balance at AsOf and Opening the balance before the first entry.
+type Account struct {
+	UserID  int
+	AsOf    time.Time
+	Opening float64
+	Closing float64
+	Entries []Entry
+}
+
+// Exporter writes accounts in the supported formats
+type Exporter struct {
+	cfg Config
+}
@@ -0,0 +1,167 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/wallet"
+)
+
+// ofxHeader declares OFX 2.2, the XML flavour of OFX
+const ofxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+`
+
+// ofxAccountType is how wallets are presented to accounting tools
+const ofxAccountType = "CHECKING"
+
+type ofxDocument struct {
+	XMLName xml.Name `xml:"OFX"`
+	SignOn  struct {
+		Response struct {
+			Status   ofxStatus `xml:"STATUS"`
+			Server   string    `xml:"DTSERVER"`
+			Language string    `xml:"LANGUAGE"`
+		} `xml:"SONRS"`
+	} `xml:"SIGNONMSGSRSV1"`
+	Bank struct {
+		Response struct {
+			TrnUID    string       `xml:"TRNUID"`
+			Status    ofxStatus    `xml:"STATUS"`
+			Statement ofxStatement `xml:"STMTRS"`
+		} `xml:"STMTTRNRS"`
+	} `xml:"BANKMSGSRSV1"`
+}
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxAccount struct {
+	BankID string `xml:"BANKID"`
+	AcctID string `xml:"ACCTID"`
+	Type   string `xml:"ACCTTYPE"`
+}
+
+type ofxStatement struct {
+	Currency     string     `xml:"CURDEF"`
+	Account      ofxAccount `xml:"BANKACCTFROM"`
+	Transactions struct {
+		Start   string           `xml:"DTSTART"`
+		End     string           `xml:"DTEND"`
+		Entries []ofxTransaction `xml:"STMTTRN"`
+	} `xml:"BANKTRANLIST"`
+	Ledger struct {
+		Amount string `xml:"BALAMT"`
+		AsOf   string `xml:"DTASOF"`
+	} `xml:"LEDGERBAL"`
+}
+
+type ofxTransaction struct {
+	Type      string      `xml:"TRNTYPE"`
+	Posted    string      `xml:"DTPOSTED"`
+	Amount    string      `xml:"TRNAMT"`
+	FITID     string      `xml:"FITID"`
+	Name      string      `xml:"NAME,omitempty"`
+	AccountTo *ofxAccount `xml:"BANKACCTTO,omitempty"`
+	Memo      string      `xml:"MEMO"`
+}
+
+// ofxTime formats a time as OFX datetime in GMT
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405.000") + "[0:GMT]"
+}
+
+// ofxTransactionType maps wallet transaction types to OFX TRNTYPE
+func ofxTransactionType(e Entry) string {
+	switch {
+	case e.Type == wallet.EventDeposit:
+		return "DEP"
+	case e.Type == wallet.EventTransfer:
+		return "XFER"
+	case e.Amount < 0:
+		return "DEBIT"
+	default:
+		return "CREDIT"
+	}
+}
+
+// memo describes the entry, transfers name the direction and the other wallet
+func memo(e Entry) string {
+	switch {
+	case e.CounterpartyID == 0:
+		return e.Type
+	case e.Amount < 0:
+		return fmt.Sprintf("%s to user %d", e.Type, e.CounterpartyID)
+	default:
+		return fmt.Sprintf("%s from user %d", e.Type, e.CounterpartyID)
+	}
+}
+
+func (e *Exporter) writeOFX(w io.Writer, a Account) error {
+	var doc ofxDocument
+
+	ok := ofxStatus{Code: 0, Severity: "INFO"}
+
+	doc.SignOn.Response.Status = ok
+	doc.SignOn.Response.Server = ofxTime(a.AsOf)
+	doc.SignOn.Response.Language = "ENG"
+	doc.Bank.Response.TrnUID = "0"
+	doc.Bank.Response.Status = ok
+
+	stmt := &doc.Bank.Response.Statement
+	stmt.Currency = e.cfg.Currency
+	stmt.Account = ofxAccount{BankID: e.cfg.BankID, AcctID: strconv.Itoa(a.UserID), Type: ofxAccountType}
+	stmt.Transactions.Start = ofxTime(a.AsOf)
+	stmt.Transactions.End = ofxTime(a.AsOf)
+	stmt.Ledger.Amount = money(a.Closing)
+	stmt.Ledger.AsOf = ofxTime(a.AsOf)
+
+	if len(a.Entries) > 0 {
+		stmt.Transactions.Start = ofxTime(a.Entries[0].BookedAt)
+	}
+
+	stmt.Transactions.Entries = make([]ofxTransaction, 0, len(a.Entries))
+
+	for _, entry := range a.Entries {
+		t := ofxTransaction{
+			Type:   ofxTransactionType(entry),
+			Posted: ofxTime(entry.BookedAt),
+			Amount: money(entry.Amount),
+			FITID:  strconv.Itoa(entry.TransactionID),
+			Memo:   memo(entry),
+		}
+
+		if entry.CounterpartyID != 0 {
+			t.Name = "User " + strconv.Itoa(entry.CounterpartyID)
+		}
+
+		// OFX only names the account of outgoing transfers
+		if entry.CounterpartyID != 0 && entry.Amount < 0 {
+			t.AccountTo = &ofxAccount{BankID: e.cfg.BankID, AcctID: strconv.Itoa(entry.CounterpartyID), Type: ofxAccountType}
+		}
+
+		stmt.Transactions.Entries = append(stmt.Transactions.Entries, t)
+	}
+
+	if _, err := io.WriteString(w, ofxHeader); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode document: %w", err)
+	}
+
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("failed to write document: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+// Package health reports whether the service can take traffic, and what it runs
+// against: build, schema version, cache circuit and webhook outbox.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/migrate"
+	"github.com/amelonpie/wallet-service/pkg/breaker"
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Version of the build, set with -ldflags "-X github.com/amelonpie/wallet-service/internal/health.Version=v1.2.3".
+// Without it the VCS revision the binary was built from is reported.
+var Version = "" //nolint:gochecknoglobals // set by the linker
+
+// Results of a dependency check
+const (
+	CheckUp       = "up"
+	CheckDown     = "down"
+	CheckDisabled = "disabled"
+)
+
+type Config struct {
+	// Timeout of every dependency ping
+	Timeout time.Duration
+	// RequireRedis makes readiness fail while Redis is down. Balances are served
+	// from Postgres alone then, so by default it is only reported.
+	RequireRedis bool
+}
+
+func NewConfig() Config {
+	viper.SetDefault("health.timeout", "2s")
+	viper.SetDefault("health.require_redis", false)
+
+	return Config{
+		Timeout:      viper.GetDuration("health.timeout"),
+		RequireRedis: viper.GetBool("health.require_redis"),
+	}
+}
+
+// Readiness is the result of pinging the dependencies
+type Readiness struct {
+	Ready    bool              `json:"ready"`
+	Draining bool              `json:"draining,omitempty"`
+	Checks   map[string]string `json:"checks"`
+}
+
+// Outbox is the backlog of webhook deliveries still to be sent
+type Outbox struct {
+	Pending int     `json:"pending"`
+	LagSecs float64 `json:"lag_seconds"`
+}
+
+// Status describes the running instance
+type Status struct {
+	Version          string            `json:"version"`
+	StartedAt        time.Time         `json:"started_at"`
+	UptimeSecs       float64           `json:"uptime_seconds"`
+	MigrationVersion int64             `json:"migration_version"`
+	Cache            breaker.State     `json:"cache"`
+	Outbox           Outbox            `json:"outbox"`
+	Checks           map[string]string `json:"checks"`
+	Errors           map[string]string `json:"errors,omitempty"`
+}
+
+// Checker pings Postgres and Redis, either may be nil when it is not used
+type Checker struct {
+	db        *sql.DB
+	rdb       *redis.Client
+	cache     func() breaker.State
+	cfg       Config
+	startedAt time.Time
+	draining  atomic.Bool
+	logger    *logrus.Entry
+}
+
+// New creates a checker, db and rdb are nil when Postgres or Redis is not used
+func New(db *sql.DB, rdb *redis.Client, cacheState func() breaker.State, cfg Config) *Checker {
+	return &Checker{
+		db:        db,
+		rdb:       rdb,
+		cache:     cacheState,
+		cfg:       cfg,
+		startedAt: time.Now(),
+		logger:    log.NewLogger("health").WithField("module", "health"),
+	}
+}
+
+// Drain makes the instance report not ready, so load balancers stop sending new
+// requests while the ones in flight finish
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Ready pings the dependencies, Postgres is required when it is used
+func (c *Checker) Ready(ctx context.Context) Readiness {
+	checks, errs := c.ping(ctx)
+	for name, err := range errs {
+		log.FromContext(ctx, c.logger).WithField("err", err).WithField("dependency", name).Warn("dependency check failed")
+	}
+
+	draining := c.draining.Load()
+	ready := !draining && checks["postgres"] != CheckDown &&
+		(!c.cfg.RequireRedis || checks["redis"] != CheckDown)
+
+	return Readiness{Ready: ready, Draining: draining, Checks: checks}
+}
+
+// Status collects what the instance runs against. Parts that cannot be read are
+// left empty and named in Errors.
+func (c *Checker) Status(ctx context.Context) Status {
+	checks, errs := c.ping(ctx)
+
+	status := Status{
+		Version:    version(),
+		StartedAt:  c.startedAt,
+		UptimeSecs: time.Since(c.startedAt).Seconds(),
+		Cache:      c.cache(),
+		Checks:     checks,
+		Errors:     map[string]string{},
+	}
+
+	for name, err := range errs {
+		status.Errors[name] = err.Error()
+	}
+
+	if checks["postgres"] == CheckUp {
+		var err error
+		if status.MigrationVersion, err = c.migrationVersion(ctx); err != nil {
+			status.Errors["migration_version"] = err.Error()
+		}
+
+		if status.Outbox, err = c.outbox(ctx); err != nil {
+			status.Errors["outbox"] = err.Error()
+		}
+	}
+
+	return status
+}
+
+func (c *Checker) ping(ctx context.Context) (map[string]string, map[string]error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	checks := map[string]string{"postgres": CheckDisabled, "redis": CheckDisabled}
+	errs := map[string]error{}
+
+	if c.db != nil {
+		checks["postgres"] = CheckUp
+		if err := c.db.PingContext(ctx); err != nil {
+			checks["postgres"] = CheckDown
+			errs["postgres"] = fmt.Errorf("failed to ping Postgres: %w", err)
+		}
+	}
+
+	if c.rdb != nil {
+		checks["redis"] = CheckUp
+		if err := c.rdb.Ping(ctx).Err(); err != nil {
+			checks["redis"] = CheckDown
+			errs["redis"] = fmt.Errorf("failed to ping Redis: %w", err)
+		}
+	}
+
+	return checks, errs
+}
+
+func (c *Checker) migrationVersion(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	migrator, err := migrate.New(c.db)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	return migrator.Version(ctx) //nolint:wrapcheck // migrate wraps with context
+}
+
+// outbox reads the webhook deliveries not yet sent and the age of the oldest
+func (c *Checker) outbox(ctx context.Context) (Outbox, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	var outbox Outbox
+
+	err := c.db.QueryRowContext(ctx, `
+        SELECT count(*), COALESCE(EXTRACT(EPOCH FROM now() - min(created_at)), 0)
+        FROM webhook_deliveries WHERE status = 'pending'`).Scan(&outbox.Pending, &outbox.LagSecs)
+	if err != nil {
+		return Outbox{}, fmt.Errorf("failed to read webhook outbox: %w", err)
+	}
+
+	return outbox, nil
+}
+
+func version() string {
+	if Version != "" {
+		return Version
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			return setting.Value
+		}
+	}
+
+	return info.Main.Version
+}
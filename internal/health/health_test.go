@@ -0,0 +1,114 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/amelonpie/wallet-service/pkg/breaker"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+var errConnRefused = errors.New("connection refused")
+
+func newMockChecker(t *testing.T, rdb *redis.Client, cfg Config) (*Checker, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mockSQL, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+
+	return New(db, rdb, func() breaker.State { return breaker.Closed }, cfg), mockSQL
+}
+
+// unreachableRedis refuses connections, nothing listens on port 1
+func unreachableRedis() *redis.Client {
+	return redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+}
+
+func TestChecker_Ready(t *testing.T) {
+	tests := []struct {
+		name     string
+		pingErr  error
+		rdb      *redis.Client
+		cfg      Config
+		drain    bool
+		expected Readiness
+	}{
+		{
+			name:     "postgres up",
+			cfg:      Config{Timeout: time.Second},
+			expected: Readiness{Ready: true, Checks: map[string]string{"postgres": CheckUp, "redis": CheckDisabled}},
+		},
+		{
+			name:     "postgres down",
+			pingErr:  errConnRefused,
+			cfg:      Config{Timeout: time.Second},
+			expected: Readiness{Ready: false, Checks: map[string]string{"postgres": CheckDown, "redis": CheckDisabled}},
+		},
+		{
+			name:     "redis down is only reported",
+			rdb:      unreachableRedis(),
+			cfg:      Config{Timeout: time.Second},
+			expected: Readiness{Ready: true, Checks: map[string]string{"postgres": CheckUp, "redis": CheckDown}},
+		},
+		{
+			name:     "redis required",
+			rdb:      unreachableRedis(),
+			cfg:      Config{Timeout: time.Second, RequireRedis: true},
+			expected: Readiness{Ready: false, Checks: map[string]string{"postgres": CheckUp, "redis": CheckDown}},
+		},
+		{
+			name:  "draining",
+			cfg:   Config{Timeout: time.Second},
+			drain: true,
+			expected: Readiness{
+				Ready: false, Draining: true,
+				Checks: map[string]string{"postgres": CheckUp, "redis": CheckDisabled},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			checker, mockSQL := newMockChecker(t, tt.rdb, tt.cfg)
+			mockSQL.ExpectPing().WillReturnError(tt.pingErr)
+
+			if tt.drain {
+				checker.Drain()
+			}
+
+			// Act
+			readiness := checker.Ready(context.Background())
+
+			// Assert
+			require.Equal(t, tt.expected, readiness)
+			require.NoError(t, mockSQL.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestChecker_Status(t *testing.T) {
+	// Arrange
+	checker, mockSQL := newMockChecker(t, nil, Config{Timeout: time.Second})
+	mockSQL.ExpectPing()
+	mockSQL.ExpectQuery(`SELECT to_regclass`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mockSQL.ExpectQuery(`SELECT COALESCE\(MAX\(version\), 0\) FROM schema_migrations`).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(9))
+	mockSQL.ExpectQuery(`FROM webhook_deliveries WHERE status = 'pending'`).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "lag"}).AddRow(3, 42.5))
+
+	// Act
+	status := checker.Status(context.Background())
+
+	// Assert
+	require.Empty(t, status.Errors)
+	require.NotEmpty(t, status.Version)
+	require.Equal(t, int64(9), status.MigrationVersion)
+	require.Equal(t, Outbox{Pending: 3, LagSecs: 42.5}, status.Outbox)
+	require.Equal(t, breaker.Closed, status.Cache)
+	require.NoError(t, mockSQL.ExpectationsWereMet())
+}
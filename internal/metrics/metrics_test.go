@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware_LabelsByRoute(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/wallet/:user_id/balance", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/metrics", gin.WrapH(Handler()))
+
+	// Act
+	for _, url := range []string{"/wallet/1/balance", "/wallet/2/balance", "/nowhere"} {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body := w.Body.String()
+	require.Contains(t, body,
+		`wallet_http_request_duration_seconds_count{method="GET",route="/wallet/:user_id/balance",status="200"} 2`)
+	require.Contains(t, body,
+		`wallet_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`)
+	require.Contains(t, body, "go_goroutines")
+}
+
+func TestRegisterDB(t *testing.T) {
+	// Arrange
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+
+	other, _, err := sqlmock.New()
+	require.NoError(t, err)
+
+	// Act
+	require.NoError(t, RegisterDB(db, "test"))
+	require.NoError(t, RegisterDB(other, "test"))
+
+	// Assert: one pool of the name is collected
+	count, err := testutil.GatherAndCount(Registry, "go_sql_open_connections")
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+}
@@ -0,0 +1,9 @@
+package migrate
+
+import "errors"
+
+var (
+	ErrInvalidMigration = errors.New("invalid migration file")
+	ErrChecksumMismatch = errors.New("applied migration was modified")
+	ErrUnknownVersion   = errors.New("database has a migration this binary does not know")
+)
@@ -0,0 +1,356 @@
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/amelonpie/wallet-service/pkg/log"
+)
+
+// advisoryLockID is the pg_advisory_lock key held while migrations run, so that
+// replicas auto-migrating on start and an operator do not run them concurrently
+const advisoryLockID = 7365231
+
+//go:embed migrations/*.sql
+var embedded embed.FS //nolint:gochecknoglobals // read-only files compiled into the binary
+
+// NNNN_name.up.sql and NNNN_name.down.sql
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`) //nolint:gochecknoglobals // compiled once
+
+// New creates a migrator for the migrations embedded in the binary
+func New(db *sql.DB) (*Migrator, error) {
+	dir, err := fs.Sub(embedded, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedded migrations: %w", err)
+	}
+
+	return newMigrator(db, dir)
+}
+
+func newMigrator(db *sql.DB, dir fs.FS) (*Migrator, error) {
+	migrations, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		logger:     log.NewLogger("migrate").WithField("module", "migrate"),
+	}, nil
+}
+
+// Load reads the migrations of a directory ordered by version. Every version
+// needs both an up and a down script.
+func Load(dir fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(dir, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidMigration, entry.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("%w: %q has no positive version", ErrInvalidMigration, entry.Name())
+		}
+
+		content, err := fs.ReadFile(dir, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %q: %w", entry.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mig
+		}
+
+		if mig.Name != match[2] {
+			return nil, fmt.Errorf("%w: version %d is used by %q and %q",
+				ErrInvalidMigration, version, mig.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			mig.Up = string(content)
+			mig.Checksum = checksum(content)
+		} else {
+			mig.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("%w: version %d needs an up and a down script", ErrInvalidMigration, mig.Version)
+		}
+
+		migrations = append(migrations, *mig)
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int { return cmp.Compare(a.Version, b.Version) })
+
+	return migrations, nil
+}
+
+// checksum of the up script, the statements an applied migration has run
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Up applies every pending migration in version order and returns how many ran.
+// Each migration runs in its own transaction together with its bookkeeping row.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	var count int
+
+	err := m.locked(ctx, func(conn *sql.Conn, applied map[int64]appliedMigration) error {
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+
+			err := inTx(ctx, conn, mig.Up,
+				`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+				mig.Version, mig.Name, mig.Checksum)
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+
+			m.logger.WithField("version", mig.Version).WithField("name", mig.Name).Info("migration applied")
+			count++
+		}
+
+		return nil
+	})
+
+	return count, err
+}
+
+// Down reverts the latest steps applied migrations and returns how many ran
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	var count int
+
+	err := m.locked(ctx, func(conn *sql.Conn, applied map[int64]appliedMigration) error {
+		for i := len(m.migrations) - 1; i >= 0 && count < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+
+			err := inTx(ctx, conn, mig.Down, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+			if err != nil {
+				return fmt.Errorf("failed to revert migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+
+			m.logger.WithField("version", mig.Version).WithField("name", mig.Name).Info("migration reverted")
+			count++
+		}
+
+		return nil
+	})
+
+	return count, err
+}
+
+// Status lists the known migrations, whether and when they were applied. It only
+// reads, a database without schema_migrations has none applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	exists, err := tableExists(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[int64]appliedMigration{}
+
+	if exists {
+		if applied, err = appliedMigrations(ctx, m.db); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+
+	for _, mig := range m.migrations {
+		status := Status{Version: mig.Version, Name: mig.Name}
+
+		if a, ok := applied[mig.Version]; ok {
+			status.AppliedAt = &a.appliedAt
+			status.Modified = a.checksum != mig.Checksum
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Version returns the highest applied migration, 0 on an empty database. It only
+// reads, so it needs no rights to create schema_migrations.
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	exists, err := tableExists(ctx, m.db)
+	if err != nil || !exists {
+		return 0, err
+	}
+
+	var version int64
+
+	err = m.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	return version, nil
+}
+
+// locked runs fn on a single connection holding the advisory lock, after checking
+// that the applied migrations are the ones embedded in this binary
+func (m *Migrator) locked(
+	ctx context.Context,
+	fn func(conn *sql.Conn, applied map[int64]appliedMigration) error,
+) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, advisoryLockID); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+
+	defer func() {
+		// released with a fresh context, ctx may be why fn gave up
+		_, unlockErr := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, advisoryLockID)
+		if unlockErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to release migration lock: %w", unlockErr))
+		}
+	}()
+
+	if err = ensureTable(ctx, conn); err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	if err = m.verify(applied); err != nil {
+		return err
+	}
+
+	return fn(conn, applied)
+}
+
+// verify refuses to touch a database whose history differs from the embedded files
+func (m *Migrator) verify(applied map[int64]appliedMigration) error {
+	for version, a := range applied {
+		idx := slices.IndexFunc(m.migrations, func(mig Migration) bool { return mig.Version == version })
+		if idx < 0 {
+			return fmt.Errorf("%w: version %d", ErrUnknownVersion, version)
+		}
+
+		if mig := m.migrations[idx]; mig.Checksum != a.checksum {
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, mig.Version, mig.Name)
+		}
+	}
+
+	return nil
+}
+
+type execQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func ensureTable(ctx context.Context, db execQuerier) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	return nil
+}
+
+// tableExists tells whether schema_migrations exists, looked up on the search
+// path like the statements that use it
+func tableExists(ctx context.Context, db *sql.DB) (bool, error) {
+	var exists bool
+
+	err := db.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up schema_migrations: %w", err)
+	}
+
+	return exists, nil
+}
+
+func appliedMigrations(ctx context.Context, db execQuerier) (map[int64]appliedMigration, error) {
+	rows, err := db.QueryContext(ctx, `SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int64]appliedMigration{}
+
+	for rows.Next() {
+		var a appliedMigration
+		if err = rows.Scan(&a.version, &a.checksum, &a.appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+
+		applied[a.version] = a
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+
+	return applied, nil
+}
+
+// inTx runs a migration script and its bookkeeping statement atomically
+func inTx(ctx context.Context, conn *sql.Conn, script, bookkeeping string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, script); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+
+	if _, err = tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,291 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_users.up.sql":      {Data: []byte("CREATE TABLE users (id INT);")},
+		"0001_users.down.sql":    {Data: []byte("DROP TABLE users;")},
+		"0002_wallets.up.sql":    {Data: []byte("CREATE TABLE wallets (id INT);")},
+		"0002_wallets.down.sql":  {Data: []byte("DROP TABLE wallets;")},
+		"0010_balances.up.sql":   {Data: []byte("ALTER TABLE wallets ADD balance INT;")},
+		"0010_balances.down.sql": {Data: []byte("ALTER TABLE wallets DROP balance;")},
+	}
+}
+
+func setupMigrator(t *testing.T) (*Migrator, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mockSQL, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	m, err := newMigrator(db, testFS())
+	require.NoError(t, err)
+
+	return m, mockSQL
+}
+
+func expectLocked(mockSQL sqlmock.Sqlmock, applied *sqlmock.Rows) {
+	mockSQL.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).
+		WithArgs(advisoryLockID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mockSQL.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mockSQL.ExpectQuery(`SELECT version, checksum, applied_at FROM schema_migrations`).
+		WillReturnRows(applied)
+}
+
+func expectTable(mockSQL sqlmock.Sqlmock, exists bool) {
+	mockSQL.ExpectQuery(`SELECT to_regclass\('schema_migrations'\) IS NOT NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(exists))
+}
+
+func expectUnlock(mockSQL sqlmock.Sqlmock) {
+	mockSQL.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).
+		WithArgs(advisoryLockID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+// appliedRows marks versions of m as applied with unchanged checksums
+func appliedRows(m *Migrator, versions ...int64) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"version", "checksum", "applied_at"})
+
+	for _, mig := range m.migrations {
+		if slices.Contains(versions, mig.Version) {
+			rows.AddRow(mig.Version, mig.Checksum, time.Now())
+		}
+	}
+
+	return rows
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	// Act
+	m, err := New(nil)
+
+	// Assert
+	require.NoError(t, err)
+	require.NotEmpty(t, m.migrations)
+
+	for i, mig := range m.migrations {
+		require.Equal(t, int64(i+1), mig.Version, "embedded versions must be contiguous")
+		require.NotEmpty(t, mig.Up)
+		require.NotEmpty(t, mig.Down)
+	}
+}
+
+func TestLoad_OrdersByVersion(t *testing.T) {
+	// Act
+	migrations, err := Load(testFS())
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, migrations, 3)
+	require.Equal(t, []int64{1, 2, 10}, []int64{migrations[0].Version, migrations[1].Version, migrations[2].Version})
+	require.Equal(t, "wallets", migrations[1].Name)
+	require.Equal(t, checksum([]byte("CREATE TABLE wallets (id INT);")), migrations[1].Checksum)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"missing down":    {"0001_users.up.sql": {Data: []byte("SELECT 1;")}},
+		"unexpected file": {"README.md": {Data: []byte("")}},
+		"zero version": {
+			"0000_users.up.sql":   {Data: []byte("SELECT 1;")},
+			"0000_users.down.sql": {Data: []byte("SELECT 1;")},
+		},
+		"conflicting names": {
+			"0001_users.up.sql":      {Data: []byte("SELECT 1;")},
+			"0001_accounts.down.sql": {Data: []byte("SELECT 1;")},
+		},
+	}
+
+	for name, dir := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			_, err := Load(dir)
+
+			// Assert
+			require.ErrorIs(t, err, ErrInvalidMigration)
+		})
+	}
+}
+
+func TestUp_AppliesPendingInOrder(t *testing.T) {
+	// Arrange
+	m, mockSQL := setupMigrator(t)
+
+	expectLocked(mockSQL, appliedRows(m, 1))
+
+	for _, mig := range m.migrations[1:] {
+		mockSQL.ExpectBegin()
+		mockSQL.ExpectExec(regexp.QuoteMeta(mig.Up)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mockSQL.ExpectExec(`INSERT INTO schema_migrations \(version, name, checksum\) VALUES \(\$1, \$2, \$3\)`).
+			WithArgs(mig.Version, mig.Name, mig.Checksum).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mockSQL.ExpectCommit()
+	}
+
+	expectUnlock(mockSQL)
+
+	// Act
+	count, err := m.Up(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	require.NoError(t, mockSQL.ExpectationsWereMet())
+}
+
+func TestUp_RollsBackFailedMigration(t *testing.T) {
+	// Arrange
+	m, mockSQL := setupMigrator(t)
+
+	expectLocked(mockSQL, appliedRows(m, 1, 2))
+	mockSQL.ExpectBegin()
+	mockSQL.ExpectExec(`ALTER TABLE wallets ADD balance INT`).WillReturnError(errors.New("syntax error"))
+	mockSQL.ExpectRollback()
+	expectUnlock(mockSQL)
+
+	// Act
+	count, err := m.Up(context.Background())
+
+	// Assert
+	require.ErrorContains(t, err, "failed to apply migration 10_balances")
+	require.Zero(t, count)
+	require.NoError(t, mockSQL.ExpectationsWereMet())
+}
+
+func TestUp_RefusesModifiedMigration(t *testing.T) {
+	// Arrange
+	m, mockSQL := setupMigrator(t)
+
+	expectLocked(mockSQL, sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).
+		AddRow(int64(1), "edited", time.Now()))
+	expectUnlock(mockSQL)
+
+	// Act
+	_, err := m.Up(context.Background())
+
+	// Assert
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+	require.NoError(t, mockSQL.ExpectationsWereMet())
+}
+
+func TestUp_RefusesUnknownVersion(t *testing.T) {
+	// Arrange
+	m, mockSQL := setupMigrator(t)
+
+	expectLocked(mockSQL, sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).
+		AddRow(int64(11), "from a newer binary", time.Now()))
+	expectUnlock(mockSQL)
+
+	// Act
+	_, err := m.Up(context.Background())
+
+	// Assert
+	require.ErrorIs(t, err, ErrUnknownVersion)
+	require.NoError(t, mockSQL.ExpectationsWereMet())
+}
+
+func TestDown_RevertsLatest(t *testing.T) {
+	// Arrange
+	m, mockSQL := setupMigrator(t)
+
+	expectLocked(mockSQL, appliedRows(m, 1, 2, 10))
+
+	for _, mig := range []Migration{m.migrations[2], m.migrations[1]} {
+		mockSQL.ExpectBegin()
+		mockSQL.ExpectExec(regexp.QuoteMeta(mig.Down)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mockSQL.ExpectExec(`DELETE FROM schema_migrations WHERE version = \$1`).
+			WithArgs(mig.Version).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mockSQL.ExpectCommit()
+	}
+
+	expectUnlock(mockSQL)
+
+	// Act
+	count, err := m.Down(context.Background(), 2)
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	require.NoError(t, mockSQL.ExpectationsWereMet())
+}
+
+func TestStatus(t *testing.T) {
+	// Arrange
+	m, mockSQL := setupMigrator(t)
+
+	appliedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	expectTable(mockSQL, true)
+	mockSQL.ExpectQuery(`SELECT version, checksum, applied_at FROM schema_migrations`).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).
+			AddRow(int64(1), m.migrations[0].Checksum, appliedAt).
+			AddRow(int64(2), "edited", appliedAt))
+
+	// Act
+	statuses, err := m.Status(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, []Status{
+		{Version: 1, Name: "users", AppliedAt: &appliedAt},
+		{Version: 2, Name: "wallets", AppliedAt: &appliedAt, Modified: true},
+		{Version: 10, Name: "balances"},
+	}, statuses)
+}
+
+func TestVersion(t *testing.T) {
+	// Arrange
+	m, mockSQL := setupMigrator(t)
+
+	expectTable(mockSQL, true)
+	mockSQL.ExpectQuery(`SELECT COALESCE\(MAX\(version\), 0\) FROM schema_migrations`).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(int64(2)))
+
+	// Act
+	version, err := m.Version(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, int64(2), version)
+}
+
+func TestStatusAndVersion_WithoutTable(t *testing.T) {
+	// Arrange
+	m, mockSQL := setupMigrator(t)
+
+	expectTable(mockSQL, false)
+	expectTable(mockSQL, false)
+
+	// Act
+	statuses, statusErr := m.Status(context.Background())
+	version, versionErr := m.Version(context.Background())
+
+	// Assert: nothing is created by read-only calls
+	require.NoError(t, statusErr)
+	require.NoError(t, versionErr)
+	require.Len(t, statuses, 3)
+
+	for _, status := range statuses {
+		require.Nil(t, status.AppliedAt)
+	}
+
+	require.Zero(t, version)
+	require.NoError(t, mockSQL.ExpectationsWereMet())
+}
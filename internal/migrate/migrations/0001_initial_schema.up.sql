@@ -0,0 +1,22 @@
+-- Baseline of the schema that configs/init.sql used to create.
+-- IF NOT EXISTS lets databases created by init.sql adopt migrations.
+CREATE TABLE IF NOT EXISTS users (
+    user_id SERIAL PRIMARY KEY,
+    username VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS wallets (
+    wallet_id SERIAL PRIMARY KEY,
+    user_id INT REFERENCES users(user_id) ON DELETE CASCADE,
+    balance DECIMAL(15, 2) DEFAULT 0.00
+);
+
+CREATE TABLE IF NOT EXISTS transactions (
+    transaction_id SERIAL PRIMARY KEY,
+    from_user_id INT REFERENCES users(user_id) ON DELETE CASCADE,
+    to_user_id INT REFERENCES users(user_id),
+    amount DECIMAL(15, 2),
+    transaction_type VARCHAR(20), -- 'deposit', 'withdraw', 'transfer'
+    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
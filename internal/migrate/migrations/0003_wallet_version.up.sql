@@ -0,0 +1,2 @@
+-- incremented by every balance update, orders cached balances against the database
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 0;
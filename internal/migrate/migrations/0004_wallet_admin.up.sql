@@ -0,0 +1,13 @@
+-- a frozen wallet rejects deposits, withdrawals and transfers, only adjustments apply
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS frozen BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- audit trail of manual balance corrections, each also logged in transactions
+CREATE TABLE IF NOT EXISTS wallet_adjustments (
+    adjustment_id SERIAL PRIMARY KEY,
+    user_id INT REFERENCES users(user_id) ON DELETE CASCADE,
+    transaction_id INT REFERENCES transactions(transaction_id),
+    amount DECIMAL(15, 2) NOT NULL, -- negative debits the wallet
+    reason TEXT NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
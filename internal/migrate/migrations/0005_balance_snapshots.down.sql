@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS balance_snapshots;
+DROP INDEX IF EXISTS transactions_to_user_idx;
+DROP INDEX IF EXISTS transactions_from_user_idx;
+DROP VIEW IF EXISTS wallet_movements;
@@ -0,0 +1,20 @@
+-- signed balance changes per wallet, a transfer is one row for each side
+CREATE OR REPLACE VIEW wallet_movements AS
+    SELECT transaction_id, from_user_id AS user_id,
+           CASE WHEN transaction_type IN ('deposit', 'adjustment') THEN amount ELSE -amount END AS amount,
+           timestamp
+    FROM transactions
+    UNION ALL
+    SELECT transaction_id, to_user_id AS user_id, amount, timestamp
+    FROM transactions WHERE transaction_type = 'transfer';
+
+CREATE INDEX IF NOT EXISTS transactions_from_user_idx ON transactions (from_user_id, timestamp);
+CREATE INDEX IF NOT EXISTS transactions_to_user_idx ON transactions (to_user_id, timestamp);
+
+-- balance of every wallet at taken_at, the net of all its movements up to then
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+    user_id INT REFERENCES users(user_id) ON DELETE CASCADE,
+    taken_at TIMESTAMP NOT NULL,
+    balance DECIMAL(15, 2) NOT NULL,
+    PRIMARY KEY (user_id, taken_at)
+);
@@ -0,0 +1,8 @@
+-- statements of closed periods, stored once so every later download is identical
+CREATE TABLE IF NOT EXISTS statements (
+    user_id INT REFERENCES users(user_id) ON DELETE CASCADE,
+    period CHAR(7) NOT NULL, -- YYYY-MM
+    statement JSONB NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, period)
+);
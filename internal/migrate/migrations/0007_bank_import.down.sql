@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS suspense_items;
+DROP TABLE IF EXISTS deposit_keys;
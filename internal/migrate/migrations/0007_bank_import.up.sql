@@ -0,0 +1,27 @@
+-- keys of idempotent deposits, a key is recorded in the transaction of its deposit
+CREATE TABLE IF NOT EXISTS deposit_keys (
+    idempotency_key VARCHAR(255) PRIMARY KEY,
+    user_id INT REFERENCES users(user_id) ON DELETE CASCADE,
+    amount DECIMAL(15, 2) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- imported bank credits that could not be deposited, until an admin allocates them
+CREATE TABLE IF NOT EXISTS suspense_items (
+    item_id SERIAL PRIMARY KEY,
+    bank_reference VARCHAR(255) NOT NULL UNIQUE,
+    amount DECIMAL(15, 2) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    booked_at TIMESTAMPTZ NOT NULL,
+    debtor_name TEXT NOT NULL DEFAULT '',
+    debtor_account TEXT NOT NULL DEFAULT '',
+    reference TEXT NOT NULL DEFAULT '',
+    virtual_account TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open', -- open or allocated
+    allocated_user_id INT REFERENCES users(user_id),
+    allocated_by VARCHAR(255),
+    allocated_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS suspense_items_status_idx ON suspense_items (status, item_id);
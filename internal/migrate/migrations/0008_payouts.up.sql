@@ -0,0 +1,29 @@
+-- payout files sent to the bank, the control sums are the ones written in the file
+CREATE TABLE IF NOT EXISTS payout_batches (
+    batch_id SERIAL PRIMARY KEY,
+    scheme VARCHAR(10) NOT NULL, -- 'sepa' or 'nacha'
+    payout_count INT NOT NULL,
+    control_sum DECIMAL(15, 2) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- withdrawals to a bank account, the wallet is debited while the payout is requested
+CREATE TABLE IF NOT EXISTS payouts (
+    payout_id SERIAL PRIMARY KEY,
+    user_id INT REFERENCES users(user_id) ON DELETE CASCADE,
+    amount DECIMAL(15, 2) NOT NULL,
+    scheme VARCHAR(10) NOT NULL,
+    beneficiary_name TEXT NOT NULL,
+    account TEXT NOT NULL, -- IBAN or account number
+    bank_code TEXT NOT NULL DEFAULT '', -- BIC or ABA routing number
+    account_type VARCHAR(10) NOT NULL DEFAULT '', -- 'checking' or 'savings' for NACHA
+    remittance TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'requested', -- requested, pending, batched, settled, returned
+    batch_id INT REFERENCES payout_batches(batch_id),
+    reference VARCHAR(35) UNIQUE, -- end-to-end id or trace number, set when batched
+    return_reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS payouts_status_idx ON payouts (status, payout_id);
+CREATE INDEX IF NOT EXISTS payouts_user_idx ON payouts (user_id, payout_id);
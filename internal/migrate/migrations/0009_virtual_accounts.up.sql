@@ -0,0 +1,7 @@
+-- virtual account numbers bank transfers are sent to, one per wallet
+CREATE TABLE IF NOT EXISTS virtual_accounts (
+    account_number VARCHAR(34) PRIMARY KEY,
+    user_id INT NOT NULL UNIQUE REFERENCES users(user_id) ON DELETE CASCADE,
+    scheme VARCHAR(10) NOT NULL, -- 'mod97' or 'luhn'
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,10 @@
+-- audit trail of freezes and unfreezes, the current state is wallets.frozen
+CREATE TABLE IF NOT EXISTS wallet_freezes (
+    freeze_id SERIAL PRIMARY KEY,
+    user_id INT REFERENCES users(user_id) ON DELETE CASCADE,
+    frozen BOOLEAN NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    actor VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS wallet_freezes_user_idx ON wallet_freezes (user_id, freeze_id);
@@ -0,0 +1,39 @@
+package migrate
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Migration is a pair of embedded up/down scripts sharing a version
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Status of a known migration. AppliedAt is nil while the migration is pending,
+// Modified reports an applied migration whose file changed since.
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Modified  bool       `json:"modified,omitempty"`
+}
+
+// Migrator applies the embedded migrations to a Postgres database
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	logger     *logrus.Entry
+}
+
+type appliedMigration struct {
+	version   int64
+	checksum  string
+	appliedAt time.Time
+}
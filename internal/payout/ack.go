@@ -0,0 +1,152 @@
+package payout
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseAckFormat accepts the acknowledgement formats by name
+func ParseAckFormat(name string) (AckFormat, error) {
+	switch f := AckFormat(name); f {
+	case AckPain002, AckNACHA, AckCSV:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w %q", ErrUnknownFormat, name)
+	}
+}
+
+// ParseAcks reads the settlements and returns of an acknowledgement file
+func ParseAcks(r io.Reader, f AckFormat) ([]Ack, error) {
+	switch f {
+	case AckPain002:
+		return parsePain002(r)
+	case AckNACHA:
+		return parseNACHAReturns(r)
+	case AckCSV:
+		return parseAckCSV(r)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, f)
+	}
+}
+
+// pain.002 CustomerPaymentStatusReport, elements are matched by local name so
+// versions 03 to 10 are read alike
+type pain002Document struct {
+	Payments []struct {
+		Transactions []struct {
+			EndToEndID string `xml:"OrgnlEndToEndId"`
+			Status     string `xml:"TxSts"`
+			Reasons    []struct {
+				Code        string   `xml:"Rsn>Cd"`
+				Proprietary string   `xml:"Rsn>Prtry"`
+				Info        []string `xml:"AddtlInf"`
+			} `xml:"StsRsnInf"`
+		} `xml:"TxInfAndSts"`
+	} `xml:"CstmrPmtStsRpt>OrgnlPmtInfAndSts"`
+}
+
+// parsePain002 maps transaction statuses: ACSC and ACCC are settled, RJCT is
+// returned; statuses of payments still in progress are left out
+func parsePain002(r io.Reader) ([]Ack, error) {
+	var doc pain002Document
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+
+	var acks []Ack
+
+	for _, payment := range doc.Payments {
+		for _, tx := range payment.Transactions {
+			ack := Ack{Reference: strings.TrimSpace(tx.EndToEndID)}
+
+			switch tx.Status {
+			case "ACSC", "ACCC":
+				ack.Status = StatusSettled
+			case "RJCT":
+				ack.Status = StatusReturned
+			default:
+				continue
+			}
+
+			var reasons []string
+
+			for _, reason := range tx.Reasons {
+				code := reason.Code
+				if code == "" {
+					code = reason.Proprietary
+				}
+
+				reasons = append(reasons, strings.TrimSpace(strings.Join(append([]string{code}, reason.Info...), " ")))
+			}
+
+			ack.Reason = strings.Join(reasons, "; ")
+			acks = append(acks, ack)
+		}
+	}
+
+	return acks, nil
+}
+
+// parseAckCSV reads a CSV with the columns reference, status and an optional reason,
+// status is settled or returned
+func parseAckCSV(r io.Reader) ([]Ack, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read header: %w", ErrInvalidFile, err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, name := range []string{"reference", "status"} {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("%w: missing column %q", ErrInvalidFile, name)
+		}
+	}
+
+	column := func(record []string, name string) string {
+		i, ok := index[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+
+		return strings.TrimSpace(record[i])
+	}
+
+	var acks []Ack
+
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidFile, line, err)
+		}
+
+		ack := Ack{
+			Reference: column(record, "reference"),
+			Status:    strings.ToLower(column(record, "status")),
+			Reason:    column(record, "reason"),
+		}
+
+		if ack.Reference == "" || (ack.Status != StatusSettled && ack.Status != StatusReturned) {
+			return nil, fmt.Errorf("%w: line %d: reference %q, status %q", ErrInvalidFile, line, ack.Reference, ack.Status)
+		}
+
+		acks = append(acks, ack)
+	}
+
+	return acks, nil
+}
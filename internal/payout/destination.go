@@ -0,0 +1,109 @@
+package payout
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/amelonpie/wallet-service/pkg/checkdigit"
+)
+
+// Account types of NACHA destinations
+const (
+	AccountChecking = "checking"
+	AccountSavings  = "savings"
+)
+
+const (
+	maxSEPANameLength = 70
+	routingDigits     = 9
+	maxAccountNumber  = 17
+)
+
+var (
+	bicPattern     = regexp.MustCompile(`^[A-Z]{6}[A-Z0-9]{2}([A-Z0-9]{3})?$`) //nolint:gochecknoglobals // compiled once
+	accountPattern = regexp.MustCompile(`^[0-9A-Z-]{1,17}$`)                   //nolint:gochecknoglobals // compiled once
+)
+
+// validRoutingNumber checks an ABA routing number, its digits weighted 3, 7, 1
+// add up to a multiple of 10
+func validRoutingNumber(routing string) bool {
+	if len(routing) != routingDigits {
+		return false
+	}
+
+	weights := [routingDigits]int{3, 7, 1, 3, 7, 1, 3, 7, 1}
+	sum := 0
+
+	for i, r := range routing {
+		if r < '0' || r > '9' {
+			return false
+		}
+
+		sum += weights[i] * int(r-'0')
+	}
+
+	return sum%10 == 0
+}
+
+// normalize validates the destination for the scheme and returns it in the form
+// written to payout files
+func normalize(dest Destination, scheme Scheme) (Destination, error) {
+	dest.Name = strings.TrimSpace(dest.Name)
+	if dest.Name == "" {
+		return Destination{}, fmt.Errorf("%w: name is required", ErrInvalidDestination)
+	}
+
+	switch scheme {
+	case SchemeSEPA:
+		if len(dest.Name) > maxSEPANameLength {
+			return Destination{}, fmt.Errorf("%w: name is longer than %d characters", ErrInvalidDestination, maxSEPANameLength)
+		}
+
+		dest.IBAN = strings.ToUpper(strings.ReplaceAll(dest.IBAN, " ", ""))
+		if !checkdigit.ValidIBAN(dest.IBAN) {
+			return Destination{}, fmt.Errorf("%w: iban %q", ErrInvalidDestination, dest.IBAN)
+		}
+
+		dest.BIC = strings.ToUpper(strings.TrimSpace(dest.BIC))
+		if dest.BIC != "" && !bicPattern.MatchString(dest.BIC) {
+			return Destination{}, fmt.Errorf("%w: bic %q", ErrInvalidDestination, dest.BIC)
+		}
+
+		dest.RoutingNumber, dest.AccountNumber, dest.AccountType = "", "", ""
+	case SchemeNACHA:
+		dest.RoutingNumber = strings.TrimSpace(dest.RoutingNumber)
+		if !validRoutingNumber(dest.RoutingNumber) {
+			return Destination{}, fmt.Errorf("%w: routing number %q", ErrInvalidDestination, dest.RoutingNumber)
+		}
+
+		dest.AccountNumber = strings.ToUpper(strings.TrimSpace(dest.AccountNumber))
+		if !accountPattern.MatchString(dest.AccountNumber) {
+			return Destination{}, fmt.Errorf("%w: account number %q, up to %d characters",
+				ErrInvalidDestination, dest.AccountNumber, maxAccountNumber)
+		}
+
+		switch dest.AccountType {
+		case "":
+			dest.AccountType = AccountChecking
+		case AccountChecking, AccountSavings:
+		default:
+			return Destination{}, fmt.Errorf("%w: account type %q", ErrInvalidDestination, dest.AccountType)
+		}
+
+		dest.IBAN, dest.BIC = "", ""
+	default:
+		return Destination{}, fmt.Errorf("%w %q", ErrUnknownScheme, scheme)
+	}
+
+	return dest, nil
+}
+
+// account is the number stored for the destination, bankCode the bank it is held at
+func (d Destination) account() (string, string) {
+	if d.IBAN != "" {
+		return d.IBAN, d.BIC
+	}
+
+	return d.AccountNumber, d.RoutingNumber
+}
@@ -0,0 +1,14 @@
+package payout
+
+import "errors"
+
+var (
+	ErrUnknownScheme      = errors.New("unknown payout scheme")
+	ErrUnknownFormat      = errors.New("unknown acknowledgement format")
+	ErrInvalidDestination = errors.New("invalid payout destination")
+	ErrInvalidAmount      = errors.New("invalid payout amount")
+	ErrInvalidFile        = errors.New("invalid acknowledgement file")
+	ErrPayoutNotFound     = errors.New("payout not found")
+	ErrBatchNotFound      = errors.New("payout batch not found")
+	ErrNothingToBatch     = errors.New("no pending payouts")
+)
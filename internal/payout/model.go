@@ -0,0 +1,192 @@
+package payout
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Scheme is the bank scheme payouts are sent with, it decides the file format
+type Scheme string
+
+// Supported schemes, SEPA credit transfers as pain.001 and ACH credits as NACHA files
+const (
+	SchemeSEPA  Scheme = "sepa"
+	SchemeNACHA Scheme = "nacha"
+)
+
+// Payout states. The wallet is debited while a payout is requested; a requested
+// payout whose withdrawal is refused is deleted. Pending payouts wait for the next
+// batch, batched ones for the bank's acknowledgement. A return re-credits the wallet.
+const (
+	StatusRequested = "requested"
+	StatusPending   = "pending"
+	StatusBatched   = "batched"
+	StatusSettled   = "settled"
+	StatusReturned  = "returned"
+)
+
+// AckFormat is a format of acknowledgement files from the bank
+type AckFormat string
+
+// Supported acknowledgement formats: payment status reports for SEPA, return files
+// for NACHA and a CSV for anything else
+const (
+	AckPain002 AckFormat = "pain002"
+	AckNACHA   AckFormat = "nacha"
+	AckCSV     AckFormat = "csv"
+)
+
+// Outcomes of an acknowledged payout
+const (
+	OutcomeSettled   = "settled"
+	OutcomeReturned  = "returned"
+	OutcomeUnchanged = "unchanged"
+	OutcomeUnknown   = "unknown"
+	OutcomeFailed    = "failed"
+)
+
+// Destination is the bank account a payout is sent to, IBAN and BIC for SEPA,
+// routing number, account number and account type for NACHA
+type Destination struct {
+	Name          string `json:"name"`
+	IBAN          string `json:"iban,omitempty"`
+	BIC           string `json:"bic,omitempty"`
+	RoutingNumber string `json:"routing_number,omitempty"`
+	AccountNumber string `json:"account_number,omitempty"`
+	AccountType   string `json:"account_type,omitempty"`
+}
+
+// Payout is a withdrawal to a bank account
+type Payout struct {
+	PayoutID     int         `json:"payout_id"`
+	UserID       int         `json:"user_id"`
+	Amount       float64     `json:"amount"`
+	Scheme       Scheme      `json:"scheme"`
+	Destination  Destination `json:"destination"`
+	Remittance   string      `json:"remittance,omitempty"`
+	Status       string      `json:"status"`
+	BatchID      int         `json:"batch_id,omitempty"`
+	Reference    string      `json:"reference,omitempty"`
+	ReturnReason string      `json:"return_reason,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+}
+
+// Batch is a payout file, Count and ControlSum are the file-level control values
+type Batch struct {
+	BatchID    int       `json:"batch_id"`
+	Scheme     Scheme    `json:"scheme"`
+	Count      int       `json:"payout_count"`
+	ControlSum float64   `json:"control_sum"`
+	CreatedAt  time.Time `json:"created_at"`
+	Payouts    []Payout  `json:"payouts"`
+}
+
+// Ack is the bank's verdict on one payout, identified by its reference
+type Ack struct {
+	Reference string
+	Status    string
+	Reason    string
+}
+
+// AckResult is what happened to the payout of one acknowledgement
+type AckResult struct {
+	Reference string `json:"reference"`
+	PayoutID  int    `json:"payout_id,omitempty"`
+	Outcome   string `json:"outcome"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// AckReport summarises an ingested acknowledgement file
+type AckReport struct {
+	Acks      int         `json:"acks"`
+	Settled   int         `json:"settled"`
+	Returned  int         `json:"returned"`
+	Unchanged int         `json:"unchanged"`
+	Unknown   int         `json:"unknown"`
+	Failed    int         `json:"failed"`
+	Results   []AckResult `json:"results"`
+}
+
+// Repository stores payouts and their batches
+type Repository interface {
+	// CreatePayout stores a requested payout
+	CreatePayout(ctx context.Context, p Payout) (Payout, error)
+	// MarkPending moves a requested payout on once its withdrawal is booked
+	MarkPending(ctx context.Context, payoutID int) error
+	// DeleteRequested removes a requested payout whose withdrawal was refused
+	DeleteRequested(ctx context.Context, payoutID int) error
+	GetPayout(ctx context.Context, payoutID int) (Payout, error)
+	// FindPayout returns the batched payout with the reference or ErrPayoutNotFound
+	FindPayout(ctx context.Context, reference string) (Payout, error)
+	// ListPayouts filters by user and status, 0 and "" match all
+	ListPayouts(ctx context.Context, userID int, status string) ([]Payout, error)
+	// CreateBatch moves every pending payout of the scheme into a new batch and gives
+	// each a reference of the prefix and its id, ErrNothingToBatch if none is pending
+	CreateBatch(ctx context.Context, scheme Scheme, referencePrefix string) (Batch, error)
+	GetBatch(ctx context.Context, batchID int) (Batch, error)
+	// Settle marks a batched payout settled, false if it is not batched
+	Settle(ctx context.Context, payoutID int) (bool, error)
+	// Return marks a batched or settled payout returned, false if it is neither
+	Return(ctx context.Context, payoutID int, reason string) (bool, error)
+}
+
+// Wallets books the withdrawal of a payout and the re-credit of its return once
+// per key, wallet.Service is one
+type Wallets interface {
+	WithdrawIdempotent(ctx context.Context, userID int, amount float64, key string) (float64, error)
+	DepositIdempotent(ctx context.Context, userID int, amount float64, key string) (float64, error)
+}
+
+// Service requests payouts, batches them into files and ingests acknowledgements
+type Service interface {
+	Request(ctx context.Context, userID int, amount float64, dest Destination, remittance string) (Payout, error)
+	List(ctx context.Context, userID int, status string) ([]Payout, error)
+	// Batch creates a batch of the pending payouts, the file is written by WriteBatch
+	Batch(ctx context.Context) (Batch, error)
+	GetBatch(ctx context.Context, batchID int) (Batch, error)
+	WriteBatch(w io.Writer, batch Batch) error
+	Ingest(ctx context.Context, r io.Reader, f AckFormat) (AckReport, error)
+}
+
+// SEPAConfig is the debtor account of SEPA payouts
+type SEPAConfig struct {
+	DebtorName string
+	IBAN       string
+	BIC        string
+}
+
+// NACHAConfig identifies the originator of NACHA files
+type NACHAConfig struct {
+	ImmediateDestination string // routing number of the bank receiving the file
+	DestinationName      string
+	ImmediateOrigin      string // usually the company id
+	OriginName           string
+	CompanyName          string
+	CompanyID            string
+	ODFI                 string // first 8 digits of the originating bank's routing number
+	EntryDescription     string
+}
+
+// Config of the payout service
+type Config struct {
+	Scheme Scheme
+	SEPA   SEPAConfig
+	NACHA  NACHAConfig
+}
+
+type payoutService struct {
+	repo    Repository
+	wallets Wallets
+	cfg     Config
+	logger  *logrus.Entry
+	now     func() time.Time
+}
+
+type payoutRepository struct {
+	db *sql.DB
+}
@@ -0,0 +1,255 @@
+package payout
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// NACHA files are blocks of ten 94 character records
+const (
+	nachaRecordLength = 94
+	nachaBlockingRecs = 10
+	nachaMaxBatch     = 10000000
+	nachaHashModulus  = 10000000000
+	nachaCreditsOnly  = "220"
+	nachaCheckingCode = "22"
+	nachaSavingsCode  = "32"
+	nachaDateLayout   = "060102"
+	nachaTimeLayout   = "1504"
+)
+
+// returnReasons describes the common ACH return codes
+//
+//nolint:gochecknoglobals // read-only
+var returnReasons = map[string]string{
+	"R01": "insufficient funds",
+	"R02": "account closed",
+	"R03": "no account, unable to locate account",
+	"R04": "invalid account number",
+	"R06": "returned per ODFI request",
+	"R07": "authorization revoked",
+	"R08": "payment stopped",
+	"R10": "customer advises not authorized",
+	"R16": "account frozen",
+	"R20": "non-transaction account",
+}
+
+// nachaField is a field of a record, text is upper case, left justified and padded
+// with spaces, a number right justified and padded with zeros
+type nachaField struct {
+	text    string
+	number  int64
+	width   int
+	numeric bool
+}
+
+func (f nachaField) String() string {
+	if f.numeric {
+		return fmt.Sprintf("%0*d", f.width, f.number)
+	}
+
+	s := strings.Map(func(r rune) rune {
+		if r < ' ' || r > '~' {
+			return ' '
+		}
+
+		return r
+	}, strings.ToUpper(f.text))
+
+	if len(s) > f.width {
+		return s[:f.width]
+	}
+
+	return s + strings.Repeat(" ", f.width-len(s))
+}
+
+func nachaRecord(fields ...nachaField) string {
+	var b strings.Builder
+
+	for _, f := range fields {
+		b.WriteString(f.String())
+	}
+
+	return b.String()
+}
+
+// routingField is a routing number field, a blank followed by the nine digits
+func routingField(s string) string {
+	return fmt.Sprintf("%10s", s)
+}
+
+// writeNACHA writes the batch as a NACHA file of one PPD credit batch. The entry
+// hash and the total credit in the batch and file control records are the control
+// sums the bank checks the file against.
+//
+//nolint:funlen // one block per record layout
+func (s *payoutService) writeNACHA(w io.Writer, batch Batch) error {
+	cfg := s.cfg.NACHA
+	created := batch.CreatedAt.UTC()
+	batchNumber := int64(batch.BatchID % nachaMaxBatch)
+	records := make([]string, 0, len(batch.Payouts)+nachaBlockingRecs)
+
+	records = append(records, nachaRecord(
+		nachaField{text: "1", width: 1},
+		nachaField{text: "01", width: 2},
+		nachaField{text: routingField(cfg.ImmediateDestination), width: 10},
+		nachaField{text: routingField(cfg.ImmediateOrigin), width: 10},
+		nachaField{text: created.Format(nachaDateLayout), width: 6},
+		nachaField{text: created.Format(nachaTimeLayout), width: 4},
+		nachaField{text: "A", width: 1},
+		nachaField{text: "094", width: 3},
+		nachaField{text: "10", width: 2},
+		nachaField{text: "1", width: 1},
+		nachaField{text: cfg.DestinationName, width: 23},
+		nachaField{text: cfg.OriginName, width: 23},
+		nachaField{width: 8},
+	))
+
+	records = append(records, nachaRecord(
+		nachaField{text: "5", width: 1},
+		nachaField{text: nachaCreditsOnly, width: 3},
+		nachaField{text: cfg.CompanyName, width: 16},
+		nachaField{width: 20},
+		nachaField{text: cfg.CompanyID, width: 10},
+		nachaField{text: "PPD", width: 3},
+		nachaField{text: cfg.EntryDescription, width: 10},
+		nachaField{width: 6},
+		nachaField{text: created.Format(nachaDateLayout), width: 6},
+		nachaField{width: 3},
+		nachaField{text: "1", width: 1},
+		nachaField{text: cfg.ODFI, width: 8},
+		nachaField{number: batchNumber, width: 7, numeric: true},
+	))
+
+	var hash, total int64
+
+	for _, p := range batch.Payouts {
+		code := nachaCheckingCode
+		if p.Destination.AccountType == AccountSavings {
+			code = nachaSavingsCode
+		}
+
+		routing := p.Destination.RoutingNumber
+
+		rdfi, err := strconv.ParseInt(routing[:8], 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed to write payout %d: %w: routing number %q", p.PayoutID, ErrInvalidDestination, routing)
+		}
+
+		hash += rdfi
+		total += cents(p.Amount)
+
+		records = append(records, nachaRecord(
+			nachaField{text: "6", width: 1},
+			nachaField{text: code, width: 2},
+			nachaField{text: routing, width: 9},
+			nachaField{text: p.Destination.AccountNumber, width: 17},
+			nachaField{number: cents(p.Amount), width: 10, numeric: true},
+			nachaField{text: "P" + strconv.Itoa(p.PayoutID), width: 15},
+			nachaField{text: p.Destination.Name, width: 22},
+			nachaField{width: 2},
+			nachaField{text: "0", width: 1},
+			nachaField{text: p.Reference, width: 15},
+		))
+	}
+
+	hash %= nachaHashModulus
+	count := int64(len(batch.Payouts))
+
+	records = append(records, nachaRecord(
+		nachaField{text: "8", width: 1},
+		nachaField{text: nachaCreditsOnly, width: 3},
+		nachaField{number: count, width: 6, numeric: true},
+		nachaField{number: hash, width: 10, numeric: true},
+		nachaField{width: 12, numeric: true},
+		nachaField{number: total, width: 12, numeric: true},
+		nachaField{text: cfg.CompanyID, width: 10},
+		nachaField{width: 25},
+		nachaField{text: cfg.ODFI, width: 8},
+		nachaField{number: batchNumber, width: 7, numeric: true},
+	))
+
+	// the file control record counts the blocks including itself
+	blocks := (len(records) + nachaBlockingRecs) / nachaBlockingRecs
+
+	records = append(records, nachaRecord(
+		nachaField{text: "9", width: 1},
+		nachaField{number: 1, width: 6, numeric: true},
+		nachaField{number: int64(blocks), width: 6, numeric: true},
+		nachaField{number: count, width: 8, numeric: true},
+		nachaField{number: hash, width: 10, numeric: true},
+		nachaField{width: 12, numeric: true},
+		nachaField{number: total, width: 12, numeric: true},
+		nachaField{width: 39},
+	))
+
+	for len(records)%nachaBlockingRecs != 0 {
+		records = append(records, strings.Repeat("9", nachaRecordLength))
+	}
+
+	bw := bufio.NewWriter(w)
+
+	for _, record := range records {
+		if _, err := bw.WriteString(record + "\n"); err != nil {
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to flush: %w", err)
+	}
+
+	return nil
+}
+
+// parseNACHAReturns reads the return entries of a NACHA return file, the addenda
+// records of type 99 carry the return code and the trace number of the payout
+func parseNACHAReturns(r io.Reader) ([]Ack, error) {
+	scanner := bufio.NewScanner(r)
+
+	var (
+		acks   []Ack
+		header bool
+	)
+
+	for line := 1; scanner.Scan(); line++ {
+		record := strings.TrimRight(scanner.Text(), "\r")
+		if record == "" || strings.Trim(record, "9") == "" {
+			continue
+		}
+
+		if len(record) != nachaRecordLength {
+			return nil, fmt.Errorf("%w: line %d is %d characters", ErrInvalidFile, line, len(record))
+		}
+
+		if record[0] == '1' {
+			header = true
+		}
+
+		if !strings.HasPrefix(record, "799") {
+			continue
+		}
+
+		code := record[3:6]
+		reason := code
+
+		if description, ok := returnReasons[code]; ok {
+			reason += " " + description
+		}
+
+		acks = append(acks, Ack{Reference: record[6:21], Status: StatusReturned, Reason: reason})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+
+	if !header {
+		return nil, fmt.Errorf("%w: no file header record", ErrInvalidFile)
+	}
+
+	return acks, nil
+}
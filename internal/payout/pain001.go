@@ -0,0 +1,163 @@
+package payout
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// pain001Namespace is the CustomerCreditTransferInitiation version written
+const pain001Namespace = "urn:iso:std:iso:20022:tech:xsd:pain.001.001.09"
+
+const (
+	sepaCurrency      = "EUR"
+	maxRemittance     = 140
+	pain001DateLayout = "2006-01-02"
+	pain001TimeLayout = "2006-01-02T15:04:05Z"
+)
+
+type painDocument struct {
+	XMLName    xml.Name `xml:"Document"`
+	Namespace  string   `xml:"xmlns,attr"`
+	Initiation struct {
+		Header struct {
+			MsgID      string `xml:"MsgId"`
+			Created    string `xml:"CreDtTm"`
+			Count      int    `xml:"NbOfTxs"`
+			ControlSum string `xml:"CtrlSum"`
+			Initiator  string `xml:"InitgPty>Nm"`
+		} `xml:"GrpHdr"`
+		Payment painPayment `xml:"PmtInf"`
+	} `xml:"CstmrCdtTrfInitn"`
+}
+
+type painPayment struct {
+	ID            string         `xml:"PmtInfId"`
+	Method        string         `xml:"PmtMtd"`
+	BatchBooking  bool           `xml:"BtchBookg"`
+	Count         int            `xml:"NbOfTxs"`
+	ControlSum    string         `xml:"CtrlSum"`
+	ServiceLevel  string         `xml:"PmtTpInf>SvcLvl>Cd"`
+	ExecutionDate string         `xml:"ReqdExctnDt>Dt"`
+	Debtor        string         `xml:"Dbtr>Nm"`
+	DebtorIBAN    string         `xml:"DbtrAcct>Id>IBAN"`
+	DebtorBIC     string         `xml:"DbtrAgt>FinInstnId>BICFI"`
+	ChargeBearer  string         `xml:"ChrgBr"`
+	Transfers     []painTransfer `xml:"CdtTrfTxInf"`
+}
+
+type painTransfer struct {
+	EndToEndID    string     `xml:"PmtId>EndToEndId"`
+	Amount        painAmount `xml:"Amt>InstdAmt"`
+	CreditorAgent *painAgent `xml:"CdtrAgt,omitempty"`
+	Creditor      string     `xml:"Cdtr>Nm"`
+	CreditorIBAN  string     `xml:"CdtrAcct>Id>IBAN"`
+	Remittance    string     `xml:"RmtInf>Ustrd,omitempty"`
+}
+
+type painAgent struct {
+	BIC string `xml:"FinInstnId>BICFI"`
+}
+
+type painAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// sepaLatin transliterates the common accented letters into the SEPA basic Latin set
+//
+//nolint:gochecknoglobals // read-only
+var sepaLatin = strings.NewReplacer(
+	"À", "A", "Á", "A", "Â", "A", "Ã", "A", "Ä", "A", "Å", "A", "Æ", "AE", "Ç", "C",
+	"È", "E", "É", "E", "Ê", "E", "Ë", "E", "Ì", "I", "Í", "I", "Î", "I", "Ï", "I",
+	"Ñ", "N", "Ò", "O", "Ó", "O", "Ô", "O", "Õ", "O", "Ö", "O", "Ø", "O",
+	"Ù", "U", "Ú", "U", "Û", "U", "Ü", "U", "Ý", "Y", "ß", "ss",
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "æ", "ae", "ç", "c",
+	"è", "e", "é", "e", "ê", "e", "ë", "e", "ì", "i", "í", "i", "î", "i", "ï", "i",
+	"ñ", "n", "ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ý", "y", "ÿ", "y", "&", "+",
+)
+
+// sepaText keeps the characters of the SEPA basic Latin set, others become spaces
+func sepaText(s string, limit int) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("/-?:().,'+ ", r):
+			return r
+		default:
+			return ' '
+		}
+	}, sepaLatin.Replace(s))
+
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > limit {
+		s = strings.TrimSpace(s[:limit])
+	}
+
+	return s
+}
+
+// writePain001 writes the batch as one SEPA payment of the debtor account, every
+// payout a credit transfer identified by its reference
+func (s *payoutService) writePain001(w io.Writer, batch Batch) error {
+	var doc painDocument
+
+	cfg := s.cfg.SEPA
+	sum := formatCents(controlCents(batch.Payouts))
+
+	doc.Namespace = pain001Namespace
+	doc.Initiation.Header.MsgID = batch.MessageID()
+	doc.Initiation.Header.Created = batch.CreatedAt.UTC().Format(pain001TimeLayout)
+	doc.Initiation.Header.Count = len(batch.Payouts)
+	doc.Initiation.Header.ControlSum = sum
+	doc.Initiation.Header.Initiator = sepaText(cfg.DebtorName, maxSEPANameLength)
+
+	payment := &doc.Initiation.Payment
+	payment.ID = batch.MessageID()
+	payment.Method = "TRF"
+	payment.BatchBooking = true
+	payment.Count = len(batch.Payouts)
+	payment.ControlSum = sum
+	payment.ServiceLevel = "SEPA"
+	payment.ExecutionDate = batch.CreatedAt.UTC().Format(pain001DateLayout)
+	payment.Debtor = sepaText(cfg.DebtorName, maxSEPANameLength)
+	payment.DebtorIBAN = cfg.IBAN
+	payment.DebtorBIC = cfg.BIC
+	payment.ChargeBearer = "SLEV"
+	payment.Transfers = make([]painTransfer, 0, len(batch.Payouts))
+
+	for _, p := range batch.Payouts {
+		transfer := painTransfer{
+			EndToEndID:   p.Reference,
+			Amount:       painAmount{Currency: sepaCurrency, Value: formatCents(cents(p.Amount))},
+			Creditor:     sepaText(p.Destination.Name, maxSEPANameLength),
+			CreditorIBAN: p.Destination.IBAN,
+			Remittance:   sepaText(p.Remittance, maxRemittance),
+		}
+
+		// the BIC is optional within SEPA
+		if p.Destination.BIC != "" {
+			transfer.CreditorAgent = &painAgent{BIC: p.Destination.BIC}
+		}
+
+		payment.Transfers = append(payment.Transfers, transfer)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode document: %w", err)
+	}
+
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("failed to write document: %w", err)
+	}
+
+	return nil
+}
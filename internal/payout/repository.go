@@ -0,0 +1,288 @@
+package payout
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/amelonpie/wallet-service/internal/database"
+	"github.com/lib/pq"
+)
+
+const payoutColumns = `payout_id, user_id, amount, scheme, beneficiary_name, account, bank_code, account_type,
+    remittance, status, COALESCE(batch_id, 0), COALESCE(reference, ''), return_reason, created_at, updated_at`
+
+//nolint:ireturn // stick to interface
+func InitRepository() (Repository, error) {
+	dbConfig, err := database.NewDatabaseConfig()
+	if err != nil {
+		return nil, err //nolint:wrapcheck // names the secret that failed
+	}
+
+	postgre, err := dbConfig.ConnectPostgre()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+
+	return NewRepository(postgre), nil
+}
+
+// NewRepository returns the Postgres backed repository on db.
+//
+//nolint:ireturn // stick to interface
+func NewRepository(db *sql.DB) Repository {
+	return &payoutRepository{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPayout(row rowScanner) (Payout, error) {
+	var (
+		p                 Payout
+		account, bankCode string
+	)
+
+	err := row.Scan(
+		&p.PayoutID, &p.UserID, &p.Amount, &p.Scheme, &p.Destination.Name, &account, &bankCode,
+		&p.Destination.AccountType, &p.Remittance, &p.Status, &p.BatchID, &p.Reference, &p.ReturnReason,
+		&p.CreatedAt, &p.UpdatedAt,
+	)
+	if err != nil {
+		return Payout{}, err //nolint:wrapcheck // callers wrap with context
+	}
+
+	if p.Scheme == SchemeSEPA {
+		p.Destination.IBAN, p.Destination.BIC = account, bankCode
+	} else {
+		p.Destination.AccountNumber, p.Destination.RoutingNumber = account, bankCode
+	}
+
+	return p, nil
+}
+
+func scanPayouts(rows *sql.Rows) ([]Payout, error) {
+	defer rows.Close()
+
+	//nolint:prealloc // row count unknown
+	var payouts []Payout
+
+	for rows.Next() {
+		p, err := scanPayout(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payout: %w", err)
+		}
+
+		payouts = append(payouts, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error arises during rows iteration: %w", err)
+	}
+
+	return payouts, nil
+}
+
+func (r *payoutRepository) CreatePayout(ctx context.Context, p Payout) (Payout, error) {
+	account, bankCode := p.Destination.account()
+
+	query := `INSERT INTO payouts (user_id, amount, scheme, beneficiary_name, account, bank_code, account_type,
+              remittance, status)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+              RETURNING ` + payoutColumns
+
+	created, err := scanPayout(r.db.QueryRowContext(ctx, query, p.UserID, p.Amount, p.Scheme, p.Destination.Name,
+		account, bankCode, p.Destination.AccountType, p.Remittance, p.Status))
+	if err != nil {
+		return Payout{}, fmt.Errorf("failed to insert payout for user %d: %w", p.UserID, err)
+	}
+
+	return created, nil
+}
+
+func (r *payoutRepository) MarkPending(ctx context.Context, payoutID int) error {
+	query := `UPDATE payouts SET status = $2, updated_at = CURRENT_TIMESTAMP WHERE payout_id = $1 AND status = $3`
+
+	if _, err := r.db.ExecContext(ctx, query, payoutID, StatusPending, StatusRequested); err != nil {
+		return fmt.Errorf("failed to update payout %d: %w", payoutID, err)
+	}
+
+	return nil
+}
+
+func (r *payoutRepository) DeleteRequested(ctx context.Context, payoutID int) error {
+	query := `DELETE FROM payouts WHERE payout_id = $1 AND status = $2`
+
+	if _, err := r.db.ExecContext(ctx, query, payoutID, StatusRequested); err != nil {
+		return fmt.Errorf("failed to delete payout %d: %w", payoutID, err)
+	}
+
+	return nil
+}
+
+func (r *payoutRepository) GetPayout(ctx context.Context, payoutID int) (Payout, error) {
+	query := `SELECT ` + payoutColumns + ` FROM payouts WHERE payout_id = $1`
+
+	p, err := scanPayout(r.db.QueryRowContext(ctx, query, payoutID))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrPayoutNotFound
+	}
+
+	if err != nil {
+		return Payout{}, fmt.Errorf("failed to query payout %d: %w", payoutID, err)
+	}
+
+	return p, nil
+}
+
+func (r *payoutRepository) FindPayout(ctx context.Context, reference string) (Payout, error) {
+	query := `SELECT ` + payoutColumns + ` FROM payouts WHERE reference = $1`
+
+	p, err := scanPayout(r.db.QueryRowContext(ctx, query, reference))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrPayoutNotFound
+	}
+
+	if err != nil {
+		return Payout{}, fmt.Errorf("failed to query payout %s: %w", reference, err)
+	}
+
+	return p, nil
+}
+
+func (r *payoutRepository) ListPayouts(ctx context.Context, userID int, status string) ([]Payout, error) {
+	query := `SELECT ` + payoutColumns + ` FROM payouts
+              WHERE ($1 = 0 OR user_id = $1) AND ($2 = '' OR status = $2)
+              ORDER BY payout_id`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query payouts: %w", err)
+	}
+
+	return scanPayouts(rows)
+}
+
+// CreateBatch batches in one transaction, a concurrent run waits for the row locks
+// and finds the payouts batched. The 7 digit sequence of a reference wraps after
+// ten million payouts, the unique reference then fails the batch.
+func (r *payoutRepository) CreateBatch(ctx context.Context, scheme Scheme, referencePrefix string) (Batch, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Batch{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	batch, err := r.createBatch(ctx, tx, scheme, referencePrefix)
+	if err != nil {
+		return Batch{}, errors.Join(err, tx.Rollback())
+	}
+
+	if err = tx.Commit(); err != nil {
+		return Batch{}, fmt.Errorf("failed to commit payout batch: %w", err)
+	}
+
+	return batch, nil
+}
+
+func (r *payoutRepository) createBatch(ctx context.Context, tx *sql.Tx, scheme Scheme, referencePrefix string) (Batch, error) {
+	batch := Batch{Scheme: scheme}
+
+	query := `INSERT INTO payout_batches (scheme, payout_count, control_sum) VALUES ($1, 0, 0)
+              RETURNING batch_id, created_at`
+	if err := tx.QueryRowContext(ctx, query, scheme).Scan(&batch.BatchID, &batch.CreatedAt); err != nil {
+		return Batch{}, fmt.Errorf("failed to insert payout batch: %w", err)
+	}
+
+	query = `UPDATE payouts
+             SET status = $3, batch_id = $1, reference = $4 || LPAD((payout_id % 10000000)::TEXT, 7, '0'),
+                 updated_at = CURRENT_TIMESTAMP
+             WHERE status = $5 AND scheme = $2
+             RETURNING ` + payoutColumns
+
+	rows, err := tx.QueryContext(ctx, query, batch.BatchID, scheme, StatusBatched, referencePrefix, StatusPending)
+	if err != nil {
+		return Batch{}, fmt.Errorf("failed to batch payouts: %w", err)
+	}
+
+	batch.Payouts, err = scanPayouts(rows)
+	if err != nil {
+		return Batch{}, err
+	}
+
+	if len(batch.Payouts) == 0 {
+		return Batch{}, ErrNothingToBatch
+	}
+
+	sort.Slice(batch.Payouts, func(i, j int) bool { return batch.Payouts[i].PayoutID < batch.Payouts[j].PayoutID })
+
+	batch.Count = len(batch.Payouts)
+	batch.ControlSum = float64(controlCents(batch.Payouts)) / 100 //nolint:mnd // cents
+
+	query = `UPDATE payout_batches SET payout_count = $2, control_sum = $3 WHERE batch_id = $1`
+	if _, err = tx.ExecContext(ctx, query, batch.BatchID, batch.Count, batch.ControlSum); err != nil {
+		return Batch{}, fmt.Errorf("failed to update payout batch %d: %w", batch.BatchID, err)
+	}
+
+	return batch, nil
+}
+
+func (r *payoutRepository) GetBatch(ctx context.Context, batchID int) (Batch, error) {
+	var batch Batch
+
+	query := `SELECT batch_id, scheme, payout_count, control_sum, created_at FROM payout_batches WHERE batch_id = $1`
+
+	err := r.db.QueryRowContext(ctx, query, batchID).
+		Scan(&batch.BatchID, &batch.Scheme, &batch.Count, &batch.ControlSum, &batch.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrBatchNotFound
+	}
+
+	if err != nil {
+		return Batch{}, fmt.Errorf("failed to query payout batch %d: %w", batchID, err)
+	}
+
+	query = `SELECT ` + payoutColumns + ` FROM payouts WHERE batch_id = $1 ORDER BY payout_id`
+
+	rows, err := r.db.QueryContext(ctx, query, batchID)
+	if err != nil {
+		return Batch{}, fmt.Errorf("failed to query payouts of batch %d: %w", batchID, err)
+	}
+
+	batch.Payouts, err = scanPayouts(rows)
+	if err != nil {
+		return Batch{}, err
+	}
+
+	return batch, nil
+}
+
+// transition moves the payout to status if it is in one of from, false otherwise
+func (r *payoutRepository) transition(ctx context.Context, payoutID int, status, reason string, from ...string) (bool, error) {
+	query := `UPDATE payouts
+              SET status = $2, return_reason = CASE WHEN $3 = '' THEN return_reason ELSE $3 END,
+                  updated_at = CURRENT_TIMESTAMP
+              WHERE payout_id = $1 AND status = ANY($4)`
+
+	result, err := r.db.ExecContext(ctx, query, payoutID, status, reason, pq.Array(from))
+	if err != nil {
+		return false, fmt.Errorf("failed to update payout %d: %w", payoutID, err)
+	}
+
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update payout %d: %w", payoutID, err)
+	}
+
+	return changed > 0, nil
+}
+
+func (r *payoutRepository) Settle(ctx context.Context, payoutID int) (bool, error) {
+	return r.transition(ctx, payoutID, StatusSettled, "", StatusBatched)
+}
+
+func (r *payoutRepository) Return(ctx context.Context, payoutID int, reason string) (bool, error) {
+	return r.transition(ctx, payoutID, StatusReturned, reason, StatusBatched, StatusSettled)
+}
@@ -0,0 +1,54 @@
+package payout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateBatch_NothingPending(t *testing.T) {
+	// Arrange
+	db, mockSQL, err := sqlmock.New()
+	require.NoError(t, err)
+
+	repo := NewRepository(db)
+
+	mockSQL.ExpectBegin()
+	mockSQL.ExpectQuery(`INSERT INTO payout_batches \(scheme, payout_count, control_sum\)`).
+		WithArgs(SchemeNACHA).
+		WillReturnRows(sqlmock.NewRows([]string{"batch_id", "created_at"}).AddRow(3, time.Now()))
+	mockSQL.ExpectQuery(`UPDATE payouts`).
+		WithArgs(3, SchemeNACHA, StatusBatched, "02100002", StatusPending).
+		WillReturnRows(sqlmock.NewRows(nil))
+	mockSQL.ExpectRollback()
+
+	// Act
+	_, err = repo.CreateBatch(context.Background(), SchemeNACHA, "02100002")
+
+	// Assert: the empty batch is rolled back
+	require.ErrorIs(t, err, ErrNothingToBatch)
+	require.NoError(t, mockSQL.ExpectationsWereMet())
+}
+
+func TestSettle_NotBatched(t *testing.T) {
+	// Arrange
+	db, mockSQL, err := sqlmock.New()
+	require.NoError(t, err)
+
+	repo := NewRepository(db)
+
+	mockSQL.ExpectExec(`UPDATE payouts`).
+		WithArgs(5, StatusSettled, "", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// Act
+	settled, err := repo.Settle(context.Background(), 5)
+
+	// Assert
+	require.NoError(t, err)
+	require.False(t, settled)
+	require.NoError(t, mockSQL.ExpectationsWereMet())
+}
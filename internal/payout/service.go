@@ -0,0 +1,367 @@
+package payout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/spf13/viper"
+)
+
+// resumeAfter is how old a requested payout is before a batch run books its
+// withdrawal, younger ones are likely still being requested
+const resumeAfter = time.Minute
+
+func NewConfig() Config {
+	viper.SetDefault("payout.scheme", string(SchemeNACHA))
+	viper.SetDefault("payout.sepa.debtor_name", "Wallet Service")
+	viper.SetDefault("payout.sepa.iban", "")
+	viper.SetDefault("payout.sepa.bic", "")
+	viper.SetDefault("payout.nacha.immediate_destination", "")
+	viper.SetDefault("payout.nacha.destination_name", "")
+	viper.SetDefault("payout.nacha.immediate_origin", "")
+	viper.SetDefault("payout.nacha.origin_name", "")
+	viper.SetDefault("payout.nacha.company_name", "Wallet Service")
+	viper.SetDefault("payout.nacha.company_id", "")
+	viper.SetDefault("payout.nacha.odfi", "")
+	viper.SetDefault("payout.nacha.entry_description", "PAYOUT")
+
+	return Config{
+		Scheme: Scheme(viper.GetString("payout.scheme")),
+		SEPA: SEPAConfig{
+			DebtorName: viper.GetString("payout.sepa.debtor_name"),
+			IBAN:       strings.ToUpper(strings.ReplaceAll(viper.GetString("payout.sepa.iban"), " ", "")),
+			BIC:        strings.ToUpper(viper.GetString("payout.sepa.bic")),
+		},
+		NACHA: NACHAConfig{
+			ImmediateDestination: viper.GetString("payout.nacha.immediate_destination"),
+			DestinationName:      viper.GetString("payout.nacha.destination_name"),
+			ImmediateOrigin:      viper.GetString("payout.nacha.immediate_origin"),
+			OriginName:           viper.GetString("payout.nacha.origin_name"),
+			CompanyName:          viper.GetString("payout.nacha.company_name"),
+			CompanyID:            viper.GetString("payout.nacha.company_id"),
+			ODFI:                 viper.GetString("payout.nacha.odfi"),
+			EntryDescription:     viper.GetString("payout.nacha.entry_description"),
+		},
+	}
+}
+
+// InitService connects the payout service to its tables in PostgreSQL
+//
+//nolint:ireturn // stick to interface
+func InitService(wallets Wallets, cfg Config) (Service, error) {
+	repo, err := InitRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewService(repo, wallets, cfg), nil
+}
+
+//nolint:ireturn // stick to interface
+func NewService(repo Repository, wallets Wallets, cfg Config) Service {
+	return &payoutService{
+		repo:    repo,
+		wallets: wallets,
+		cfg:     cfg,
+		logger:  log.NewLogger("payout").WithField("module", "service"),
+		now:     time.Now,
+	}
+}
+
+// WithdrawKey is the idempotency key of the withdrawal of a payout
+func WithdrawKey(payoutID int) string {
+	return "payout:" + strconv.Itoa(payoutID)
+}
+
+// ReturnKey is the idempotency key of the re-credit of a returned payout
+func ReturnKey(payoutID int) string {
+	return "payout-return:" + strconv.Itoa(payoutID)
+}
+
+// MessageID identifies the batch file at the bank
+func (b Batch) MessageID() string {
+	return "PAYOUTS-" + strconv.Itoa(b.BatchID)
+}
+
+func cents(amount float64) int64 {
+	return int64(math.Round(amount * 100)) //nolint:mnd // cents
+}
+
+func formatCents(c int64) string {
+	return fmt.Sprintf("%d.%02d", c/100, c%100) //nolint:mnd // cents
+}
+
+// controlCents adds up the amounts in cents, so the control sum has no rounding error
+func controlCents(payouts []Payout) int64 {
+	var sum int64
+	for _, p := range payouts {
+		sum += cents(p.Amount)
+	}
+
+	return sum
+}
+
+// refused tells whether the wallet turned down a withdrawal or deposit for good
+func refused(err error) bool {
+	return errors.Is(err, wallet.ErrInsufficientFunds) || errors.Is(err, wallet.ErrWalletNotFound) ||
+		errors.Is(err, wallet.ErrWalletFrozen)
+}
+
+// Request debits the wallet and queues the payout for the next batch. The payout
+// is stored before the withdrawal, whose key is the payout id, so a crash in
+// between leaves a requested payout that the next batch run completes. A
+// withdrawal that fails other than by a refusal may have been booked all the
+// same: the payout is returned still requested, for the next batch run to
+// complete, rather than as an error the client would answer with a second payout.
+func (s *payoutService) Request(
+	ctx context.Context,
+	userID int,
+	amount float64,
+	dest Destination,
+	remittance string,
+) (Payout, error) {
+	if amount <= 0 || math.Abs(amount*100-math.Round(amount*100)) > 1e-6 {
+		return Payout{}, fmt.Errorf("%w: %v, a positive amount in cents is required", ErrInvalidAmount, amount)
+	}
+
+	dest, err := normalize(dest, s.cfg.Scheme)
+	if err != nil {
+		return Payout{}, err
+	}
+
+	p, err := s.repo.CreatePayout(ctx, Payout{
+		UserID:      userID,
+		Amount:      amount,
+		Scheme:      s.cfg.Scheme,
+		Destination: dest,
+		Remittance:  strings.TrimSpace(remittance),
+		Status:      StatusRequested,
+	})
+	if err != nil {
+		return Payout{}, fmt.Errorf("failed to create payout for user %d: %w", userID, err)
+	}
+
+	completed, err := s.complete(ctx, p)
+	if err != nil && !refused(err) {
+		s.logger.WithField("err", err).WithField("payout_id", p.PayoutID).
+			Warn("payout left requested, the next batch run completes it")
+
+		return p, nil
+	}
+
+	return completed, err
+}
+
+// complete books the withdrawal of a requested payout and marks it pending. A
+// payout the wallet refuses is deleted, other errors leave it to be completed later.
+func (s *payoutService) complete(ctx context.Context, p Payout) (Payout, error) {
+	_, err := s.wallets.WithdrawIdempotent(ctx, p.UserID, p.Amount, WithdrawKey(p.PayoutID))
+	if err != nil && !errors.Is(err, wallet.ErrDuplicateWithdraw) {
+		if refused(err) {
+			if deleteErr := s.repo.DeleteRequested(ctx, p.PayoutID); deleteErr != nil {
+				s.logger.WithField("err", deleteErr).WithField("payout_id", p.PayoutID).
+					Error("failed to delete refused payout")
+			}
+		}
+
+		return Payout{}, fmt.Errorf("failed to withdraw payout %d: %w", p.PayoutID, err)
+	}
+
+	if err = s.repo.MarkPending(ctx, p.PayoutID); err != nil {
+		return Payout{}, fmt.Errorf("failed to mark payout %d pending: %w", p.PayoutID, err)
+	}
+
+	p.Status = StatusPending
+
+	s.logger.WithField("payout_id", p.PayoutID).WithField("user_id", p.UserID).
+		WithField("amount", p.Amount).Info("payout requested")
+
+	return p, nil
+}
+
+func (s *payoutService) List(ctx context.Context, userID int, status string) ([]Payout, error) {
+	payouts, err := s.repo.ListPayouts(ctx, userID, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payouts: %w", err)
+	}
+
+	return payouts, nil
+}
+
+// resume completes payouts left requested by an interrupted request
+func (s *payoutService) resume(ctx context.Context) error {
+	requested, err := s.repo.ListPayouts(ctx, 0, StatusRequested)
+	if err != nil {
+		return fmt.Errorf("failed to list requested payouts: %w", err)
+	}
+
+	for _, p := range requested {
+		if s.now().Sub(p.CreatedAt) < resumeAfter {
+			continue
+		}
+
+		if _, err = s.complete(ctx, p); err != nil && !refused(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Batch puts every pending payout of the configured scheme into a new batch
+func (s *payoutService) Batch(ctx context.Context) (Batch, error) {
+	if err := s.resume(ctx); err != nil {
+		return Batch{}, err
+	}
+
+	// references are the prefix and 7 digits of the payout id: the NACHA trace
+	// number is the originating bank and an entry sequence number
+	prefix := "PAYOUT-"
+	if s.cfg.Scheme == SchemeNACHA {
+		prefix = s.cfg.NACHA.ODFI
+	}
+
+	batch, err := s.repo.CreateBatch(ctx, s.cfg.Scheme, prefix)
+	if err != nil {
+		return Batch{}, fmt.Errorf("failed to create payout batch: %w", err)
+	}
+
+	s.logger.WithField("batch_id", batch.BatchID).WithField("scheme", batch.Scheme).
+		WithField("payouts", batch.Count).WithField("control_sum", batch.ControlSum).Info("payout batch created")
+
+	return batch, nil
+}
+
+func (s *payoutService) GetBatch(ctx context.Context, batchID int) (Batch, error) {
+	batch, err := s.repo.GetBatch(ctx, batchID)
+	if err != nil {
+		return Batch{}, fmt.Errorf("failed to get payout batch %d: %w", batchID, err)
+	}
+
+	return batch, nil
+}
+
+// WriteBatch writes the payout file of the batch, a batch can be written again
+// and gives the same file
+func (s *payoutService) WriteBatch(w io.Writer, batch Batch) error {
+	switch batch.Scheme {
+	case SchemeSEPA:
+		return s.writePain001(w, batch)
+	case SchemeNACHA:
+		return s.writeNACHA(w, batch)
+	default:
+		return fmt.Errorf("%w %q", ErrUnknownScheme, batch.Scheme)
+	}
+}
+
+// Ingest settles and returns the payouts acknowledged in the file. Ingesting a
+// file again changes nothing. A return the wallet refuses to re-credit is reported
+// as failed and applied when the file is ingested again.
+func (s *payoutService) Ingest(ctx context.Context, r io.Reader, f AckFormat) (AckReport, error) {
+	acks, err := ParseAcks(r, f)
+	if err != nil {
+		return AckReport{}, err
+	}
+
+	report := AckReport{Acks: len(acks), Results: make([]AckResult, 0, len(acks))}
+
+	for _, ack := range acks {
+		result, err := s.ingestAck(ctx, ack)
+		if err != nil {
+			return report, fmt.Errorf("failed to ingest acknowledgement of %s: %w", ack.Reference, err)
+		}
+
+		switch result.Outcome {
+		case OutcomeSettled:
+			report.Settled++
+		case OutcomeReturned:
+			report.Returned++
+		case OutcomeUnchanged:
+			report.Unchanged++
+		case OutcomeUnknown:
+			report.Unknown++
+		case OutcomeFailed:
+			report.Failed++
+		}
+
+		report.Results = append(report.Results, result)
+	}
+
+	s.logger.WithField("format", f).WithField("acks", report.Acks).WithField("settled", report.Settled).
+		WithField("returned", report.Returned).WithField("unknown", report.Unknown).
+		WithField("failed", report.Failed).Info("payout acknowledgements ingested")
+
+	return report, nil
+}
+
+func (s *payoutService) ingestAck(ctx context.Context, ack Ack) (AckResult, error) {
+	result := AckResult{Reference: ack.Reference, Outcome: OutcomeUnchanged}
+
+	p, err := s.repo.FindPayout(ctx, ack.Reference)
+	if errors.Is(err, ErrPayoutNotFound) {
+		s.logger.WithField("reference", ack.Reference).Warn("acknowledgement of an unknown payout")
+
+		result.Outcome = OutcomeUnknown
+
+		return result, nil
+	}
+
+	if err != nil {
+		return AckResult{}, err
+	}
+
+	result.PayoutID = p.PayoutID
+
+	if ack.Status == StatusSettled {
+		settled, err := s.repo.Settle(ctx, p.PayoutID)
+		if err != nil {
+			return AckResult{}, err
+		}
+
+		if settled {
+			result.Outcome = OutcomeSettled
+		}
+
+		return result, nil
+	}
+
+	if p.Status != StatusBatched && p.Status != StatusSettled {
+		return result, nil
+	}
+
+	// the re-credit comes first, its key makes a retry after a crash harmless
+	_, err = s.wallets.DepositIdempotent(ctx, p.UserID, p.Amount, ReturnKey(p.PayoutID))
+	if err != nil && !errors.Is(err, wallet.ErrDuplicateDeposit) {
+		if refused(err) {
+			result.Outcome = OutcomeFailed
+			result.Reason = err.Error()
+
+			return result, nil
+		}
+
+		return AckResult{}, err
+	}
+
+	returned, err := s.repo.Return(ctx, p.PayoutID, ack.Reason)
+	if err != nil {
+		return AckResult{}, err
+	}
+
+	if returned {
+		result.Outcome = OutcomeReturned
+		result.Reason = ack.Reason
+
+		s.logger.WithField("payout_id", p.PayoutID).WithField("user_id", p.UserID).
+			WithField("reason", ack.Reason).Warn("payout returned, wallet re-credited")
+	}
+
+	return result, nil
+}
@@ -0,0 +1,554 @@
+package payout
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+// batchTime is when the test batches are created
+//
+//nolint:gochecknoglobals // read-only
+var batchTime = time.Date(2026, 10, 19, 14, 30, 0, 0, time.UTC)
+
+// fakeRepository keeps payouts in process
+type fakeRepository struct {
+	mu      sync.Mutex
+	payouts []Payout
+	batches []Batch
+}
+
+func (r *fakeRepository) CreatePayout(_ context.Context, p Payout) (Payout, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p.PayoutID = len(r.payouts) + 1
+	p.CreatedAt = batchTime
+	r.payouts = append(r.payouts, p)
+
+	return p, nil
+}
+
+func (r *fakeRepository) payout(payoutID int) *Payout {
+	for i := range r.payouts {
+		if r.payouts[i].PayoutID == payoutID {
+			return &r.payouts[i]
+		}
+	}
+
+	return nil
+}
+
+func (r *fakeRepository) MarkPending(_ context.Context, payoutID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if p := r.payout(payoutID); p != nil && p.Status == StatusRequested {
+		p.Status = StatusPending
+	}
+
+	return nil
+}
+
+func (r *fakeRepository) DeleteRequested(_ context.Context, payoutID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, p := range r.payouts {
+		if p.PayoutID == payoutID && p.Status == StatusRequested {
+			r.payouts = append(r.payouts[:i], r.payouts[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+func (r *fakeRepository) GetPayout(_ context.Context, payoutID int) (Payout, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if p := r.payout(payoutID); p != nil {
+		return *p, nil
+	}
+
+	return Payout{}, ErrPayoutNotFound
+}
+
+func (r *fakeRepository) FindPayout(_ context.Context, reference string) (Payout, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, p := range r.payouts {
+		if p.Reference == reference {
+			return p, nil
+		}
+	}
+
+	return Payout{}, ErrPayoutNotFound
+}
+
+func (r *fakeRepository) ListPayouts(_ context.Context, userID int, status string) ([]Payout, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var payouts []Payout
+
+	for _, p := range r.payouts {
+		if (userID == 0 || p.UserID == userID) && (status == "" || p.Status == status) {
+			payouts = append(payouts, p)
+		}
+	}
+
+	return payouts, nil
+}
+
+func (r *fakeRepository) CreateBatch(_ context.Context, scheme Scheme, referencePrefix string) (Batch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	batch := Batch{BatchID: len(r.batches) + 1, Scheme: scheme, CreatedAt: batchTime}
+
+	for i := range r.payouts {
+		p := &r.payouts[i]
+		if p.Status != StatusPending || p.Scheme != scheme {
+			continue
+		}
+
+		p.Status = StatusBatched
+		p.BatchID = batch.BatchID
+		p.Reference = referencePrefix + strings.Repeat("0", 7-len(strconv.Itoa(p.PayoutID))) + strconv.Itoa(p.PayoutID)
+		batch.Payouts = append(batch.Payouts, *p)
+	}
+
+	if len(batch.Payouts) == 0 {
+		return Batch{}, ErrNothingToBatch
+	}
+
+	sort.Slice(batch.Payouts, func(i, j int) bool { return batch.Payouts[i].PayoutID < batch.Payouts[j].PayoutID })
+
+	batch.Count = len(batch.Payouts)
+	batch.ControlSum = float64(controlCents(batch.Payouts)) / 100
+	r.batches = append(r.batches, batch)
+
+	return batch, nil
+}
+
+func (r *fakeRepository) GetBatch(_ context.Context, batchID int) (Batch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if batchID < 1 || batchID > len(r.batches) {
+		return Batch{}, ErrBatchNotFound
+	}
+
+	return r.batches[batchID-1], nil
+}
+
+func (r *fakeRepository) transition(payoutID int, status, reason string, from ...string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := r.payout(payoutID)
+	if p == nil {
+		return false
+	}
+
+	for _, s := range from {
+		if p.Status == s {
+			p.Status = status
+			if reason != "" {
+				p.ReturnReason = reason
+			}
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *fakeRepository) Settle(_ context.Context, payoutID int) (bool, error) {
+	return r.transition(payoutID, StatusSettled, "", StatusBatched), nil
+}
+
+func (r *fakeRepository) Return(_ context.Context, payoutID int, reason string) (bool, error) {
+	return r.transition(payoutID, StatusReturned, reason, StatusBatched, StatusSettled), nil
+}
+
+func testConfig(scheme Scheme) Config {
+	return Config{
+		Scheme: scheme,
+		SEPA:   SEPAConfig{DebtorName: "Wallet Service GmbH", IBAN: "DE89370400440532013000", BIC: "COBADEFFXXX"},
+		NACHA: NACHAConfig{
+			ImmediateDestination: "021000021",
+			DestinationName:      "JPMORGAN CHASE",
+			ImmediateOrigin:      "1234567890",
+			OriginName:           "WALLET SERVICE",
+			CompanyName:          "WALLET SERVICE",
+			CompanyID:            "1234567890",
+			ODFI:                 "02100002",
+			EntryDescription:     "PAYOUT",
+		},
+	}
+}
+
+func newTestService(scheme Scheme) (*payoutService, *fakeRepository, wallet.Service) {
+	repo := &fakeRepository{}
+	wallets := wallet.NewService(wallet.NewMemoryRepository(map[int]float64{1: 500, 2: 100}), wallet.NewNopCache())
+
+	svc, _ := NewService(repo, wallets, testConfig(scheme)).(*payoutService)
+	svc.now = func() time.Time { return batchTime }
+
+	return svc, repo, wallets
+}
+
+func balance(t *testing.T, wallets wallet.Service, userID int) float64 {
+	t.Helper()
+
+	amount, err := wallets.GetBalance(context.Background(), userID)
+	require.NoError(t, err)
+
+	return amount
+}
+
+func sepaDestination(name, iban string) Destination {
+	return Destination{Name: name, IBAN: iban}
+}
+
+func nachaDestination(name, routing, account, accountType string) Destination {
+	return Destination{Name: name, RoutingNumber: routing, AccountNumber: account, AccountType: accountType}
+}
+
+func TestRequest(t *testing.T) {
+	// Arrange
+	svc, repo, wallets := newTestService(SchemeSEPA)
+
+	// Act
+	p, err := svc.Request(context.Background(), 1, 120.5, sepaDestination("Ada Lovelace", "de89 3704 0044 0532 0130 00"), "Payout")
+
+	// Assert: the wallet is debited when the payout is requested
+	require.NoError(t, err)
+	require.Equal(t, StatusPending, p.Status)
+	require.Equal(t, "DE89370400440532013000", p.Destination.IBAN)
+	require.InDelta(t, 379.5, balance(t, wallets, 1), 0.001)
+	require.Equal(t, StatusPending, repo.payouts[0].Status)
+}
+
+func TestRequest_Refused(t *testing.T) {
+	tests := []struct {
+		name    string
+		userID  int
+		amount  float64
+		dest    Destination
+		wantErr error
+	}{
+		{name: "insufficient funds", userID: 2, amount: 150, dest: sepaDestination("Alan Turing", "GB29NWBK60161331926819"),
+			wantErr: wallet.ErrInsufficientFunds},
+		{name: "unknown wallet", userID: 9, amount: 10, dest: sepaDestination("Alan Turing", "GB29NWBK60161331926819"),
+			wantErr: wallet.ErrWalletNotFound},
+		{name: "bad check digits", userID: 1, amount: 10, dest: sepaDestination("Alan Turing", "GB28NWBK60161331926819"),
+			wantErr: ErrInvalidDestination},
+		{name: "no name", userID: 1, amount: 10, dest: sepaDestination(" ", "GB29NWBK60161331926819"),
+			wantErr: ErrInvalidDestination},
+		{name: "fraction of a cent", userID: 1, amount: 10.005, dest: sepaDestination("Alan Turing", "GB29NWBK60161331926819"),
+			wantErr: ErrInvalidAmount},
+		{name: "negative", userID: 1, amount: -10, dest: sepaDestination("Alan Turing", "GB29NWBK60161331926819"),
+			wantErr: ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			svc, repo, wallets := newTestService(SchemeSEPA)
+
+			// Act
+			_, err := svc.Request(context.Background(), tt.userID, tt.amount, tt.dest, "")
+
+			// Assert: nothing is left behind
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Empty(t, repo.payouts)
+			require.InDelta(t, 500, balance(t, wallets, 1), 0.001)
+		})
+	}
+}
+
+// lostReplyWallets books the first withdrawal but reports it failed, as when the
+// connection drops before the commit is acknowledged
+type lostReplyWallets struct {
+	Wallets
+
+	lost bool
+}
+
+func (w *lostReplyWallets) WithdrawIdempotent(ctx context.Context, userID int, amount float64, key string) (float64, error) {
+	balance, err := w.Wallets.WithdrawIdempotent(ctx, userID, amount, key)
+	if err == nil && !w.lost {
+		w.lost = true
+		return 0, errors.New("driver: bad connection")
+	}
+
+	return balance, err
+}
+
+func TestRequest_TransientFailureIsPaidOnce(t *testing.T) {
+	// Arrange
+	svc, repo, wallets := newTestService(SchemeSEPA)
+	svc.wallets = &lostReplyWallets{Wallets: wallets}
+
+	// Act: the request fails after the withdrawal was booked, the batch run resumes it
+	p, err := svc.Request(context.Background(), 1, 40, sepaDestination("Ada Lovelace", "GB29NWBK60161331926819"), "")
+	require.NoError(t, err)
+
+	svc.now = func() time.Time { return batchTime.Add(resumeAfter) }
+	batch, batchErr := svc.Batch(context.Background())
+
+	// Assert: the client learns the payout id instead of an error, the wallet is debited once
+	require.Equal(t, StatusRequested, p.Status)
+	require.Equal(t, 1, p.PayoutID)
+	require.NoError(t, batchErr)
+	require.Equal(t, 1, batch.Count)
+	require.Len(t, repo.payouts, 1)
+	require.InDelta(t, 460, balance(t, wallets, 1), 0.001)
+}
+
+func TestNormalize_NACHA(t *testing.T) {
+	// Act
+	dest, err := normalize(nachaDestination("Grace Hopper", "011000015", "12-3456", ""), SchemeNACHA)
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, AccountChecking, dest.AccountType)
+
+	_, err = normalize(nachaDestination("Grace Hopper", "011000016", "123456", ""), SchemeNACHA)
+	require.ErrorIs(t, err, ErrInvalidDestination)
+
+	_, err = normalize(nachaDestination("Grace Hopper", "011000015", "123456789012345678", ""), SchemeNACHA)
+	require.ErrorIs(t, err, ErrInvalidDestination)
+
+	_, err = normalize(nachaDestination("Grace Hopper", "011000015", "123456", "brokerage"), SchemeNACHA)
+	require.ErrorIs(t, err, ErrInvalidDestination)
+}
+
+func requestAll(t *testing.T, svc *payoutService, dests ...Destination) {
+	t.Helper()
+
+	for i, dest := range dests {
+		_, err := svc.Request(context.Background(), 1, float64(i+1)*25.1, dest, "Wallet payout "+strconv.Itoa(i+1))
+		require.NoError(t, err)
+	}
+}
+
+func TestBatch_Files(t *testing.T) {
+	tests := []struct {
+		scheme Scheme
+		file   string
+		dests  []Destination
+	}{
+		{
+			scheme: SchemeSEPA,
+			file:   "batch.pain001.xml",
+			dests: []Destination{
+				{Name: "Ada Lovelace", IBAN: "GB29NWBK60161331926819", BIC: "NWBKGB2L"},
+				sepaDestination("Renée Ökonom & Söhne", "FR1420041010050500013M02606"),
+			},
+		},
+		{
+			scheme: SchemeNACHA,
+			file:   "batch.nacha",
+			dests: []Destination{
+				nachaDestination("Grace Hopper", "011000015", "12345678", AccountChecking),
+				nachaDestination("Katherine Johnson Jackson", "021000021", "987654321", AccountSavings),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.scheme), func(t *testing.T) {
+			// Arrange
+			svc, _, _ := newTestService(tt.scheme)
+			requestAll(t, svc, tt.dests...)
+
+			expected, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			require.NoError(t, err)
+
+			// Act
+			batch, err := svc.Batch(context.Background())
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			err = svc.WriteBatch(&buf, batch)
+
+			// Assert: 25.10 + 50.20
+			require.NoError(t, err)
+			require.Equal(t, 2, batch.Count)
+			require.InDelta(t, 75.3, batch.ControlSum, 0.001)
+			require.Equal(t, string(expected), buf.String())
+		})
+	}
+}
+
+func TestBatch_NACHARecords(t *testing.T) {
+	// Arrange
+	svc, _, _ := newTestService(SchemeNACHA)
+	requestAll(t, svc, nachaDestination("Grace Hopper", "011000015", "12345678", ""))
+
+	batch, err := svc.Batch(context.Background())
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	// Act
+	require.NoError(t, svc.WriteBatch(&buf, batch))
+
+	// Assert: blocks of ten records of 94 characters
+	records := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, records, 10)
+
+	for _, record := range records {
+		require.Len(t, record, nachaRecordLength)
+	}
+
+	require.Equal(t, "021000020000001", batch.Payouts[0].Reference[:15])
+}
+
+func TestBatch_NothingPending(t *testing.T) {
+	// Arrange
+	svc, _, _ := newTestService(SchemeSEPA)
+
+	// Act
+	_, err := svc.Batch(context.Background())
+
+	// Assert
+	require.ErrorIs(t, err, ErrNothingToBatch)
+}
+
+func TestBatch_ResumesRequested(t *testing.T) {
+	// Arrange: a request that stopped after storing the payout
+	svc, repo, wallets := newTestService(SchemeSEPA)
+	_, err := repo.CreatePayout(context.Background(), Payout{
+		UserID: 1, Amount: 40, Scheme: SchemeSEPA, Status: StatusRequested,
+		Destination: sepaDestination("Ada Lovelace", "GB29NWBK60161331926819"),
+	})
+	require.NoError(t, err)
+
+	svc.now = func() time.Time { return batchTime.Add(resumeAfter) }
+
+	// Act
+	batch, err := svc.Batch(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, 1, batch.Count)
+	require.InDelta(t, 460, balance(t, wallets, 1), 0.001)
+}
+
+func batched(t *testing.T, scheme Scheme, dests ...Destination) (*payoutService, *fakeRepository, wallet.Service) {
+	t.Helper()
+
+	svc, repo, wallets := newTestService(scheme)
+	requestAll(t, svc, dests...)
+
+	_, err := svc.Batch(context.Background())
+	require.NoError(t, err)
+
+	return svc, repo, wallets
+}
+
+func ingestFile(t *testing.T, svc *payoutService, name string, f AckFormat) AckReport {
+	t.Helper()
+
+	file, err := os.Open(filepath.Join("testdata", name))
+	require.NoError(t, err)
+
+	defer file.Close()
+
+	report, err := svc.Ingest(context.Background(), file, f)
+	require.NoError(t, err)
+
+	return report
+}
+
+func TestIngest_Pain002(t *testing.T) {
+	// Arrange: payouts of 25.10 and 50.20
+	svc, repo, wallets := batched(t, SchemeSEPA,
+		sepaDestination("Ada Lovelace", "GB29NWBK60161331926819"),
+		sepaDestination("Alan Turing", "FR1420041010050500013M02606"),
+	)
+
+	// Act
+	report := ingestFile(t, svc, "status.pain002.xml", AckPain002)
+
+	// Assert: the rejected payout is re-credited, the one in progress is left out
+	require.Equal(t, 3, report.Acks)
+	require.Equal(t, 1, report.Settled)
+	require.Equal(t, 1, report.Returned)
+	require.Equal(t, 1, report.Unknown)
+	require.Equal(t, StatusSettled, repo.payouts[0].Status)
+	require.Equal(t, StatusReturned, repo.payouts[1].Status)
+	require.Equal(t, "AC04 Account closed", repo.payouts[1].ReturnReason)
+	require.InDelta(t, 500-25.1, balance(t, wallets, 1), 0.001)
+
+	// Act: the same file again
+	report = ingestFile(t, svc, "status.pain002.xml", AckPain002)
+
+	// Assert
+	require.Equal(t, 2, report.Unchanged)
+	require.InDelta(t, 500-25.1, balance(t, wallets, 1), 0.001)
+}
+
+func TestIngest_NACHAReturn(t *testing.T) {
+	// Arrange
+	svc, repo, wallets := batched(t, SchemeNACHA,
+		nachaDestination("Grace Hopper", "011000015", "12345678", ""),
+		nachaDestination("Katherine Johnson", "021000021", "987654321", AccountSavings),
+	)
+
+	_, err := svc.Ingest(context.Background(), strings.NewReader("reference,status\n021000020000001,settled\n"), AckCSV)
+	require.NoError(t, err)
+
+	// Act
+	report := ingestFile(t, svc, "returns.nacha", AckNACHA)
+
+	// Assert: a settled payout can still be returned
+	require.Equal(t, 2, report.Returned)
+	require.Equal(t, StatusReturned, repo.payouts[0].Status)
+	require.Equal(t, "R03 no account, unable to locate account", repo.payouts[0].ReturnReason)
+	require.Equal(t, "R01 insufficient funds", repo.payouts[1].ReturnReason)
+	require.InDelta(t, 500, balance(t, wallets, 1), 0.001)
+}
+
+func TestParseAcks_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		f    AckFormat
+	}{
+		{name: "csv status", file: "reference,status\nPAYOUT-0000001,paid\n", f: AckCSV},
+		{name: "csv column", file: "reference\nPAYOUT-0000001\n", f: AckCSV},
+		{name: "nacha length", file: "101 021000021\n", f: AckNACHA},
+		{name: "nacha header", file: strings.Repeat("9", nachaRecordLength) + "\n", f: AckNACHA},
+		{name: "pain002", file: "<Document>", f: AckPain002},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			_, err := ParseAcks(strings.NewReader(tt.file), tt.f)
+
+			// Assert
+			require.ErrorIs(t, err, ErrInvalidFile)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package reconcile
+
+import (
+	"context"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/sirupsen/logrus"
+)
+
+// Severity ranks a discrepancy
+type Severity string
+
+const (
+	// SeverityCritical means money is unaccounted for: a balance differs from its transactions
+	SeverityCritical Severity = "critical"
+	// SeverityWarning means a stale balance is served from the cache
+	SeverityWarning Severity = "warning"
+	// SeverityInfo means a check could not run completely
+	SeverityInfo Severity = "info"
+)
+
+// Kinds of discrepancies
+const (
+	KindLedger = "ledger"
+	KindCache  = "cache"
+)
+
+// Discrepancy is a single finding of a reconciliation run. Expected is the value
+// Postgres considers correct, Actual the one found.
+type Discrepancy struct {
+	Kind     string   `json:"kind"`
+	Severity Severity `json:"severity"`
+	UserID   int      `json:"user_id,omitempty"`
+	Expected float64  `json:"expected"`
+	Actual   float64  `json:"actual"`
+	Repaired bool     `json:"repaired,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// Report of a reconciliation run
+type Report struct {
+	StartedAt      time.Time     `json:"started_at"`
+	FinishedAt     time.Time     `json:"finished_at"`
+	WalletsChecked int           `json:"wallets_checked"`
+	CacheChecked   bool          `json:"cache_checked"`
+	Discrepancies  []Discrepancy `json:"discrepancies"`
+}
+
+// Store reads wallets and their ledger, implemented by *wallet.Admin
+type Store interface {
+	Wallet(ctx context.Context, userID int) (wallet.Wallet, error)
+	Wallets(ctx context.Context, afterUserID, limit int) ([]wallet.Wallet, error)
+	CheckLedger(ctx context.Context) ([]wallet.LedgerDiscrepancy, error)
+}
+
+// Config controls the scheduled reconciliation
+type Config struct {
+	// Interval between scheduled runs, 0 disables the schedule
+	Interval time.Duration
+	// RepairCache invalidates stale cached balances found by scheduled runs
+	RepairCache bool
+	// BatchSize is the number of wallets read per query
+	BatchSize int
+}
+
+// Reconciler compares wallet balances with the transactions and the balance cache
+type Reconciler struct {
+	store  Store
+	cache  wallet.BalanceCache
+	cfg    Config
+	logger *logrus.Entry
+	now    func() time.Time
+}
@@ -0,0 +1,216 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/database"
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// balances are stored with two decimals, smaller differences are float noise
+const centEpsilon = 0.005
+
+// LockID is the advisory lock key held by the instance that runs the scheduled
+// reconciliation, so that it is not repeated and alerted on by every instance
+const LockID = 7365232
+
+// NewConfig reads the reconciliation schedule from the config, with defaults
+func NewConfig() Config {
+	viper.SetDefault("reconcile.interval", "1h")
+	viper.SetDefault("reconcile.repair_cache", false)
+	viper.SetDefault("reconcile.batch_size", 500)
+
+	return Config{
+		Interval:    viper.GetDuration("reconcile.interval"),
+		RepairCache: viper.GetBool("reconcile.repair_cache"),
+		BatchSize:   viper.GetInt("reconcile.batch_size"),
+	}
+}
+
+// Init connects to PostgreSQL and compares against cache, which should be the
+// cache the wallet service uses
+func Init(cache wallet.BalanceCache, cfg Config) (*Reconciler, error) {
+	dbConfig, err := database.NewDatabaseConfig()
+	if err != nil {
+		return nil, err //nolint:wrapcheck // names the secret that failed
+	}
+
+	db, err := dbConfig.ConnectPostgre()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+
+	return New(wallet.NewAdmin(db, cache), cache, cfg), nil
+}
+
+func New(store Store, cache wallet.BalanceCache, cfg Config) *Reconciler {
+	if cfg.BatchSize < 1 {
+		cfg.BatchSize = 1
+	}
+
+	return &Reconciler{
+		store:  store,
+		cache:  cache,
+		cfg:    cfg,
+		logger: log.NewLogger("reconcile").WithField("module", "reconcile"),
+		now:    time.Now,
+	}
+}
+
+// Run reconciles every interval until ctx is done. The first run waits one interval,
+// so that restarts do not put extra load on the database.
+func (r *Reconciler) Run(ctx context.Context) {
+	if r.cfg.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := r.Reconcile(ctx, r.cfg.RepairCache); err != nil {
+			r.logger.WithField("err", err).Error("failed to reconcile wallets")
+		}
+	}
+}
+
+// Reconcile recomputes every balance from the transactions and compares cached balances
+// with Postgres. With repairCache stale cached balances are invalidated; the ledger is
+// never changed, money discrepancies need a person to look at them.
+func (r *Reconciler) Reconcile(ctx context.Context, repairCache bool) (Report, error) {
+	report := Report{StartedAt: r.now().UTC(), CacheChecked: true, Discrepancies: []Discrepancy{}}
+
+	ledger, err := r.store.CheckLedger(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to check ledger: %w", err)
+	}
+
+	for _, d := range ledger {
+		report.Discrepancies = append(report.Discrepancies, Discrepancy{
+			Kind:     KindLedger,
+			Severity: SeverityCritical,
+			UserID:   d.UserID,
+			Expected: d.LedgerBalance,
+			Actual:   d.Balance,
+			Detail:   "balance differs from the net of its transactions",
+		})
+	}
+
+	afterUserID := 0
+
+	for {
+		wallets, err := r.store.Wallets(ctx, afterUserID, r.cfg.BatchSize)
+		if err != nil {
+			return Report{}, fmt.Errorf("failed to read wallets: %w", err)
+		}
+
+		for _, w := range wallets {
+			if report.CacheChecked {
+				report.CacheChecked = r.checkCache(ctx, w, repairCache, &report)
+			}
+
+			report.WalletsChecked++
+			afterUserID = w.UserID
+		}
+
+		if len(wallets) < r.cfg.BatchSize {
+			break
+		}
+	}
+
+	report.FinishedAt = r.now().UTC()
+	r.log(report)
+
+	return report, nil
+}
+
+// checkCache compares the cached balance of a wallet with Postgres and reports
+// whether the cache could be checked
+func (r *Reconciler) checkCache(ctx context.Context, w wallet.Wallet, repair bool, report *Report) bool {
+	cached, ok, err := r.cache.Get(ctx, w.UserID)
+	if err != nil {
+		report.Discrepancies = append(report.Discrepancies, Discrepancy{
+			Kind:     KindCache,
+			Severity: SeverityInfo,
+			Detail:   fmt.Sprintf("cache not checked: %v", err),
+		})
+
+		return false
+	}
+
+	if !ok || sameAmount(cached, w.Balance) {
+		return true
+	}
+
+	// the wallet may have changed after it was read, compare with its current balance
+	current, err := r.store.Wallet(ctx, w.UserID)
+	if err != nil || sameAmount(cached, current.Balance) {
+		return true
+	}
+
+	d := Discrepancy{
+		Kind:     KindCache,
+		Severity: SeverityWarning,
+		UserID:   w.UserID,
+		Expected: current.Balance,
+		Actual:   cached,
+		Detail:   "cached balance is stale",
+	}
+
+	if repair {
+		if err = r.cache.Invalidate(ctx, w.UserID, current.Version); err != nil {
+			d.Detail += fmt.Sprintf(", repair failed: %v", err)
+		} else {
+			d.Repaired = true
+		}
+	}
+
+	report.Discrepancies = append(report.Discrepancies, d)
+
+	return true
+}
+
+func sameAmount(a, b float64) bool {
+	return math.Abs(a-b) < centEpsilon
+}
+
+func (r *Reconciler) log(report Report) {
+	for _, d := range report.Discrepancies {
+		entry := r.logger.WithFields(logrus.Fields{
+			"kind":     d.Kind,
+			"severity": d.Severity,
+			"user_id":  d.UserID,
+			"expected": d.Expected,
+			"actual":   d.Actual,
+			"repaired": d.Repaired,
+		})
+
+		switch d.Severity {
+		case SeverityCritical:
+			entry.Error(d.Detail)
+		case SeverityWarning:
+			entry.Warn(d.Detail)
+		default:
+			entry.Info(d.Detail)
+		}
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"wallets_checked": report.WalletsChecked,
+		"cache_checked":   report.CacheChecked,
+		"discrepancies":   len(report.Discrepancies),
+		"duration":        report.FinishedAt.Sub(report.StartedAt),
+	}).Info("reconciliation finished")
+}
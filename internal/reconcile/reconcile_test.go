@@ -0,0 +1,179 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore serves wallets from a map, changed is applied after the first read
+// of a wallet to simulate a write racing with the reconciliation
+type fakeStore struct {
+	wallets map[int]wallet.Wallet
+	ledger  []wallet.LedgerDiscrepancy
+	changed map[int]wallet.Wallet
+}
+
+func (s *fakeStore) Wallet(_ context.Context, userID int) (wallet.Wallet, error) {
+	if w, ok := s.changed[userID]; ok {
+		return w, nil
+	}
+
+	w, ok := s.wallets[userID]
+	if !ok {
+		return wallet.Wallet{}, wallet.ErrWalletNotFound
+	}
+
+	return w, nil
+}
+
+func (s *fakeStore) Wallets(_ context.Context, afterUserID, limit int) ([]wallet.Wallet, error) {
+	var wallets []wallet.Wallet
+
+	for _, w := range s.wallets {
+		if w.UserID > afterUserID {
+			wallets = append(wallets, w)
+		}
+	}
+
+	sort.Slice(wallets, func(i, j int) bool { return wallets[i].UserID < wallets[j].UserID })
+
+	if len(wallets) > limit {
+		wallets = wallets[:limit]
+	}
+
+	return wallets, nil
+}
+
+func (s *fakeStore) CheckLedger(_ context.Context) ([]wallet.LedgerDiscrepancy, error) {
+	return s.ledger, nil
+}
+
+// failingCache fails every read
+type failingCache struct {
+	wallet.BalanceCache
+}
+
+func (failingCache) Get(_ context.Context, _ int) (float64, bool, error) {
+	return 0, false, errors.New("connection refused")
+}
+
+func newCache() wallet.BalanceCache {
+	return wallet.NewMemoryCache(wallet.CacheConfig{MemorySize: 100, BalanceTTL: time.Hour, InvalidationTTL: time.Minute})
+}
+
+func newStore() *fakeStore {
+	return &fakeStore{wallets: map[int]wallet.Wallet{
+		1: {UserID: 1, Balance: 100, Version: 3},
+		2: {UserID: 2, Balance: 50, Version: 1},
+		3: {UserID: 3, Balance: 10, Version: 7},
+	}}
+}
+
+func TestReconcile_Clean(t *testing.T) {
+	// Arrange
+	cache := newCache()
+	require.NoError(t, cache.Set(context.Background(), 1, wallet.Balance{Amount: 100, Version: 3}))
+
+	r := New(newStore(), cache, Config{BatchSize: 2})
+
+	// Act
+	report, err := r.Reconcile(context.Background(), false)
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, 3, report.WalletsChecked)
+	require.True(t, report.CacheChecked)
+	require.Empty(t, report.Discrepancies)
+}
+
+func TestReconcile_LedgerMismatchIsCritical(t *testing.T) {
+	// Arrange
+	store := newStore()
+	store.ledger = []wallet.LedgerDiscrepancy{{UserID: 2, Balance: 50, LedgerBalance: 40}}
+
+	r := New(store, newCache(), Config{BatchSize: 10})
+
+	// Act
+	report, err := r.Reconcile(context.Background(), true)
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, []Discrepancy{{
+		Kind:     KindLedger,
+		Severity: SeverityCritical,
+		UserID:   2,
+		Expected: 40,
+		Actual:   50,
+		Detail:   "balance differs from the net of its transactions",
+	}}, report.Discrepancies)
+}
+
+func TestReconcile_StaleCache(t *testing.T) {
+	for _, repair := range []bool{false, true} {
+		// Arrange
+		ctx := context.Background()
+		cache := newCache()
+		require.NoError(t, cache.Set(ctx, 3, wallet.Balance{Amount: 12, Version: 6}))
+
+		r := New(newStore(), cache, Config{BatchSize: 10})
+
+		// Act
+		report, err := r.Reconcile(ctx, repair)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, report.Discrepancies, 1)
+
+		d := report.Discrepancies[0]
+		require.Equal(t, KindCache, d.Kind)
+		require.Equal(t, SeverityWarning, d.Severity)
+		require.Equal(t, 3, d.UserID)
+		require.InDelta(t, 10, d.Expected, 0.001)
+		require.InDelta(t, 12, d.Actual, 0.001)
+		require.Equal(t, repair, d.Repaired)
+
+		_, cached, err := cache.Get(ctx, 3)
+		require.NoError(t, err)
+		require.Equal(t, !repair, cached, "repair should drop the stale balance")
+	}
+}
+
+func TestReconcile_IgnoresConcurrentWrite(t *testing.T) {
+	// Arrange: the wallet is written after it was read, the cache already holds the new balance
+	ctx := context.Background()
+	store := newStore()
+	store.changed = map[int]wallet.Wallet{1: {UserID: 1, Balance: 150, Version: 4}}
+
+	cache := newCache()
+	require.NoError(t, cache.Set(ctx, 1, wallet.Balance{Amount: 150, Version: 4}))
+
+	r := New(store, cache, Config{BatchSize: 10})
+
+	// Act
+	report, err := r.Reconcile(ctx, true)
+
+	// Assert
+	require.NoError(t, err)
+	require.Empty(t, report.Discrepancies)
+}
+
+func TestReconcile_CacheUnavailable(t *testing.T) {
+	// Arrange
+	r := New(newStore(), failingCache{}, Config{BatchSize: 10})
+
+	// Act
+	report, err := r.Reconcile(context.Background(), true)
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, 3, report.WalletsChecked)
+	require.False(t, report.CacheChecked)
+	require.Len(t, report.Discrepancies, 1)
+	require.Equal(t, SeverityInfo, report.Discrepancies[0].Severity)
+}
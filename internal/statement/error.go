@@ -0,0 +1,5 @@
+package statement
+
+import "errors"
+
+var ErrUnknownFormat = errors.New("unknown statement format")
@@ -0,0 +1,11 @@
+package statement
+
+// Format is a representation a statement is rendered to
+type Format string
+
+// Supported statement formats
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+	FormatText Format = "text"
+)
@@ -0,0 +1,177 @@
+package statement
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/wallet"
+)
+
+const (
+	dateLayout     = "2006-01-02"
+	dateTimeLayout = "2006-01-02 15:04:05"
+)
+
+// ParseFormat accepts the formats by name, an empty name is JSON
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(name); f {
+	case "":
+		return FormatJSON, nil
+	case FormatJSON, FormatCSV, FormatText:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w %q", ErrUnknownFormat, name)
+	}
+}
+
+// ContentType is the media type of the rendered statement
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatText:
+		return "text/plain; charset=utf-8"
+	default:
+		return "application/json; charset=utf-8"
+	}
+}
+
+// Extension is the file name extension of the rendered statement
+func (f Format) Extension() string {
+	if f == FormatText {
+		return "txt"
+	}
+
+	return string(f)
+}
+
+// Render writes the statement in the format. The output only depends on the
+// statement, so a stored statement renders identically every time.
+func Render(w io.Writer, f Format, s wallet.Statement) error {
+	switch f {
+	case FormatJSON:
+		return renderJSON(w, s)
+	case FormatCSV:
+		return renderCSV(w, s)
+	case FormatText:
+		return renderText(w, s)
+	default:
+		return fmt.Errorf("%w %q", ErrUnknownFormat, f)
+	}
+}
+
+func money(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// debitCredit splits a signed amount into the debit and credit columns
+func debitCredit(amount float64) (string, string) {
+	if amount < 0 {
+		return money(-amount), ""
+	}
+
+	return "", money(amount)
+}
+
+func counterparty(userID int) string {
+	if userID == 0 {
+		return ""
+	}
+
+	return strconv.Itoa(userID)
+}
+
+func renderJSON(w io.Writer, s wallet.Statement) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(s); err != nil {
+		return fmt.Errorf("failed to encode statement: %w", err)
+	}
+
+	return nil
+}
+
+// renderCSV writes one record per row, the record column tells opening balance,
+// entries, closing balance and totals apart
+func renderCSV(w io.Writer, s wallet.Statement) error {
+	cw := csv.NewWriter(w)
+
+	records := [][]string{
+		{"record", "timestamp", "transaction_id", "type", "counterparty_id", "count", "debit", "credit", "balance"},
+		{"opening", s.From.Format(time.RFC3339), "", "", "", "", "", "", money(s.OpeningBalance)},
+	}
+
+	for _, e := range s.Entries {
+		debit, credit := debitCredit(e.Amount)
+		records = append(records, []string{
+			"entry", e.Timestamp.Format(time.RFC3339Nano), strconv.Itoa(e.TransactionID), e.Type,
+			counterparty(e.CounterpartyID), "", debit, credit, money(e.Balance),
+		})
+	}
+
+	records = append(records, []string{"closing", s.To.Format(time.RFC3339), "", "", "", "", "", "", money(s.ClosingBalance)})
+
+	for _, t := range s.Totals {
+		records = append(records, []string{
+			"total", "", "", t.Type, "", strconv.Itoa(t.Count), money(t.Debits), money(t.Credits), "",
+		})
+	}
+
+	if err := cw.WriteAll(records); err != nil {
+		return fmt.Errorf("failed to write statement csv: %w", err)
+	}
+
+	return nil
+}
+
+// renderText lays the statement out in fixed-width columns for printing or mail
+func renderText(w io.Writer, s wallet.Statement) error {
+	const (
+		row   = "%-19s %8s  %-12s %12s %14s %14s %14s\n"
+		total = "%-12s %8s %14s %14s\n"
+	)
+
+	status := "provisional, the period is not closed"
+	if s.Final {
+		status = "final"
+	}
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "STATEMENT OF ACCOUNT\n\n")
+	fmt.Fprintf(bw, "%-12s%d\n", "User:", s.UserID)
+	fmt.Fprintf(bw, "%-12s%s (%s to %s)\n", "Period:", s.Period,
+		s.From.Format(dateLayout), s.To.AddDate(0, 0, -1).Format(dateLayout))
+	fmt.Fprintf(bw, "%-12s%s\n", "Generated:", s.GeneratedAt.Format(time.RFC3339))
+	fmt.Fprintf(bw, "%-12s%s\n\n", "Status:", status)
+
+	fmt.Fprintf(bw, row, "DATE", "ID", "TYPE", "COUNTERPARTY", "DEBIT", "CREDIT", "BALANCE")
+	fmt.Fprintf(bw, row, s.From.Format(dateTimeLayout), "", "OPENING", "", "", "", money(s.OpeningBalance))
+
+	for _, e := range s.Entries {
+		debit, credit := debitCredit(e.Amount)
+		fmt.Fprintf(bw, row, e.Timestamp.Format(dateTimeLayout), strconv.Itoa(e.TransactionID), e.Type,
+			counterparty(e.CounterpartyID), debit, credit, money(e.Balance))
+	}
+
+	fmt.Fprintf(bw, row, "", "", "CLOSING", "", "", "", money(s.ClosingBalance))
+
+	fmt.Fprintf(bw, "\n"+total, "TOTALS", "COUNT", "DEBITS", "CREDITS")
+
+	for _, t := range s.Totals {
+		fmt.Fprintf(bw, total, t.Type, strconv.Itoa(t.Count), money(t.Debits), money(t.Credits))
+	}
+
+	// bufio keeps the first write error and reports it here
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write statement text: %w", err)
+	}
+
+	return nil
+}
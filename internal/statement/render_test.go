@@ -0,0 +1,84 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+func fixture() wallet.Statement {
+	return wallet.Statement{
+		UserID:         1,
+		Period:         "2025-02",
+		From:           time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+		To:             time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		OpeningBalance: 100,
+		ClosingBalance: 120,
+		Entries: []wallet.StatementEntry{
+			{TransactionID: 7, Timestamp: time.Date(2025, 2, 3, 9, 30, 0, 0, time.UTC), Type: "deposit", Amount: 50, Balance: 150},
+			{
+				TransactionID: 9, Timestamp: time.Date(2025, 2, 14, 18, 5, 12, 0, time.UTC), Type: "transfer",
+				CounterpartyID: 2, Amount: -30, Balance: 120,
+			},
+		},
+		Totals: []wallet.StatementTotal{
+			{Type: "deposit", Count: 1, Credits: 50},
+			{Type: "transfer", Count: 1, Debits: 30},
+		},
+		GeneratedAt: time.Date(2025, 3, 1, 0, 5, 0, 0, time.UTC),
+		Final:       true,
+	}
+}
+
+func TestRender_Golden(t *testing.T) {
+	for _, f := range []Format{FormatCSV, FormatText} {
+		t.Run(string(f), func(t *testing.T) {
+			// Arrange
+			expected, err := os.ReadFile(filepath.Join("testdata", "statement."+f.Extension()))
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+
+			// Act
+			err = Render(&buf, f, fixture())
+
+			// Assert
+			require.NoError(t, err)
+			require.Equal(t, string(expected), buf.String())
+		})
+	}
+}
+
+func TestRender_JSONRoundTrip(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+
+	// Act
+	err := Render(&buf, FormatJSON, fixture())
+
+	// Assert
+	require.NoError(t, err)
+
+	var decoded wallet.Statement
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Equal(t, fixture(), decoded)
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("")
+	require.NoError(t, err)
+	require.Equal(t, FormatJSON, f)
+
+	f, err = ParseFormat("text")
+	require.NoError(t, err)
+	require.Equal(t, FormatText, f)
+
+	_, err = ParseFormat("pdf")
+	require.ErrorIs(t, err, ErrUnknownFormat)
+}
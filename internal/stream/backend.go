@@ -0,0 +1,223 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisChannel = "wallet_stream"
+	// the replay buffer of an idle user expires after a day
+	replayTTL = 24 * time.Hour
+)
+
+// NewRedisBackend keeps the last size messages per user in a sorted set scored by id
+// and broadcasts through Redis pub/sub
+//
+//nolint:ireturn // stick to interface
+func NewRedisBackend(client *redis.Client, size int) Backend {
+	return &redisBackend{client: client, size: int64(size)}
+}
+
+// publishScript allocates the id, buffers and broadcasts in one step, so that
+// subscribers always receive the messages of a user in id order.
+// Buffered and published values are encoded as "<id> <event json>".
+//
+//nolint:gochecknoglobals // scripts are immutable and cache their sha
+var publishScript = redis.NewScript(`
+local id = redis.call('INCR', KEYS[1])
+local msg = id .. ' ' .. ARGV[1]
+redis.call('ZADD', KEYS[2], id, msg)
+redis.call('ZREMRANGEBYRANK', KEYS[2], 0, -tonumber(ARGV[2]) - 1)
+redis.call('PEXPIRE', KEYS[2], ARGV[3])
+redis.call('PUBLISH', KEYS[3], msg)
+return id
+`)
+
+func decodeMessage(raw string) (Message, error) {
+	idPart, payload, found := strings.Cut(raw, " ")
+	if !found {
+		//nolint:err113 // no need to define error class
+		return Message{}, fmt.Errorf("malformed stream message %q", raw)
+	}
+
+	id, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil {
+		return Message{}, fmt.Errorf("malformed stream message id: %w", err)
+	}
+
+	msg := Message{ID: id}
+	if err = json.Unmarshal([]byte(payload), &msg.Event); err != nil {
+		return Message{}, fmt.Errorf("failed to unmarshal event: %w", err)
+	}
+
+	return msg, nil
+}
+
+func seqKey(userID int) string {
+	return fmt.Sprintf("wallet_stream_seq:%d", userID)
+}
+
+func bufferKey(userID int) string {
+	return fmt.Sprintf("wallet_stream:%d", userID)
+}
+
+func (r *redisBackend) Publish(ctx context.Context, event wallet.Event) (Message, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	id, err := publishScript.Run(ctx, r.client,
+		[]string{seqKey(event.UserID), bufferKey(event.UserID), redisChannel},
+		payload, r.size, replayTTL.Milliseconds(),
+	).Int64()
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to publish event: %w", err)
+	}
+
+	return Message{ID: id, Event: event}, nil
+}
+
+func (r *redisBackend) Replay(ctx context.Context, userID int, afterID int64) (Replay, error) {
+	var (
+		last    *redis.StringCmd
+		members *redis.StringSliceCmd
+	)
+
+	// read the sequence and the buffer at the same point, publishScript changes both at once
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		last = pipe.Get(ctx, seqKey(userID))
+		members = pipe.ZRangeByScore(ctx, bufferKey(userID), &redis.ZRangeBy{
+			Min: "(" + strconv.FormatInt(afterID, 10),
+			Max: "+inf",
+		})
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return Replay{}, fmt.Errorf("failed to read replay buffer: %w", err)
+	}
+
+	var replay Replay
+
+	if replay.LastID, err = last.Int64(); err != nil && !errors.Is(err, redis.Nil) {
+		return Replay{}, fmt.Errorf("failed to read stream sequence: %w", err)
+	}
+
+	replay.Messages = make([]Message, 0, len(members.Val()))
+
+	for _, member := range members.Val() {
+		msg, err := decodeMessage(member)
+		if err != nil {
+			return Replay{}, err
+		}
+
+		replay.Messages = append(replay.Messages, msg)
+	}
+
+	return replay, nil
+}
+
+func (r *redisBackend) Listen(ctx context.Context, handle func(Message)) error {
+	pubsub := r.client.Subscribe(ctx, redisChannel)
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case raw, ok := <-ch:
+			if !ok {
+				return errors.New("redis subscription closed") //nolint:err113 // no need to define error class
+			}
+
+			msg, err := decodeMessage(raw.Payload)
+			if err != nil {
+				continue
+			}
+
+			handle(msg)
+		}
+	}
+}
+
+// NewMemoryBackend serves a single instance without Redis
+//
+//nolint:ireturn // stick to interface
+func NewMemoryBackend(size int) Backend {
+	return &memoryBackend{
+		size:      size,
+		seq:       map[int]int64{},
+		buffer:    map[int][]Message{},
+		listeners: map[int]func(Message){},
+	}
+}
+
+func (m *memoryBackend) Publish(_ context.Context, event wallet.Event) (Message, error) {
+	m.mu.Lock()
+
+	m.seq[event.UserID]++
+	msg := Message{ID: m.seq[event.UserID], Event: event}
+
+	buf := append(m.buffer[event.UserID], msg)
+	if len(buf) > m.size {
+		buf = buf[len(buf)-m.size:]
+	}
+
+	m.buffer[event.UserID] = buf
+
+	listeners := make([]func(Message), 0, len(m.listeners))
+	for _, handle := range m.listeners {
+		listeners = append(listeners, handle)
+	}
+
+	m.mu.Unlock()
+
+	for _, handle := range listeners {
+		handle(msg)
+	}
+
+	return msg, nil
+}
+
+func (m *memoryBackend) Replay(_ context.Context, userID int, afterID int64) (Replay, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	replay := Replay{LastID: m.seq[userID]}
+
+	for _, msg := range m.buffer[userID] {
+		if msg.ID > afterID {
+			replay.Messages = append(replay.Messages, msg)
+		}
+	}
+
+	return replay, nil
+}
+
+func (m *memoryBackend) Listen(ctx context.Context, handle func(Message)) error {
+	m.mu.Lock()
+	m.nextListener++
+	id := m.nextListener
+	m.listeners[id] = handle
+	m.mu.Unlock()
+
+	<-ctx.Done()
+
+	m.mu.Lock()
+	delete(m.listeners, id)
+	m.mu.Unlock()
+
+	return nil
+}
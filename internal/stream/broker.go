@@ -0,0 +1,162 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+)
+
+const subscriberBuffer = 16
+
+// Stream backends selectable by stream.backend
+const (
+	BackendRedis  = "redis"
+	BackendMemory = "memory"
+)
+
+type Config struct {
+	// Backend is BackendRedis, shared by all instances, or BackendMemory for a
+	// single instance
+	Backend string
+	// ReplaySize is the number of events kept per user for resuming streams
+	ReplaySize int
+	// Websocket serves the streams as WebSocket messages as well as SSE
+	Websocket bool
+}
+
+func NewConfig() Config {
+	viper.SetDefault("stream.backend", BackendRedis)
+	viper.SetDefault("stream.replay_size", 100)
+	viper.SetDefault("stream.websocket", false)
+
+	return Config{
+		Backend:    viper.GetString("stream.backend"),
+		ReplaySize: viper.GetInt("stream.replay_size"),
+		Websocket:  viper.GetBool("stream.websocket"),
+	}
+}
+
+// New builds a broker on the configured backend, client is only used for Redis
+func New(cfg Config, client *redis.Client) (*Broker, error) {
+	switch cfg.Backend {
+	case BackendRedis:
+		broker := NewBroker(NewRedisBackend(client, cfg.ReplaySize))
+		if err := client.Ping(context.Background()).Err(); err != nil {
+			// the subscription reconnects on its own, events published meanwhile are lost
+			broker.logger.WithField("err", err).Warn("Redis unavailable, balance streams start without events")
+		}
+
+		return broker, nil
+	case BackendMemory:
+		return NewBroker(NewMemoryBackend(cfg.ReplaySize)), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownBackend, cfg.Backend)
+	}
+}
+
+func NewBroker(backend Backend) *Broker {
+	return &Broker{
+		backend:     backend,
+		logger:      log.NewLogger("stream").WithField("module", "broker"),
+		subscribers: map[int]map[*subscriber]struct{}{},
+	}
+}
+
+// Notify publishes the event to every instance
+func (b *Broker) Notify(ctx context.Context, event wallet.Event) error {
+	if _, err := b.backend.Publish(ctx, event); err != nil {
+		return fmt.Errorf("failed to publish event for user %d: %w", event.UserID, err)
+	}
+
+	return nil
+}
+
+// Run forwards published messages to local subscribers until ctx is cancelled
+func (b *Broker) Run(ctx context.Context) {
+	if err := b.backend.Listen(ctx, b.dispatch); err != nil {
+		b.logger.WithField("err", err).Error("stopped listening for wallet events")
+	}
+}
+
+// Subscribe registers a stream for the user. The channel is closed when the
+// subscriber falls behind; the client is expected to reconnect with Last-Event-ID.
+func (b *Broker) Subscribe(userID int) (<-chan Message, func()) {
+	sub := &subscriber{ch: make(chan Message, subscriberBuffer)}
+
+	b.mu.Lock()
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = map[*subscriber]struct{}{}
+	}
+	b.subscribers[userID][sub] = struct{}{}
+	b.mu.Unlock()
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		b.remove(userID, sub)
+	}
+
+	return sub.ch, cancel
+}
+
+// CloseSubscribers ends every stream, on shutdown. Clients reconnect with
+// Last-Event-ID to another instance and miss nothing.
+func (b *Broker) CloseSubscribers() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for userID, subs := range b.subscribers {
+		for sub := range subs {
+			b.remove(userID, sub)
+		}
+	}
+}
+
+// Replay returns the buffered messages of the user after the given id, check
+// Replay.Complete before resuming from them
+func (b *Broker) Replay(ctx context.Context, userID int, afterID int64) (Replay, error) {
+	replay, err := b.backend.Replay(ctx, userID, afterID)
+	if err != nil {
+		return Replay{}, fmt.Errorf("failed to replay events for user %d: %w", userID, err)
+	}
+
+	return replay, nil
+}
+
+func (b *Broker) dispatch(msg Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers[msg.Event.UserID] {
+		select {
+		case sub.ch <- msg:
+		default:
+			b.logger.WithField("user_id", msg.Event.UserID).Warn("dropping slow stream subscriber")
+			b.remove(msg.Event.UserID, sub)
+		}
+	}
+}
+
+// remove must be called with mu held
+func (b *Broker) remove(userID int, sub *subscriber) {
+	subs, ok := b.subscribers[userID]
+	if !ok {
+		return
+	}
+
+	if _, ok = subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	close(sub.ch)
+
+	if len(subs) == 0 {
+		delete(b.subscribers, userID)
+	}
+}
@@ -0,0 +1,208 @@
+package stream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/wallet"
+)
+
+func listening(backend Backend) func() bool {
+	return func() bool {
+		mem, _ := backend.(*memoryBackend)
+
+		mem.mu.Lock()
+		defer mem.mu.Unlock()
+
+		return len(mem.listeners) > 0
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met in time")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBroker_FansOutToSubscribersOfUser(t *testing.T) {
+	// Arrange
+	backend := NewMemoryBackend(10)
+	broker := NewBroker(backend)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go broker.Run(ctx)
+	waitFor(t, listening(backend))
+
+	first, cancelFirst := broker.Subscribe(1)
+	defer cancelFirst()
+
+	second, cancelSecond := broker.Subscribe(1)
+	defer cancelSecond()
+
+	other, cancelOther := broker.Subscribe(2)
+	defer cancelOther()
+
+	// Act
+	if err := broker.Notify(ctx, wallet.Event{Type: wallet.EventDeposit, UserID: 1, Balance: 10}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Assert
+	for _, ch := range []<-chan Message{first, second} {
+		select {
+		case msg := <-ch:
+			if msg.ID != 1 || msg.Event.Balance != 10 {
+				t.Fatalf("unexpected message: %+v", msg)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected message for subscriber of user 1")
+		}
+	}
+
+	select {
+	case msg := <-other:
+		t.Fatalf("expected no message for user 2, got %+v", msg)
+	default:
+	}
+}
+
+func TestBroker_ReplayAfterLastEventID(t *testing.T) {
+	// Arrange
+	broker := NewBroker(NewMemoryBackend(2))
+	ctx := context.Background()
+
+	for i := range 3 {
+		_ = broker.Notify(ctx, wallet.Event{Type: wallet.EventDeposit, UserID: 1, Balance: float64(i)})
+	}
+
+	// Act
+	replay, err := broker.Replay(ctx, 1, 1)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if msgs := replay.Messages; len(msgs) != 2 || msgs[0].ID != 2 || msgs[1].ID != 3 {
+		t.Fatalf("expected messages 2 and 3, got %+v", msgs)
+	}
+
+	if !replay.Complete(1) || replay.LastID != 3 {
+		t.Fatalf("expected a complete replay up to 3, got %+v", replay)
+	}
+
+	// buffer holds the 2 newest only
+	replay, _ = broker.Replay(ctx, 1, 0)
+	if len(replay.Messages) != 2 {
+		t.Fatalf("expected replay buffer of 2, got %d", len(replay.Messages))
+	}
+}
+
+func TestReplay_Complete(t *testing.T) {
+	messages := func(ids ...int64) []Message {
+		msgs := make([]Message, 0, len(ids))
+		for _, id := range ids {
+			msgs = append(msgs, Message{ID: id})
+		}
+
+		return msgs
+	}
+
+	tests := []struct {
+		name     string
+		replay   Replay
+		afterID  int64
+		expected bool
+	}{
+		{"up to date", Replay{LastID: 5}, 5, true},
+		{"buffered after the resume point", Replay{Messages: messages(4, 5), LastID: 5}, 3, true},
+		{"trimmed after the resume point", Replay{Messages: messages(4, 5), LastID: 5}, 2, false},
+		{"buffer expired", Replay{LastID: 5}, 2, false},
+		{"ids reset", Replay{Messages: messages(1), LastID: 1}, 7, false},
+		{"ids reset before any event", Replay{}, 7, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.replay.Complete(tt.afterID); got != tt.expected {
+				t.Fatalf("expected complete %v after %d, got %v", tt.expected, tt.afterID, got)
+			}
+		})
+	}
+}
+
+func TestBroker_DropsSlowSubscriber(t *testing.T) {
+	// Arrange
+	backend := NewMemoryBackend(100)
+	broker := NewBroker(backend)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go broker.Run(ctx)
+	waitFor(t, listening(backend))
+
+	msgs, unsubscribe := broker.Subscribe(1)
+	defer unsubscribe()
+
+	// Act
+	for range subscriberBuffer + 1 {
+		_ = broker.Notify(ctx, wallet.Event{Type: wallet.EventDeposit, UserID: 1})
+	}
+
+	// Assert
+	received := 0
+	for range msgs {
+		received++
+	}
+
+	if received != subscriberBuffer {
+		t.Fatalf("expected %d buffered messages before close, got %d", subscriberBuffer, received)
+	}
+}
+
+func TestDecodeMessage(t *testing.T) {
+	msg, err := decodeMessage(`7 {"type":"deposit","user_id":3,"amount":5,"balance":15,"occurred_at":"2025-03-01T12:00:00Z"}`)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if msg.ID != 7 || msg.Event.UserID != 3 || msg.Event.Balance != 15 {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+
+	if _, err = decodeMessage("garbage"); err == nil {
+		t.Fatalf("expected error for malformed message")
+	}
+}
+
+func TestBroker_CloseSubscribers(t *testing.T) {
+	// Arrange
+	broker := NewBroker(NewMemoryBackend(10))
+
+	first, cancelFirst := broker.Subscribe(1)
+	defer cancelFirst()
+
+	second, cancelSecond := broker.Subscribe(2)
+	defer cancelSecond()
+
+	// Act
+	broker.CloseSubscribers()
+
+	// Assert
+	for _, ch := range []<-chan Message{first, second} {
+		if _, ok := <-ch; ok {
+			t.Fatalf("expected the stream to be closed")
+		}
+	}
+}
@@ -0,0 +1,5 @@
+package stream
+
+import "errors"
+
+var ErrUnknownBackend = errors.New("unknown stream backend")
@@ -0,0 +1,75 @@
+package stream
+
+import (
+	"context"
+	"sync"
+
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// Message is a wallet event with its per-user sequence number, used as SSE event id
+type Message struct {
+	ID    int64        `json:"id"`
+	Event wallet.Event `json:"event"`
+}
+
+// Replay is what the buffer still holds of a user's stream after a resume point
+type Replay struct {
+	// Messages have an id greater than the resume point, oldest first
+	Messages []Message
+	// LastID is the last id assigned to the user, 0 if none
+	LastID int64
+}
+
+// Complete tells whether Messages are every message after afterID. It is not when
+// older messages were trimmed from the buffer or expired, or when the ids were
+// reset since afterID was assigned, e.g. because Redis lost its data.
+func (r Replay) Complete(afterID int64) bool {
+	switch {
+	case afterID > r.LastID:
+		return false
+	case afterID == r.LastID:
+		return true
+	default:
+		return len(r.Messages) > 0 && r.Messages[0].ID == afterID+1
+	}
+}
+
+// Backend stores the replay buffer and fans messages out to every instance
+type Backend interface {
+	// Publish assigns the next id of the user and broadcasts the message
+	Publish(ctx context.Context, event wallet.Event) (Message, error)
+	// Replay returns buffered messages of the user with an id greater than afterID
+	Replay(ctx context.Context, userID int, afterID int64) (Replay, error)
+	// Listen calls handle for every published message until ctx is cancelled
+	Listen(ctx context.Context, handle func(Message)) error
+}
+
+// Broker delivers wallet events to the streams connected to this instance.
+// It implements wallet.Notifier.
+type Broker struct {
+	backend     Backend
+	logger      *logrus.Entry
+	mu          sync.RWMutex
+	subscribers map[int]map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	ch chan Message
+}
+
+type redisBackend struct {
+	client *redis.Client
+	size   int64
+}
+
+type memoryBackend struct {
+	mu           sync.Mutex
+	size         int
+	seq          map[int]int64
+	buffer       map[int][]Message
+	listeners    map[int]func(Message)
+	nextListener int
+}
@@ -0,0 +1,266 @@
+package wallet
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/sirupsen/logrus"
+)
+
+// NewAdmin works on the Postgres wallets behind db. Balances changed by an adjustment
+// are invalidated in cache, which should be the cache the service instances share,
+// and published to the notifiers like the service's balance changes.
+func NewAdmin(db *sql.DB, cache BalanceCache, notifiers ...Notifier) *Admin {
+	return &Admin{
+		repo: &walletRepository{
+			db:     db,
+			logger: log.NewLogger("wallet").WithField("module", "admin"),
+		},
+		cache:     cache,
+		notifiers: notifiers,
+		logger:    log.NewLogger("wallet").WithField("module", "admin"),
+	}
+}
+
+// Wallet reads a wallet from the database, bypassing the cache
+func (a *Admin) Wallet(ctx context.Context, userID int) (Wallet, error) {
+	w := Wallet{UserID: userID}
+
+	query := `SELECT balance, version, frozen FROM wallets WHERE user_id = $1`
+	err := a.repo.db.QueryRowContext(ctx, query, userID).Scan(&w.Balance, &w.Version, &w.Frozen)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrWalletNotFound
+	}
+
+	if err != nil {
+		return Wallet{}, fmt.Errorf("failed to query database for user %d: %w", userID, err)
+	}
+
+	return w, nil
+}
+
+// Wallets pages through all wallets ordered by user id, starting after afterUserID
+func (a *Admin) Wallets(ctx context.Context, afterUserID, limit int) ([]Wallet, error) {
+	query := `SELECT user_id, balance, version, frozen FROM wallets
+              WHERE user_id > $1 ORDER BY user_id LIMIT $2`
+
+	rows, err := a.repo.db.QueryContext(ctx, query, afterUserID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wallets after user %d: %w", afterUserID, err)
+	}
+	defer rows.Close()
+
+	wallets := make([]Wallet, 0, limit)
+
+	for rows.Next() {
+		var w Wallet
+		if err = rows.Scan(&w.UserID, &w.Balance, &w.Version, &w.Frozen); err != nil {
+			return nil, fmt.Errorf("failed to scan wallet: %w", err)
+		}
+
+		wallets = append(wallets, w)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error arises during wallets iteration: %w", err)
+	}
+
+	return wallets, nil
+}
+
+// History returns the transactions of a wallet, newest first
+func (a *Admin) History(ctx context.Context, userID int) ([]Transaction, error) {
+	return a.repo.GetTransactionHistory(ctx, userID)
+}
+
+// Adjust corrects a balance by a signed amount. The adjustment is logged as a transaction
+// and recorded with actor and reason; it also applies to frozen wallets, but never
+// takes a balance below zero.
+func (a *Admin) Adjust(ctx context.Context, adj Adjustment) (Adjustment, error) {
+	adj.Reason = strings.TrimSpace(adj.Reason)
+	adj.Actor = strings.TrimSpace(adj.Actor)
+
+	if adj.Amount == 0 || adj.Reason == "" || adj.Actor == "" {
+		return Adjustment{}, fmt.Errorf("%w: amount, reason and actor are required", ErrInvalidAdjustment)
+	}
+
+	tx, err := a.repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Adjustment{}, fmt.Errorf("failed to begin transaction for user %d: %w", adj.UserID, err)
+	}
+
+	adj, version, err := a.adjust(ctx, tx, adj)
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			a.logger.WithField("err", rollbackErr).Error("failed to roll back")
+		}
+
+		return Adjustment{}, fmt.Errorf("failed to adjust balance of user %d: %w", adj.UserID, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return Adjustment{}, fmt.Errorf("failed to commit adjustment for user %d: %w", adj.UserID, err)
+	}
+
+	if err = a.cache.Invalidate(ctx, adj.UserID, version); err != nil {
+		a.logger.WithField("err", err).WithField("user_id", adj.UserID).Warn("failed to invalidate cached balance")
+	}
+
+	a.logger.WithFields(logrus.Fields{
+		"adjustment_id": adj.AdjustmentID,
+		"user_id":       adj.UserID,
+		"amount":        adj.Amount,
+		"reason":        adj.Reason,
+		"actor":         adj.Actor,
+		"balance":       adj.Balance,
+	}).Info("balance adjusted")
+
+	notify(ctx, a.notifiers, a.logger, Event{Type: EventAdjustment, UserID: adj.UserID, Amount: adj.Amount, Balance: adj.Balance})
+
+	return adj, nil
+}
+
+func (a *Admin) adjust(ctx context.Context, tx *sql.Tx, adj Adjustment) (Adjustment, int64, error) {
+	var version int64
+
+	query := `UPDATE wallets SET balance = balance + $1, version = version + 1
+              WHERE user_id = $2 AND balance + $1 >= 0 RETURNING balance, version`
+	err := tx.QueryRowContext(ctx, query, adj.Amount, adj.UserID).Scan(&adj.Balance, &version)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		if err = a.repo.noRowsError(ctx, tx, adj.UserID); errors.Is(err, ErrWalletFrozen) {
+			// adjustments ignore the freeze, so the balance was too low
+			err = ErrInsufficientFunds
+		}
+	}
+
+	if err != nil {
+		return adj, 0, err
+	}
+
+	query = `INSERT INTO transactions (from_user_id, to_user_id, amount, transaction_type)
+             VALUES ($1, NULL, $2, $3) RETURNING transaction_id`
+
+	err = tx.QueryRowContext(ctx, query, adj.UserID, adj.Amount, TransactionAdjustment).Scan(&adj.TransactionID)
+	if err != nil {
+		return adj, 0, fmt.Errorf("failed to log transaction: %w", err)
+	}
+
+	query = `INSERT INTO wallet_adjustments (user_id, transaction_id, amount, reason, actor)
+             VALUES ($1, $2, $3, $4, $5) RETURNING adjustment_id, created_at`
+
+	err = tx.QueryRowContext(ctx, query, adj.UserID, adj.TransactionID, adj.Amount, adj.Reason, adj.Actor).
+		Scan(&adj.AdjustmentID, &adj.CreatedAt)
+	if err != nil {
+		return adj, 0, fmt.Errorf("failed to record adjustment: %w", err)
+	}
+
+	return adj, version, nil
+}
+
+// SetFrozen freezes or unfreezes a wallet and records it with actor and reason in
+// wallet_freezes. A frozen wallet rejects deposits, withdrawals and transfers in
+// either direction.
+func (a *Admin) SetFrozen(ctx context.Context, freeze Freeze) (Freeze, error) {
+	freeze.Reason = strings.TrimSpace(freeze.Reason)
+	freeze.Actor = strings.TrimSpace(freeze.Actor)
+
+	if freeze.Actor == "" {
+		return Freeze{}, fmt.Errorf("%w: actor is required", ErrInvalidFreeze)
+	}
+
+	tx, err := a.repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Freeze{}, fmt.Errorf("failed to begin transaction for user %d: %w", freeze.UserID, err)
+	}
+
+	freeze, err = a.setFrozen(ctx, tx, freeze)
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			a.logger.WithField("err", rollbackErr).Error("failed to roll back")
+		}
+
+		return Freeze{}, fmt.Errorf("failed to update wallet of user %d: %w", freeze.UserID, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return Freeze{}, fmt.Errorf("failed to commit freeze for user %d: %w", freeze.UserID, err)
+	}
+
+	a.logger.WithFields(logrus.Fields{
+		"freeze_id": freeze.FreezeID,
+		"user_id":   freeze.UserID,
+		"frozen":    freeze.Frozen,
+		"reason":    freeze.Reason,
+		"actor":     freeze.Actor,
+	}).Info("wallet freeze changed")
+
+	return freeze, nil
+}
+
+func (a *Admin) setFrozen(ctx context.Context, tx *sql.Tx, freeze Freeze) (Freeze, error) {
+	result, err := tx.ExecContext(ctx, `UPDATE wallets SET frozen = $1 WHERE user_id = $2`, freeze.Frozen, freeze.UserID)
+	if err != nil {
+		return freeze, err
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return freeze, ErrWalletNotFound
+	}
+
+	query := `INSERT INTO wallet_freezes (user_id, frozen, reason, actor)
+              VALUES ($1, $2, $3, $4) RETURNING freeze_id, created_at`
+
+	err = tx.QueryRowContext(ctx, query, freeze.UserID, freeze.Frozen, freeze.Reason, freeze.Actor).
+		Scan(&freeze.FreezeID, &freeze.CreatedAt)
+	if err != nil {
+		return freeze, fmt.Errorf("failed to record freeze: %w", err)
+	}
+
+	return freeze, nil
+}
+
+// CheckLedger compares every balance with the sum of the wallet's movements:
+// deposits, incoming transfers and adjustments add, withdrawals and outgoing
+// transfers subtract. Wallets whose balance was set outside the API show up too.
+//
+//nolint:prealloc // the number of discrepancies is unknown before the scan
+func (a *Admin) CheckLedger(ctx context.Context) ([]LedgerDiscrepancy, error) {
+	query := `
+    SELECT w.user_id, w.balance, COALESCE(l.total, 0)
+    FROM wallets w
+    LEFT JOIN (
+        SELECT user_id, SUM(amount) AS total FROM wallet_movements GROUP BY user_id
+    ) l ON l.user_id = w.user_id
+    WHERE w.balance <> COALESCE(l.total, 0)
+    ORDER BY w.user_id
+    `
+
+	rows, err := a.repo.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ledger: %w", err)
+	}
+	defer rows.Close()
+
+	var discrepancies []LedgerDiscrepancy
+
+	for rows.Next() {
+		var d LedgerDiscrepancy
+		if err = rows.Scan(&d.UserID, &d.Balance, &d.LedgerBalance); err != nil {
+			return nil, fmt.Errorf("failed to scan ledger: %w", err)
+		}
+
+		discrepancies = append(discrepancies, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error arises during ledger iteration: %w", err)
+	}
+
+	return discrepancies, nil
+}
@@ -0,0 +1,219 @@
+package wallet
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func setupAdmin(t *testing.T, notifiers ...Notifier) (*Admin, sqlmock.Sqlmock, BalanceCache) {
+	t.Helper()
+
+	db, mockSQL, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	cache := NewMemoryCache(testCacheConfig)
+
+	return NewAdmin(db, cache, notifiers...), mockSQL, cache
+}
+
+func TestAdmin_Adjust(t *testing.T) {
+	// Arrange
+	notifier := &recordingNotifier{}
+	admin, mockSQL, cache := setupAdmin(t, notifier)
+	ctx := context.Background()
+
+	userID := 1
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	if err := cache.Set(ctx, userID, Balance{Amount: 100, Version: 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	mockSQL.ExpectBegin()
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1, version = version \+ 1\s+WHERE user_id = \$2 AND balance \+ \$1 >= 0 RETURNING balance, version`).
+		WithArgs(-25.5, userID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(74.5, 5))
+	mockSQL.ExpectQuery(`INSERT INTO transactions \(from_user_id, to_user_id, amount, transaction_type\)\s+VALUES \(\$1, NULL, \$2, \$3\) RETURNING transaction_id`).
+		WithArgs(userID, -25.5, TransactionAdjustment).
+		WillReturnRows(sqlmock.NewRows([]string{"transaction_id"}).AddRow(42))
+	mockSQL.ExpectQuery(`INSERT INTO wallet_adjustments \(user_id, transaction_id, amount, reason, actor\)`).
+		WithArgs(userID, 42, -25.5, "duplicate deposit", "alice").
+		WillReturnRows(sqlmock.NewRows([]string{"adjustment_id", "created_at"}).AddRow(7, createdAt))
+	mockSQL.ExpectCommit()
+
+	// Act
+	adj, err := admin.Adjust(ctx, Adjustment{UserID: userID, Amount: -25.5, Reason: " duplicate deposit ", Actor: "alice"})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := Adjustment{
+		AdjustmentID:  7,
+		UserID:        userID,
+		TransactionID: 42,
+		Amount:        -25.5,
+		Reason:        "duplicate deposit",
+		Actor:         "alice",
+		Balance:       74.5,
+		CreatedAt:     createdAt,
+	}
+	if adj != want {
+		t.Fatalf("expected %+v, got %+v", want, adj)
+	}
+
+	if _, ok, _ := cache.Get(ctx, userID); ok {
+		t.Fatal("expected the cached balance to be invalidated")
+	}
+
+	if len(notifier.events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(notifier.events))
+	}
+
+	if e := notifier.events[0]; e.Type != EventAdjustment || e.UserID != userID || e.Amount != -25.5 || e.Balance != 74.5 {
+		t.Fatalf("unexpected adjustment event: %+v", e)
+	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestAdmin_Adjust_FrozenWalletWithLowBalance(t *testing.T) {
+	// Arrange
+	admin, mockSQL, _ := setupAdmin(t)
+
+	userID := 1
+
+	mockSQL.ExpectBegin()
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1`).
+		WithArgs(-500.0, userID).
+		WillReturnError(sql.ErrNoRows)
+	mockSQL.ExpectQuery(`SELECT balance, frozen FROM wallets WHERE user_id = \$1`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "frozen"}).AddRow(100.00, true))
+	mockSQL.ExpectRollback()
+
+	// Act
+	_, err := admin.Adjust(context.Background(), Adjustment{UserID: userID, Amount: -500, Reason: "chargeback", Actor: "alice"})
+
+	// Assert
+	if !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("expected error %v, got %v", ErrInsufficientFunds, err)
+	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestAdmin_Adjust_RequiresReasonAndActor(t *testing.T) {
+	// Arrange
+	admin, mockSQL, _ := setupAdmin(t)
+
+	// Act
+	_, err := admin.Adjust(context.Background(), Adjustment{UserID: 1, Amount: 10, Reason: "  ", Actor: "alice"})
+
+	// Assert
+	if !errors.Is(err, ErrInvalidAdjustment) {
+		t.Fatalf("expected error %v, got %v", ErrInvalidAdjustment, err)
+	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestAdmin_SetFrozen_WalletNotFound(t *testing.T) {
+	// Arrange
+	admin, mockSQL, _ := setupAdmin(t)
+
+	mockSQL.ExpectBegin()
+	mockSQL.ExpectExec(`UPDATE wallets SET frozen = \$1 WHERE user_id = \$2`).
+		WithArgs(true, 9).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mockSQL.ExpectRollback()
+
+	// Act
+	_, err := admin.SetFrozen(context.Background(), Freeze{UserID: 9, Frozen: true, Actor: "alice"})
+
+	// Assert
+	if !errors.Is(err, ErrWalletNotFound) {
+		t.Fatalf("expected error %v, got %v", ErrWalletNotFound, err)
+	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestAdmin_SetFrozen_RecordsAuditTrail(t *testing.T) {
+	// Arrange
+	admin, mockSQL, _ := setupAdmin(t)
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	mockSQL.ExpectBegin()
+	mockSQL.ExpectExec(`UPDATE wallets SET frozen = \$1 WHERE user_id = \$2`).
+		WithArgs(false, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mockSQL.ExpectQuery(`INSERT INTO wallet_freezes \(user_id, frozen, reason, actor\)`).
+		WithArgs(1, false, "identity verified", "alice").
+		WillReturnRows(sqlmock.NewRows([]string{"freeze_id", "created_at"}).AddRow(3, createdAt))
+	mockSQL.ExpectCommit()
+
+	// Act
+	freeze, err := admin.SetFrozen(context.Background(), Freeze{UserID: 1, Reason: " identity verified ", Actor: "alice"})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := Freeze{FreezeID: 3, UserID: 1, Reason: "identity verified", Actor: "alice", CreatedAt: createdAt}
+	if freeze != want {
+		t.Fatalf("expected %+v, got %+v", want, freeze)
+	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestAdmin_SetFrozen_RequiresActor(t *testing.T) {
+	admin, _, _ := setupAdmin(t)
+
+	_, err := admin.SetFrozen(context.Background(), Freeze{UserID: 1, Frozen: true, Actor: " "})
+	if !errors.Is(err, ErrInvalidFreeze) {
+		t.Fatalf("expected error %v, got %v", ErrInvalidFreeze, err)
+	}
+}
+
+func TestAdmin_CheckLedger(t *testing.T) {
+	// Arrange
+	admin, mockSQL, _ := setupAdmin(t)
+
+	mockSQL.ExpectQuery(`SELECT w.user_id, w.balance, COALESCE\(l.total, 0\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "balance", "total"}).AddRow(2, 50.0, 40.0))
+
+	// Act
+	discrepancies, err := admin.CheckLedger(context.Background())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(discrepancies) != 1 || discrepancies[0] != (LedgerDiscrepancy{UserID: 2, Balance: 50, LedgerBalance: 40}) {
+		t.Fatalf("unexpected discrepancies %+v", discrepancies)
+	}
+}
@@ -0,0 +1,122 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/database"
+	"github.com/amelonpie/wallet-service/pkg/breaker"
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+)
+
+// Cache backends selectable by cache.backend
+const (
+	CacheBackendRedis  = "redis"
+	CacheBackendMemory = "memory"
+	CacheBackendNone   = "none"
+)
+
+// CacheConfig controls where and how long balances are cached
+type CacheConfig struct {
+	// Backend is one of CacheBackendRedis, CacheBackendMemory or CacheBackendNone
+	Backend string
+	// MemorySize is the number of wallets the in-memory cache holds
+	MemorySize int
+	// BalanceTTL bounds how long a cached balance may be served
+	BalanceTTL time.Duration
+	// InvalidationTTL is how long the tombstone of a write is kept. It must exceed
+	// the time between a database read and the repopulation that follows it.
+	InvalidationTTL time.Duration
+	// Breaker decides when Redis is skipped and balances come from Postgres only
+	Breaker breaker.Config
+}
+
+func NewCacheConfig() CacheConfig {
+	viper.SetDefault("cache.backend", CacheBackendRedis)
+	viper.SetDefault("cache.memory_size", 10000)
+	viper.SetDefault("cache.balance_ttl", "10m")
+	viper.SetDefault("cache.invalidation_ttl", "1m")
+	viper.SetDefault("cache.breaker.failure_threshold", 5)
+	viper.SetDefault("cache.breaker.open_timeout", "30s")
+
+	return CacheConfig{
+		Backend:         viper.GetString("cache.backend"),
+		MemorySize:      viper.GetInt("cache.memory_size"),
+		BalanceTTL:      viper.GetDuration("cache.balance_ttl"),
+		InvalidationTTL: viper.GetDuration("cache.invalidation_ttl"),
+		Breaker: breaker.Config{
+			FailureThreshold: viper.GetInt("cache.breaker.failure_threshold"),
+			OpenTimeout:      viper.GetDuration("cache.breaker.open_timeout"),
+		},
+	}
+}
+
+// InitCache builds the configured cache backend, connecting to Redis for it
+//
+//nolint:ireturn // stick to interface
+func InitCache(cfg CacheConfig) (BalanceCache, error) {
+	var client *redis.Client
+	if cfg.Backend == CacheBackendRedis {
+		dbConfig, err := database.NewDatabaseConfig()
+		if err != nil {
+			return nil, err //nolint:wrapcheck // names the secret that failed
+		}
+
+		client = dbConfig.NewRedisClient()
+	}
+
+	return NewCache(cfg, client)
+}
+
+// NewCache builds the configured cache backend, client is only used for Redis.
+// Redis is guarded by a circuit breaker, an unreachable Redis does not prevent
+// the start.
+//
+//nolint:ireturn // stick to interface
+func NewCache(cfg CacheConfig, client *redis.Client) (BalanceCache, error) {
+	switch cfg.Backend {
+	case CacheBackendRedis:
+		guarded := NewGuardedCache(NewRedisCache(client, cfg), cfg.Breaker)
+		if err := client.Ping(context.Background()).Err(); err != nil {
+			log.NewLogger("wallet").WithField("module", "cache").WithField("err", err).
+				Warn("Redis unavailable, starting with balance cache disabled")
+			guarded.breaker.Trip()
+		}
+
+		return guarded, nil
+	case CacheBackendMemory:
+		return NewMemoryCache(cfg), nil
+	case CacheBackendNone:
+		return NewNopCache(), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCacheBackend, cfg.Backend)
+	}
+}
+
+type nopCache struct{}
+
+// NewNopCache caches nothing, every balance is read from the database
+//
+//nolint:ireturn // stick to interface
+func NewNopCache() BalanceCache {
+	return nopCache{}
+}
+
+func (nopCache) Get(_ context.Context, _ int) (float64, bool, error) {
+	return 0, false, nil
+}
+
+func (nopCache) Set(_ context.Context, _ int, _ Balance) error {
+	return nil
+}
+
+func (nopCache) Invalidate(_ context.Context, _ int, _ int64) error {
+	return nil
+}
+
+func (nopCache) Clear(_ context.Context) error {
+	return nil
+}
@@ -0,0 +1,196 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/amelonpie/wallet-service/pkg/breaker"
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/sirupsen/logrus"
+)
+
+// maxPendingInvalidations bounds the writes remembered while the cache is unavailable.
+// Beyond it all cached balances are dropped on recovery.
+const maxPendingInvalidations = 10000
+
+// pendingInvalidations are the writes whose invalidation did not reach the cache.
+// They are replayed before the cache serves anything again, otherwise a recovered
+// cache would hand out balances that were overwritten while it was away.
+type pendingInvalidations struct {
+	mu       sync.Mutex
+	versions map[int]int64
+	overflow bool
+}
+
+func (p *pendingInvalidations) add(userID int, version int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.overflow {
+		return
+	}
+
+	if len(p.versions) >= maxPendingInvalidations {
+		p.versions = make(map[int]int64)
+		p.overflow = true
+
+		return
+	}
+
+	if version > p.versions[userID] {
+		p.versions[userID] = version
+	}
+}
+
+func (p *pendingInvalidations) snapshot() (map[int]int64, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.versions) == 0 {
+		return nil, p.overflow
+	}
+
+	versions := make(map[int]int64, len(p.versions))
+	for userID, version := range p.versions {
+		versions[userID] = version
+	}
+
+	return versions, p.overflow
+}
+
+// done forgets a replayed invalidation unless a newer write was added meanwhile
+func (p *pendingInvalidations) done(userID int, version int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.versions[userID] == version {
+		delete(p.versions, userID)
+	}
+}
+
+func (p *pendingInvalidations) clearOverflow() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.overflow = false
+}
+
+// GuardedCache puts a circuit breaker in front of a cache that can fail. While the
+// circuit is open every call returns errCacheUnavailable without touching the cache.
+type GuardedCache struct {
+	inner   BalanceCache
+	breaker *breaker.Breaker
+	pending pendingInvalidations
+	logger  *logrus.Entry
+}
+
+func NewGuardedCache(inner BalanceCache, cfg breaker.Config) *GuardedCache {
+	return &GuardedCache{
+		inner:   inner,
+		breaker: breaker.New("cache", cfg),
+		pending: pendingInvalidations{versions: make(map[int]int64)},
+		logger:  log.NewLogger("wallet").WithField("module", "cache"),
+	}
+}
+
+// State of the circuit, anything but closed means balances bypass the cache
+func (g *GuardedCache) State() breaker.State {
+	return g.breaker.State()
+}
+
+// guard runs op unless the circuit is open, replaying pending invalidations first.
+// The outcome feeds the circuit breaker, unless ctx was cancelled or timed out by
+// the time the call failed: a client that went away says nothing about the cache,
+// and Redis reports an expired deadline as a network timeout.
+func (g *GuardedCache) guard(ctx context.Context, op func() error) error {
+	call, ok := g.breaker.Allow()
+	if !ok {
+		return errCacheUnavailable
+	}
+
+	err := g.flushPending(ctx)
+	if err == nil {
+		err = op()
+	}
+
+	if err != nil && ctx.Err() != nil {
+		g.breaker.Cancel(call)
+		return err
+	}
+
+	g.breaker.Record(call, err)
+
+	return err
+}
+
+// flushPending replays the invalidations missed while the cache was unavailable
+func (g *GuardedCache) flushPending(ctx context.Context) error {
+	versions, overflow := g.pending.snapshot()
+	if versions == nil && !overflow {
+		return nil
+	}
+
+	if overflow {
+		if err := g.inner.Clear(ctx); err != nil {
+			return fmt.Errorf("failed to drop cached balances: %w", err)
+		}
+
+		g.pending.clearOverflow()
+		g.logger.Warn("dropped all cached balances after missing too many invalidations")
+	}
+
+	for userID, version := range versions {
+		if err := g.inner.Invalidate(ctx, userID, version); err != nil {
+			return fmt.Errorf("failed to replay cache invalidation: %w", err)
+		}
+
+		g.pending.done(userID, version)
+	}
+
+	if len(versions) > 0 {
+		g.logger.WithField("count", len(versions)).Info("replayed missed cache invalidations")
+	}
+
+	return nil
+}
+
+func (g *GuardedCache) Get(ctx context.Context, userID int) (float64, bool, error) {
+	var (
+		balance float64
+		ok      bool
+	)
+
+	err := g.guard(ctx, func() error {
+		var err error
+		balance, ok, err = g.inner.Get(ctx, userID)
+
+		return err
+	})
+
+	return balance, ok, err
+}
+
+func (g *GuardedCache) Set(ctx context.Context, userID int, balance Balance) error {
+	return g.guard(ctx, func() error {
+		return g.inner.Set(ctx, userID, balance)
+	})
+}
+
+// Invalidate keeps a missed invalidation and replays it once the cache is reachable again
+func (g *GuardedCache) Invalidate(ctx context.Context, userID int, version int64) error {
+	err := g.guard(ctx, func() error {
+		return g.inner.Invalidate(ctx, userID, version)
+	})
+	if err != nil {
+		g.pending.add(userID, version)
+	}
+
+	return err
+}
+
+func (g *GuardedCache) Clear(ctx context.Context) error {
+	return g.guard(ctx, func() error {
+		return g.inner.Clear(ctx)
+	})
+}
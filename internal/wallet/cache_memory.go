@@ -0,0 +1,143 @@
+package wallet
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	userID      int
+	version     int64
+	amount      float64
+	invalidated bool
+	expiresAt   time.Time
+}
+
+// memoryCache is a size bounded LRU with the same version rules as the Redis cache.
+// Evicting a tombstone gives up its protection against stale repopulation, so the
+// size should comfortably exceed the wallets written within InvalidationTTL.
+type memoryCache struct {
+	mu      sync.Mutex
+	cfg     CacheConfig
+	entries map[int]*list.Element
+	// order holds *memoryEntry, most recently used first
+	order *list.List
+	now   func() time.Time
+}
+
+// NewMemoryCache caches balances in process, for a single instance or local development
+//
+//nolint:ireturn // stick to interface
+func NewMemoryCache(cfg CacheConfig) BalanceCache {
+	return newMemoryCache(cfg)
+}
+
+func newMemoryCache(cfg CacheConfig) *memoryCache {
+	if cfg.MemorySize < 1 {
+		cfg.MemorySize = 1
+	}
+
+	return &memoryCache{
+		cfg:     cfg,
+		entries: make(map[int]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// lookup returns the live entry of the user, dropping it once expired
+func (m *memoryCache) lookup(userID int) (*memoryEntry, bool) {
+	elem, ok := m.entries[userID]
+	if !ok {
+		return nil, false
+	}
+
+	entry, _ := elem.Value.(*memoryEntry)
+	if !m.now().Before(entry.expiresAt) {
+		m.order.Remove(elem)
+		delete(m.entries, userID)
+
+		return nil, false
+	}
+
+	m.order.MoveToFront(elem)
+
+	return entry, true
+}
+
+func (m *memoryCache) put(entry *memoryEntry, ttl time.Duration) {
+	entry.expiresAt = m.now().Add(ttl)
+
+	if elem, ok := m.entries[entry.userID]; ok {
+		elem.Value = entry
+		m.order.MoveToFront(elem)
+
+		return
+	}
+
+	m.entries[entry.userID] = m.order.PushFront(entry)
+
+	for m.order.Len() > m.cfg.MemorySize {
+		oldest := m.order.Back()
+		evicted, _ := oldest.Value.(*memoryEntry)
+
+		m.order.Remove(oldest)
+		delete(m.entries, evicted.userID)
+	}
+}
+
+func (m *memoryCache) Get(_ context.Context, userID int) (float64, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.lookup(userID)
+	if !ok || entry.invalidated {
+		return 0, false, nil
+	}
+
+	return entry.amount, true, nil
+}
+
+func (m *memoryCache) Set(_ context.Context, userID int, balance Balance) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if cur, ok := m.lookup(userID); ok {
+		if cur.invalidated && cur.version > balance.Version {
+			return nil
+		}
+
+		if !cur.invalidated && cur.version >= balance.Version {
+			return nil
+		}
+	}
+
+	m.put(&memoryEntry{userID: userID, version: balance.Version, amount: balance.Amount}, m.cfg.BalanceTTL)
+
+	return nil
+}
+
+func (m *memoryCache) Invalidate(_ context.Context, userID int, version int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if cur, ok := m.lookup(userID); ok && cur.version > version {
+		return nil
+	}
+
+	m.put(&memoryEntry{userID: userID, version: version, invalidated: true}, m.cfg.InvalidationTTL)
+
+	return nil
+}
+
+func (m *memoryCache) Clear(_ context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries = make(map[int]*list.Element)
+	m.order.Init()
+
+	return nil
+}
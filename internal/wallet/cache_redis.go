@@ -0,0 +1,131 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Cached balances are stored as "<version>:<balance>". A write replaces the entry by
+// the tombstone "<version>:-" instead of deleting it, so that a reader which loaded an
+// older version from the database before the write cannot repopulate a stale value.
+const (
+	invalidated    = "-"
+	cacheKeyPrefix = "wallet_balance:"
+)
+
+// repopulateScript stores the balance unless the cache already holds a newer one.
+// A tombstone accepts its own version, a live entry only a higher one.
+//
+//nolint:gochecknoglobals // scripts are immutable and cache their sha
+var repopulateScript = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if cur then
+  local sep = string.find(cur, ':', 1, true)
+  local version = tonumber(string.sub(cur, 1, sep - 1))
+  local value = string.sub(cur, sep + 1)
+  local incoming = tonumber(ARGV[1])
+  if value == '-' and version > incoming then return 0 end
+  if value ~= '-' and version >= incoming then return 0 end
+end
+redis.call('SET', KEYS[1], ARGV[1] .. ':' .. ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+
+// invalidateScript replaces the entry by a tombstone unless it is already newer
+//
+//nolint:gochecknoglobals // scripts are immutable and cache their sha
+var invalidateScript = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if cur then
+  local sep = string.find(cur, ':', 1, true)
+  if tonumber(string.sub(cur, 1, sep - 1)) > tonumber(ARGV[1]) then return 0 end
+end
+redis.call('SET', KEYS[1], ARGV[1] .. ':-', 'PX', ARGV[2])
+return 1
+`)
+
+type redisCache struct {
+	client *redis.Client
+	cfg    CacheConfig
+}
+
+// NewRedisCache shares cached balances between all instances
+//
+//nolint:ireturn // stick to interface
+func NewRedisCache(client *redis.Client, cfg CacheConfig) BalanceCache {
+	return &redisCache{client: client, cfg: cfg}
+}
+
+func cacheKey(userID int) string {
+	return fmt.Sprintf("%s%d", cacheKeyPrefix, userID)
+}
+
+func (r *redisCache) Get(ctx context.Context, userID int) (float64, bool, error) {
+	cached, err := r.client.Get(ctx, cacheKey(userID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, false, nil
+	}
+
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read cached balance for user %d: %w", userID, err)
+	}
+
+	_, value, found := strings.Cut(cached, ":")
+	if !found || value == invalidated {
+		return 0, false, nil
+	}
+
+	balance, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false, nil
+	}
+
+	return balance, true, nil
+}
+
+func (r *redisCache) Set(ctx context.Context, userID int, balance Balance) error {
+	err := repopulateScript.Run(ctx, r.client, []string{cacheKey(userID)},
+		balance.Version,
+		strconv.FormatFloat(balance.Amount, 'f', -1, 64),
+		r.cfg.BalanceTTL.Milliseconds(),
+	).Err()
+	if err != nil {
+		return fmt.Errorf("failed to cache balance for user %d: %w", userID, err)
+	}
+
+	return nil
+}
+
+func (r *redisCache) Invalidate(ctx context.Context, userID int, version int64) error {
+	err := invalidateScript.Run(ctx, r.client, []string{cacheKey(userID)},
+		version,
+		r.cfg.InvalidationTTL.Milliseconds(),
+	).Err()
+	if err != nil {
+		return fmt.Errorf("failed to invalidate cached balance for user %d: %w", userID, err)
+	}
+
+	return nil
+}
+
+// Clear deletes every cached balance. Unlike Invalidate it leaves no tombstones.
+func (r *redisCache) Clear(ctx context.Context) error {
+	iter := r.client.Scan(ctx, 0, cacheKeyPrefix+"*", 500).Iterator()
+
+	for iter.Next(ctx) {
+		if err := r.client.Del(ctx, iter.Val()).Err(); err != nil {
+			return fmt.Errorf("failed to drop cached balance %s: %w", iter.Val(), err)
+		}
+	}
+
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("failed to scan cached balances: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+)
+
+func TestMemoryCache_VersionOrdering(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	cache := NewMemoryCache(testCacheConfig)
+
+	// Act: a write of version 5 happened after a reader loaded version 4
+	_ = cache.Invalidate(ctx, 1, 5)
+	_ = cache.Set(ctx, 1, Balance{Amount: 40, Version: 4})
+
+	// Assert
+	if balance, ok, _ := cache.Get(ctx, 1); ok {
+		t.Fatalf("expected stale version to be rejected, got %v", balance)
+	}
+
+	_ = cache.Set(ctx, 1, Balance{Amount: 50, Version: 5})
+	_ = cache.Set(ctx, 1, Balance{Amount: 40, Version: 4})
+
+	if balance, ok, _ := cache.Get(ctx, 1); !ok || balance != 50 {
+		t.Fatalf("expected version 5 to be kept, got %v (%v)", balance, ok)
+	}
+
+	// an older invalidation does not drop a newer balance
+	_ = cache.Invalidate(ctx, 1, 3)
+
+	if _, ok, _ := cache.Get(ctx, 1); !ok {
+		t.Fatalf("expected version 5 to survive an older invalidation")
+	}
+}
+
+func TestMemoryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	cfg := testCacheConfig
+	cfg.MemorySize = 2
+	cache := NewMemoryCache(cfg)
+
+	_ = cache.Set(ctx, 1, Balance{Amount: 10, Version: 1})
+	_ = cache.Set(ctx, 2, Balance{Amount: 20, Version: 1})
+
+	// Act
+	_, _, _ = cache.Get(ctx, 1)
+	_ = cache.Set(ctx, 3, Balance{Amount: 30, Version: 1})
+
+	// Assert
+	if _, ok, _ := cache.Get(ctx, 2); ok {
+		t.Fatalf("expected user 2 to be evicted")
+	}
+
+	for _, userID := range []int{1, 3} {
+		if _, ok, _ := cache.Get(ctx, userID); !ok {
+			t.Fatalf("expected user %d to be cached", userID)
+		}
+	}
+}
+
+func TestMemoryCache_Expires(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	cache := newMemoryCache(testCacheConfig)
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	_ = cache.Set(ctx, 1, Balance{Amount: 10, Version: 1})
+
+	// Act
+	now = now.Add(testCacheConfig.BalanceTTL)
+
+	// Assert
+	if _, ok, _ := cache.Get(ctx, 1); ok {
+		t.Fatalf("expected balance to expire after the TTL")
+	}
+}
+
+func TestRedisCache(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	client, mockRedis := redismock.NewClientMock()
+	cache := NewRedisCache(client, testCacheConfig)
+
+	mockRedis.ExpectGet("wallet_balance:1").SetVal("7:150.5")
+	mockRedis.ExpectGet("wallet_balance:2").SetVal("8:-")
+	mockRedis.ExpectGet("wallet_balance:3").RedisNil()
+	mockRedis.ExpectGet("wallet_balance:4").SetErr(errors.New("connection refused"))
+	mockRedis.ExpectEvalSha(repopulateScript.Hash(), []string{"wallet_balance:1"},
+		int64(7), "150.5", testCacheConfig.BalanceTTL.Milliseconds()).SetVal(int64(1))
+	mockRedis.ExpectEvalSha(invalidateScript.Hash(), []string{"wallet_balance:1"},
+		int64(8), testCacheConfig.InvalidationTTL.Milliseconds()).SetVal(int64(1))
+
+	// Act & Assert
+	if balance, ok, err := cache.Get(ctx, 1); err != nil || !ok || balance != 150.5 {
+		t.Fatalf("expected cached 150.5, got %v (%v, %v)", balance, ok, err)
+	}
+
+	if _, ok, err := cache.Get(ctx, 2); err != nil || ok {
+		t.Fatalf("expected tombstone to be a miss, got %v, %v", ok, err)
+	}
+
+	if _, ok, err := cache.Get(ctx, 3); err != nil || ok {
+		t.Fatalf("expected missing key to be a miss, got %v, %v", ok, err)
+	}
+
+	if _, _, err := cache.Get(ctx, 4); err == nil {
+		t.Fatalf("expected error from failing Redis")
+	}
+
+	if err := cache.Set(ctx, 1, Balance{Amount: 150.5, Version: 7}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := cache.Invalidate(ctx, 1, 8); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := mockRedis.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet redis expectations: %v", err)
+	}
+}
+
+func TestInitCache_SelectsBackend(t *testing.T) {
+	cfg := testCacheConfig
+
+	cfg.Backend = CacheBackendMemory
+	if cache, err := InitCache(cfg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	} else if _, ok := cache.(*memoryCache); !ok {
+		t.Fatalf("expected memory cache, got %T", cache)
+	}
+
+	cfg.Backend = CacheBackendNone
+	if cache, err := InitCache(cfg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	} else if _, ok := cache.(nopCache); !ok {
+		t.Fatalf("expected no-op cache, got %T", cache)
+	}
+
+	cfg.Backend = "memcached"
+	if _, err := InitCache(cfg); !errors.Is(err, ErrUnknownCacheBackend) {
+		t.Fatalf("expected error %v, got %v", ErrUnknownCacheBackend, err)
+	}
+}
@@ -2,16 +2,21 @@ package wallet
 
 import "errors"
 
-type walletErrors struct {
-	InsufficientFunds error
-	RecipientNotFound error
-}
+var (
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrInvalidAmount     = errors.New("amount must be positive")
+	ErrRecipientNotFound = errors.New("recipient not found")
+	ErrWalletNotFound    = errors.New("wallet not found")
+	ErrWalletFrozen      = errors.New("wallet is frozen")
+	ErrInvalidAdjustment = errors.New("invalid adjustment")
+	ErrInvalidFreeze     = errors.New("invalid freeze")
+	ErrInvalidPeriod     = errors.New("invalid period")
+	ErrStatementNotFound = errors.New("statement not found")
+	ErrDuplicateDeposit  = errors.New("deposit already made")
+	ErrDuplicateWithdraw = errors.New("withdrawal already made")
 
-func errorFactory() walletErrors {
-	return walletErrors{
-		//nolint:err113 // false positive
-		InsufficientFunds: errors.New("insufficient funds"),
-		//nolint:err113 // false positive
-		RecipientNotFound: errors.New("recipient not found"),
-	}
-}
+	ErrUnknownCacheBackend = errors.New("unknown cache backend")
+
+	// errCacheUnavailable is returned while the circuit around the cache is open
+	errCacheUnavailable = errors.New("balance cache unavailable")
+)
@@ -0,0 +1,89 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/amelonpie/wallet-service/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Outcomes of balance operations counted by operationsTotal
+const (
+	outcomeSuccess           = "success"
+	outcomeInsufficientFunds = "insufficient_funds"
+	outcomeNotFound          = "not_found"
+	outcomeFrozen            = "frozen"
+	outcomeDuplicate         = "duplicate"
+	outcomeError             = "error"
+)
+
+// Results of balance cache reads counted by cacheRequestsTotal
+const (
+	cacheHit   = "hit"
+	cacheMiss  = "miss"
+	cacheError = "error"
+)
+
+//nolint:gochecknoglobals // registered once
+var (
+	operationsTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Name:      "operations_total",
+		Help:      "Deposits, withdrawals and transfers by outcome.",
+	}, []string{"operation", "outcome"})
+
+	operationAmountTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Name:      "operation_amount_total",
+		Help:      "Sum of the amounts of successful deposits, withdrawals and transfers.",
+	}, []string{"operation"})
+
+	cacheRequestsTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "balance_cache",
+		Name:      "requests_total",
+		Help:      "Balance cache reads of GetBalance by result: hit, miss or error.",
+	}, []string{"result"})
+
+	repositoryRetriesTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "repository",
+		Name:      "retries_total",
+		Help:      "Transactions run again after a deadlock or serialization failure.",
+	}, []string{"operation"})
+
+	repositoryRollbacksTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "repository",
+		Name:      "rollbacks_total",
+		Help:      "Balance update transactions rolled back.",
+	}, []string{"operation"})
+)
+
+// outcome classifies the error of a balance operation
+func outcome(err error) string {
+	switch {
+	case err == nil:
+		return outcomeSuccess
+	case errors.Is(err, ErrInsufficientFunds):
+		return outcomeInsufficientFunds
+	case errors.Is(err, ErrWalletNotFound), errors.Is(err, ErrRecipientNotFound):
+		return outcomeNotFound
+	case errors.Is(err, ErrWalletFrozen):
+		return outcomeFrozen
+	case errors.Is(err, ErrDuplicateDeposit), errors.Is(err, ErrDuplicateWithdraw):
+		return outcomeDuplicate
+	default:
+		return outcomeError
+	}
+}
+
+// observe counts a balance operation and the amount it moved
+func observe(operation string, amount float64, err error) {
+	operationsTotal.WithLabelValues(operation, outcome(err)).Inc()
+
+	if err == nil {
+		operationAmountTotal.WithLabelValues(operation).Add(amount)
+	}
+}
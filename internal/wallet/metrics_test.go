@@ -0,0 +1,163 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestOutcome(t *testing.T) {
+	tests := map[string]error{
+		outcomeSuccess:           nil,
+		outcomeInsufficientFunds: fmt.Errorf("failed to update database for user 1: %w", ErrInsufficientFunds),
+		outcomeNotFound:          ErrRecipientNotFound,
+		outcomeFrozen:            ErrWalletFrozen,
+		outcomeDuplicate:         fmt.Errorf("idempotency key %q: %w", "k", ErrDuplicateWithdraw),
+		outcomeError:             errCacheDown,
+	}
+
+	for want, err := range tests {
+		if got := outcome(err); got != want {
+			t.Errorf("outcome(%v) = %q, want %q", err, got, want)
+		}
+	}
+}
+
+func TestWalletService_CountsOperations(t *testing.T) {
+	// Arrange
+	service, mockSQL, _ := setupMockRepo(t)
+	succeeded := testutil.ToFloat64(operationsTotal.WithLabelValues(EventWithdraw, outcomeSuccess))
+	refused := testutil.ToFloat64(operationsTotal.WithLabelValues(EventWithdraw, outcomeInsufficientFunds))
+	amount := testutil.ToFloat64(operationAmountTotal.WithLabelValues(EventWithdraw))
+
+	withdraw := `UPDATE wallets SET balance = balance - \$1`
+
+	mockSQL.ExpectBegin()
+	mockSQL.ExpectQuery(withdraw).WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(70.0, 2))
+	mockSQL.ExpectExec(`INSERT INTO transactions`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mockSQL.ExpectCommit()
+	mockSQL.ExpectBegin()
+	mockSQL.ExpectQuery(withdraw).WillReturnRows(sqlmock.NewRows(nil))
+	mockSQL.ExpectQuery(`SELECT balance, frozen FROM wallets`).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "frozen"}).AddRow(70.0, false))
+	mockSQL.ExpectRollback()
+
+	// Act
+	_, okErr := service.Withdraw(context.Background(), 1, 30)
+	_, refusedErr := service.Withdraw(context.Background(), 1, 500)
+
+	// Assert
+	if okErr != nil || refusedErr == nil {
+		t.Fatalf("expected one withdrawal to succeed and one to fail, got %v and %v", okErr, refusedErr)
+	}
+
+	if got := testutil.ToFloat64(operationsTotal.WithLabelValues(EventWithdraw, outcomeSuccess)) - succeeded; got != 1 {
+		t.Errorf("expected 1 successful withdrawal counted, got %v", got)
+	}
+
+	if got := testutil.ToFloat64(operationsTotal.WithLabelValues(EventWithdraw, outcomeInsufficientFunds)) - refused; got != 1 {
+		t.Errorf("expected 1 refused withdrawal counted, got %v", got)
+	}
+
+	if got := testutil.ToFloat64(operationAmountTotal.WithLabelValues(EventWithdraw)) - amount; got != 30 {
+		t.Errorf("expected 30 withdrawn counted, got %v", got)
+	}
+}
+
+func TestWalletService_CountsCacheReads(t *testing.T) {
+	// Arrange
+	service, mockSQL, _ := setupMockRepo(t)
+	hits := testutil.ToFloat64(cacheRequestsTotal.WithLabelValues(cacheHit))
+	misses := testutil.ToFloat64(cacheRequestsTotal.WithLabelValues(cacheMiss))
+
+	mockSQL.ExpectQuery(`SELECT balance, version FROM wallets WHERE user_id=\$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(150.0, 3))
+
+	// Act: the first read misses and fills the cache for the second
+	for range 2 {
+		if _, err := service.GetBalance(context.Background(), 1); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	// Assert
+	if got := testutil.ToFloat64(cacheRequestsTotal.WithLabelValues(cacheHit)) - hits; got != 1 {
+		t.Errorf("expected 1 cache hit, got %v", got)
+	}
+
+	if got := testutil.ToFloat64(cacheRequestsTotal.WithLabelValues(cacheMiss)) - misses; got != 1 {
+		t.Errorf("expected 1 cache miss, got %v", got)
+	}
+}
+
+func TestTransfer_RetriesDeadlock(t *testing.T) {
+	// Arrange
+	repo, mockSQL := setupMockDB(t)
+	retries := testutil.ToFloat64(repositoryRetriesTotal.WithLabelValues(EventTransfer))
+	rollbacks := testutil.ToFloat64(repositoryRollbacksTotal.WithLabelValues(EventTransfer))
+
+	withdraw := `UPDATE wallets SET balance = balance - \$1`
+	deposit := `UPDATE wallets SET balance = balance \+ \$1`
+
+	mockSQL.ExpectBegin()
+	mockSQL.ExpectQuery(withdraw).WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(20.0, 3))
+	mockSQL.ExpectQuery(deposit).WillReturnError(&pq.Error{Code: "40P01", Message: "deadlock detected"})
+	mockSQL.ExpectRollback()
+	mockSQL.ExpectBegin()
+	mockSQL.ExpectQuery(withdraw).WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(20.0, 3))
+	mockSQL.ExpectQuery(deposit).WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(50.0, 3))
+	mockSQL.ExpectExec(`INSERT INTO transactions`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mockSQL.ExpectCommit()
+
+	// Act
+	_, toBalance, err := repo.Transfer(context.Background(), 1, 2, 30)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("expected the retry to succeed, got %v", err)
+	}
+
+	if toBalance.Amount != 50 {
+		t.Fatalf("expected to user balance to be 50, got %v", toBalance.Amount)
+	}
+
+	if got := testutil.ToFloat64(repositoryRetriesTotal.WithLabelValues(EventTransfer)) - retries; got != 1 {
+		t.Errorf("expected 1 retry counted, got %v", got)
+	}
+
+	if got := testutil.ToFloat64(repositoryRollbacksTotal.WithLabelValues(EventTransfer)) - rollbacks; got != 1 {
+		t.Errorf("expected 1 rollback counted, got %v", got)
+	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestDeposit_GivesUpAfterMaxAttempts(t *testing.T) {
+	// Arrange
+	repo, mockSQL := setupMockDB(t)
+
+	for range maxTxAttempts {
+		mockSQL.ExpectBegin()
+		mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1`).
+			WillReturnError(&pq.Error{Code: "40001", Message: "could not serialize access"})
+		mockSQL.ExpectRollback()
+	}
+
+	// Act
+	_, err := repo.Deposit(context.Background(), 1, 10)
+
+	// Assert
+	if !retryable(err) {
+		t.Fatalf("expected the serialization failure, got %v", err)
+	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
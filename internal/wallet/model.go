@@ -3,8 +3,10 @@ package wallet
 import (
 	"context"
 	"database/sql"
+	"time"
 
-	"github.com/redis/go-redis/v9"
+	"github.com/amelonpie/wallet-service/internal/database"
+	"github.com/amelonpie/wallet-service/pkg/breaker"
 	"github.com/sirupsen/logrus"
 )
 
@@ -18,30 +20,195 @@ type Transaction struct {
 	Timestamp       string  `json:"timestamp"`
 }
 
-// Repository defines methods to interact with the wallet data.
+// Balance of a wallet. Version is incremented by every balance update and
+// orders cached values against the database.
+type Balance struct {
+	Amount  float64
+	Version int64
+}
+
+// Event types published to notifiers, equal to the transaction_type logged
+const (
+	EventDeposit  = "deposit"
+	EventWithdraw = "withdraw"
+	EventTransfer = "transfer"
+	// EventAdjustment is a manual correction by an operator, its amount is signed
+	EventAdjustment = TransactionAdjustment
+)
+
+// TransactionAdjustment is the transaction_type of a manual correction, its amount is signed
+const TransactionAdjustment = "adjustment"
+
+// Wallet is the administrative view of a wallet row
+type Wallet struct {
+	UserID  int     `json:"user_id"`
+	Balance float64 `json:"balance"`
+	Version int64   `json:"version"`
+	Frozen  bool    `json:"frozen"`
+}
+
+// Adjustment is an audited manual correction stored in wallet_adjustments. A negative
+// Amount debits the wallet, Balance is the balance right after the adjustment.
+type Adjustment struct {
+	AdjustmentID  int       `json:"adjustment_id"`
+	UserID        int       `json:"user_id"`
+	TransactionID int       `json:"transaction_id"`
+	Amount        float64   `json:"amount"`
+	Reason        string    `json:"reason"`
+	Actor         string    `json:"actor"`
+	Balance       float64   `json:"balance"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Freeze is an audited freeze or unfreeze of a wallet stored in wallet_freezes
+type Freeze struct {
+	FreezeID  int       `json:"freeze_id"`
+	UserID    int       `json:"user_id"`
+	Frozen    bool      `json:"frozen"`
+	Reason    string    `json:"reason,omitempty"`
+	Actor     string    `json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LedgerDiscrepancy is a wallet whose balance differs from the sum of its transactions
+type LedgerDiscrepancy struct {
+	UserID        int     `json:"user_id"`
+	Balance       float64 `json:"balance"`
+	LedgerBalance float64 `json:"ledger_balance"`
+}
+
+// Period is a calendar month in UTC, the unit statements are issued for
+type Period struct {
+	Year  int
+	Month time.Month
+}
+
+// StatementEntry is a transaction as it appears on the statement of one wallet.
+// Amount is negative when money left the wallet, Balance is the running balance after it.
+type StatementEntry struct {
+	TransactionID  int       `json:"transaction_id"`
+	Timestamp      time.Time `json:"timestamp"`
+	Type           string    `json:"type"`
+	CounterpartyID int       `json:"counterparty_id,omitempty"`
+	Amount         float64   `json:"amount"`
+	Balance        float64   `json:"balance"`
+}
+
+// StatementTotal sums the entries of one transaction type, Debits is positive
+type StatementTotal struct {
+	Type    string  `json:"type"`
+	Count   int     `json:"count"`
+	Credits float64 `json:"credits"`
+	Debits  float64 `json:"debits"`
+}
+
+// Statement lists the transactions of a wallet in a period, oldest first, from the
+// balance at From to the balance right before To. Final statements belong to a closed
+// period and are stored, so they never change.
+type Statement struct {
+	UserID         int              `json:"user_id"`
+	Period         string           `json:"period"`
+	From           time.Time        `json:"from"`
+	To             time.Time        `json:"to"`
+	OpeningBalance float64          `json:"opening_balance"`
+	ClosingBalance float64          `json:"closing_balance"`
+	Entries        []StatementEntry `json:"entries"`
+	Totals         []StatementTotal `json:"totals"`
+	GeneratedAt    time.Time        `json:"generated_at"`
+	Final          bool             `json:"final"`
+}
+
+// Event describes a committed balance change of a single wallet. Amount is negative
+// when money leaves the wallet; a transfer produces one event for each side.
+type Event struct {
+	Type           string    `json:"type"`
+	UserID         int       `json:"user_id"`
+	CounterpartyID int       `json:"counterparty_id,omitempty"`
+	Amount         float64   `json:"amount"`
+	Balance        float64   `json:"balance"`
+	OccurredAt     time.Time `json:"occurred_at"`
+}
+
+// Notifier is informed about wallet events after the database transaction is committed
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// BalanceCache caches balances in front of the repository. Entries are ordered by
+// Balance.Version: a write leaves a tombstone of its version, and a balance is only
+// stored when nothing newer is cached, so a slow reader cannot put back a stale value.
+type BalanceCache interface {
+	// Get returns the cached balance, ok is false on a miss or a tombstone
+	Get(ctx context.Context, userID int) (balance float64, ok bool, err error)
+	// Set stores a balance read from the database unless a newer version is cached
+	Set(ctx context.Context, userID int, balance Balance) error
+	// Invalidate replaces the cached balance by a tombstone of a committed write
+	Invalidate(ctx context.Context, userID int, version int64) error
+	// Clear drops every cached balance
+	Clear(ctx context.Context) error
+}
+
+// Repository defines methods to interact with the wallet data. Implementations
+// reject overdrafts with ErrInsufficientFunds, apply a transfer entirely or not at
+// all and return the history newest first.
 type Repository interface {
-	Deposit(ctx context.Context, userID int, amount float64) (float64, error)
-	Withdraw(ctx context.Context, userID int, amount float64) (float64, error)
-	Transfer(ctx context.Context, fromUserID, toUserID int, amount float64) (float64, float64, error)
-	GetBalance(ctx context.Context, userID int) (float64, error)
-	LogTransaction(ctx context.Context, tx *sql.Tx, fromUserID, toUserID *int, amount float64, transactionType string) error
+	Deposit(ctx context.Context, userID int, amount float64) (Balance, error)
+	// DepositIdempotent deposits once per key, a used key returns ErrDuplicateDeposit
+	DepositIdempotent(ctx context.Context, userID int, amount float64, key string) (Balance, error)
+	Withdraw(ctx context.Context, userID int, amount float64) (Balance, error)
+	// WithdrawIdempotent withdraws once per key, a used key returns ErrDuplicateWithdraw
+	WithdrawIdempotent(ctx context.Context, userID int, amount float64, key string) (Balance, error)
+	Transfer(ctx context.Context, fromUserID, toUserID int, amount float64) (Balance, Balance, error)
+	GetBalance(ctx context.Context, userID int) (Balance, error)
+	// GetBalanceAsOf returns the balance after every transaction up to and including asOf
+	GetBalanceAsOf(ctx context.Context, userID int, asOf time.Time) (float64, error)
 	GetTransactionHistory(ctx context.Context, userID int) ([]Transaction, error)
+	// GetTransactionsBetween returns the history from from up to but excluding to,
+	// read from the primary
+	GetTransactionsBetween(ctx context.Context, userID int, from, to time.Time) ([]Transaction, error)
+	// GetStatement returns a stored statement or ErrStatementNotFound
+	GetStatement(ctx context.Context, userID int, period string) (Statement, error)
+	// SaveStatement stores the statement unless one of the period is stored already,
+	// it returns the stored statement
+	SaveStatement(ctx context.Context, statement Statement) (Statement, error)
 }
 
 type Service interface {
 	Deposit(ctx context.Context, userID int, amount float64) (float64, error)
+	DepositIdempotent(ctx context.Context, userID int, amount float64, key string) (float64, error)
 	Withdraw(ctx context.Context, userID int, amount float64) (float64, error)
+	WithdrawIdempotent(ctx context.Context, userID int, amount float64, key string) (float64, error)
 	Transfer(ctx context.Context, fromUserID, toUserID int, amount float64) (float64, float64, error)
 	GetBalance(ctx context.Context, userID int) (float64, error)
+	GetBalanceAsOf(ctx context.Context, userID int, asOf time.Time) (float64, error)
 	GetTransactionHistory(ctx context.Context, userID int) ([]Transaction, error)
+	// GetTransactionsAsOf returns the history up to and including asOf, read from the
+	// primary like GetBalanceAsOf
+	GetTransactionsAsOf(ctx context.Context, userID int, asOf time.Time) ([]Transaction, error)
+	GetStatement(ctx context.Context, userID int, period Period) (Statement, error)
+	CacheState() breaker.State
 }
 
 type walletService struct {
-	repo  Repository
-	cache *redis.Client
+	repo      Repository
+	cache     BalanceCache
+	notifiers []Notifier
+	logger    *logrus.Entry
+	now       func() time.Time
+}
+
+// Admin performs support operations directly on the Postgres wallets
+type Admin struct {
+	repo      *walletRepository
+	cache     BalanceCache
+	notifiers []Notifier
+	logger    *logrus.Entry
 }
 
 type walletRepository struct {
-	db     *sql.DB
-	logger *logrus.Entry
+	// db is the primary, every write and transaction runs on it
+	db *sql.DB
+	// replicas serve GetBalance and GetTransactionHistory, nil to read the primary
+	replicas *database.ReplicaSet
+	logger   *logrus.Entry
 }
@@ -3,164 +3,441 @@ package wallet
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"os/signal"
-	"syscall"
+	"time"
 
 	"github.com/amelonpie/wallet-service/internal/database"
+	"github.com/amelonpie/wallet-service/internal/metrics"
 	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/lib/pq"
+	"github.com/spf13/viper"
 )
 
+// Repository backends selectable by repository.backend
+const (
+	RepositoryBackendPostgres = "postgres"
+	RepositoryBackendMemory   = "memory"
+)
+
+// RepositoryConfig selects where wallets are stored
+type RepositoryConfig struct {
+	// Backend is one of RepositoryBackendPostgres or RepositoryBackendMemory
+	Backend string
+	// MemoryWallets are the balances the memory repository starts with, by user id
+	MemoryWallets map[int]float64
+}
+
+func NewRepositoryConfig() (RepositoryConfig, error) {
+	viper.SetDefault("repository.backend", RepositoryBackendPostgres)
+
+	var seed []struct {
+		UserID  int     `mapstructure:"user_id"`
+		Balance float64 `mapstructure:"balance"`
+	}
+
+	if err := viper.UnmarshalKey("repository.memory_wallets", &seed); err != nil {
+		return RepositoryConfig{}, fmt.Errorf("failed to read repository.memory_wallets: %w", err)
+	}
+
+	wallets := make(map[int]float64, len(seed))
+	for _, w := range seed {
+		wallets[w.UserID] = w.Balance
+	}
+
+	return RepositoryConfig{
+		Backend:       viper.GetString("repository.backend"),
+		MemoryWallets: wallets,
+	}, nil
+}
+
+// InitRepository connects to PostgreSQL, or with repository.backend "memory" keeps
+// the wallets listed in repository.memory_wallets in process for local development
+//
 //nolint:ireturn // stick to interface
 func InitRepository() (Repository, error) {
-	dbConfig := database.NewDatabaseConfig()
+	cfg, err := NewRepositoryConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Backend == RepositoryBackendMemory {
+		return NewMemoryRepository(cfg.MemoryWallets), nil
+	}
+
+	dbConfig, err := database.NewDatabaseConfig()
+	if err != nil {
+		return nil, err //nolint:wrapcheck // names the secret that failed
+	}
 
 	postgre, err := dbConfig.ConnectPostgre()
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
 	}
 
-	go func() {
-		stop := make(chan os.Signal, 1)
-		signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
-		<-stop
-		postgre.Close()
-
-		os.Exit(0)
-	}()
+	if err = metrics.RegisterDB(postgre, "wallet"); err != nil {
+		return nil, fmt.Errorf("failed to register connection pool metrics: %w", err)
+	}
 
-	return newWalletRepository(postgre), nil
+	return NewRepository(postgre), nil
 }
 
 // I think should return Repository as interface, not to return the concret type *walletRepository
 // but the linter requires me to do so. Should I just ignore it?
 
+// NewRepository returns the Postgres backed repository on db.
+//
+//nolint:ireturn // stick to interface
+func NewRepository(db *sql.DB) Repository {
+	return NewReplicatedRepository(db, nil)
+}
+
+// NewReplicatedRepository returns the Postgres backed repository on the primary
+// of replicas, which reads balances and histories from a caught up replica
+//
 //nolint:ireturn // stick to interface
-func newWalletRepository(db *sql.DB) Repository {
+func NewReplicatedRepository(db *sql.DB, replicas *database.ReplicaSet) Repository {
 	return &walletRepository{
-		db:     db,
-		logger: log.NewLogger("wallet").WithField("module", "endpoint"),
+		db:       db,
+		replicas: replicas,
+		logger:   log.NewLogger("wallet").WithField("module", "endpoint"),
+	}
+}
+
+// read runs query on a replica within the allowed lag. On any error, a wallet a
+// lagging replica does not have yet included, it runs again on the primary.
+func (r *walletRepository) read(ctx context.Context, query func(db *sql.DB) error) error {
+	if r.replicas == nil {
+		return query(r.db)
+	}
+
+	db := r.replicas.Reader()
+
+	err := query(db)
+	if err == nil || db == r.db {
+		return err
+	}
+
+	log.FromContext(ctx, r.logger).WithField("err", err).Debug("replica read failed, reading from the primary")
+
+	return query(r.db)
+}
+
+// maxTxAttempts bounds the runs of a transaction that keeps deadlocking
+const maxTxAttempts = 3
+
+// retryable tells whether Postgres aborted the transaction for a deadlock or a
+// serialization failure, which a new run of the same transaction may not hit
+func retryable(err error) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) &&
+		(pqErr.Code == "40001" || pqErr.Code == "40P01") // serialization_failure, deadlock_detected
+}
+
+// retry runs the transaction again when it was aborted by a deadlock or a
+// serialization failure, every rollback undid the previous run
+func (r *walletRepository) retry(ctx context.Context, operation string, run func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := run()
+		if err == nil || !retryable(err) || attempt == maxTxAttempts || ctx.Err() != nil {
+			return err
+		}
+
+		repositoryRetriesTotal.WithLabelValues(operation).Inc()
+		log.FromContext(ctx, r.logger).WithField("err", err).WithField("operation", operation).WithField("attempt", attempt).
+			Warn("retrying aborted transaction")
 	}
 }
 
-func (r *walletRepository) handleTransaction(ctx context.Context, userID int, amount float64, query string, transactionType string) (float64, error) {
+// handleTransaction applies the balance update of query and logs it. A non-empty
+// idempotencyKey is recorded in the same transaction and rejects a second use.
+func (r *walletRepository) handleTransaction(
+	ctx context.Context,
+	userID int,
+	amount float64,
+	query string,
+	transactionType string,
+	idempotencyKey string,
+) (Balance, error) {
+	var balance Balance
+
+	err := r.retry(ctx, transactionType, func() error {
+		var err error
+		balance, err = r.runTransaction(ctx, userID, amount, query, transactionType, idempotencyKey)
+
+		return err
+	})
+
+	return balance, err
+}
+
+func (r *walletRepository) runTransaction(
+	ctx context.Context,
+	userID int,
+	amount float64,
+	query string,
+	transactionType string,
+	idempotencyKey string,
+) (Balance, error) {
 	var err error
 	errptr := &err
 
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		errptr = &err
-		return 0, fmt.Errorf("failed to begin transaction for user %d: %w", userID, err)
+		return Balance{}, fmt.Errorf("failed to begin transaction for user %d: %w", userID, err)
 	}
 
 	defer func() {
 		if *errptr != nil {
+			repositoryRollbacksTotal.WithLabelValues(transactionType).Inc()
+
 			if rollbackErr := tx.Rollback(); rollbackErr != nil {
-				r.logger.WithField("err", rollbackErr).Error("failed to roll back")
+				log.FromContext(ctx, r.logger).WithField("err", rollbackErr).Error("failed to roll back")
 			}
 		}
 	}()
 
-	var newBalance float64
+	if idempotencyKey != "" {
+		err = r.useIdempotencyKey(ctx, tx, idempotencyKey, userID, amount)
+		if errors.Is(err, ErrDuplicateDeposit) && transactionType == "withdraw" {
+			err = fmt.Errorf("idempotency key %q: %w", idempotencyKey, ErrDuplicateWithdraw)
+		}
+
+		if err != nil {
+			errptr = &err
+			return Balance{}, err
+		}
+	}
+
+	var newBalance Balance
 	// Update the wallet balance based on the provided query
-	err = tx.QueryRowContext(ctx, query, amount, userID).Scan(&newBalance)
+	err = tx.QueryRowContext(ctx, query, amount, userID).Scan(&newBalance.Amount, &newBalance.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = r.noRowsError(ctx, tx, userID)
+	}
+
 	if err != nil {
 		errptr = &err
-		return 0, fmt.Errorf("failed to query database for user %d: %w", userID, err)
+		return Balance{}, fmt.Errorf("failed to query database for user %d: %w", userID, err)
 	}
 
 	// Log the transaction within the same transaction
 	err = r.LogTransaction(ctx, tx, &userID, nil, amount, transactionType)
 	if err != nil {
 		errptr = &err
-		return 0, fmt.Errorf("failed to log transaction for user %d: %w", userID, err)
+		return Balance{}, fmt.Errorf("failed to log transaction for user %d: %w", userID, err)
 	}
 
 	// Commit the transaction
 	if err = tx.Commit(); err != nil {
 		errptr = &err
-		return 0, fmt.Errorf("failed to commit transaction for user %d: %w", userID, err)
+		return Balance{}, fmt.Errorf("failed to commit transaction for user %d: %w", userID, err)
 	}
 
 	return newBalance, nil
 }
 
+// noRowsError tells why a conditional balance update matched no row:
+// the wallet does not exist, is frozen or its balance is too low
+func (r *walletRepository) noRowsError(ctx context.Context, tx *sql.Tx, userID int) error {
+	var (
+		balance float64
+		frozen  bool
+	)
+
+	query := `SELECT balance, frozen FROM wallets WHERE user_id = $1`
+	err := tx.QueryRowContext(ctx, query, userID).Scan(&balance, &frozen)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrWalletNotFound
+	case err != nil:
+		return fmt.Errorf("failed to query database for user %d: %w", userID, err)
+	case frozen:
+		return ErrWalletFrozen
+	default:
+		return ErrInsufficientFunds
+	}
+}
+
 // Deposit adds the given amount to the user's wallet
-func (r *walletRepository) Deposit(ctx context.Context, userID int, amount float64) (float64, error) {
-	query := `UPDATE wallets SET balance = balance + $1 WHERE user_id = $2 RETURNING balance`
-	return r.handleTransaction(ctx, userID, amount, query, "deposit")
+func (r *walletRepository) Deposit(ctx context.Context, userID int, amount float64) (Balance, error) {
+	query := `UPDATE wallets SET balance = balance + $1, version = version + 1 WHERE user_id = $2 AND NOT frozen RETURNING balance, version`
+	return r.handleTransaction(ctx, userID, amount, query, "deposit", "")
+}
+
+// DepositIdempotent deposits like Deposit unless the key was used before. Concurrent
+// calls with the same key wait for each other, only one of them deposits.
+func (r *walletRepository) DepositIdempotent(ctx context.Context, userID int, amount float64, key string) (Balance, error) {
+	query := `UPDATE wallets SET balance = balance + $1, version = version + 1 WHERE user_id = $2 AND NOT frozen RETURNING balance, version`
+	return r.handleTransaction(ctx, userID, amount, query, "deposit", key)
+}
+
+// useIdempotencyKey records the key, ErrDuplicateDeposit when it is taken
+func (r *walletRepository) useIdempotencyKey(ctx context.Context, tx *sql.Tx, key string, userID int, amount float64) error {
+	query := `INSERT INTO deposit_keys (idempotency_key, user_id, amount) VALUES ($1, $2, $3)
+              ON CONFLICT (idempotency_key) DO NOTHING`
+
+	result, err := tx.ExecContext(ctx, query, key, userID, amount)
+	if err != nil {
+		return fmt.Errorf("failed to record idempotency key %q: %w", key, err)
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to record idempotency key %q: %w", key, err)
+	}
+
+	if inserted == 0 {
+		return fmt.Errorf("idempotency key %q: %w", key, ErrDuplicateDeposit)
+	}
+
+	return nil
 }
 
-// Withdraw subtracts the given amount from the user's wallet
-func (r *walletRepository) Withdraw(ctx context.Context, userID int, amount float64) (float64, error) {
-	query := `UPDATE wallets SET balance = balance - $1 WHERE user_id = $2 RETURNING balance`
-	return r.handleTransaction(ctx, userID, amount, query, "withdraw")
+// Withdraw subtracts the given amount from the user's wallet, the balance never goes below zero
+func (r *walletRepository) Withdraw(ctx context.Context, userID int, amount float64) (Balance, error) {
+	query := `UPDATE wallets SET balance = balance - $1, version = version + 1 WHERE user_id = $2 AND NOT frozen AND balance >= $1 RETURNING balance, version`
+	return r.handleTransaction(ctx, userID, amount, query, "withdraw", "")
 }
 
-// Transfer moves `amount` from `fromUserID` to `toUserID`
-func (r *walletRepository) Transfer(ctx context.Context, fromUserID, toUserID int, amount float64) (float64, float64, error) {
+// WithdrawIdempotent withdraws like Withdraw unless the key was used before. Deposits
+// and withdrawals share the keys, callers prefix them by purpose.
+func (r *walletRepository) WithdrawIdempotent(ctx context.Context, userID int, amount float64, key string) (Balance, error) {
+	query := `UPDATE wallets SET balance = balance - $1, version = version + 1 WHERE user_id = $2 AND NOT frozen AND balance >= $1 RETURNING balance, version`
+	return r.handleTransaction(ctx, userID, amount, query, "withdraw", key)
+}
+
+// Transfer moves `amount` from `fromUserID` to `toUserID`. Opposite transfers lock
+// the two wallets in opposite order, the one Postgres aborts for the deadlock is retried.
+func (r *walletRepository) Transfer(ctx context.Context, fromUserID, toUserID int, amount float64) (Balance, Balance, error) {
+	var fromBalance, toBalance Balance
+
+	err := r.retry(ctx, EventTransfer, func() error {
+		var err error
+		fromBalance, toBalance, err = r.transfer(ctx, fromUserID, toUserID, amount)
+
+		return err
+	})
+
+	return fromBalance, toBalance, err
+}
+
+func (r *walletRepository) transfer(ctx context.Context, fromUserID, toUserID int, amount float64) (Balance, Balance, error) {
 	tx, err := r.db.BeginTx(ctx, nil)
 	errptr := &err
 
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to begin transaction for from user %d and to user %d: %w", fromUserID, toUserID, err)
+		return Balance{}, Balance{}, fmt.Errorf("failed to begin transaction for from user %d and to user %d: %w", fromUserID, toUserID, err)
 	}
 
 	defer func() {
 		if *errptr != nil {
+			repositoryRollbacksTotal.WithLabelValues(EventTransfer).Inc()
+
 			if rollbackErr := tx.Rollback(); rollbackErr != nil {
-				r.logger.WithField("err", rollbackErr).Error("failed to roll back")
+				log.FromContext(ctx, r.logger).WithField("err", rollbackErr).Error("failed to roll back")
 			}
 		}
 	}()
 
 	// Subtract amount from `fromUserID`
-	var fromBalance float64
+	var fromBalance Balance
 
-	queryFrom := `UPDATE wallets SET balance = balance - $1 WHERE user_id = $2 RETURNING balance`
-	err = tx.QueryRowContext(ctx, queryFrom, amount, fromUserID).Scan(&fromBalance)
+	queryFrom := `UPDATE wallets SET balance = balance - $1, version = version + 1 WHERE user_id = $2 AND NOT frozen AND balance >= $1 RETURNING balance, version`
+	err = tx.QueryRowContext(ctx, queryFrom, amount, fromUserID).Scan(&fromBalance.Amount, &fromBalance.Version)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		err = r.noRowsError(ctx, tx, fromUserID)
+	}
 
 	if err != nil {
 		errptr = &err
-		return 0, 0, fmt.Errorf("failed to query database for user %d: %w", fromUserID, err)
+		return Balance{}, Balance{}, fmt.Errorf("failed to query database for user %d: %w", fromUserID, err)
 	}
 
 	// Add amount to `toUserID`
-	var toBalance float64
+	var toBalance Balance
 
-	queryTo := `UPDATE wallets SET balance = balance + $1 WHERE user_id = $2 RETURNING balance`
-	err = tx.QueryRowContext(ctx, queryTo, amount, toUserID).Scan(&toBalance)
+	queryTo := `UPDATE wallets SET balance = balance + $1, version = version + 1 WHERE user_id = $2 AND NOT frozen RETURNING balance, version`
+	err = tx.QueryRowContext(ctx, queryTo, amount, toUserID).Scan(&toBalance.Amount, &toBalance.Version)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		if err = r.noRowsError(ctx, tx, toUserID); errors.Is(err, ErrWalletNotFound) {
+			err = ErrRecipientNotFound
+		}
+	}
 
 	if err != nil {
 		errptr = &err
-		return 0, 0, fmt.Errorf("failed to query database for user %d: %w", toUserID, err)
+		return Balance{}, Balance{}, fmt.Errorf("failed to query database for user %d: %w", toUserID, err)
 	}
 
 	// Log the transaction within the same transaction
 	err = r.LogTransaction(ctx, tx, &fromUserID, &toUserID, amount, "transfer")
 	if err != nil {
 		errptr = &err
-		return 0, 0, fmt.Errorf("failed to log transaction for from user %d and to %d: %w", fromUserID, toUserID, err)
+		return Balance{}, Balance{}, fmt.Errorf("failed to log transaction for from user %d and to %d: %w", fromUserID, toUserID, err)
 	}
 
 	if err = tx.Commit(); err != nil {
 		errptr = &err
-		return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+		return Balance{}, Balance{}, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return fromBalance, toBalance, nil
 }
 
-// GetBalance returns the current balance of the specified user
-func (r *walletRepository) GetBalance(ctx context.Context, userID int) (float64, error) {
+// GetBalance returns the current balance of the specified user with its version
+func (r *walletRepository) GetBalance(ctx context.Context, userID int) (Balance, error) {
+	var balance Balance
+
+	query := `SELECT balance, version FROM wallets WHERE user_id=$1`
+	err := r.read(ctx, func(db *sql.DB) error {
+		return db.QueryRowContext(ctx, query, userID).Scan(&balance.Amount, &balance.Version)
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrWalletNotFound
+	}
+
+	if err != nil {
+		return Balance{}, fmt.Errorf("failed to query database for user %d: %w", userID, err)
+	}
+
+	return balance, nil
+}
+
+// GetBalanceAsOf starts from the latest balance snapshot taken at or before asOf and
+// adds the movements after it, so only the history since that snapshot is read
+func (r *walletRepository) GetBalanceAsOf(ctx context.Context, userID int, asOf time.Time) (float64, error) {
+	query := `
+    SELECT COALESCE(s.balance, 0) + COALESCE((
+        SELECT SUM(m.amount) FROM wallet_movements m
+        WHERE m.user_id = w.user_id AND m.timestamp > COALESCE(s.taken_at, '-infinity') AND m.timestamp <= $2
+    ), 0)
+    FROM wallets w
+    LEFT JOIN LATERAL (
+        SELECT balance, taken_at FROM balance_snapshots
+        WHERE user_id = w.user_id AND taken_at <= $2
+        ORDER BY taken_at DESC LIMIT 1
+    ) s ON TRUE
+    WHERE w.user_id = $1
+    `
+
 	var balance float64
 
-	query := `SELECT balance FROM wallets WHERE user_id=$1`
-	err := r.db.QueryRowContext(ctx, query, userID).Scan(&balance)
+	// timestamps are stored without time zone, in UTC
+	err := r.db.QueryRowContext(ctx, query, userID, asOf.UTC()).Scan(&balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrWalletNotFound
+	}
 
 	if err != nil {
-		return 0, fmt.Errorf("failed to query database for user %d: %w", userID, err)
+		return 0, fmt.Errorf("failed to query balance of user %d as of %s: %w", userID, asOf.Format(time.RFC3339), err)
 	}
 
 	return balance, nil
@@ -195,28 +472,74 @@ func (r *walletRepository) LogTransaction(
 //		return nil, err
 //	}
 func (r *walletRepository) GetTransactionHistory(ctx context.Context, userID int) ([]Transaction, error) {
+	var txs []Transaction
+
+	err := r.read(ctx, func(db *sql.DB) error {
+		var err error
+		txs, err = transactionHistory(ctx, db, userID)
+
+		return err
+	})
+
+	return txs, err
+}
+
+func transactionHistory(ctx context.Context, db *sql.DB, userID int) ([]Transaction, error) {
 	query := `
     SELECT transaction_id, from_user_id, to_user_id, amount, transaction_type, timestamp
     FROM transactions
     WHERE from_user_id = $1 OR to_user_id = $1
-    ORDER BY timestamp DESC
+    ORDER BY timestamp DESC, transaction_id DESC
     `
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := db.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query database for user %d: %w", userID, err)
 	}
 	defer rows.Close()
 
-	//nolint:prealloc // see function start
+	return scanTransactions(rows, userID)
+}
+
+// GetTransactionsBetween retrieves the transactions of a user from from up to but
+// excluding to. It reads the primary: statements are stored once, one built on a
+// lagging replica would miss the last transactions of its period for good.
+func (r *walletRepository) GetTransactionsBetween(
+	ctx context.Context,
+	userID int,
+	from, to time.Time,
+) ([]Transaction, error) {
+	query := `
+    SELECT transaction_id, from_user_id, to_user_id, amount, transaction_type, timestamp
+    FROM transactions
+    WHERE (from_user_id = $1 OR to_user_id = $1) AND timestamp >= $2 AND timestamp < $3
+    ORDER BY timestamp DESC, transaction_id DESC
+    `
+
+	// timestamps are stored without time zone, in UTC
+	rows, err := r.db.QueryContext(ctx, query, userID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query database for user %d: %w", userID, err)
+	}
+	defer rows.Close()
+
+	return scanTransactions(rows, userID)
+}
+
+func scanTransactions(rows *sql.Rows, userID int) ([]Transaction, error) {
+	//nolint:prealloc // see GetTransactionHistory
 	var txs []Transaction
 
 	for rows.Next() {
-		var t Transaction
-		err = rows.Scan(
+		var (
+			t        Transaction
+			toUserID sql.NullInt64 // NULL for deposits and withdrawals
+		)
+
+		err := rows.Scan(
 			&t.TransactionID,
 			&t.FromUserID,
-			&t.ToUserID,
+			&toUserID,
 			&t.Amount,
 			&t.TransactionType,
 			&t.Timestamp,
@@ -226,12 +549,54 @@ func (r *walletRepository) GetTransactionHistory(ctx context.Context, userID int
 			return nil, fmt.Errorf("failed to scan certain transaction for user %d: %w", userID, err)
 		}
 
+		t.ToUserID = int(toUserID.Int64)
 		txs = append(txs, t)
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error arises during rows intertation for user %d: %w", userID, err)
 	}
 
 	return txs, nil
 }
+
+// GetStatement returns a stored statement
+func (r *walletRepository) GetStatement(ctx context.Context, userID int, period string) (Statement, error) {
+	query := `SELECT statement FROM statements WHERE user_id = $1 AND period = $2`
+
+	var data []byte
+
+	err := r.db.QueryRowContext(ctx, query, userID, period).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrStatementNotFound
+	}
+
+	if err != nil {
+		return Statement{}, fmt.Errorf("failed to query statement %s of user %d: %w", period, userID, err)
+	}
+
+	var statement Statement
+	if err = json.Unmarshal(data, &statement); err != nil {
+		return Statement{}, fmt.Errorf("failed to decode statement %s of user %d: %w", period, userID, err)
+	}
+
+	return statement, nil
+}
+
+// SaveStatement stores the statement unless another request stored the period first,
+// either way the stored statement is returned
+func (r *walletRepository) SaveStatement(ctx context.Context, statement Statement) (Statement, error) {
+	data, err := json.Marshal(statement)
+	if err != nil {
+		return Statement{}, fmt.Errorf("failed to encode statement %s of user %d: %w", statement.Period, statement.UserID, err)
+	}
+
+	query := `INSERT INTO statements (user_id, period, statement) VALUES ($1, $2, $3)
+              ON CONFLICT (user_id, period) DO NOTHING`
+
+	if _, err = r.db.ExecContext(ctx, query, statement.UserID, statement.Period, data); err != nil {
+		return Statement{}, fmt.Errorf("failed to insert statement %s of user %d: %w", statement.Period, statement.UserID, err)
+	}
+
+	return r.GetStatement(ctx, statement.UserID, statement.Period)
+}
@@ -0,0 +1,408 @@
+package wallet
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// postgresDSNEnv points the conformance suite at a Postgres database with the wallet
+// schema applied. The suite creates its own users and removes them afterwards.
+const postgresDSNEnv = "WALLET_TEST_POSTGRES_DSN"
+
+// missingUserID never has a wallet, user ids are serial and positive
+const missingUserID = -1
+
+// repositoryFactory creates a repository holding one wallet per balance and returns
+// the user ids of these wallets in the same order
+type repositoryFactory func(t *testing.T, balances ...float64) (Repository, []int)
+
+func TestMemoryRepository_Conformance(t *testing.T) {
+	testRepositoryConformance(t, func(_ *testing.T, balances ...float64) (Repository, []int) {
+		wallets := make(map[int]float64, len(balances))
+		users := make([]int, 0, len(balances))
+
+		for i, balance := range balances {
+			wallets[i+1] = balance
+			users = append(users, i+1)
+		}
+
+		return NewMemoryRepository(wallets), users
+	})
+}
+
+func TestPostgresRepository_Conformance(t *testing.T) {
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("set %s to run the conformance suite against Postgres", postgresDSNEnv)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("failed to open Postgres: %v", err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	testRepositoryConformance(t, func(t *testing.T, balances ...float64) (Repository, []int) {
+		t.Helper()
+
+		ctx := context.Background()
+		users := make([]int, 0, len(balances))
+
+		for _, balance := range balances {
+			userID := 1_000_000 + rand.IntN(1_000_000_000)
+
+			_, err := db.ExecContext(ctx,
+				`INSERT INTO users (user_id, username, email) VALUES ($1, 'conformance', 'conformance@example.com')`, userID)
+			if err != nil {
+				t.Fatalf("failed to create user: %v", err)
+			}
+
+			_, err = db.ExecContext(ctx, `INSERT INTO wallets (user_id, balance) VALUES ($1, $2)`, userID, balance)
+			if err != nil {
+				t.Fatalf("failed to create wallet: %v", err)
+			}
+
+			// the opening balance has no transaction, a snapshot accounts for it
+			_, err = db.ExecContext(ctx,
+				`INSERT INTO balance_snapshots (user_id, taken_at, balance) VALUES ($1, LOCALTIMESTAMP, $2)`, userID, balance)
+			if err != nil {
+				t.Fatalf("failed to create snapshot: %v", err)
+			}
+
+			users = append(users, userID)
+		}
+
+		t.Cleanup(func() {
+			// transactions and wallets cascade
+			_, _ = db.ExecContext(ctx, `DELETE FROM transactions WHERE to_user_id = ANY($1)`, pq.Array(users))
+			_, _ = db.ExecContext(ctx, `DELETE FROM users WHERE user_id = ANY($1)`, pq.Array(users))
+		})
+
+		return NewRepository(db), users
+	})
+}
+
+func requireBalance(t *testing.T, repo Repository, userID int, expected float64) Balance {
+	t.Helper()
+
+	balance, err := repo.GetBalance(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if balance.Amount != expected {
+		t.Fatalf("expected balance of user %d to be %v, got %v", userID, expected, balance.Amount)
+	}
+
+	return balance
+}
+
+func requireHistoryLen(t *testing.T, repo Repository, userID int, expected int) []Transaction {
+	t.Helper()
+
+	txs, err := repo.GetTransactionHistory(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(txs) != expected {
+		t.Fatalf("expected %d transactions for user %d, got %d", expected, userID, len(txs))
+	}
+
+	return txs
+}
+
+//nolint:maintidx // one subtest per behavior
+func testRepositoryConformance(t *testing.T, newRepo repositoryFactory) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	t.Run("deposit increments balance and version", func(t *testing.T) {
+		repo, users := newRepo(t, 100)
+		before := requireBalance(t, repo, users[0], 100)
+
+		balance, err := repo.Deposit(ctx, users[0], 50.25)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		after := requireBalance(t, repo, users[0], 150.25)
+		if balance != after || after.Version <= before.Version {
+			t.Fatalf("expected returned %+v to equal stored %+v with a newer version than %+v", balance, after, before)
+		}
+	})
+
+	t.Run("idempotent deposit is made once per key", func(t *testing.T) {
+		repo, users := newRepo(t, 100, 50)
+		key := "conformance-" + strconv.Itoa(users[0])
+
+		if _, err := repo.DepositIdempotent(ctx, users[0], 25, key); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		for _, userID := range users {
+			if _, err := repo.DepositIdempotent(ctx, userID, 25, key); !errors.Is(err, ErrDuplicateDeposit) {
+				t.Fatalf("expected error %v, got %v", ErrDuplicateDeposit, err)
+			}
+		}
+
+		requireBalance(t, repo, users[0], 125)
+		requireBalance(t, repo, users[1], 50)
+		requireHistoryLen(t, repo, users[0], 1)
+
+		// a deposit that fails does not use up its key
+		if _, err := repo.DepositIdempotent(ctx, missingUserID, 25, key+"-missing"); !errors.Is(err, ErrWalletNotFound) {
+			t.Fatalf("expected error %v, got %v", ErrWalletNotFound, err)
+		}
+
+		if _, err := repo.DepositIdempotent(ctx, users[1], 25, key+"-missing"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("idempotent withdrawal is made once per key", func(t *testing.T) {
+		repo, users := newRepo(t, 100)
+		key := "conformance-withdraw-" + strconv.Itoa(users[0])
+
+		// an overdraft does not use up its key
+		if _, err := repo.WithdrawIdempotent(ctx, users[0], 150, key); !errors.Is(err, ErrInsufficientFunds) {
+			t.Fatalf("expected error %v, got %v", ErrInsufficientFunds, err)
+		}
+
+		if _, err := repo.WithdrawIdempotent(ctx, users[0], 40, key); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if _, err := repo.WithdrawIdempotent(ctx, users[0], 40, key); !errors.Is(err, ErrDuplicateWithdraw) {
+			t.Fatalf("expected error %v, got %v", ErrDuplicateWithdraw, err)
+		}
+
+		requireBalance(t, repo, users[0], 60)
+		requireHistoryLen(t, repo, users[0], 1)
+	})
+
+	t.Run("withdraw down to zero", func(t *testing.T) {
+		repo, users := newRepo(t, 100)
+
+		balance, err := repo.Withdraw(ctx, users[0], 100)
+		if err != nil || balance.Amount != 0 {
+			t.Fatalf("expected balance 0, got %v (%v)", balance.Amount, err)
+		}
+	})
+
+	t.Run("withdraw rejects overdraft", func(t *testing.T) {
+		repo, users := newRepo(t, 100)
+
+		_, err := repo.Withdraw(ctx, users[0], 100.01)
+		if !errors.Is(err, ErrInsufficientFunds) {
+			t.Fatalf("expected error %v, got %v", ErrInsufficientFunds, err)
+		}
+
+		requireBalance(t, repo, users[0], 100)
+		requireHistoryLen(t, repo, users[0], 0)
+	})
+
+	t.Run("unknown wallet", func(t *testing.T) {
+		repo, _ := newRepo(t)
+
+		if _, err := repo.Deposit(ctx, missingUserID, 10); !errors.Is(err, ErrWalletNotFound) {
+			t.Fatalf("deposit: expected error %v, got %v", ErrWalletNotFound, err)
+		}
+
+		if _, err := repo.Withdraw(ctx, missingUserID, 10); !errors.Is(err, ErrWalletNotFound) {
+			t.Fatalf("withdraw: expected error %v, got %v", ErrWalletNotFound, err)
+		}
+
+		if _, err := repo.GetBalance(ctx, missingUserID); !errors.Is(err, ErrWalletNotFound) {
+			t.Fatalf("balance: expected error %v, got %v", ErrWalletNotFound, err)
+		}
+	})
+
+	t.Run("transfer moves money", func(t *testing.T) {
+		repo, users := newRepo(t, 100, 50)
+
+		from, to, err := repo.Transfer(ctx, users[0], users[1], 30)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if from.Amount != 70 || to.Amount != 80 {
+			t.Fatalf("expected balances 70 and 80, got %v and %v", from.Amount, to.Amount)
+		}
+	})
+
+	t.Run("transfer is all or nothing", func(t *testing.T) {
+		repo, users := newRepo(t, 100, 50)
+
+		if _, _, err := repo.Transfer(ctx, users[0], missingUserID, 30); !errors.Is(err, ErrRecipientNotFound) {
+			t.Fatalf("expected error %v, got %v", ErrRecipientNotFound, err)
+		}
+
+		if _, _, err := repo.Transfer(ctx, users[0], users[1], 500); !errors.Is(err, ErrInsufficientFunds) {
+			t.Fatalf("expected error %v, got %v", ErrInsufficientFunds, err)
+		}
+
+		requireBalance(t, repo, users[0], 100)
+		requireBalance(t, repo, users[1], 50)
+		requireHistoryLen(t, repo, users[0], 0)
+	})
+
+	t.Run("history is newest first", func(t *testing.T) {
+		repo, users := newRepo(t, 100, 50)
+
+		_, _ = repo.Deposit(ctx, users[0], 10)
+		_, _ = repo.Withdraw(ctx, users[0], 20)
+		_, _, _ = repo.Transfer(ctx, users[0], users[1], 30)
+
+		txs := requireHistoryLen(t, repo, users[0], 3)
+
+		for i, expected := range []struct {
+			transactionType string
+			toUserID        int
+			amount          float64
+		}{
+			{"transfer", users[1], 30},
+			{"withdraw", 0, 20},
+			{"deposit", 0, 10},
+		} {
+			got := txs[i]
+			if got.TransactionType != expected.transactionType || got.ToUserID != expected.toUserID ||
+				got.FromUserID != users[0] || got.Amount != expected.amount {
+				t.Fatalf("unexpected transaction %d: %+v", i, got)
+			}
+		}
+
+		received := requireHistoryLen(t, repo, users[1], 1)
+		if received[0].TransactionID != txs[0].TransactionID {
+			t.Fatalf("expected recipient to see the transfer, got %+v", received[0])
+		}
+	})
+
+	t.Run("balance as of now is the current balance", func(t *testing.T) {
+		repo, users := newRepo(t, 100, 50)
+
+		_, _ = repo.Deposit(ctx, users[0], 10)
+		_, _, _ = repo.Transfer(ctx, users[0], users[1], 30)
+		_, _ = repo.Withdraw(ctx, users[1], 5)
+
+		for i, expected := range []float64{80, 75} {
+			balance, err := repo.GetBalanceAsOf(ctx, users[i], time.Now().Add(time.Hour))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if balance != expected {
+				t.Fatalf("expected balance of user %d to be %v, got %v", users[i], expected, balance)
+			}
+		}
+
+		if _, err := repo.GetBalanceAsOf(ctx, missingUserID, time.Now()); !errors.Is(err, ErrWalletNotFound) {
+			t.Fatalf("expected error %v, got %v", ErrWalletNotFound, err)
+		}
+	})
+
+	t.Run("transactions between keeps to the period", func(t *testing.T) {
+		repo, users := newRepo(t, 100, 50)
+
+		_, _ = repo.Deposit(ctx, users[0], 10)
+		_, _, _ = repo.Transfer(ctx, users[1], users[0], 5)
+
+		now := time.Now()
+
+		txs, err := repo.GetTransactionsBetween(ctx, users[0], now.Add(-time.Hour), now.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if len(txs) != 2 || txs[0].TransactionType != "transfer" || txs[1].TransactionType != "deposit" {
+			t.Fatalf("expected the transfer and the deposit, newest first, got %+v", txs)
+		}
+
+		later, err := repo.GetTransactionsBetween(ctx, users[0], now.Add(time.Hour), now.Add(2*time.Hour))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if len(later) != 0 {
+			t.Fatalf("expected no transactions after the period, got %+v", later)
+		}
+	})
+
+	t.Run("the first stored statement of a period is kept", func(t *testing.T) {
+		repo, users := newRepo(t, 100)
+
+		if _, err := repo.GetStatement(ctx, users[0], "2024-01"); !errors.Is(err, ErrStatementNotFound) {
+			t.Fatalf("expected error %v, got %v", ErrStatementNotFound, err)
+		}
+
+		first := Statement{
+			UserID:         users[0],
+			Period:         "2024-01",
+			OpeningBalance: 100,
+			ClosingBalance: 100,
+			Entries:        []StatementEntry{},
+			Totals:         []StatementTotal{},
+			GeneratedAt:    time.Date(2024, 2, 1, 0, 5, 0, 0, time.UTC),
+			Final:          true,
+		}
+
+		if _, err := repo.SaveStatement(ctx, first); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		second := first
+		second.ClosingBalance = 1
+
+		stored, err := repo.SaveStatement(ctx, second)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if stored.ClosingBalance != first.ClosingBalance || !stored.GeneratedAt.Equal(first.GeneratedAt) {
+			t.Fatalf("expected the first statement to be kept, got %+v", stored)
+		}
+	})
+
+	t.Run("concurrent withdrawals never overdraw", func(t *testing.T) {
+		repo, users := newRepo(t, 100)
+
+		var (
+			wg        sync.WaitGroup
+			mu        sync.Mutex
+			succeeded int
+		)
+
+		for range 10 {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				if _, err := repo.Withdraw(ctx, users[0], 20); err == nil {
+					mu.Lock()
+					succeeded++
+					mu.Unlock()
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		if succeeded != 5 {
+			t.Fatalf("expected 5 withdrawals to succeed, got %d", succeeded)
+		}
+
+		requireBalance(t, repo, users[0], 0)
+	})
+}
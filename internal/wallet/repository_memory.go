@@ -0,0 +1,308 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryRepository keeps wallets in process with the semantics of the Postgres
+// repository: every operation is atomic, balances never go below zero and amounts
+// are rounded like DECIMAL(15, 2).
+type memoryRepository struct {
+	mu           sync.Mutex
+	wallets      map[int]*Balance
+	opening      map[int]float64
+	transactions []Transaction
+	statements   map[statementKey][]byte
+	depositKeys  map[string]struct{}
+	now          func() time.Time
+}
+
+type statementKey struct {
+	userID int
+	period string
+}
+
+// NewMemoryRepository creates a repository holding the given wallets, keyed by user
+//
+//nolint:ireturn // stick to interface
+func NewMemoryRepository(wallets map[int]float64) Repository {
+	return newMemoryRepository(wallets)
+}
+
+func newMemoryRepository(wallets map[int]float64) *memoryRepository {
+	repo := &memoryRepository{
+		wallets:     make(map[int]*Balance, len(wallets)),
+		opening:     make(map[int]float64, len(wallets)),
+		statements:  make(map[statementKey][]byte),
+		depositKeys: make(map[string]struct{}),
+		now:         time.Now,
+	}
+
+	for userID, amount := range wallets {
+		repo.wallets[userID] = &Balance{Amount: roundCents(amount)}
+		repo.opening[userID] = roundCents(amount)
+	}
+
+	return repo
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// logTransaction appends to the history, the caller holds the lock
+func (r *memoryRepository) logTransaction(fromUserID, toUserID int, amount float64, transactionType string) {
+	r.transactions = append(r.transactions, Transaction{
+		TransactionID:   len(r.transactions) + 1,
+		FromUserID:      fromUserID,
+		ToUserID:        toUserID,
+		Amount:          roundCents(amount),
+		TransactionType: transactionType,
+		Timestamp:       r.now().UTC().Format(time.RFC3339Nano),
+	})
+}
+
+func (r *memoryRepository) Deposit(_ context.Context, userID int, amount float64) (Balance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.deposit(userID, amount)
+}
+
+// DepositIdempotent deposits unless the key was used, a failed deposit leaves the key unused
+func (r *memoryRepository) DepositIdempotent(_ context.Context, userID int, amount float64, key string) (Balance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.depositKeys[key]; ok {
+		return Balance{}, fmt.Errorf("failed to deposit for user %d, idempotency key %q: %w", userID, key, ErrDuplicateDeposit)
+	}
+
+	balance, err := r.deposit(userID, amount)
+	if err != nil {
+		return Balance{}, err
+	}
+
+	r.depositKeys[key] = struct{}{}
+
+	return balance, nil
+}
+
+// deposit credits the wallet, the caller holds the lock
+func (r *memoryRepository) deposit(userID int, amount float64) (Balance, error) {
+	wallet, ok := r.wallets[userID]
+	if !ok {
+		return Balance{}, fmt.Errorf("failed to deposit for user %d: %w", userID, ErrWalletNotFound)
+	}
+
+	wallet.Amount = roundCents(wallet.Amount + amount)
+	wallet.Version++
+	r.logTransaction(userID, 0, amount, "deposit")
+
+	return *wallet, nil
+}
+
+func (r *memoryRepository) Withdraw(_ context.Context, userID int, amount float64) (Balance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.withdraw(userID, amount)
+}
+
+// WithdrawIdempotent withdraws unless the key was used, deposits and withdrawals share the keys
+func (r *memoryRepository) WithdrawIdempotent(_ context.Context, userID int, amount float64, key string) (Balance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.depositKeys[key]; ok {
+		return Balance{}, fmt.Errorf("failed to withdraw for user %d, idempotency key %q: %w", userID, key, ErrDuplicateWithdraw)
+	}
+
+	balance, err := r.withdraw(userID, amount)
+	if err != nil {
+		return Balance{}, err
+	}
+
+	r.depositKeys[key] = struct{}{}
+
+	return balance, nil
+}
+
+// withdraw debits the wallet, the caller holds the lock
+func (r *memoryRepository) withdraw(userID int, amount float64) (Balance, error) {
+	wallet, err := r.debitable(userID, amount)
+	if err != nil {
+		return Balance{}, fmt.Errorf("failed to withdraw for user %d: %w", userID, err)
+	}
+
+	wallet.Amount = roundCents(wallet.Amount - amount)
+	wallet.Version++
+	r.logTransaction(userID, 0, amount, "withdraw")
+
+	return *wallet, nil
+}
+
+// Transfer checks both wallets before changing either, which is what the rollback
+// of the Postgres transaction amounts to
+func (r *memoryRepository) Transfer(_ context.Context, fromUserID, toUserID int, amount float64) (Balance, Balance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	from, err := r.debitable(fromUserID, amount)
+	if err != nil {
+		return Balance{}, Balance{}, fmt.Errorf("failed to transfer from user %d: %w", fromUserID, err)
+	}
+
+	to, ok := r.wallets[toUserID]
+	if !ok {
+		return Balance{}, Balance{}, fmt.Errorf("failed to transfer to user %d: %w", toUserID, ErrRecipientNotFound)
+	}
+
+	from.Amount = roundCents(from.Amount - amount)
+	from.Version++
+	to.Amount = roundCents(to.Amount + amount)
+	to.Version++
+	r.logTransaction(fromUserID, toUserID, amount, "transfer")
+
+	return *from, *to, nil
+}
+
+// debitable returns the wallet if it exists and covers the amount, the caller holds the lock
+func (r *memoryRepository) debitable(userID int, amount float64) (*Balance, error) {
+	wallet, ok := r.wallets[userID]
+	if !ok {
+		return nil, ErrWalletNotFound
+	}
+
+	if wallet.Amount < amount {
+		return nil, ErrInsufficientFunds
+	}
+
+	return wallet, nil
+}
+
+func (r *memoryRepository) GetBalance(_ context.Context, userID int) (Balance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wallet, ok := r.wallets[userID]
+	if !ok {
+		return Balance{}, fmt.Errorf("failed to get balance for user %d: %w", userID, ErrWalletNotFound)
+	}
+
+	return *wallet, nil
+}
+
+// GetBalanceAsOf adds the movements up to asOf to the balance the wallet was created with
+func (r *memoryRepository) GetBalanceAsOf(_ context.Context, userID int, asOf time.Time) (float64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	balance, ok := r.opening[userID]
+	if !ok {
+		return 0, fmt.Errorf("failed to get balance for user %d: %w", userID, ErrWalletNotFound)
+	}
+
+	for _, t := range r.transactions {
+		at, err := time.Parse(time.RFC3339Nano, t.Timestamp)
+		if err != nil || at.After(asOf) {
+			continue
+		}
+
+		balance += t.SignedAmount(userID)
+	}
+
+	return roundCents(balance), nil
+}
+
+// GetTransactionHistory returns the transactions of the user, newest first
+func (r *memoryRepository) GetTransactionHistory(_ context.Context, userID int) ([]Transaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	//nolint:prealloc // the number of matching transactions is unknown
+	var txs []Transaction
+
+	for _, t := range r.transactions {
+		if t.FromUserID == userID || t.ToUserID == userID {
+			txs = append(txs, t)
+		}
+	}
+
+	sort.SliceStable(txs, func(i, j int) bool {
+		return txs[i].TransactionID > txs[j].TransactionID
+	})
+
+	return txs, nil
+}
+
+// GetTransactionsBetween returns the transactions of the user from from up to but
+// excluding to, newest first
+func (r *memoryRepository) GetTransactionsBetween(
+	ctx context.Context,
+	userID int,
+	from, to time.Time,
+) ([]Transaction, error) {
+	history, err := r.GetTransactionHistory(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	//nolint:prealloc // the number of matching transactions is unknown
+	var txs []Transaction
+
+	for _, t := range history {
+		at, err := time.Parse(time.RFC3339Nano, t.Timestamp)
+		if err != nil || at.Before(from) || !at.Before(to) {
+			continue
+		}
+
+		txs = append(txs, t)
+	}
+
+	return txs, nil
+}
+
+// GetStatement returns a stored statement
+func (r *memoryRepository) GetStatement(_ context.Context, userID int, period string) (Statement, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, ok := r.statements[statementKey{userID, period}]
+	if !ok {
+		return Statement{}, fmt.Errorf("failed to get statement %s of user %d: %w", period, userID, ErrStatementNotFound)
+	}
+
+	var statement Statement
+	if err := json.Unmarshal(data, &statement); err != nil {
+		return Statement{}, fmt.Errorf("failed to decode statement %s of user %d: %w", period, userID, err)
+	}
+
+	return statement, nil
+}
+
+// SaveStatement stores the statement as JSON like the Postgres repository, the first
+// statement of a period is kept
+func (r *memoryRepository) SaveStatement(ctx context.Context, statement Statement) (Statement, error) {
+	data, err := json.Marshal(statement)
+	if err != nil {
+		return Statement{}, fmt.Errorf("failed to encode statement %s of user %d: %w", statement.Period, statement.UserID, err)
+	}
+
+	r.mu.Lock()
+
+	key := statementKey{statement.UserID, statement.Period}
+	if _, ok := r.statements[key]; !ok {
+		r.statements[key] = data
+	}
+
+	r.mu.Unlock()
+
+	return r.GetStatement(ctx, statement.UserID, statement.Period)
+}
@@ -0,0 +1,47 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryRepository_GetBalanceAsOf(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	repo := newMemoryRepository(map[int]float64{1: 100, 2: 50})
+
+	clock := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	repo.now = func() time.Time { return clock }
+
+	_, _ = repo.Deposit(ctx, 1, 10)
+
+	clock = clock.Add(24 * time.Hour)
+	_, _, _ = repo.Transfer(ctx, 1, 2, 30)
+
+	monthEnd := time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC)
+
+	for _, tc := range []struct {
+		userID   int
+		asOf     time.Time
+		expected float64
+	}{
+		{1, monthEnd.AddDate(0, 0, -1), 100},
+		{1, monthEnd, 110},
+		{2, monthEnd, 50},
+		{1, clock, 80},
+		{2, clock, 80},
+	} {
+		// Act
+		balance, err := repo.GetBalanceAsOf(ctx, tc.userID, tc.asOf)
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if balance != tc.expected {
+			t.Fatalf("expected balance of user %d as of %s to be %v, got %v", tc.userID, tc.asOf, tc.expected, balance)
+		}
+	}
+}
@@ -5,25 +5,31 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"go.uber.org/goleak"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/amelonpie/wallet-service/internal/database"
 	_ "github.com/lib/pq"
 )
 
-// func TestMain(m *testing.M) {
-// 	goleak.VerifyTestMain(m)
-// }
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
 
 //nolint:ireturn // stick to interface
-func setupMockDB() (Repository, sqlmock.Sqlmock) {
+func setupMockDB(t *testing.T) (Repository, sqlmock.Sqlmock) {
+	t.Helper()
+
 	db, mockSQL, err := sqlmock.New() // mock db
 	if err != nil {
-		panic(err)
+		t.Fatal(err)
 	}
 
-	repo := newWalletRepository(db)
+	t.Cleanup(func() { db.Close() })
+
+	repo := NewRepository(db)
 
 	return repo, mockSQL
 }
@@ -31,7 +37,7 @@ func setupMockDB() (Repository, sqlmock.Sqlmock) {
 /* Normal case */
 func TestDeposit(t *testing.T) {
 	// Arrange
-	repo, mockSQL := setupMockDB()
+	repo, mockSQL := setupMockDB(t)
 
 	userID := 1
 	amount := 100.00
@@ -39,9 +45,9 @@ func TestDeposit(t *testing.T) {
 
 	// Assert
 	mockSQL.ExpectBegin()
-	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1 WHERE user_id = \$2 RETURNING balance`).
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen RETURNING balance, version`).
 		WithArgs(amount, userID).
-		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(newBalance))
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(newBalance, 3))
 	mockSQL.ExpectExec(`INSERT INTO transactions \(from_user_id, to_user_id, amount, transaction_type\)`).
 		WithArgs(userID, nil, amount, "deposit").
 		WillReturnResult(sqlmock.NewResult(1, 1))
@@ -55,8 +61,8 @@ func TestDeposit(t *testing.T) {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
-	if updatedBalance != newBalance {
-		t.Fatalf("expected balance to be %v, got %v", newBalance, updatedBalance)
+	if updatedBalance.Amount != newBalance {
+		t.Fatalf("expected balance to be %v, got %v", newBalance, updatedBalance.Amount)
 	}
 
 	if err := mockSQL.ExpectationsWereMet(); err != nil {
@@ -66,7 +72,7 @@ func TestDeposit(t *testing.T) {
 
 func TestWithdraw(t *testing.T) {
 	// Arrange
-	repo, mockSQL := setupMockDB()
+	repo, mockSQL := setupMockDB(t)
 
 	userID := 1
 	amount := 50.00
@@ -74,9 +80,9 @@ func TestWithdraw(t *testing.T) {
 
 	// Assert
 	mockSQL.ExpectBegin()
-	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance - \$1 WHERE user_id = \$2 RETURNING balance`).
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance - \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen AND balance >= \$1 RETURNING balance, version`).
 		WithArgs(amount, userID).
-		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(newBalance))
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(newBalance, 3))
 	mockSQL.ExpectExec(`INSERT INTO transactions \(from_user_id, to_user_id, amount, transaction_type\)`).
 		WithArgs(userID, nil, amount, "withdraw").
 		WillReturnResult(sqlmock.NewResult(1, 1))
@@ -90,8 +96,8 @@ func TestWithdraw(t *testing.T) {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
-	if updatedBalance != newBalance {
-		t.Fatalf("expected balance to be %v, got %v", newBalance, updatedBalance)
+	if updatedBalance.Amount != newBalance {
+		t.Fatalf("expected balance to be %v, got %v", newBalance, updatedBalance.Amount)
 	}
 
 	if err := mockSQL.ExpectationsWereMet(); err != nil {
@@ -101,7 +107,7 @@ func TestWithdraw(t *testing.T) {
 
 func TestTransfer(t *testing.T) {
 	// Arrange
-	repo, mockSQL := setupMockDB()
+	repo, mockSQL := setupMockDB(t)
 
 	fromUserID := 1
 	toUserID := 2
@@ -112,14 +118,14 @@ func TestTransfer(t *testing.T) {
 	mockSQL.ExpectBegin()
 
 	// Assert: withdraw from user 1
-	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance - \$1 WHERE user_id = \$2 RETURNING balance`).
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance - \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen AND balance >= \$1 RETURNING balance, version`).
 		WithArgs(amount, fromUserID).
-		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(fromNewBalance))
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(fromNewBalance, 3))
 
 	// Assert: deposit to user 2
-	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1 WHERE user_id = \$2 RETURNING balance`).
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen RETURNING balance, version`).
 		WithArgs(amount, toUserID).
-		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(toNewBalance))
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(toNewBalance, 3))
 	mockSQL.ExpectExec(`INSERT INTO transactions \(from_user_id, to_user_id, amount, transaction_type\)`).
 		WithArgs(fromUserID, toUserID, amount, "transfer").
 		WillReturnResult(sqlmock.NewResult(1, 1))
@@ -134,11 +140,11 @@ func TestTransfer(t *testing.T) {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
-	if fromBalance != fromNewBalance {
+	if fromBalance.Amount != fromNewBalance {
 		t.Fatalf("expected from user balance to be %v, got %v", fromNewBalance, fromBalance)
 	}
 
-	if toBalance != toNewBalance {
+	if toBalance.Amount != toNewBalance {
 		t.Fatalf("expected to user balance to be %v, got %v", toNewBalance, toBalance)
 	}
 
@@ -148,15 +154,15 @@ func TestTransfer(t *testing.T) {
 }
 
 func TestGetBalance(t *testing.T) {
-	repo, mockSQL := setupMockDB()
+	repo, mockSQL := setupMockDB(t)
 
 	userID := 1
 	expectedBalance := 100.00
 
 	// Assert
-	mockSQL.ExpectQuery(`SELECT balance FROM wallets WHERE user_id=\$1`).
+	mockSQL.ExpectQuery(`SELECT balance, version FROM wallets WHERE user_id=\$1`).
 		WithArgs(userID).
-		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(expectedBalance))
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(expectedBalance, 3))
 
 	// Act
 	balance, err := repo.GetBalance(context.Background(), userID)
@@ -166,7 +172,7 @@ func TestGetBalance(t *testing.T) {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
-	if balance != expectedBalance {
+	if balance.Amount != expectedBalance {
 		t.Fatalf("expected balance to be %v, got %v", expectedBalance, balance)
 	}
 
@@ -177,7 +183,7 @@ func TestGetBalance(t *testing.T) {
 
 func TestGetTransactionHistory(t *testing.T) {
 	// Arrange
-	repo, mockSQL := setupMockDB()
+	repo, mockSQL := setupMockDB(t)
 
 	userID := 1
 	expectedTransactions := []Transaction{
@@ -231,13 +237,13 @@ func TestGetTransactionHistory(t *testing.T) {
 // Deposit
 func TestDeposit_Error(t *testing.T) {
 	// Arrange
-	repo, mockSQL := setupMockDB()
+	repo, mockSQL := setupMockDB(t)
 
 	userID := 1
 	amount := 100.00
 
 	mockSQL.ExpectBegin()
-	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1 WHERE user_id = \$2 RETURNING balance`).
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen RETURNING balance, version`).
 		WithArgs(amount, userID).
 		WillReturnError(sql.ErrConnDone)
 	mockSQL.ExpectRollback()
@@ -261,7 +267,7 @@ func TestDeposit_Error(t *testing.T) {
 
 func TestDeposit_ErrorInCommit(t *testing.T) {
 	// Arrange
-	repo, mockSQL := setupMockDB()
+	repo, mockSQL := setupMockDB(t)
 
 	userID := 1
 	amount := 100.00
@@ -269,9 +275,9 @@ func TestDeposit_ErrorInCommit(t *testing.T) {
 
 	mockSQL.ExpectBegin()
 
-	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1 WHERE user_id = \$2 RETURNING balance`).
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen RETURNING balance, version`).
 		WithArgs(amount, userID).
-		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(newBalance))
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(newBalance, 3))
 	mockSQL.ExpectExec(`INSERT INTO transactions \(from_user_id, to_user_id, amount, transaction_type\)`).
 		WithArgs(userID, nil, amount, "deposit").
 		WillReturnResult(sqlmock.NewResult(1, 1))
@@ -292,7 +298,7 @@ func TestDeposit_ErrorInCommit(t *testing.T) {
 		t.Fatalf("expected: %v, got: %v", expectedErr, err)
 	}
 
-	if updatedBalance != 0 {
+	if updatedBalance != (Balance{}) {
 		t.Fatalf("expected updated balance to be 0, got: %v", updatedBalance)
 	}
 
@@ -303,7 +309,7 @@ func TestDeposit_ErrorInCommit(t *testing.T) {
 
 func TestDeposit_ErrorInLogTransaction(t *testing.T) {
 	// Arrange
-	repo, mockSQL := setupMockDB()
+	repo, mockSQL := setupMockDB(t)
 
 	userID := 1
 	amount := 100.00
@@ -311,9 +317,9 @@ func TestDeposit_ErrorInLogTransaction(t *testing.T) {
 
 	// Assert
 	mockSQL.ExpectBegin()
-	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1 WHERE user_id = \$2 RETURNING balance`).
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen RETURNING balance, version`).
 		WithArgs(amount, userID).
-		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(newBalance))
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(newBalance, 3))
 	mockSQL.ExpectExec(`INSERT INTO transactions \(from_user_id, to_user_id, amount, transaction_type\)`).
 		WithArgs(userID, nil, amount, "deposit").
 		WillReturnError(sql.ErrConnDone)
@@ -327,8 +333,8 @@ func TestDeposit_ErrorInLogTransaction(t *testing.T) {
 		t.Fatalf("expected error, got nil")
 	}
 
-	if updatedBalance != 0 {
-		t.Fatalf("expected balance to be %v, got %v", newBalance, updatedBalance)
+	if updatedBalance != (Balance{}) {
+		t.Fatalf("expected balance to be %v, got %v", newBalance, updatedBalance.Amount)
 	}
 
 	if err := mockSQL.ExpectationsWereMet(); err != nil {
@@ -338,13 +344,13 @@ func TestDeposit_ErrorInLogTransaction(t *testing.T) {
 
 func TestWithdraw_Error(t *testing.T) {
 	// Arrange
-	repo, mockSQL := setupMockDB()
+	repo, mockSQL := setupMockDB(t)
 
 	userID := 1
 	amount := 50.00
 
 	mockSQL.ExpectBegin()
-	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance - \$1 WHERE user_id = \$2 RETURNING balance`).
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance - \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen AND balance >= \$1 RETURNING balance, version`).
 		WithArgs(amount, userID).
 		WillReturnError(sql.ErrConnDone)
 	mockSQL.ExpectRollback()
@@ -366,9 +372,211 @@ func TestWithdraw_Error(t *testing.T) {
 	}
 }
 
+func TestWithdraw_InsufficientFunds(t *testing.T) {
+	// Arrange
+	repo, mockSQL := setupMockDB(t)
+
+	userID := 1
+	amount := 500.00
+
+	mockSQL.ExpectBegin()
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance - \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen AND balance >= \$1 RETURNING balance, version`).
+		WithArgs(amount, userID).
+		WillReturnError(sql.ErrNoRows)
+	mockSQL.ExpectQuery(`SELECT balance, frozen FROM wallets WHERE user_id = \$1`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "frozen"}).AddRow(100.00, false))
+	mockSQL.ExpectRollback()
+
+	// Act
+	_, err := repo.Withdraw(context.Background(), userID, amount)
+
+	// Assert
+	if !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("expected error %v, got %v", ErrInsufficientFunds, err)
+	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestWithdraw_WalletNotFound(t *testing.T) {
+	// Arrange
+	repo, mockSQL := setupMockDB(t)
+
+	userID := 1
+	amount := 50.00
+
+	mockSQL.ExpectBegin()
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance - \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen AND balance >= \$1 RETURNING balance, version`).
+		WithArgs(amount, userID).
+		WillReturnError(sql.ErrNoRows)
+	mockSQL.ExpectQuery(`SELECT balance, frozen FROM wallets WHERE user_id = \$1`).
+		WithArgs(userID).
+		WillReturnError(sql.ErrNoRows)
+	mockSQL.ExpectRollback()
+
+	// Act
+	_, err := repo.Withdraw(context.Background(), userID, amount)
+
+	// Assert
+	if !errors.Is(err, ErrWalletNotFound) {
+		t.Fatalf("expected error %v, got %v", ErrWalletNotFound, err)
+	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestDeposit_WalletFrozen(t *testing.T) {
+	// Arrange
+	repo, mockSQL := setupMockDB(t)
+
+	userID := 1
+	amount := 50.00
+
+	mockSQL.ExpectBegin()
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen RETURNING balance, version`).
+		WithArgs(amount, userID).
+		WillReturnError(sql.ErrNoRows)
+	mockSQL.ExpectQuery(`SELECT balance, frozen FROM wallets WHERE user_id = \$1`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "frozen"}).AddRow(100.00, true))
+	mockSQL.ExpectRollback()
+
+	// Act
+	_, err := repo.Deposit(context.Background(), userID, amount)
+
+	// Assert
+	if !errors.Is(err, ErrWalletFrozen) {
+		t.Fatalf("expected error %v, got %v", ErrWalletFrozen, err)
+	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestDepositIdempotent_DuplicateKey(t *testing.T) {
+	// Arrange
+	repo, mockSQL := setupMockDB(t)
+
+	userID := 1
+	amount := 50.00
+	key := "bank:REF-1"
+
+	mockSQL.ExpectBegin()
+	mockSQL.ExpectExec(`INSERT INTO deposit_keys \(idempotency_key, user_id, amount\)`).
+		WithArgs(key, userID, amount).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mockSQL.ExpectRollback()
+
+	// Act
+	_, err := repo.DepositIdempotent(context.Background(), userID, amount, key)
+
+	// Assert
+	if !errors.Is(err, ErrDuplicateDeposit) {
+		t.Fatalf("expected error %v, got %v", ErrDuplicateDeposit, err)
+	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestWithdrawIdempotent_DuplicateKey(t *testing.T) {
+	// Arrange
+	repo, mockSQL := setupMockDB(t)
+
+	userID := 1
+	amount := 50.00
+	key := "payout:7"
+
+	mockSQL.ExpectBegin()
+	mockSQL.ExpectExec(`INSERT INTO deposit_keys \(idempotency_key, user_id, amount\)`).
+		WithArgs(key, userID, amount).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mockSQL.ExpectRollback()
+
+	// Act
+	_, err := repo.WithdrawIdempotent(context.Background(), userID, amount, key)
+
+	// Assert
+	if !errors.Is(err, ErrDuplicateWithdraw) {
+		t.Fatalf("expected error %v, got %v", ErrDuplicateWithdraw, err)
+	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestGetBalanceAsOf(t *testing.T) {
+	// Arrange
+	repo, mockSQL := setupMockDB(t)
+
+	userID := 1
+	asOf := time.Date(2024, 1, 31, 23, 59, 59, 0, time.FixedZone("CET", 3600))
+
+	mockSQL.ExpectQuery(`SELECT COALESCE\(s.balance, 0\) \+ COALESCE\(`).
+		WithArgs(userID, asOf.UTC()).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(120.50))
+
+	// Act
+	balance, err := repo.GetBalanceAsOf(context.Background(), userID, asOf)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if balance != 120.50 {
+		t.Fatalf("expected balance 120.50, got %v", balance)
+	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestTransfer_RecipientNotFound(t *testing.T) {
+	// Arrange
+	repo, mockSQL := setupMockDB(t)
+
+	fromUserID := 1
+	toUserID := 2
+	amount := 30.00
+
+	mockSQL.ExpectBegin()
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance - \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen AND balance >= \$1 RETURNING balance, version`).
+		WithArgs(amount, fromUserID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(20.00, 3))
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen RETURNING balance, version`).
+		WithArgs(amount, toUserID).
+		WillReturnError(sql.ErrNoRows)
+	mockSQL.ExpectQuery(`SELECT balance, frozen FROM wallets WHERE user_id = \$1`).
+		WithArgs(toUserID).
+		WillReturnError(sql.ErrNoRows)
+	mockSQL.ExpectRollback()
+
+	// Act
+	_, _, err := repo.Transfer(context.Background(), fromUserID, toUserID, amount)
+
+	// Assert
+	if !errors.Is(err, ErrRecipientNotFound) {
+		t.Fatalf("expected error %v, got %v", ErrRecipientNotFound, err)
+	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
 func TestTransfer_Error(t *testing.T) {
 	// Arrange
-	repo, mockSQL := setupMockDB()
+	repo, mockSQL := setupMockDB(t)
 
 	fromUserID := 1
 	toUserID := 2
@@ -377,7 +585,7 @@ func TestTransfer_Error(t *testing.T) {
 	// Mock connection error
 	mockSQL.ExpectBegin()
 	// withdraw from user 1
-	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance - \$1 WHERE user_id = \$2 RETURNING balance`).
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance - \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen AND balance >= \$1 RETURNING balance, version`).
 		WithArgs(amount, fromUserID).
 		WillReturnError(sql.ErrConnDone)
 	mockSQL.ExpectRollback()
@@ -401,11 +609,11 @@ func TestTransfer_Error(t *testing.T) {
 
 func TestGetBalance_Error(t *testing.T) {
 	// Arrange
-	repo, mockSQL := setupMockDB()
+	repo, mockSQL := setupMockDB(t)
 
 	userID := 1
 
-	mockSQL.ExpectQuery(`SELECT balance FROM wallets WHERE user_id=\$1`).
+	mockSQL.ExpectQuery(`SELECT balance, version FROM wallets WHERE user_id=\$1`).
 		WithArgs(userID).
 		WillReturnError(sql.ErrConnDone)
 	// Act
@@ -427,7 +635,7 @@ func TestGetBalance_Error(t *testing.T) {
 
 func TestGetTransactionHistory_Error(t *testing.T) {
 	// Arrange
-	repo, mockSQL := setupMockDB()
+	repo, mockSQL := setupMockDB(t)
 
 	userID := 1
 
@@ -486,3 +694,157 @@ func TestGetTransactionHistory_ScanError(t *testing.T) {
 		t.Errorf("there were unmet expectations: %v", err)
 	}
 }
+
+func setupReplicatedMockDB(t *testing.T, maxLag time.Duration) (Repository, *database.ReplicaSet, sqlmock.Sqlmock, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mockSQL, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replica, mockReplica, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		db.Close()
+		replica.Close()
+	})
+
+	replicas := database.NewReplicaSet(db, []*sql.DB{replica}, database.ReplicaConfig{MaxLag: maxLag, CheckInterval: time.Second})
+
+	return NewReplicatedRepository(db, replicas), replicas, mockSQL, mockReplica
+}
+
+func TestGetBalance_ReadsFromCaughtUpReplica(t *testing.T) {
+	repo, replicas, mockSQL, mockReplica := setupReplicatedMockDB(t, 2*time.Second)
+
+	mockReplica.ExpectQuery(`pg_is_in_recovery`).
+		WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(0.5))
+	replicas.Check(context.Background())
+
+	// Arrange
+	mockReplica.ExpectQuery(`SELECT balance, version FROM wallets WHERE user_id=\$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(100.00, 3))
+
+	// Act
+	balance, err := repo.GetBalance(context.Background(), 1)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if balance.Amount != 100.00 {
+		t.Fatalf("expected balance to be 100, got %v", balance)
+	}
+
+	if err := mockReplica.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet replica expectations: %v", err)
+	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet primary expectations: %v", err)
+	}
+}
+
+func TestGetBalance_LaggingReplicaReadsFromPrimary(t *testing.T) {
+	repo, replicas, mockSQL, mockReplica := setupReplicatedMockDB(t, 2*time.Second)
+
+	mockReplica.ExpectQuery(`pg_is_in_recovery`).
+		WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(30.0))
+	replicas.Check(context.Background())
+
+	// Arrange
+	mockSQL.ExpectQuery(`SELECT balance, version FROM wallets WHERE user_id=\$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(100.00, 3))
+
+	// Act
+	_, err := repo.GetBalance(context.Background(), 1)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := mockReplica.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet replica expectations: %v", err)
+	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet primary expectations: %v", err)
+	}
+}
+
+func TestGetTransactionHistory_ReplicaErrorFallsBackToPrimary(t *testing.T) {
+	repo, replicas, mockSQL, mockReplica := setupReplicatedMockDB(t, 2*time.Second)
+
+	mockReplica.ExpectQuery(`pg_is_in_recovery`).
+		WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(0))
+	replicas.Check(context.Background())
+
+	// Arrange
+	mockReplica.ExpectQuery(`SELECT (.+) FROM transactions`).
+		WithArgs(1).
+		WillReturnError(errors.New("connection reset"))
+	mockSQL.ExpectQuery(`SELECT (.+) FROM transactions`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "from_user_id", "to_user_id", "amount", "transaction_type", "timestamp"}))
+
+	// Act
+	_, err := repo.GetTransactionHistory(context.Background(), 1)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := mockReplica.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet replica expectations: %v", err)
+	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet primary expectations: %v", err)
+	}
+}
+
+func TestGetTransactionsBetween_ReadsPrimary(t *testing.T) {
+	repo, replicas, mockSQL, mockReplica := setupReplicatedMockDB(t, 2*time.Second)
+
+	mockReplica.ExpectQuery(`pg_is_in_recovery`).
+		WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(0))
+	replicas.Check(context.Background())
+
+	// Arrange: the replica is caught up, the period is read from the primary all the same
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	mockSQL.ExpectQuery(`SELECT (.+) FROM transactions WHERE \(from_user_id = \$1 OR to_user_id = \$1\) AND timestamp >= \$2 AND timestamp < \$3`).
+		WithArgs(1, from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "from_user_id", "to_user_id", "amount", "transaction_type", "timestamp"}).
+			AddRow(4, 1, nil, 10.00, "deposit", "2024-01-31T23:59:00Z"))
+
+	// Act
+	txs, err := repo.GetTransactionsBetween(context.Background(), 1, from, to)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(txs) != 1 || txs[0].TransactionID != 4 {
+		t.Fatalf("expected transaction 4, got %v", txs)
+	}
+
+	if err := mockReplica.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet replica expectations: %v", err)
+	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet primary expectations: %v", err)
+	}
+}
@@ -2,142 +2,246 @@ package wallet
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strconv"
+	"time"
 
-	"github.com/amelonpie/wallet-service/internal/database"
-	"github.com/redis/go-redis/v9"
+	"github.com/amelonpie/wallet-service/pkg/breaker"
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/sirupsen/logrus"
 )
 
+// InitService builds the wallet service with the cache selected by cache.backend.
+// Notifiers are called for every committed balance change.
+//
 //nolint:ireturn // stick to interface
-func InitService(repo Repository) (Service, error) {
-	dbConfig := database.NewDatabaseConfig()
-
-	cache, err := dbConfig.ConnectRedis()
+func InitService(repo Repository, notifiers ...Notifier) (Service, error) {
+	cache, err := InitCache(NewCacheConfig())
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+		return nil, fmt.Errorf("failed to initialize balance cache: %w", err)
 	}
 
-	return newWalletService(repo, cache), nil
+	return traced(newWalletService(repo, cache, notifiers...)), nil
 }
 
+// NewService builds the wallet service on a cache shared with other components
+//
 //nolint:ireturn // stick to interface
-func newWalletService(repo Repository, cache *redis.Client) Service {
+func NewService(repo Repository, cache BalanceCache, notifiers ...Notifier) Service {
+	return traced(newWalletService(repo, cache, notifiers...))
+}
+
+func newWalletService(repo Repository, cache BalanceCache, notifiers ...Notifier) *walletService {
 	return &walletService{
-		repo:  repo,
-		cache: cache,
+		repo:      repo,
+		cache:     cache,
+		notifiers: notifiers,
+		logger:    log.NewLogger("wallet").WithField("module", "service"),
+		now:       time.Now,
+	}
+}
+
+// CacheState reports whether balances are currently cached or served from Postgres only.
+// Caches that cannot fail are always closed.
+func (s *walletService) CacheState() breaker.State {
+	if guarded, ok := s.cache.(interface{ State() breaker.State }); ok {
+		return guarded.State()
+	}
+
+	return breaker.Closed
+}
+
+// invalidate drops the cached balance after a committed write of the given version
+func (s *walletService) invalidate(ctx context.Context, userID int, version int64) {
+	if err := s.cache.Invalidate(ctx, userID, version); err != nil {
+		s.logCacheError(ctx, err, userID, "failed to invalidate cached balance")
+	}
+}
+
+// logCacheError only logs: the database is the source of truth and a failing cache
+// must not fail a request whose money already moved. Nothing is logged per request
+// while the circuit is open, the breaker logs its state changes.
+func (s *walletService) logCacheError(ctx context.Context, err error, userID int, msg string) {
+	if errors.Is(err, errCacheUnavailable) {
+		return
+	}
+
+	log.FromContext(ctx, s.logger).WithFields(logrus.Fields{
+		"err":     err,
+		"user_id": userID,
+	}).Warn(msg)
+}
+
+func (s *walletService) notify(ctx context.Context, event Event) {
+	notify(ctx, s.notifiers, s.logger, event)
+}
+
+// notify hands the event to every notifier. The money already moved, so a failing
+// notifier is only logged and never fails the request.
+func notify(ctx context.Context, notifiers []Notifier, logger *logrus.Entry, event Event) {
+	event.OccurredAt = time.Now().UTC()
+
+	for _, n := range notifiers {
+		if err := n.Notify(ctx, event); err != nil {
+			log.FromContext(ctx, logger).WithFields(logrus.Fields{
+				"err":     err,
+				"event":   event.Type,
+				"user_id": event.UserID,
+			}).Error("failed to notify wallet event")
+		}
 	}
 }
 
+// checkAmount refuses an amount that is not positive: the repositories add and
+// subtract it as it is, a negative deposit would take money out of the wallet
+// without the overdraft check of a withdrawal
+func checkAmount(amount float64) error {
+	if amount <= 0 {
+		return fmt.Errorf("%w: %v", ErrInvalidAmount, amount)
+	}
+
+	return nil
+}
+
 func (s *walletService) Deposit(ctx context.Context, userID int, amount float64) (float64, error) {
+	if err := checkAmount(amount); err != nil {
+		return 0, err
+	}
+
 	newBalance, err := s.repo.Deposit(ctx, userID, amount)
+	observe(EventDeposit, amount, err)
+
 	if err != nil {
 		return 0, fmt.Errorf("failed to deposit to database for user %d: %w", userID, err)
 	}
 
-	// Update Redis cache
-	key := fmt.Sprintf("wallet_balance:%d", userID)
-	err = s.cache.Set(ctx, key, newBalance, 0).Err()
+	s.invalidate(ctx, userID, newBalance.Version)
+	s.notify(ctx, Event{Type: EventDeposit, UserID: userID, Amount: amount, Balance: newBalance.Amount})
+
+	return newBalance.Amount, nil
+}
+
+// DepositIdempotent deposits once per key, e.g. a bank reference, so a retried or
+// re-imported deposit is not booked twice. A repeated key returns ErrDuplicateDeposit.
+func (s *walletService) DepositIdempotent(ctx context.Context, userID int, amount float64, key string) (float64, error) {
+	if err := checkAmount(amount); err != nil {
+		return 0, err
+	}
+
+	newBalance, err := s.repo.DepositIdempotent(ctx, userID, amount, key)
+	observe(EventDeposit, amount, err)
 
 	if err != nil {
-		return 0, fmt.Errorf("failed to update redis for user %d: %w", userID, err)
+		return 0, fmt.Errorf("failed to deposit to database for user %d: %w", userID, err)
 	}
 
-	return newBalance, nil
+	s.invalidate(ctx, userID, newBalance.Version)
+	s.notify(ctx, Event{Type: EventDeposit, UserID: userID, Amount: amount, Balance: newBalance.Amount})
+
+	return newBalance.Amount, nil
 }
 
+// Withdraw relies on the database to reject overdrafts, a cached balance may be stale
 func (s *walletService) Withdraw(ctx context.Context, userID int, amount float64) (float64, error) {
-	// Check balance
-	balance, err := s.GetBalance(ctx, userID)
-	if err != nil {
+	if err := checkAmount(amount); err != nil {
 		return 0, err
 	}
 
-	if balance < amount {
-		return 0, errorFactory().InsufficientFunds
-	}
-
 	newBalance, err := s.repo.Withdraw(ctx, userID, amount)
+	observe(EventWithdraw, amount, err)
+
 	if err != nil {
 		return 0, fmt.Errorf("failed to update database for user %d: %w", userID, err)
 	}
 
-	// Update Redis cache
-	key := fmt.Sprintf("wallet_balance:%d", userID)
-	err = s.cache.Set(ctx, key, newBalance, 0).Err()
+	s.invalidate(ctx, userID, newBalance.Version)
+	s.notify(ctx, Event{Type: EventWithdraw, UserID: userID, Amount: -amount, Balance: newBalance.Amount})
 
-	if err != nil {
-		return 0, fmt.Errorf("failed to update redis for user %d: %w", userID, err)
+	return newBalance.Amount, nil
+}
+
+// WithdrawIdempotent withdraws once per key, e.g. a payout, so a retried withdrawal
+// is not booked twice. A repeated key returns ErrDuplicateWithdraw.
+func (s *walletService) WithdrawIdempotent(ctx context.Context, userID int, amount float64, key string) (float64, error) {
+	if err := checkAmount(amount); err != nil {
+		return 0, err
 	}
 
-	return newBalance, nil
-}
+	newBalance, err := s.repo.WithdrawIdempotent(ctx, userID, amount, key)
+	observe(EventWithdraw, amount, err)
 
-func (s *walletService) Transfer(ctx context.Context, fromUserID, toUserID int, amount float64) (float64, float64, error) {
-	// Check fromUserID balance
-	fromBalance, err := s.GetBalance(ctx, fromUserID)
 	if err != nil {
-		return 0, 0, err
+		return 0, fmt.Errorf("failed to update database for user %d: %w", userID, err)
 	}
 
-	if fromBalance < amount {
-		return 0, 0, errorFactory().InsufficientFunds
-	}
+	s.invalidate(ctx, userID, newBalance.Version)
+	s.notify(ctx, Event{Type: EventWithdraw, UserID: userID, Amount: -amount, Balance: newBalance.Amount})
 
-	_, err = s.GetBalance(ctx, toUserID)
-	if err != nil {
-		return 0, 0, errorFactory().RecipientNotFound
+	return newBalance.Amount, nil
+}
+
+// Transfer relies on the database to reject overdrafts and unknown recipients
+func (s *walletService) Transfer(ctx context.Context, fromUserID, toUserID int, amount float64) (float64, float64, error) {
+	if err := checkAmount(amount); err != nil {
+		return 0, 0, err
 	}
 
 	newFromBalance, newToBalance, err := s.repo.Transfer(ctx, fromUserID, toUserID, amount)
+	observe(EventTransfer, amount, err)
+
 	if err != nil {
 		return 0, 0, fmt.Errorf("failed to update database: %w", err)
 	}
 
-	// Update Redis caches
-	fromKey := fmt.Sprintf("wallet_balance:%d", fromUserID)
-	toKey := fmt.Sprintf("wallet_balance:%d", toUserID)
-
-	// Update fromUser
-	err = s.cache.Set(ctx, fromKey, newFromBalance, 0).Err()
-	if err != nil {
-		return 0, 0, fmt.Errorf("failed to update redis: %w", err)
-	}
+	s.invalidate(ctx, fromUserID, newFromBalance.Version)
+	s.invalidate(ctx, toUserID, newToBalance.Version)
 
-	// Update toUser
-	err = s.cache.Set(ctx, toKey, newToBalance, 0).Err()
-	if err != nil {
-		return 0, 0, fmt.Errorf("failed to update redis: %w", err)
-	}
+	s.notify(ctx, Event{
+		Type: EventTransfer, UserID: fromUserID, CounterpartyID: toUserID, Amount: -amount, Balance: newFromBalance.Amount,
+	})
+	s.notify(ctx, Event{
+		Type: EventTransfer, UserID: toUserID, CounterpartyID: fromUserID, Amount: amount, Balance: newToBalance.Amount,
+	})
 
-	return newFromBalance, newToBalance, nil
+	return newFromBalance.Amount, newToBalance.Amount, nil
 }
 
 func (s *walletService) GetBalance(ctx context.Context, userID int) (float64, error) {
-	// Check Redis first
-	key := fmt.Sprintf("wallet_balance:%d", userID)
-	cachedBalance, err := s.cache.Get(ctx, key).Result()
+	// Check the cache first
+	cached, ok, err := s.cache.Get(ctx, userID)
+	if err != nil {
+		cacheRequestsTotal.WithLabelValues(cacheError).Inc()
+		s.logCacheError(ctx, err, userID, "failed to read cached balance")
+	}
 
-	if err == nil {
-		// If found in cache, parse to float
-		var balance float64
-		balance, err = strconv.ParseFloat(cachedBalance, 64)
+	if ok {
+		cacheRequestsTotal.WithLabelValues(cacheHit).Inc()
+		return cached, nil
+	}
 
-		if err == nil {
-			return balance, nil
-		}
+	if err == nil {
+		cacheRequestsTotal.WithLabelValues(cacheMiss).Inc()
 	}
 
 	// Fallback to Postgres
 	balance, err := s.repo.GetBalance(ctx, userID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get balance to get balance for user %d: %w", userID, err)
+		return 0, fmt.Errorf("failed to get balance for user %d: %w", userID, err)
 	}
 
-	// Update cache for next time
-	err = s.cache.Set(ctx, key, balance, 0).Err()
+	// Update cache for next time, unless a newer version got there first
+	if err = s.cache.Set(ctx, userID, balance); err != nil {
+		s.logCacheError(ctx, err, userID, "failed to repopulate cached balance")
+	}
+
+	return balance.Amount, nil
+}
+
+// GetBalanceAsOf returns the balance at a point in time, it is never cached
+func (s *walletService) GetBalanceAsOf(ctx context.Context, userID int, asOf time.Time) (float64, error) {
+	balance, err := s.repo.GetBalanceAsOf(ctx, userID, asOf)
 	if err != nil {
-		return 0, fmt.Errorf("failed to update redis for user %d: %w", userID, err)
+		return 0, fmt.Errorf("failed to get balance for user %d as of %s: %w", userID, asOf.Format(time.RFC3339), err)
 	}
 
 	return balance, nil
@@ -152,3 +256,15 @@ func (s *walletService) GetTransactionHistory(ctx context.Context, userID int) (
 
 	return txs, nil
 }
+
+// GetTransactionsAsOf reads the history from the primary, so it agrees with the
+// balance GetBalanceAsOf returns for the same instant
+func (s *walletService) GetTransactionsAsOf(ctx context.Context, userID int, asOf time.Time) ([]Transaction, error) {
+	// timestamps have microsecond precision, the end of the range is exclusive
+	txs, err := s.repo.GetTransactionsBetween(ctx, userID, time.Time{}, asOf.Add(time.Microsecond))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions for user %d as of %s: %w", userID, asOf.Format(time.RFC3339), err)
+	}
+
+	return txs, nil
+}
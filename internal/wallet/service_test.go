@@ -4,46 +4,103 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
-	"github.com/go-redis/redismock/v9"
+	"github.com/amelonpie/wallet-service/pkg/breaker"
 )
 
-//nolint:ireturn // stick to interface
-func setupMockRepo() (Service, sqlmock.Sqlmock, redismock.ClientMock) {
+//nolint:gochecknoglobals // read-only test fixture
+var testCacheConfig = CacheConfig{
+	Backend:         CacheBackendMemory,
+	MemorySize:      100,
+	BalanceTTL:      10 * time.Minute,
+	InvalidationTTL: time.Minute,
+	Breaker:         breaker.Config{FailureThreshold: 5, OpenTimeout: time.Minute},
+}
+
+var errCacheDown = errors.New("connection refused")
+
+// flakyCache fails every call while err is set
+type flakyCache struct {
+	BalanceCache
+	err   error
+	calls int
+}
+
+func (f *flakyCache) Get(ctx context.Context, userID int) (float64, bool, error) {
+	f.calls++
+	if f.err != nil {
+		return 0, false, f.err
+	}
+
+	return f.BalanceCache.Get(ctx, userID)
+}
+
+func (f *flakyCache) Set(ctx context.Context, userID int, balance Balance) error {
+	f.calls++
+	if f.err != nil {
+		return f.err
+	}
+
+	return f.BalanceCache.Set(ctx, userID, balance)
+}
+
+func (f *flakyCache) Invalidate(ctx context.Context, userID int, version int64) error {
+	f.calls++
+	if f.err != nil {
+		return f.err
+	}
+
+	return f.BalanceCache.Invalidate(ctx, userID, version)
+}
+
+func setupMockRepo(t *testing.T) (*walletService, sqlmock.Sqlmock, BalanceCache) {
+	t.Helper()
+
 	db, mockSQL, err := sqlmock.New()
 	if err != nil {
-		panic(err)
+		t.Fatal(err)
 	}
 
-	repo := newWalletRepository(db)
-	mockRedisClient, mockRedis := redismock.NewClientMock()
+	t.Cleanup(func() { db.Close() })
 
-	service := newWalletService(repo, mockRedisClient)
+	repo := NewRepository(db)
+	cache := NewMemoryCache(testCacheConfig)
 
-	return service, mockSQL, mockRedis
+	service := newWalletService(repo, cache)
+
+	return service, mockSQL, cache
+}
+
+func requireCacheMiss(t *testing.T, cache BalanceCache, userID int) {
+	t.Helper()
+
+	if balance, ok, _ := cache.Get(context.Background(), userID); ok {
+		t.Fatalf("expected cached balance of user %d to be invalidated, got %v", userID, balance)
+	}
 }
 
 func TestWalletService_Deposit(t *testing.T) {
 	// Arrange
-	service, mockSQL, mockRedis := setupMockRepo()
+	service, mockSQL, cache := setupMockRepo(t)
 
 	userID := 1
 	amount := 100.00
 	newBalance := 200.00
 
+	_ = cache.Set(context.Background(), userID, Balance{Amount: 100, Version: 2})
+
 	// Assert
 	mockSQL.ExpectBegin()
-	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1 WHERE user_id = \$2 RETURNING balance`).
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen RETURNING balance, version`).
 		WithArgs(amount, userID).
-		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(newBalance))
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(newBalance, 3))
 	mockSQL.ExpectExec(`INSERT INTO transactions \(from_user_id, to_user_id, amount, transaction_type\)`).
 		WithArgs(userID, nil, amount, "deposit").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mockSQL.ExpectCommit()
-	mockRedis.ExpectSet(`wallet_balance:1`, newBalance, 0).SetVal("OK")
 
 	// Act
 	updatedBalance, err := service.Deposit(context.Background(), userID, amount)
@@ -57,24 +114,24 @@ func TestWalletService_Deposit(t *testing.T) {
 		t.Fatalf("expected balance to be %v, got %v", newBalance, updatedBalance)
 	}
 
+	requireCacheMiss(t, cache, userID)
+
 	if err := mockSQL.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unmet expectations: %v", err)
 	}
-
-	if err := mockRedis.ExpectationsWereMet(); err != nil {
-		t.Errorf("there were unmet redis expectations: %v", err)
-	}
 }
 
 func TestWalletService_Deposit_Error(t *testing.T) {
 	// Arrange
-	service, mockSQL, mockRedis := setupMockRepo()
+	service, mockSQL, cache := setupMockRepo(t)
 
 	userID := 1
 	amount := 100.00
 
+	_ = cache.Set(context.Background(), userID, Balance{Amount: 100, Version: 2})
+
 	mockSQL.ExpectBegin()
-	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1 WHERE user_id = \$2 RETURNING balance`).
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen RETURNING balance, version`).
 		WithArgs(amount, userID).
 		WillReturnError(sql.ErrConnDone)
 	mockSQL.ExpectRollback()
@@ -91,34 +148,65 @@ func TestWalletService_Deposit_Error(t *testing.T) {
 		t.Fatalf("expected error %v, got %v", sql.ErrConnDone, err)
 	}
 
+	// nothing was written, the cached balance stays valid
+	if balance, ok, _ := cache.Get(context.Background(), userID); !ok || balance != 100 {
+		t.Fatalf("expected cached balance 100 to be kept, got %v (%v)", balance, ok)
+	}
+
 	if err := mockSQL.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unmet expectations: %v", err)
 	}
+}
+
+func TestWalletService_NonPositiveAmount(t *testing.T) {
+	// Arrange: no query is expected
+	service, mockSQL, _ := setupMockRepo(t)
+	ctx := context.Background()
+
+	// Act
+	errs := make([]error, 0, 5)
+
+	_, err := service.Deposit(ctx, 1, -50)
+	errs = append(errs, err)
+	_, err = service.DepositIdempotent(ctx, 1, -50, "bank:BR-1")
+	errs = append(errs, err)
+	_, err = service.Withdraw(ctx, 1, 0)
+	errs = append(errs, err)
+	_, err = service.WithdrawIdempotent(ctx, 1, -50, "payout:1")
+	errs = append(errs, err)
+	_, _, err = service.Transfer(ctx, 1, 2, -50)
+	errs = append(errs, err)
 
-	if err := mockRedis.ExpectationsWereMet(); err != nil {
-		t.Errorf("there were unmet redis expectations: %v", err)
+	// Assert
+	for i, err := range errs {
+		if !errors.Is(err, ErrInvalidAmount) {
+			t.Fatalf("call %d: expected error %v, got %v", i, ErrInvalidAmount, err)
+		}
+	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
 	}
 }
 
 func TestWalletService_Withdraw(t *testing.T) {
 	// Arrange
-	service, mockSQL, mockRedis := setupMockRepo()
+	service, mockSQL, cache := setupMockRepo(t)
 
 	userID := 1
 	amount := 50.00
 	newBalance := 150.00
-	original := amount + newBalance
+
+	_ = cache.Set(context.Background(), userID, Balance{Amount: 200, Version: 2})
 
 	mockSQL.ExpectBegin()
-	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance - \$1 WHERE user_id = \$2 RETURNING balance`).
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance - \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen AND balance >= \$1 RETURNING balance, version`).
 		WithArgs(amount, userID).
-		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(newBalance))
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(newBalance, 3))
 	mockSQL.ExpectExec(`INSERT INTO transactions \(from_user_id, to_user_id, amount, transaction_type\)`).
 		WithArgs(userID, nil, amount, "withdraw").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mockSQL.ExpectCommit()
-	mockRedis.ExpectGet(fmt.Sprintf("wallet_balance:%d", userID)).SetVal(fmt.Sprintf("%f", original))
-	mockRedis.ExpectSet(fmt.Sprintf("wallet_balance:%d", userID), newBalance, 0).SetVal("OK")
 
 	// Act
 	updatedBalance, err := service.Withdraw(context.Background(), userID, amount)
@@ -132,30 +220,25 @@ func TestWalletService_Withdraw(t *testing.T) {
 		t.Fatalf("expected balance to be %v, got %v", newBalance, updatedBalance)
 	}
 
+	requireCacheMiss(t, cache, userID)
+
 	if err := mockSQL.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unmet expectations: %v", err)
 	}
-
-	if err := mockRedis.ExpectationsWereMet(); err != nil {
-		t.Errorf("there were unmet redis expectations: %v", err)
-	}
 }
 
 func TestWalletService_Withdraw_Error(t *testing.T) {
 	// Arrange
-	service, mockSQL, mockRedis := setupMockRepo()
+	service, mockSQL, _ := setupMockRepo(t)
 
 	userID := 1
 	amount := 50.00
-	newBalance := 150.00
-	original := amount + newBalance
 
 	mockSQL.ExpectBegin()
-	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance - \$1 WHERE user_id = \$2 RETURNING balance`).
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance - \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen AND balance >= \$1 RETURNING balance, version`).
 		WithArgs(amount, userID).
 		WillReturnError(sql.ErrConnDone)
 	mockSQL.ExpectRollback()
-	mockRedis.ExpectGet(fmt.Sprintf("wallet_balance:%d", userID)).SetVal(fmt.Sprintf("%f", original))
 
 	// Act
 	_, err := service.Withdraw(context.Background(), userID, amount)
@@ -172,15 +255,43 @@ func TestWalletService_Withdraw_Error(t *testing.T) {
 	if err := mockSQL.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unmet expectations: %v", err)
 	}
+}
+
+func TestWalletService_Withdraw_InsufficientFunds(t *testing.T) {
+	// Arrange
+	service, mockSQL, cache := setupMockRepo(t)
+
+	userID := 1
+	amount := 500.00
+
+	// a stale cached balance must not decide over an overdraft
+	_ = cache.Set(context.Background(), userID, Balance{Amount: 1000, Version: 2})
+
+	mockSQL.ExpectBegin()
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance - \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen AND balance >= \$1 RETURNING balance, version`).
+		WithArgs(amount, userID).
+		WillReturnError(sql.ErrNoRows)
+	mockSQL.ExpectQuery(`SELECT balance, frozen FROM wallets WHERE user_id = \$1`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "frozen"}).AddRow(100.00, false))
+	mockSQL.ExpectRollback()
+
+	// Act
+	_, err := service.Withdraw(context.Background(), userID, amount)
+
+	// Assert
+	if !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("expected error %v, got %v", ErrInsufficientFunds, err)
+	}
 
-	if err := mockRedis.ExpectationsWereMet(); err != nil {
-		t.Errorf("there were unmet redis expectations: %v", err)
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
 	}
 }
 
 func TestWalletService_Transfer(t *testing.T) {
 	// Arrange
-	service, mockSQL, mockRedis := setupMockRepo()
+	service, mockSQL, cache := setupMockRepo(t)
 
 	fromUserID := 1
 	toUserID := 2
@@ -188,23 +299,22 @@ func TestWalletService_Transfer(t *testing.T) {
 	fromNewBalance := 20.00
 	toNewBalance := 50.00
 
+	_ = cache.Set(context.Background(), fromUserID, Balance{Amount: 50, Version: 2})
+	_ = cache.Set(context.Background(), toUserID, Balance{Amount: 20, Version: 2})
+
 	mockSQL.ExpectBegin()
 
-	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance - \$1 WHERE user_id = \$2 RETURNING balance`).
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance - \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen AND balance >= \$1 RETURNING balance, version`).
 		WithArgs(amount, fromUserID).
-		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(fromNewBalance))
-	mockRedis.ExpectGet(fmt.Sprintf("wallet_balance:%d", fromUserID)).SetVal(fmt.Sprintf("%f", fromNewBalance+amount))
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(fromNewBalance, 3))
 
-	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1 WHERE user_id = \$2 RETURNING balance`).
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen RETURNING balance, version`).
 		WithArgs(amount, toUserID).
-		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(toNewBalance))
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(toNewBalance, 3))
 	mockSQL.ExpectExec(`INSERT INTO transactions \(from_user_id, to_user_id, amount, transaction_type\)`).
 		WithArgs(fromUserID, toUserID, amount, "transfer").
 		WillReturnResult(sqlmock.NewResult(1, 1))
-	mockRedis.ExpectGet(fmt.Sprintf("wallet_balance:%d", toUserID)).SetVal(fmt.Sprintf("%f", toNewBalance-amount))
 	mockSQL.ExpectCommit()
-	mockRedis.ExpectSet(`wallet_balance:1`, fromNewBalance, 0).SetVal("OK")
-	mockRedis.ExpectSet(`wallet_balance:2`, toNewBalance, 0).SetVal("OK")
 
 	// Act
 	fromBalance, toBalance, err := service.Transfer(context.Background(), fromUserID, toUserID, amount)
@@ -222,30 +332,24 @@ func TestWalletService_Transfer(t *testing.T) {
 		t.Fatalf("expected to user balance to be %v, got %v", toNewBalance, toBalance)
 	}
 
+	requireCacheMiss(t, cache, fromUserID)
+	requireCacheMiss(t, cache, toUserID)
+
 	if err := mockSQL.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unmet expectations: %v", err)
 	}
-
-	if err := mockRedis.ExpectationsWereMet(); err != nil {
-		t.Errorf("there were unmet redis expectations: %v", err)
-	}
 }
 
 func TestWalletService_Transfer_Error(t *testing.T) {
 	// Arrange
-	service, mockSQL, mockRedis := setupMockRepo()
+	service, mockSQL, _ := setupMockRepo(t)
 
 	fromUserID := 1
 	toUserID := 2
 	amount := 30.00
-	fromNewBalance := 20.00
-	toNewBalance := 50.00
-
-	mockRedis.ExpectGet(fmt.Sprintf("wallet_balance:%d", fromUserID)).SetVal(fmt.Sprintf("%f", fromNewBalance+amount))
-	mockRedis.ExpectGet(fmt.Sprintf("wallet_balance:%d", toUserID)).SetVal(fmt.Sprintf("%f", toNewBalance-amount))
 
 	mockSQL.ExpectBegin()
-	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance - \$1 WHERE user_id = \$2 RETURNING balance`).
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance - \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen AND balance >= \$1 RETURNING balance, version`).
 		WithArgs(amount, fromUserID).
 		WillReturnError(sql.ErrConnDone)
 	mockSQL.ExpectRollback()
@@ -265,19 +369,15 @@ func TestWalletService_Transfer_Error(t *testing.T) {
 	if err := mockSQL.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unmet expectations: %v", err)
 	}
-
-	if err := mockRedis.ExpectationsWereMet(); err != nil {
-		t.Errorf("there were unmet redis expectations: %v", err)
-	}
 }
 
 // GetBalance
-func TestWalletService_GetBalance_FromRedis(t *testing.T) {
-	service, _, mockRedis := setupMockRepo()
+func TestWalletService_GetBalance_FromCache(t *testing.T) {
+	service, mockSQL, cache := setupMockRepo(t)
 	userID := 1
 	balance := 150.00
 
-	mockRedis.ExpectGet(fmt.Sprintf("wallet_balance:%d", userID)).SetVal(fmt.Sprintf("%f", balance))
+	_ = cache.Set(context.Background(), userID, Balance{Amount: balance, Version: 7})
 
 	returnedBalance, err := service.GetBalance(context.Background(), userID)
 
@@ -288,20 +388,21 @@ func TestWalletService_GetBalance_FromRedis(t *testing.T) {
 	if balance != returnedBalance {
 		t.Fatalf("expected to user balance to be %v, got %v", returnedBalance, balance)
 	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet SQL expectations: %v", err)
+	}
 }
 
 func TestWalletService_GetBalance_FromDatabase(t *testing.T) {
 	// Arrange
-	service, mockSQL, mockRedis := setupMockRepo()
+	service, mockSQL, cache := setupMockRepo(t)
 	userID := 1
 	balance := 150.00
 
-	mockRedis.ExpectGet(fmt.Sprintf("wallet_balance:%d", userID)).RedisNil()
-
-	mockSQL.ExpectQuery(`SELECT balance FROM wallets WHERE user_id=\$1`).
+	mockSQL.ExpectQuery(`SELECT balance, version FROM wallets WHERE user_id=\$1`).
 		WithArgs(userID).
-		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(balance))
-	mockRedis.ExpectSet(fmt.Sprintf("wallet_balance:%d", userID), balance, 0).SetVal("OK")
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(balance, 3))
 
 	returnedBalance, err := service.GetBalance(context.Background(), userID)
 
@@ -313,43 +414,267 @@ func TestWalletService_GetBalance_FromDatabase(t *testing.T) {
 		t.Fatalf("expected to user balance to be %v, got %v", returnedBalance, balance)
 	}
 
-	if err := mockSQL.ExpectationsWereMet(); err != nil {
-		t.Errorf("there were unmet SQL expectations: %v", err)
+	if cached, ok, _ := cache.Get(context.Background(), userID); !ok || cached != balance {
+		t.Fatalf("expected balance to be cached, got %v (%v)", cached, ok)
 	}
 
-	if err := mockRedis.ExpectationsWereMet(); err != nil {
-		t.Errorf("there were unmet Redis expectations: %v", err)
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet SQL expectations: %v", err)
 	}
 }
 
 func TestWalletService_GetBalance_FromDatabase_FailedToSetCache(t *testing.T) {
 	// Arrange
-	service, mockSQL, mockRedis := setupMockRepo()
+	db, mockSQL, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	service := newWalletService(NewRepository(db), &flakyCache{BalanceCache: NewNopCache(), err: errCacheDown})
 	userID := 1
 	balance := 150.00
 
-	mockRedis.ExpectGet(fmt.Sprintf("wallet_balance:%d", userID)).RedisNil()
-	mockSQL.ExpectQuery(`SELECT balance FROM wallets WHERE user_id=\$1`).
+	mockSQL.ExpectQuery(`SELECT balance, version FROM wallets WHERE user_id=\$1`).
 		WithArgs(userID).
-		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(balance))
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(balance, 3))
+
+	returnedBalance, err := service.GetBalance(context.Background(), userID)
+
+	// the database answered, a failing cache does not fail the read
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if returnedBalance != balance {
+		t.Fatalf("expected to user balance to be %v, got %v", balance, returnedBalance)
+	}
 
-	mockRedis.ExpectSet(fmt.Sprintf("wallet_balance:%d", userID), balance, 0).SetErr(errors.New("failed to set cache"))
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet SQL expectations: %v", err)
+	}
+}
+
+func TestWalletService_GetBalance_InvalidatedEntry(t *testing.T) {
+	// Arrange
+	service, mockSQL, cache := setupMockRepo(t)
+	userID := 1
+	balance := 80.00
 
+	_ = cache.Invalidate(context.Background(), userID, 4)
+
+	mockSQL.ExpectQuery(`SELECT balance, version FROM wallets WHERE user_id=\$1`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(balance, 4))
+
+	// Act
 	returnedBalance, err := service.GetBalance(context.Background(), userID)
 
-	if err == nil {
-		t.Fatalf("expected error, got nil")
+	// Assert
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
 	}
 
-	if returnedBalance != 0.0 {
-		t.Fatalf("expected to user balance to be %v, got %v", 0, returnedBalance)
+	if returnedBalance != balance {
+		t.Fatalf("expected balance to be %v, got %v", balance, returnedBalance)
+	}
+
+	if cached, ok, _ := cache.Get(context.Background(), userID); !ok || cached != balance {
+		t.Fatalf("expected balance of the written version to be cached, got %v (%v)", cached, ok)
 	}
 
 	if err := mockSQL.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unmet SQL expectations: %v", err)
 	}
+}
+
+type recordingNotifier struct {
+	events []Event
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, event Event) error {
+	n.events = append(n.events, event)
+	return errors.New("notifier failure must not fail the request")
+}
+
+func TestWalletService_Transfer_NotifiesBothSides(t *testing.T) {
+	// Arrange
+	db, mockSQL, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	notifier := &recordingNotifier{}
+	service := newWalletService(NewRepository(db), NewNopCache(), notifier)
+
+	mockSQL.ExpectBegin()
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance - \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen AND balance >= \$1 RETURNING balance, version`).
+		WithArgs(30.0, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(20.0, 3))
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen RETURNING balance, version`).
+		WithArgs(30.0, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(50.0, 3))
+	mockSQL.ExpectExec(`INSERT INTO transactions`).
+		WithArgs(1, 2, 30.0, "transfer").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mockSQL.ExpectCommit()
+
+	// Act
+	_, _, err = service.Transfer(context.Background(), 1, 2, 30)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(notifier.events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(notifier.events))
+	}
+
+	sent, received := notifier.events[0], notifier.events[1]
+	if sent.UserID != 1 || sent.Amount != -30 || sent.Balance != 20 || sent.CounterpartyID != 2 {
+		t.Fatalf("unexpected sender event: %+v", sent)
+	}
+
+	if received.UserID != 2 || received.Amount != 30 || received.Balance != 50 || received.CounterpartyID != 1 {
+		t.Fatalf("unexpected recipient event: %+v", received)
+	}
+}
+
+func setupDegradableService(t *testing.T, openTimeout time.Duration) (*walletService, sqlmock.Sqlmock, *flakyCache) {
+	t.Helper()
+
+	db, mockSQL, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	flaky := &flakyCache{BalanceCache: NewMemoryCache(testCacheConfig)}
+	guarded := NewGuardedCache(flaky, breaker.Config{FailureThreshold: 1, OpenTimeout: openTimeout})
+
+	return newWalletService(NewRepository(db), guarded), mockSQL, flaky
+}
+
+func expectDeposit(mockSQL sqlmock.Sqlmock, userID int, amount, newBalance float64, version int64) {
+	mockSQL.ExpectBegin()
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance \+ \$1, version = version \+ 1 WHERE user_id = \$2 AND NOT frozen RETURNING balance, version`).
+		WithArgs(amount, userID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(newBalance, version))
+	mockSQL.ExpectExec(`INSERT INTO transactions`).
+		WithArgs(userID, nil, amount, "deposit").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mockSQL.ExpectCommit()
+}
+
+func TestWalletService_CacheDown_FallsBackToPostgres(t *testing.T) {
+	// Arrange
+	service, mockSQL, flaky := setupDegradableService(t, time.Hour)
+	userID := 1
+	flaky.err = errCacheDown
 
-	if err := mockRedis.ExpectationsWereMet(); err != nil {
-		t.Errorf("there were unmet Redis expectations: %v", err)
+	expectDeposit(mockSQL, userID, 50, 150, 3)
+	expectDeposit(mockSQL, userID, 50, 200, 4)
+	mockSQL.ExpectQuery(`SELECT balance, version FROM wallets WHERE user_id=\$1`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(200.0, 4))
+
+	// Act
+	_, firstErr := service.Deposit(context.Background(), userID, 50)
+	_, secondErr := service.Deposit(context.Background(), userID, 50)
+	balance, balanceErr := service.GetBalance(context.Background(), userID)
+
+	// Assert
+	if firstErr != nil || secondErr != nil || balanceErr != nil {
+		t.Fatalf("expected no errors, got %v, %v, %v", firstErr, secondErr, balanceErr)
+	}
+
+	if balance != 200 {
+		t.Fatalf("expected balance 200, got %v", balance)
+	}
+
+	// the circuit opened at the first failure, the cache was not asked again
+	if flaky.calls != 1 {
+		t.Fatalf("expected 1 cache call, got %d", flaky.calls)
+	}
+
+	if service.CacheState() != breaker.Open {
+		t.Fatalf("expected open circuit, got %v", service.CacheState())
+	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet SQL expectations: %v", err)
+	}
+}
+
+func TestGuardedCache_CallerContextDoesNotTrip(t *testing.T) {
+	// Arrange
+	flaky := &flakyCache{BalanceCache: NewMemoryCache(testCacheConfig)}
+	guarded := NewGuardedCache(flaky, breaker.Config{FailureThreshold: 1, OpenTimeout: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	flaky.err = context.Canceled
+
+	// Act
+	_, _, cancelledErr := guarded.Get(ctx, 1)
+
+	flaky.err = errCacheDown
+	_, _, downErr := guarded.Get(ctx, 1)
+
+	// Assert
+	if !errors.Is(cancelledErr, context.Canceled) || !errors.Is(downErr, errCacheDown) {
+		t.Fatalf("expected the cache errors, got %v, %v", cancelledErr, downErr)
+	}
+
+	if guarded.State() != breaker.Closed {
+		t.Fatalf("expected errors of a cancelled caller to keep the circuit closed, got %v", guarded.State())
+	}
+
+	_, _, _ = guarded.Get(context.Background(), 1)
+
+	if guarded.State() != breaker.Open {
+		t.Fatalf("expected a failure of a live caller to open the circuit, got %v", guarded.State())
+	}
+}
+
+func TestWalletService_CacheRecovered_ReplaysMissedInvalidations(t *testing.T) {
+	// Arrange
+	service, mockSQL, flaky := setupDegradableService(t, 0)
+	userID := 1
+
+	// cached before the outage, overwritten during it
+	_ = flaky.Set(context.Background(), userID, Balance{Amount: 100, Version: 2})
+	flaky.err = errCacheDown
+
+	expectDeposit(mockSQL, userID, 50, 150, 3)
+	mockSQL.ExpectQuery(`SELECT balance, version FROM wallets WHERE user_id=\$1`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "version"}).AddRow(150.0, 3))
+
+	// Act
+	_, depositErr := service.Deposit(context.Background(), userID, 50)
+
+	flaky.err = nil
+	balance, balanceErr := service.GetBalance(context.Background(), userID)
+
+	// Assert
+	if depositErr != nil || balanceErr != nil {
+		t.Fatalf("expected no errors, got %v, %v", depositErr, balanceErr)
+	}
+
+	if balance != 150 {
+		t.Fatalf("expected balance 150 instead of the stale cached 100, got %v", balance)
+	}
+
+	if service.CacheState() != breaker.Closed {
+		t.Fatalf("expected closed circuit, got %v", service.CacheState())
+	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet SQL expectations: %v", err)
 	}
 }
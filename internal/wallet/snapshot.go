@@ -0,0 +1,119 @@
+package wallet
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// SnapshotConfig controls how often balance snapshots are taken
+type SnapshotConfig struct {
+	// Interval between snapshots, 0 disables the writer. Snapshots are taken at
+	// multiples of Interval since the Unix epoch, e.g. at midnight UTC for 24h.
+	Interval time.Duration
+	// SettleDelay is how far a snapshot trails the clock. A transaction is timestamped
+	// when it starts and may commit later; it must have committed before its time is
+	// covered by a snapshot.
+	SettleDelay time.Duration
+}
+
+// SnapshotLockID is the advisory lock key held by the instance that writes the
+// scheduled snapshots
+const SnapshotLockID = 7365233
+
+// SnapshotWriter periodically stores the balance of every wallet, which bounds the
+// history GetBalanceAsOf has to read
+type SnapshotWriter struct {
+	db     *sql.DB
+	cfg    SnapshotConfig
+	logger *logrus.Entry
+	now    func() time.Time
+}
+
+func NewSnapshotConfig() SnapshotConfig {
+	viper.SetDefault("snapshot.interval", "24h")
+	viper.SetDefault("snapshot.settle_delay", "5m")
+
+	return SnapshotConfig{
+		Interval:    viper.GetDuration("snapshot.interval"),
+		SettleDelay: viper.GetDuration("snapshot.settle_delay"),
+	}
+}
+
+func NewSnapshotWriter(db *sql.DB, cfg SnapshotConfig) *SnapshotWriter {
+	return &SnapshotWriter{
+		db:     db,
+		cfg:    cfg,
+		logger: log.NewLogger("wallet").WithField("module", "snapshot"),
+		now:    time.Now,
+	}
+}
+
+// Run takes a snapshot whenever one is due until ctx is done
+func (w *SnapshotWriter) Run(ctx context.Context) {
+	if w.cfg.Interval <= 0 {
+		return
+	}
+
+	for {
+		if _, err := w.WriteSnapshots(ctx); err != nil {
+			w.logger.WithField("err", err).Error("failed to write balance snapshots")
+		}
+
+		next := w.due().Add(w.cfg.Interval).Add(w.cfg.SettleDelay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(next.Sub(w.now())):
+		}
+	}
+}
+
+// due is the latest snapshot time that has settled
+func (w *SnapshotWriter) due() time.Time {
+	return w.now().UTC().Add(-w.cfg.SettleDelay).Truncate(w.cfg.Interval)
+}
+
+// WriteSnapshots stores the balance of every wallet at the latest due snapshot time,
+// computed from the previous snapshot and the movements since. Snapshots that already
+// exist are kept, so restarts and several instances write each one once.
+func (w *SnapshotWriter) WriteSnapshots(ctx context.Context) (int64, error) {
+	takenAt := w.due()
+
+	query := `
+    INSERT INTO balance_snapshots (user_id, taken_at, balance)
+    SELECT w.user_id, $1, COALESCE(s.balance, 0) + COALESCE((
+        SELECT SUM(m.amount) FROM wallet_movements m
+        WHERE m.user_id = w.user_id AND m.timestamp > COALESCE(s.taken_at, '-infinity') AND m.timestamp <= $1
+    ), 0)
+    FROM wallets w
+    LEFT JOIN LATERAL (
+        SELECT balance, taken_at FROM balance_snapshots
+        WHERE user_id = w.user_id AND taken_at < $1
+        ORDER BY taken_at DESC LIMIT 1
+    ) s ON TRUE
+    ON CONFLICT (user_id, taken_at) DO NOTHING
+    `
+
+	result, err := w.db.ExecContext(ctx, query, takenAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write snapshots at %s: %w", takenAt.Format(time.RFC3339), err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count snapshots: %w", err)
+	}
+
+	if count > 0 {
+		w.logger.WithField("taken_at", takenAt).WithField("wallets", count).Info("balance snapshots written")
+	}
+
+	return count, nil
+}
@@ -0,0 +1,44 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSnapshotWriter_WriteSnapshots(t *testing.T) {
+	// Arrange
+	db, mockSQL, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	writer := NewSnapshotWriter(db, SnapshotConfig{Interval: 24 * time.Hour, SettleDelay: 5 * time.Minute})
+	writer.now = func() time.Time { return time.Date(2024, 2, 1, 0, 3, 0, 0, time.UTC) }
+
+	// 00:03 is not yet settled, the latest due snapshot is the one of the previous midnight
+	takenAt := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	mockSQL.ExpectExec(`INSERT INTO balance_snapshots \(user_id, taken_at, balance\)`).
+		WithArgs(takenAt).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	// Act
+	count, err := writer.WriteSnapshots(context.Background())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if count != 2 {
+		t.Fatalf("expected 2 snapshots, got %d", count)
+	}
+
+	if err := mockSQL.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// periodLayout is how periods are written in URLs and stored statements
+const periodLayout = "2006-01"
+
+// statementSettleDelay is how long after its end a period is closed. A transaction is
+// timestamped when it starts and may commit later, it must be on the final statement.
+const statementSettleDelay = 5 * time.Minute
+
+// ParsePeriod parses a period written as YYYY-MM
+func ParsePeriod(value string) (Period, error) {
+	t, err := time.Parse(periodLayout, value)
+	if err != nil {
+		return Period{}, fmt.Errorf("%w %q, expected YYYY-MM: %w", ErrInvalidPeriod, value, err)
+	}
+
+	return Period{Year: t.Year(), Month: t.Month()}, nil
+}
+
+func (p Period) String() string {
+	return p.Start().Format(periodLayout)
+}
+
+// Start is the first instant of the period
+func (p Period) Start() time.Time {
+	return time.Date(p.Year, p.Month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// End is the first instant after the period
+func (p Period) End() time.Time {
+	return p.Start().AddDate(0, 1, 0)
+}
+
+// SignedAmount is the change of the balance of userID by the transaction, negative
+// when money left the wallet
+func (t Transaction) SignedAmount(userID int) float64 {
+	switch {
+	case t.FromUserID == userID && (t.TransactionType == EventDeposit || t.TransactionType == TransactionAdjustment):
+		return t.Amount
+	case t.FromUserID == userID:
+		return -t.Amount
+	case t.ToUserID == userID:
+		return t.Amount
+	default:
+		return 0
+	}
+}
+
+// Counterparty is the other wallet of a transfer, 0 for every other transaction
+func (t Transaction) Counterparty(userID int) int {
+	switch {
+	case t.TransactionType != EventTransfer:
+		return 0
+	case t.FromUserID == userID:
+		return t.ToUserID
+	default:
+		return t.FromUserID
+	}
+}
+
+// GetStatement returns the statement of a period. The statement of a closed period
+// is built once and stored, the running period is built on every call.
+func (s *walletService) GetStatement(ctx context.Context, userID int, period Period) (Statement, error) {
+	now := s.now()
+	if !period.Start().Before(now) {
+		return Statement{}, fmt.Errorf("%w: %s has not started", ErrInvalidPeriod, period)
+	}
+
+	closed := !now.Before(period.End().Add(statementSettleDelay))
+
+	if closed {
+		stored, err := s.repo.GetStatement(ctx, userID, period.String())
+		if err == nil {
+			return stored, nil
+		}
+
+		if !errors.Is(err, ErrStatementNotFound) {
+			return Statement{}, fmt.Errorf("failed to get statement %s of user %d: %w", period, userID, err)
+		}
+	}
+
+	statement, err := s.buildStatement(ctx, userID, period, now)
+	if err != nil {
+		return Statement{}, err
+	}
+
+	if !closed {
+		return statement, nil
+	}
+
+	statement.Final = true
+
+	stored, err := s.repo.SaveStatement(ctx, statement)
+	if err != nil {
+		return Statement{}, fmt.Errorf("failed to store statement %s of user %d: %w", period, userID, err)
+	}
+
+	return stored, nil
+}
+
+// buildStatement starts from the balance right before the period and applies the
+// transactions of the period, oldest first. Both are read from the primary, a
+// closed period's statement is stored and must not miss what a replica lags behind.
+func (s *walletService) buildStatement(ctx context.Context, userID int, period Period, now time.Time) (Statement, error) {
+	// timestamps have microsecond precision, nothing falls between the two instants
+	opening, err := s.repo.GetBalanceAsOf(ctx, userID, period.Start().Add(-time.Microsecond))
+	if err != nil {
+		return Statement{}, fmt.Errorf("failed to get opening balance of statement %s of user %d: %w", period, userID, err)
+	}
+
+	txs, err := s.repo.GetTransactionsBetween(ctx, userID, period.Start(), period.End())
+	if err != nil {
+		return Statement{}, fmt.Errorf("failed to get transactions of statement %s of user %d: %w", period, userID, err)
+	}
+
+	statement := Statement{
+		UserID:         userID,
+		Period:         period.String(),
+		From:           period.Start(),
+		To:             period.End(),
+		OpeningBalance: opening,
+		ClosingBalance: opening,
+		Entries:        []StatementEntry{},
+		Totals:         []StatementTotal{},
+		GeneratedAt:    now.UTC(),
+	}
+
+	totals := make(map[string]*StatementTotal)
+
+	// the history is newest first
+	for i := len(txs) - 1; i >= 0; i-- {
+		t := txs[i]
+
+		at, err := time.Parse(time.RFC3339Nano, t.Timestamp)
+		if err != nil {
+			return Statement{}, fmt.Errorf("failed to parse timestamp of transaction %d: %w", t.TransactionID, err)
+		}
+
+		entry := StatementEntry{
+			TransactionID:  t.TransactionID,
+			Timestamp:      at.UTC(),
+			Type:           t.TransactionType,
+			CounterpartyID: t.Counterparty(userID),
+			Amount:         t.SignedAmount(userID),
+		}
+
+		statement.ClosingBalance = roundCents(statement.ClosingBalance + entry.Amount)
+		entry.Balance = statement.ClosingBalance
+		statement.Entries = append(statement.Entries, entry)
+
+		total, ok := totals[entry.Type]
+		if !ok {
+			total = &StatementTotal{Type: entry.Type}
+			totals[entry.Type] = total
+		}
+
+		total.Count++
+
+		if entry.Amount < 0 {
+			total.Debits = roundCents(total.Debits - entry.Amount)
+		} else {
+			total.Credits = roundCents(total.Credits + entry.Amount)
+		}
+	}
+
+	for _, total := range totals {
+		statement.Totals = append(statement.Totals, *total)
+	}
+
+	sort.Slice(statement.Totals, func(i, j int) bool { return statement.Totals[i].Type < statement.Totals[j].Type })
+
+	return statement, nil
+}
@@ -0,0 +1,141 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePeriod(t *testing.T) {
+	p, err := ParsePeriod("2024-12")
+	require.NoError(t, err)
+	require.Equal(t, Period{Year: 2024, Month: time.December}, p)
+	require.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), p.End())
+	require.Equal(t, "2024-12", p.String())
+
+	for _, invalid := range []string{"2024-13", "2024-1", "december", ""} {
+		_, err = ParsePeriod(invalid)
+		require.ErrorIs(t, err, ErrInvalidPeriod, invalid)
+	}
+}
+
+// statementFixture has a deposit and an incoming transfer on the last day of
+// January and an outgoing transfer on the first of February
+func statementFixture(t *testing.T) (*walletService, *memoryRepository, *time.Time) {
+	t.Helper()
+
+	ctx := context.Background()
+	repo := newMemoryRepository(map[int]float64{1: 100, 2: 50})
+	clock := time.Date(2024, 1, 31, 23, 59, 0, 0, time.UTC)
+	repo.now = func() time.Time { return clock }
+
+	_, err := repo.Deposit(ctx, 1, 10)
+	require.NoError(t, err)
+	_, _, err = repo.Transfer(ctx, 2, 1, 5.5)
+	require.NoError(t, err)
+
+	clock = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	_, _, err = repo.Transfer(ctx, 1, 2, 30)
+	require.NoError(t, err)
+
+	svc := newWalletService(repo, NewNopCache())
+	svc.now = func() time.Time { return clock }
+
+	return svc, repo, &clock
+}
+
+func TestWalletService_GetStatement(t *testing.T) {
+	// Arrange
+	svc, _, clock := statementFixture(t)
+	*clock = time.Date(2024, 2, 1, 0, 5, 0, 0, time.UTC)
+
+	// Act
+	statement, err := svc.GetStatement(context.Background(), 1, Period{Year: 2024, Month: time.January})
+
+	// Assert
+	require.NoError(t, err)
+	require.True(t, statement.Final)
+	require.InDelta(t, 100, statement.OpeningBalance, 0.001)
+	require.InDelta(t, 115.5, statement.ClosingBalance, 0.001)
+	require.Len(t, statement.Entries, 2)
+	require.Equal(t, StatementEntry{
+		TransactionID:  2,
+		Timestamp:      time.Date(2024, 1, 31, 23, 59, 0, 0, time.UTC),
+		Type:           EventTransfer,
+		CounterpartyID: 2,
+		Amount:         5.5,
+		Balance:        115.5,
+	}, statement.Entries[1])
+	require.Equal(t, []StatementTotal{
+		{Type: EventDeposit, Count: 1, Credits: 10},
+		{Type: EventTransfer, Count: 1, Credits: 5.5},
+	}, statement.Totals)
+}
+
+func TestWalletService_GetStatement_OpenPeriod(t *testing.T) {
+	// Arrange: the period ended, but transactions may still commit
+	svc, _, clock := statementFixture(t)
+	*clock = time.Date(2024, 2, 1, 0, 1, 0, 0, time.UTC)
+
+	// Act
+	february, err := svc.GetStatement(context.Background(), 1, Period{Year: 2024, Month: time.February})
+	require.NoError(t, err)
+
+	january, err := svc.GetStatement(context.Background(), 1, Period{Year: 2024, Month: time.January})
+	require.NoError(t, err)
+
+	_, err = svc.GetStatement(context.Background(), 1, Period{Year: 2024, Month: time.March})
+
+	// Assert
+	require.False(t, february.Final)
+	require.InDelta(t, 115.5, february.OpeningBalance, 0.001)
+	require.InDelta(t, 85.5, february.ClosingBalance, 0.001)
+	require.Equal(t, -30.0, february.Entries[0].Amount)
+	require.False(t, january.Final)
+	require.ErrorIs(t, err, ErrInvalidPeriod)
+}
+
+func TestWalletService_GetStatement_StoredOnce(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	svc, repo, clock := statementFixture(t)
+	*clock = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	first, err := svc.GetStatement(ctx, 1, Period{Year: 2024, Month: time.January})
+	require.NoError(t, err)
+
+	// a late write into the closed period must not change the stored statement
+	repo.now = func() time.Time { return time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC) }
+	_, err = repo.Deposit(ctx, 1, 1000)
+	require.NoError(t, err)
+
+	*clock = clock.Add(time.Hour)
+
+	// Act
+	second, err := svc.GetStatement(ctx, 1, Period{Year: 2024, Month: time.January})
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+	require.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), second.GeneratedAt)
+}
+
+func TestWalletService_GetTransactionsAsOf(t *testing.T) {
+	// Arrange
+	svc, _, _ := statementFixture(t)
+	ctx := context.Background()
+
+	// Act: asOf includes a transaction booked at that instant
+	january, err := svc.GetTransactionsAsOf(ctx, 1, time.Date(2024, 1, 31, 23, 59, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	february, err := svc.GetTransactionsAsOf(ctx, 1, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	// Assert
+	require.Len(t, january, 2)
+	require.Len(t, february, 3)
+	require.Equal(t, "transfer", february[0].TransactionType)
+}
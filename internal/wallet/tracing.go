@@ -0,0 +1,129 @@
+package wallet
+
+import (
+	"context"
+	"time"
+
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/amelonpie/wallet-service/internal/wallet"
+
+// tracedService records a span around every call of the service and names the
+// operation in the log fields of its context. The SQL statements and Redis
+// commands it runs are traced as children of that span.
+type tracedService struct {
+	Service
+	tracer trace.Tracer
+}
+
+//nolint:ireturn // stick to interface
+func traced(svc Service) Service {
+	return &tracedService{Service: svc, tracer: otel.Tracer(tracerName)}
+}
+
+func (t *tracedService) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx = log.WithOperation(ctx, name)
+
+	return t.tracer.Start(ctx, "walletService."+name, trace.WithAttributes(attrs...)) //nolint:spancheck // ended by end
+}
+
+// end records a failed call on the span and ends it
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, outcome(err))
+	}
+
+	span.End()
+}
+
+func (t *tracedService) Deposit(ctx context.Context, userID int, amount float64) (float64, error) {
+	ctx, span := t.start(ctx, "Deposit", attribute.Int("user_id", userID), attribute.Float64("amount", amount))
+	balance, err := t.Service.Deposit(ctx, userID, amount)
+	end(span, err)
+
+	return balance, err //nolint:wrapcheck // the service wraps with context
+}
+
+func (t *tracedService) DepositIdempotent(ctx context.Context, userID int, amount float64, key string) (float64, error) {
+	ctx, span := t.start(ctx, "DepositIdempotent", attribute.Int("user_id", userID),
+		attribute.Float64("amount", amount), attribute.String("idempotency_key", key))
+	balance, err := t.Service.DepositIdempotent(ctx, userID, amount, key)
+	end(span, err)
+
+	return balance, err //nolint:wrapcheck // the service wraps with context
+}
+
+func (t *tracedService) Withdraw(ctx context.Context, userID int, amount float64) (float64, error) {
+	ctx, span := t.start(ctx, "Withdraw", attribute.Int("user_id", userID), attribute.Float64("amount", amount))
+	balance, err := t.Service.Withdraw(ctx, userID, amount)
+	end(span, err)
+
+	return balance, err //nolint:wrapcheck // the service wraps with context
+}
+
+func (t *tracedService) WithdrawIdempotent(ctx context.Context, userID int, amount float64, key string) (float64, error) {
+	ctx, span := t.start(ctx, "WithdrawIdempotent", attribute.Int("user_id", userID),
+		attribute.Float64("amount", amount), attribute.String("idempotency_key", key))
+	balance, err := t.Service.WithdrawIdempotent(ctx, userID, amount, key)
+	end(span, err)
+
+	return balance, err //nolint:wrapcheck // the service wraps with context
+}
+
+func (t *tracedService) Transfer(ctx context.Context, fromUserID, toUserID int, amount float64) (float64, float64, error) {
+	ctx, span := t.start(ctx, "Transfer", attribute.Int("from_user_id", fromUserID),
+		attribute.Int("to_user_id", toUserID), attribute.Float64("amount", amount))
+	fromBalance, toBalance, err := t.Service.Transfer(ctx, fromUserID, toUserID, amount)
+	end(span, err)
+
+	return fromBalance, toBalance, err //nolint:wrapcheck // the service wraps with context
+}
+
+func (t *tracedService) GetBalance(ctx context.Context, userID int) (float64, error) {
+	ctx, span := t.start(ctx, "GetBalance", attribute.Int("user_id", userID))
+	balance, err := t.Service.GetBalance(ctx, userID)
+	end(span, err)
+
+	return balance, err //nolint:wrapcheck // the service wraps with context
+}
+
+func (t *tracedService) GetBalanceAsOf(ctx context.Context, userID int, asOf time.Time) (float64, error) {
+	ctx, span := t.start(ctx, "GetBalanceAsOf", attribute.Int("user_id", userID),
+		attribute.String("as_of", asOf.UTC().Format(time.RFC3339)))
+	balance, err := t.Service.GetBalanceAsOf(ctx, userID, asOf)
+	end(span, err)
+
+	return balance, err //nolint:wrapcheck // the service wraps with context
+}
+
+func (t *tracedService) GetTransactionHistory(ctx context.Context, userID int) ([]Transaction, error) {
+	ctx, span := t.start(ctx, "GetTransactionHistory", attribute.Int("user_id", userID))
+	txs, err := t.Service.GetTransactionHistory(ctx, userID)
+	end(span, err)
+
+	return txs, err //nolint:wrapcheck // the service wraps with context
+}
+
+func (t *tracedService) GetTransactionsAsOf(ctx context.Context, userID int, asOf time.Time) ([]Transaction, error) {
+	ctx, span := t.start(ctx, "GetTransactionsAsOf", attribute.Int("user_id", userID),
+		attribute.String("as_of", asOf.UTC().Format(time.RFC3339)))
+	txs, err := t.Service.GetTransactionsAsOf(ctx, userID, asOf)
+	end(span, err)
+
+	return txs, err //nolint:wrapcheck // the service wraps with context
+}
+
+func (t *tracedService) GetStatement(ctx context.Context, userID int, period Period) (Statement, error) {
+	ctx, span := t.start(ctx, "GetStatement", attribute.Int("user_id", userID),
+		attribute.String("period", period.String()))
+	statement, err := t.Service.GetStatement(ctx, userID, period)
+	end(span, err)
+
+	return statement, err //nolint:wrapcheck // the service wraps with context
+}
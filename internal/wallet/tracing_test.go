@@ -0,0 +1,58 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracedService_RecordsChildSpan(t *testing.T) {
+	// Arrange
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := provider.Tracer(tracerName)
+
+	service, mockSQL, _ := setupMockRepo(t)
+	svc := &tracedService{Service: service, tracer: tracer}
+
+	mockSQL.ExpectBegin()
+	mockSQL.ExpectQuery(`UPDATE wallets SET balance = balance - \$1`).WillReturnRows(sqlmock.NewRows(nil))
+	mockSQL.ExpectQuery(`SELECT balance, frozen FROM wallets`).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "frozen"}).AddRow(70.0, false))
+	mockSQL.ExpectRollback()
+
+	ctx, parent := tracer.Start(context.Background(), "request")
+
+	// Act
+	_, err := svc.Withdraw(ctx, 1, 500)
+
+	parent.End()
+
+	// Assert
+	if !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("expected ErrInsufficientFunds, got %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	span := spans[0]
+	if span.Name() != "walletService.Withdraw" {
+		t.Errorf("expected span walletService.Withdraw, got %q", span.Name())
+	}
+
+	if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("expected the service span to be a child of the request span")
+	}
+
+	if span.Status().Code != codes.Error || span.Status().Description != outcomeInsufficientFunds {
+		t.Errorf("expected error status %q, got %+v", outcomeInsufficientFunds, span.Status())
+	}
+}
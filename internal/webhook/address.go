@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, which
+// netip.Addr.IsPrivate does not cover
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// lookupFunc resolves a host name, net.DefaultResolver.LookupIPAddr by default
+type lookupFunc func(ctx context.Context, host string) ([]net.IPAddr, error)
+
+// internalAddress tells whether ip belongs to this host or an internal network:
+// loopback, RFC 1918 and unique local, link local (which includes the cloud
+// metadata endpoint 169.254.169.254), shared, unspecified and multicast addresses
+func internalAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+
+	return !ip.IsValid() ||
+		ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() ||
+		sharedAddressSpace.Contains(ip)
+}
+
+// checkURL parses a receiver url and, unless allowInternal is set, refuses it
+// when its host resolves to an internal address
+func checkURL(ctx context.Context, lookup lookupFunc, rawURL string, allowInternal bool) (*url.URL, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidURL, rawURL)
+	}
+
+	if allowInternal {
+		return parsed, nil
+	}
+
+	addrs, err := lookup(ctx, parsed.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return nil, fmt.Errorf("%w: cannot resolve %q", ErrInvalidURL, parsed.Hostname())
+	}
+
+	for _, addr := range addrs {
+		ip, ok := netip.AddrFromSlice(addr.IP)
+		if !ok || internalAddress(ip) {
+			return nil, fmt.Errorf("%w: %q resolves to %s", ErrInternalAddress, parsed.Hostname(), addr.IP)
+		}
+	}
+
+	return parsed, nil
+}
+
+// newDeliveryClient returns the client deliveries are posted with. Unless
+// allowInternal is set it refuses to connect to internal addresses, checked on
+// the address actually dialed, so that neither a redirect nor a host name that
+// resolves differently since the subscription was created reaches them.
+func newDeliveryClient(timeout time.Duration, allowInternal bool) *http.Client {
+	if allowInternal {
+		return &http.Client{Timeout: timeout}
+	}
+
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrInternalAddress, address)
+			}
+
+			if internalAddress(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrInternalAddress, addrPort.Addr())
+			}
+
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert // the default is a *http.Transport
+	transport.DialContext = dialer.DialContext
+	// a proxy would be dialed instead of the receiver and defeat the check
+	transport.Proxy = nil
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
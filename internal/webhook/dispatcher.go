@@ -0,0 +1,224 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Headers set on every outbound delivery
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+)
+
+const maxErrorBody = 512
+
+// NewDispatcherConfig reads the retry schedule from the config, with defaults
+func NewDispatcherConfig() DispatcherConfig {
+	viper.SetDefault("webhook.max_attempts", 8)
+	viper.SetDefault("webhook.initial_backoff", "10s")
+	viper.SetDefault("webhook.max_backoff", "1h")
+	viper.SetDefault("webhook.poll_interval", "2s")
+	viper.SetDefault("webhook.timeout", "10s")
+	viper.SetDefault("webhook.batch_size", 20)
+	viper.SetDefault("webhook.allow_internal_addresses", false)
+
+	return DispatcherConfig{
+		MaxAttempts:    viper.GetInt("webhook.max_attempts"),
+		InitialBackoff: viper.GetDuration("webhook.initial_backoff"),
+		MaxBackoff:     viper.GetDuration("webhook.max_backoff"),
+		PollInterval:   viper.GetDuration("webhook.poll_interval"),
+		Timeout:        viper.GetDuration("webhook.timeout"),
+		BatchSize:      viper.GetInt("webhook.batch_size"),
+
+		AllowInternalAddresses: viper.GetBool("webhook.allow_internal_addresses"),
+	}
+}
+
+func NewDispatcher(repo Repository, cfg DispatcherConfig) *Dispatcher {
+	return &Dispatcher{
+		repo:   repo,
+		client: newDeliveryClient(cfg.Timeout, cfg.AllowInternalAddresses),
+		cfg:    cfg,
+		logger: log.NewLogger("webhook").WithField("module", "dispatcher"),
+		now:    time.Now,
+	}
+}
+
+// Sign returns the signature header value for a payload: the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the subscription secret, prefixed by the timestamp.
+// Receivers recompute it and should reject stale timestamps.
+func Sign(secret string, timestamp int64, body []byte) string {
+	ts := strconv.FormatInt(timestamp, 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Notify queues one delivery per subscription of the user interested in the event
+func (d *Dispatcher) Notify(ctx context.Context, event wallet.Event) error {
+	subs, err := d.repo.ListSubscriptionsForEvent(ctx, event.UserID, event.Type)
+	if err != nil {
+		return fmt.Errorf("failed to list subscriptions for user %d: %w", event.UserID, err)
+	}
+
+	if len(subs) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(struct {
+		Type string       `json:"type"`
+		Data wallet.Event `json:"data"`
+	}{Type: event.Type, Data: event})
+	if err != nil {
+		return fmt.Errorf("failed to marshal event for user %d: %w", event.UserID, err)
+	}
+
+	for _, sub := range subs {
+		_, err = d.repo.CreateDelivery(ctx, Delivery{
+			SubscriptionID: sub.SubscriptionID,
+			EventType:      event.Type,
+			Payload:        payload,
+			Status:         StatusPending,
+			NextAttemptAt:  d.now(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to queue delivery for subscription %d: %w", sub.SubscriptionID, err)
+		}
+	}
+
+	return nil
+}
+
+// Run delivers due deliveries every poll interval until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.DeliverDue(ctx); err != nil {
+			d.logger.WithField("err", err).Error("failed to deliver due webhooks")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue makes one attempt for every due delivery and returns how many were attempted
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	// lease covers the time one batch may take, so a crashed instance only delays deliveries
+	lease := d.cfg.Timeout * time.Duration(d.cfg.BatchSize+1)
+
+	deliveries, err := d.repo.ClaimDueDeliveries(ctx, d.cfg.BatchSize, lease)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+
+	for _, delivery := range deliveries {
+		d.attempt(ctx, delivery)
+	}
+
+	return len(deliveries), nil
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery Delivery) {
+	logger := d.logger.WithFields(logrus.Fields{
+		"delivery_id":     delivery.DeliveryID,
+		"subscription_id": delivery.SubscriptionID,
+	})
+
+	sub, err := d.repo.GetSubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		logger.WithField("err", err).Error("failed to load subscription for delivery")
+		return
+	}
+
+	delivery.Attempts++
+	delivery.LastStatusCode, err = d.send(ctx, sub, delivery)
+
+	now := d.now()
+
+	switch {
+	case err == nil:
+		delivery.Status = StatusDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= d.cfg.MaxAttempts:
+		delivery.Status = StatusDead
+		delivery.LastError = err.Error()
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+	}
+
+	if err = d.repo.UpdateDelivery(ctx, delivery); err != nil {
+		logger.WithField("err", err).Error("failed to record delivery attempt")
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"status":   delivery.Status,
+		"attempts": delivery.Attempts,
+	}).Info("webhook delivery attempted")
+}
+
+// send posts the payload and returns the response status code
+func (d *Dispatcher) send(ctx context.Context, sub Subscription, delivery Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(delivery.DeliveryID))
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, d.now().Unix(), delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		//nolint:err113 // carries the receiver response
+		return resp.StatusCode, fmt.Errorf("receiver responded %d: %s", resp.StatusCode, body)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff doubles the wait after every failed attempt, capped at MaxBackoff
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.cfg.InitialBackoff
+
+	for i := 1; i < attempts && wait < d.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+
+	return min(wait, d.cfg.MaxBackoff)
+}
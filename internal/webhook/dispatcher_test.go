@@ -0,0 +1,460 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/wallet"
+)
+
+// fakeRepository keeps subscriptions and deliveries in memory
+type fakeRepository struct {
+	mu         sync.Mutex
+	now        func() time.Time
+	subs       map[int]Subscription
+	deliveries map[int]Delivery
+	nextID     int
+}
+
+func newFakeRepository(now func() time.Time) *fakeRepository {
+	return &fakeRepository{
+		now:        now,
+		subs:       map[int]Subscription{},
+		deliveries: map[int]Delivery{},
+	}
+}
+
+func (r *fakeRepository) CreateSubscription(_ context.Context, sub Subscription) (Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	sub.SubscriptionID = r.nextID
+	sub.CreatedAt = r.now()
+	r.subs[sub.SubscriptionID] = sub
+
+	return sub, nil
+}
+
+func (r *fakeRepository) GetSubscription(_ context.Context, subscriptionID int) (Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub, ok := r.subs[subscriptionID]
+	if !ok {
+		return Subscription{}, ErrSubscriptionNotFound
+	}
+
+	return sub, nil
+}
+
+func (r *fakeRepository) DeleteSubscription(_ context.Context, subscriptionID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.subs[subscriptionID]; !ok {
+		return ErrSubscriptionNotFound
+	}
+
+	delete(r.subs, subscriptionID)
+
+	return nil
+}
+
+func (r *fakeRepository) ListSubscriptionsForEvent(_ context.Context, userID int, eventType string) ([]Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var subs []Subscription
+
+	for _, sub := range r.subs {
+		if sub.UserID == userID && slices.Contains(sub.EventTypes, eventType) {
+			subs = append(subs, sub)
+		}
+	}
+
+	return subs, nil
+}
+
+func (r *fakeRepository) CreateDelivery(_ context.Context, delivery Delivery) (Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	delivery.DeliveryID = r.nextID
+	delivery.CreatedAt = r.now()
+	r.deliveries[delivery.DeliveryID] = delivery
+
+	return delivery, nil
+}
+
+func (r *fakeRepository) ClaimDueDeliveries(_ context.Context, limit int, lease time.Duration) ([]Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []Delivery
+
+	for id, d := range r.deliveries {
+		if len(due) == limit {
+			break
+		}
+
+		if d.Status == StatusPending && !d.NextAttemptAt.After(r.now()) {
+			due = append(due, d)
+			d.NextAttemptAt = r.now().Add(lease)
+			r.deliveries[id] = d
+		}
+	}
+
+	return due, nil
+}
+
+func (r *fakeRepository) UpdateDelivery(_ context.Context, delivery Delivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.deliveries[delivery.DeliveryID] = delivery
+
+	return nil
+}
+
+func (r *fakeRepository) ListDeliveries(_ context.Context, subscriptionID int, _ int) ([]Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deliveries []Delivery
+
+	for _, d := range r.deliveries {
+		if d.SubscriptionID == subscriptionID {
+			deliveries = append(deliveries, d)
+		}
+	}
+
+	return deliveries, nil
+}
+
+func (r *fakeRepository) ResetDeliveries(_ context.Context, status string, subscriptionID int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+
+	for id, d := range r.deliveries {
+		if d.Status != status || (subscriptionID != 0 && d.SubscriptionID != subscriptionID) {
+			continue
+		}
+
+		d.Status = StatusPending
+		d.Attempts = 0
+		d.NextAttemptAt = r.now()
+		d.DeliveredAt = nil
+		r.deliveries[id] = d
+		count++
+	}
+
+	return count, nil
+}
+
+func (r *fakeRepository) ResetDelivery(_ context.Context, subscriptionID, deliveryID int) (Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, ok := r.deliveries[deliveryID]
+	if !ok || d.SubscriptionID != subscriptionID {
+		return Delivery{}, ErrDeliveryNotFound
+	}
+
+	d.Status = StatusPending
+	d.Attempts = 0
+	d.NextAttemptAt = r.now()
+	d.DeliveredAt = nil
+	r.deliveries[deliveryID] = d
+
+	return d, nil
+}
+
+var _ Repository = (*fakeRepository)(nil)
+
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func setupDispatcher(t *testing.T, handler http.HandlerFunc) (*Dispatcher, *fakeRepository, *testClock, Subscription) {
+	t.Helper()
+
+	return setupDispatcherWith(t, handler, true)
+}
+
+// setupDispatcherWith posts to a receiver on the loopback interface, which is
+// only reachable with allowInternal
+func setupDispatcherWith(
+	t *testing.T,
+	handler http.HandlerFunc,
+	allowInternal bool,
+) (*Dispatcher, *fakeRepository, *testClock, Subscription) {
+	t.Helper()
+
+	receiver := httptest.NewServer(handler)
+	t.Cleanup(receiver.Close)
+
+	clock := &testClock{now: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)}
+	repo := newFakeRepository(clock.Now)
+
+	dispatcher := NewDispatcher(repo, DispatcherConfig{
+		MaxAttempts:    3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+		PollInterval:   time.Second,
+		Timeout:        time.Second,
+		BatchSize:      10,
+
+		AllowInternalAddresses: allowInternal,
+	})
+	dispatcher.now = clock.Now
+
+	sub, err := repo.CreateSubscription(context.Background(), Subscription{
+		UserID:     1,
+		URL:        receiver.URL,
+		EventTypes: []string{wallet.EventDeposit},
+		Secret:     "s3cret",
+	})
+	if err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+
+	return dispatcher, repo, clock, sub
+}
+
+func TestSign(t *testing.T) {
+	// Arrange
+	body := []byte(`{"type":"deposit"}`)
+
+	// Act
+	first := Sign("secret", 1700000000, body)
+	second := Sign("other", 1700000000, body)
+
+	// Assert
+	if !strings.HasPrefix(first, "t=1700000000,v1=") {
+		t.Fatalf("unexpected signature format: %s", first)
+	}
+
+	if first == second {
+		t.Fatalf("expected signatures with different secrets to differ")
+	}
+}
+
+func TestDispatcher_DeliversSignedPayload(t *testing.T) {
+	// Arrange
+	var (
+		mu       sync.Mutex
+		received []*http.Request
+		bodies   [][]byte
+	)
+
+	dispatcher, repo, clock, sub := setupDispatcher(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		received = append(received, r)
+		bodies = append(bodies, body)
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	event := wallet.Event{Type: wallet.EventDeposit, UserID: 1, Amount: 10, Balance: 110}
+
+	// Act
+	if err := dispatcher.Notify(context.Background(), event); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// not subscribed event type and other user must not be queued
+	_ = dispatcher.Notify(context.Background(), wallet.Event{Type: wallet.EventWithdraw, UserID: 1})
+	_ = dispatcher.Notify(context.Background(), wallet.Event{Type: wallet.EventDeposit, UserID: 2})
+
+	attempted, err := dispatcher.DeliverDue(context.Background())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if attempted != 1 || len(received) != 1 {
+		t.Fatalf("expected exactly one delivery, attempted %d, received %d", attempted, len(received))
+	}
+
+	req := received[0]
+	expected := Sign(sub.Secret, clock.Now().Unix(), bodies[0])
+
+	if got := req.Header.Get(HeaderSignature); got != expected {
+		t.Fatalf("expected signature %s, got %s", expected, got)
+	}
+
+	if got := req.Header.Get(HeaderEvent); got != wallet.EventDeposit {
+		t.Fatalf("expected event header %s, got %s", wallet.EventDeposit, got)
+	}
+
+	var payload struct {
+		Type string       `json:"type"`
+		Data wallet.Event `json:"data"`
+	}
+	if err = json.Unmarshal(bodies[0], &payload); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+
+	if payload.Data.Balance != event.Balance {
+		t.Fatalf("expected balance %v, got %v", event.Balance, payload.Data.Balance)
+	}
+
+	deliveryID, _ := strconv.Atoi(req.Header.Get(HeaderDelivery))
+	if d := repo.deliveries[deliveryID]; d.Status != StatusDelivered || d.DeliveredAt == nil {
+		t.Fatalf("expected delivery to be delivered, got %+v", d)
+	}
+}
+
+func TestDispatcher_RefusesInternalAddress(t *testing.T) {
+	// Arrange
+	var calls atomic.Int32
+
+	dispatcher, repo, _, _ := setupDispatcherWith(t, func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}, false)
+
+	if err := dispatcher.Notify(context.Background(), wallet.Event{Type: wallet.EventDeposit, UserID: 1}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Act
+	attempted, err := dispatcher.DeliverDue(context.Background())
+
+	// Assert
+	if err != nil || attempted != 1 {
+		t.Fatalf("expected one attempt without error, got %d, %v", attempted, err)
+	}
+
+	if calls.Load() != 0 {
+		t.Fatalf("expected the internal receiver not to be called, got %d calls", calls.Load())
+	}
+
+	for _, d := range repo.deliveries {
+		if d.Status != StatusPending || !strings.Contains(d.LastError, ErrInternalAddress.Error()) {
+			t.Fatalf("expected delivery to be refused for its address, got %+v", d)
+		}
+	}
+}
+
+func TestDispatcher_RetriesWithBackoffUntilDead(t *testing.T) {
+	// Arrange
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+
+	dispatcher, repo, clock, _ := setupDispatcher(t, func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	if err := dispatcher.Notify(context.Background(), wallet.Event{Type: wallet.EventDeposit, UserID: 1}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Act & Assert: first attempt fails and is scheduled 1s later
+	_, _ = dispatcher.DeliverDue(context.Background())
+
+	var delivery Delivery
+	for _, d := range repo.deliveries {
+		delivery = d
+	}
+
+	if delivery.Status != StatusPending || delivery.Attempts != 1 || delivery.LastStatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected pending delivery after first failure, got %+v", delivery)
+	}
+
+	if wait := delivery.NextAttemptAt.Sub(clock.Now()); wait != time.Second {
+		t.Fatalf("expected first backoff of 1s, got %v", wait)
+	}
+
+	// not due yet
+	if attempted, _ := dispatcher.DeliverDue(context.Background()); attempted != 0 {
+		t.Fatalf("expected no attempt before backoff elapsed, got %d", attempted)
+	}
+
+	clock.Advance(time.Second)
+	_, _ = dispatcher.DeliverDue(context.Background())
+
+	delivery = repo.deliveries[delivery.DeliveryID]
+	if wait := delivery.NextAttemptAt.Sub(clock.Now()); wait != 2*time.Second {
+		t.Fatalf("expected second backoff of 2s, got %v", wait)
+	}
+
+	clock.Advance(2 * time.Second)
+	_, _ = dispatcher.DeliverDue(context.Background())
+
+	delivery = repo.deliveries[delivery.DeliveryID]
+	if delivery.Status != StatusDead || delivery.Attempts != 3 {
+		t.Fatalf("expected dead delivery after 3 attempts, got %+v", delivery)
+	}
+
+	if calls != 3 {
+		t.Fatalf("expected receiver to be called 3 times, got %d", calls)
+	}
+
+	// Act: manual redelivery revives a dead delivery
+	svc := NewService(repo, DispatcherConfig{})
+
+	redelivered, err := svc.Redeliver(context.Background(), delivery.SubscriptionID, delivery.DeliveryID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if redelivered.Status != StatusPending || redelivered.Attempts != 0 {
+		t.Fatalf("expected delivery to be pending again, got %+v", redelivered)
+	}
+
+	if attempted, _ := dispatcher.DeliverDue(context.Background()); attempted != 1 {
+		t.Fatalf("expected redelivery to be attempted, got %d", attempted)
+	}
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	dispatcher := &Dispatcher{cfg: DispatcherConfig{InitialBackoff: 10 * time.Second, MaxBackoff: time.Minute}}
+
+	expected := []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute}
+
+	for i, want := range expected {
+		if got := dispatcher.backoff(i + 1); got != want {
+			t.Fatalf("attempt %d: expected backoff %v, got %v", i+1, want, got)
+		}
+	}
+}
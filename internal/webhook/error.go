@@ -0,0 +1,12 @@
+package webhook
+
+import "errors"
+
+var (
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+	ErrDeliveryNotFound     = errors.New("delivery not found")
+	ErrInvalidURL           = errors.New("invalid webhook url")
+	ErrInternalAddress      = errors.New("webhook url points to an internal address")
+	ErrInvalidEventType     = errors.New("invalid event type")
+	ErrInvalidStatus        = errors.New("invalid delivery status")
+)
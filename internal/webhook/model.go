@@ -0,0 +1,102 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Delivery states. A delivery stays pending while it has attempts left,
+// and ends up either delivered or dead.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+// Subscription struct is used to map records from webhook_subscriptions table
+type Subscription struct {
+	SubscriptionID int       `json:"subscription_id"`
+	UserID         int       `json:"user_id"`
+	URL            string    `json:"url"`
+	EventTypes     []string  `json:"event_types"`
+	Secret         string    `json:"secret,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Delivery struct is used to map records from webhook_deliveries table
+type Delivery struct {
+	DeliveryID     int             `json:"delivery_id"`
+	SubscriptionID int             `json:"subscription_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// Repository defines methods to interact with the webhook data.
+type Repository interface {
+	CreateSubscription(ctx context.Context, sub Subscription) (Subscription, error)
+	GetSubscription(ctx context.Context, subscriptionID int) (Subscription, error)
+	DeleteSubscription(ctx context.Context, subscriptionID int) error
+	ListSubscriptionsForEvent(ctx context.Context, userID int, eventType string) ([]Subscription, error)
+	CreateDelivery(ctx context.Context, delivery Delivery) (Delivery, error)
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error)
+	UpdateDelivery(ctx context.Context, delivery Delivery) error
+	ListDeliveries(ctx context.Context, subscriptionID int, limit int) ([]Delivery, error)
+	ResetDelivery(ctx context.Context, subscriptionID, deliveryID int) (Delivery, error)
+	ResetDeliveries(ctx context.Context, status string, subscriptionID int) (int64, error)
+}
+
+// Service manages subscriptions and their delivery log
+type Service interface {
+	CreateSubscription(ctx context.Context, userID int, url string, eventTypes []string, secret string) (Subscription, error)
+	GetSubscription(ctx context.Context, subscriptionID int) (Subscription, error)
+	DeleteSubscription(ctx context.Context, subscriptionID int) error
+	ListDeliveries(ctx context.Context, subscriptionID int, limit int) ([]Delivery, error)
+	Redeliver(ctx context.Context, subscriptionID, deliveryID int) (Delivery, error)
+	ReplayDeliveries(ctx context.Context, status string, subscriptionID int) (int64, error)
+}
+
+// DispatcherConfig controls the retry schedule of outbound deliveries
+type DispatcherConfig struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	PollInterval   time.Duration
+	Timeout        time.Duration
+	BatchSize      int
+	// AllowInternalAddresses lets receivers on loopback and private networks
+	// subscribe, for local development only
+	AllowInternalAddresses bool
+}
+
+// Dispatcher turns wallet events into deliveries and sends them.
+// It implements wallet.Notifier.
+type Dispatcher struct {
+	repo   Repository
+	client *http.Client
+	cfg    DispatcherConfig
+	logger *logrus.Entry
+	now    func() time.Time
+}
+
+type webhookService struct {
+	repo          Repository
+	lookup        lookupFunc
+	allowInternal bool
+	logger        *logrus.Entry
+}
+
+type webhookRepository struct {
+	db *sql.DB
+}
@@ -0,0 +1,303 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/database"
+	"github.com/lib/pq"
+)
+
+const deliveryColumns = `delivery_id, subscription_id, event_type, payload, status, attempts,
+    last_status_code, last_error, next_attempt_at, created_at, delivered_at`
+
+//nolint:ireturn // stick to interface
+func InitRepository() (Repository, error) {
+	dbConfig, err := database.NewDatabaseConfig()
+	if err != nil {
+		return nil, err //nolint:wrapcheck // names the secret that failed
+	}
+
+	postgre, err := dbConfig.ConnectPostgre()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+
+	return NewRepository(postgre), nil
+}
+
+// NewRepository returns the Postgres backed repository on db.
+//
+//nolint:ireturn // stick to interface
+func NewRepository(db *sql.DB) Repository {
+	return &webhookRepository{db: db}
+}
+
+// CreateSubscription inserts a new subscription and returns it with its id
+func (r *webhookRepository) CreateSubscription(ctx context.Context, sub Subscription) (Subscription, error) {
+	query := `INSERT INTO webhook_subscriptions (user_id, url, event_types, secret)
+              VALUES ($1, $2, $3, $4) RETURNING subscription_id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query, sub.UserID, sub.URL, pq.Array(sub.EventTypes), sub.Secret).
+		Scan(&sub.SubscriptionID, &sub.CreatedAt)
+	if err != nil {
+		return Subscription{}, fmt.Errorf("failed to insert subscription for user %d: %w", sub.UserID, err)
+	}
+
+	return sub, nil
+}
+
+// GetSubscription returns the subscription including its secret
+func (r *webhookRepository) GetSubscription(ctx context.Context, subscriptionID int) (Subscription, error) {
+	var sub Subscription
+
+	query := `SELECT subscription_id, user_id, url, event_types, secret, created_at
+              FROM webhook_subscriptions WHERE subscription_id = $1`
+
+	err := r.db.QueryRowContext(ctx, query, subscriptionID).Scan(
+		&sub.SubscriptionID,
+		&sub.UserID,
+		&sub.URL,
+		pq.Array(&sub.EventTypes),
+		&sub.Secret,
+		&sub.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Subscription{}, ErrSubscriptionNotFound
+	}
+
+	if err != nil {
+		return Subscription{}, fmt.Errorf("failed to query subscription %d: %w", subscriptionID, err)
+	}
+
+	return sub, nil
+}
+
+// DeleteSubscription removes the subscription, its deliveries are removed by cascade
+func (r *webhookRepository) DeleteSubscription(ctx context.Context, subscriptionID int) error {
+	query := `DELETE FROM webhook_subscriptions WHERE subscription_id = $1`
+
+	res, err := r.db.ExecContext(ctx, query, subscriptionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete subscription %d: %w", subscriptionID, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows for subscription %d: %w", subscriptionID, err)
+	}
+
+	if affected == 0 {
+		return ErrSubscriptionNotFound
+	}
+
+	return nil
+}
+
+// ListSubscriptionsForEvent returns the subscriptions of a user interested in the event type
+func (r *webhookRepository) ListSubscriptionsForEvent(ctx context.Context, userID int, eventType string) ([]Subscription, error) {
+	query := `SELECT subscription_id, user_id, url, event_types, secret, created_at
+              FROM webhook_subscriptions WHERE user_id = $1 AND $2 = ANY(event_types)`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, eventType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subscriptions for user %d: %w", userID, err)
+	}
+	defer rows.Close()
+
+	//nolint:prealloc // row count unknown
+	var subs []Subscription
+
+	for rows.Next() {
+		var sub Subscription
+
+		err = rows.Scan(&sub.SubscriptionID, &sub.UserID, &sub.URL, pq.Array(&sub.EventTypes), &sub.Secret, &sub.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subscription for user %d: %w", userID, err)
+		}
+
+		subs = append(subs, sub)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error arises during rows iteration for user %d: %w", userID, err)
+	}
+
+	return subs, nil
+}
+
+// CreateDelivery queues a delivery for the dispatcher
+func (r *webhookRepository) CreateDelivery(ctx context.Context, delivery Delivery) (Delivery, error) {
+	query := `INSERT INTO webhook_deliveries (subscription_id, event_type, payload, status, next_attempt_at)
+              VALUES ($1, $2, $3, $4, $5) RETURNING delivery_id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		delivery.SubscriptionID,
+		delivery.EventType,
+		[]byte(delivery.Payload),
+		delivery.Status,
+		delivery.NextAttemptAt,
+	).Scan(&delivery.DeliveryID, &delivery.CreatedAt)
+	if err != nil {
+		return Delivery{}, fmt.Errorf("failed to insert delivery for subscription %d: %w", delivery.SubscriptionID, err)
+	}
+
+	return delivery, nil
+}
+
+// ClaimDueDeliveries picks pending deliveries whose next attempt is due and pushes
+// their next attempt out by lease, so that other instances skip them meanwhile
+func (r *webhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error) {
+	query := `
+    UPDATE webhook_deliveries SET next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond'
+    WHERE delivery_id IN (
+        SELECT delivery_id FROM webhook_deliveries
+        WHERE status = 'pending' AND next_attempt_at <= NOW()
+        ORDER BY next_attempt_at
+        LIMIT $1
+        FOR UPDATE SKIP LOCKED
+    )
+    RETURNING ` + deliveryColumns
+
+	rows, err := r.db.QueryContext(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDeliveries(rows)
+}
+
+// UpdateDelivery records the outcome of an attempt
+func (r *webhookRepository) UpdateDelivery(ctx context.Context, delivery Delivery) error {
+	query := `UPDATE webhook_deliveries
+              SET status = $1, attempts = $2, last_status_code = $3, last_error = $4,
+                  next_attempt_at = $5, delivered_at = $6
+              WHERE delivery_id = $7`
+
+	_, err := r.db.ExecContext(ctx, query,
+		delivery.Status,
+		delivery.Attempts,
+		sql.NullInt64{Int64: int64(delivery.LastStatusCode), Valid: delivery.LastStatusCode != 0},
+		sql.NullString{String: delivery.LastError, Valid: delivery.LastError != ""},
+		delivery.NextAttemptAt,
+		delivery.DeliveredAt,
+		delivery.DeliveryID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update delivery %d: %w", delivery.DeliveryID, err)
+	}
+
+	return nil
+}
+
+// ListDeliveries returns the delivery log of a subscription, newest first
+func (r *webhookRepository) ListDeliveries(ctx context.Context, subscriptionID int, limit int) ([]Delivery, error) {
+	query := `SELECT ` + deliveryColumns + `
+    FROM webhook_deliveries WHERE subscription_id = $1
+    ORDER BY created_at DESC, delivery_id DESC
+    LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, subscriptionID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deliveries for subscription %d: %w", subscriptionID, err)
+	}
+	defer rows.Close()
+
+	return scanDeliveries(rows)
+}
+
+// ResetDelivery puts a delivery back into the queue with a fresh attempt budget
+func (r *webhookRepository) ResetDelivery(ctx context.Context, subscriptionID, deliveryID int) (Delivery, error) {
+	query := `UPDATE webhook_deliveries
+              SET status = 'pending', attempts = 0, next_attempt_at = NOW(), delivered_at = NULL
+              WHERE subscription_id = $1 AND delivery_id = $2
+              RETURNING ` + deliveryColumns
+
+	rows, err := r.db.QueryContext(ctx, query, subscriptionID, deliveryID)
+	if err != nil {
+		return Delivery{}, fmt.Errorf("failed to reset delivery %d: %w", deliveryID, err)
+	}
+	defer rows.Close()
+
+	deliveries, err := scanDeliveries(rows)
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	if len(deliveries) == 0 {
+		return Delivery{}, ErrDeliveryNotFound
+	}
+
+	return deliveries[0], nil
+}
+
+// ResetDeliveries puts every delivery in the given status back into the queue,
+// limited to one subscription unless subscriptionID is 0
+func (r *webhookRepository) ResetDeliveries(ctx context.Context, status string, subscriptionID int) (int64, error) {
+	query := `UPDATE webhook_deliveries
+              SET status = 'pending', attempts = 0, next_attempt_at = NOW(), delivered_at = NULL
+              WHERE status = $1 AND ($2 = 0 OR subscription_id = $2)`
+
+	result, err := r.db.ExecContext(ctx, query, status, subscriptionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reset %s deliveries: %w", status, err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count reset deliveries: %w", err)
+	}
+
+	return count, nil
+}
+
+func scanDeliveries(rows *sql.Rows) ([]Delivery, error) {
+	var deliveries []Delivery
+
+	for rows.Next() {
+		var (
+			d          Delivery
+			payload    []byte
+			statusCode sql.NullInt64
+			lastErr    sql.NullString
+			delivered  sql.NullTime
+		)
+
+		err := rows.Scan(
+			&d.DeliveryID,
+			&d.SubscriptionID,
+			&d.EventType,
+			&payload,
+			&d.Status,
+			&d.Attempts,
+			&statusCode,
+			&lastErr,
+			&d.NextAttemptAt,
+			&d.CreatedAt,
+			&delivered,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+
+		d.Payload = payload
+		d.LastStatusCode = int(statusCode.Int64)
+		d.LastError = lastErr.String
+
+		if delivered.Valid {
+			d.DeliveredAt = &delivered.Time
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error arises during rows iteration: %w", err)
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,157 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"slices"
+
+	"github.com/amelonpie/wallet-service/internal/wallet"
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	secretBytes         = 32
+	defaultDeliveryList = 50
+)
+
+// EventTypes lists the wallet events a subscription can ask for
+func EventTypes() []string {
+	return []string{wallet.EventDeposit, wallet.EventWithdraw, wallet.EventTransfer, wallet.EventAdjustment}
+}
+
+// NewService refuses subscriptions to internal addresses unless
+// cfg.AllowInternalAddresses is set
+//
+//nolint:ireturn // stick to interface
+func NewService(repo Repository, cfg DispatcherConfig) Service {
+	return &webhookService{
+		repo:          repo,
+		lookup:        net.DefaultResolver.LookupIPAddr,
+		allowInternal: cfg.AllowInternalAddresses,
+		logger:        log.NewLogger("webhook").WithField("module", "service"),
+	}
+}
+
+// CreateSubscription validates and stores a subscription. The url must not
+// resolve to an internal address. When no secret is given a random one is
+// generated; it is only returned by this call.
+func (s *webhookService) CreateSubscription(
+	ctx context.Context,
+	userID int,
+	rawURL string,
+	eventTypes []string,
+	secret string,
+) (Subscription, error) {
+	parsed, err := checkURL(ctx, s.lookup, rawURL, s.allowInternal)
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	if len(eventTypes) == 0 {
+		return Subscription{}, fmt.Errorf("%w: at least one is required", ErrInvalidEventType)
+	}
+
+	for _, t := range eventTypes {
+		if !slices.Contains(EventTypes(), t) {
+			return Subscription{}, fmt.Errorf("%w: %q", ErrInvalidEventType, t)
+		}
+	}
+
+	if secret == "" {
+		buf := make([]byte, secretBytes)
+		if _, err = rand.Read(buf); err != nil {
+			return Subscription{}, fmt.Errorf("failed to generate secret: %w", err)
+		}
+
+		secret = hex.EncodeToString(buf)
+	}
+
+	sub, err := s.repo.CreateSubscription(ctx, Subscription{
+		UserID:     userID,
+		URL:        parsed.String(),
+		EventTypes: eventTypes,
+		Secret:     secret,
+	})
+	if err != nil {
+		return Subscription{}, fmt.Errorf("failed to create subscription for user %d: %w", userID, err)
+	}
+
+	s.logger.WithField("subscription_id", sub.SubscriptionID).Info("subscription created")
+
+	return sub, nil
+}
+
+// GetSubscription returns the subscription without its secret
+func (s *webhookService) GetSubscription(ctx context.Context, subscriptionID int) (Subscription, error) {
+	sub, err := s.repo.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		return Subscription{}, fmt.Errorf("failed to get subscription %d: %w", subscriptionID, err)
+	}
+
+	sub.Secret = ""
+
+	return sub, nil
+}
+
+func (s *webhookService) DeleteSubscription(ctx context.Context, subscriptionID int) error {
+	if err := s.repo.DeleteSubscription(ctx, subscriptionID); err != nil {
+		return fmt.Errorf("failed to delete subscription %d: %w", subscriptionID, err)
+	}
+
+	return nil
+}
+
+// ListDeliveries returns the newest deliveries of a subscription
+func (s *webhookService) ListDeliveries(ctx context.Context, subscriptionID int, limit int) ([]Delivery, error) {
+	if _, err := s.repo.GetSubscription(ctx, subscriptionID); err != nil {
+		return nil, fmt.Errorf("failed to get subscription %d: %w", subscriptionID, err)
+	}
+
+	if limit <= 0 {
+		limit = defaultDeliveryList
+	}
+
+	deliveries, err := s.repo.ListDeliveries(ctx, subscriptionID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries for subscription %d: %w", subscriptionID, err)
+	}
+
+	return deliveries, nil
+}
+
+// Redeliver queues the delivery again, also when it is already dead or delivered
+func (s *webhookService) Redeliver(ctx context.Context, subscriptionID, deliveryID int) (Delivery, error) {
+	delivery, err := s.repo.ResetDelivery(ctx, subscriptionID, deliveryID)
+	if err != nil {
+		return Delivery{}, fmt.Errorf("failed to redeliver %d: %w", deliveryID, err)
+	}
+
+	s.logger.WithField("delivery_id", deliveryID).Info("delivery queued for redelivery")
+
+	return delivery, nil
+}
+
+// ReplayDeliveries queues every dead or delivered delivery again, of one subscription
+// or of all when subscriptionID is 0, and returns how many were queued
+func (s *webhookService) ReplayDeliveries(ctx context.Context, status string, subscriptionID int) (int64, error) {
+	if status != StatusDead && status != StatusDelivered {
+		return 0, fmt.Errorf("%w: %q, replay %s or %s deliveries", ErrInvalidStatus, status, StatusDead, StatusDelivered)
+	}
+
+	count, err := s.repo.ResetDeliveries(ctx, status, subscriptionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to replay deliveries: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"status":          status,
+		"subscription_id": subscriptionID,
+		"count":           count,
+	}).Info("deliveries queued for replay")
+
+	return count, nil
+}
@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/amelonpie/wallet-service/internal/wallet"
+)
+
+// newTestService resolves host names from a fixed table instead of DNS
+//
+//nolint:ireturn // stick to interface
+func newTestService(repo Repository) Service {
+	svc := NewService(repo, DispatcherConfig{}).(*webhookService) //nolint:forcetypeassert // built right above
+	hosts := map[string][]net.IPAddr{
+		"merchant.example": {{IP: net.ParseIP("93.184.216.34")}},
+		"intranet.example": {{IP: net.ParseIP("93.184.216.34")}, {IP: net.ParseIP("10.1.2.3")}},
+	}
+	svc.lookup = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		if addrs, ok := hosts[host]; ok {
+			return addrs, nil
+		}
+
+		return net.DefaultResolver.LookupIPAddr(ctx, host)
+	}
+
+	return svc
+}
+
+func TestWebhookService_CreateSubscription(t *testing.T) {
+	// Arrange
+	repo := newFakeRepository(time.Now)
+	svc := newTestService(repo)
+
+	// Act
+	sub, err := svc.CreateSubscription(context.Background(), 1, "https://merchant.example/hook", []string{wallet.EventDeposit}, "")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(sub.Secret) != 2*secretBytes {
+		t.Fatalf("expected generated secret of %d hex chars, got %q", 2*secretBytes, sub.Secret)
+	}
+
+	fetched, err := svc.GetSubscription(context.Background(), sub.SubscriptionID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if fetched.Secret != "" {
+		t.Fatalf("expected secret to be hidden, got %q", fetched.Secret)
+	}
+}
+
+func TestWebhookService_CreateSubscription_Invalid(t *testing.T) {
+	svc := newTestService(newFakeRepository(time.Now))
+
+	tests := []struct {
+		name       string
+		url        string
+		eventTypes []string
+		expected   error
+	}{
+		{"relative url", "/hook", []string{wallet.EventDeposit}, ErrInvalidURL},
+		{"unsupported scheme", "ftp://merchant.example/hook", []string{wallet.EventDeposit}, ErrInvalidURL},
+		{"loopback", "http://127.0.0.1:8080/hook", []string{wallet.EventDeposit}, ErrInternalAddress},
+		{"ipv6 loopback", "http://[::1]/hook", []string{wallet.EventDeposit}, ErrInternalAddress},
+		{"private network", "https://192.168.1.10/hook", []string{wallet.EventDeposit}, ErrInternalAddress},
+		{"metadata endpoint", "http://169.254.169.254/latest/meta-data", []string{wallet.EventDeposit}, ErrInternalAddress},
+		{"mapped private address", "http://[::ffff:10.0.0.1]/hook", []string{wallet.EventDeposit}, ErrInternalAddress},
+		{"one internal address", "https://intranet.example/hook", []string{wallet.EventDeposit}, ErrInternalAddress},
+		{"no event types", "https://merchant.example/hook", nil, ErrInvalidEventType},
+		{"unknown event type", "https://merchant.example/hook", []string{"refund"}, ErrInvalidEventType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.CreateSubscription(context.Background(), 1, tt.url, tt.eventTypes, "secret")
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("expected error %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestWebhookService_ListDeliveries_UnknownSubscription(t *testing.T) {
+	svc := newTestService(newFakeRepository(time.Now))
+
+	_, err := svc.ListDeliveries(context.Background(), 42, 0)
+	if !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Fatalf("expected error %v, got %v", ErrSubscriptionNotFound, err)
+	}
+}
+
+func TestWebhookService_ReplayDeliveries(t *testing.T) {
+	// Arrange
+	repo := newFakeRepository(time.Now)
+	svc := newTestService(repo)
+
+	repo.deliveries = map[int]Delivery{
+		1: {DeliveryID: 1, SubscriptionID: 1, Status: StatusDead, Attempts: 8},
+		2: {DeliveryID: 2, SubscriptionID: 2, Status: StatusDead, Attempts: 8},
+		3: {DeliveryID: 3, SubscriptionID: 1, Status: StatusDelivered, Attempts: 1},
+	}
+
+	// Act
+	count, err := svc.ReplayDeliveries(context.Background(), StatusDead, 1)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if count != 1 || repo.deliveries[1].Status != StatusPending || repo.deliveries[1].Attempts != 0 {
+		t.Fatalf("expected only delivery 1 to be queued again, got %d: %+v", count, repo.deliveries)
+	}
+
+	if repo.deliveries[2].Status != StatusDead || repo.deliveries[3].Status != StatusDelivered {
+		t.Fatalf("expected other deliveries untouched, got %+v", repo.deliveries)
+	}
+
+	if _, err = svc.ReplayDeliveries(context.Background(), StatusPending, 0); !errors.Is(err, ErrInvalidStatus) {
+		t.Fatalf("expected error %v, got %v", ErrInvalidStatus, err)
+	}
+}
@@ -0,0 +1,201 @@
+package breaker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/sirupsen/logrus"
+)
+
+// State of a circuit breaker
+type State int
+
+const (
+	// Closed lets every call through
+	Closed State = iota
+	// Open rejects every call until the open timeout passed
+	Open
+	// HalfOpen lets a single trial call through to probe for recovery
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText writes the state by name in JSON and logs
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+type Config struct {
+	// FailureThreshold is the number of consecutive failures that opens the circuit
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before a trial call
+	OpenTimeout time.Duration
+}
+
+// Breaker is a consecutive-failure circuit breaker, safe for concurrent use.
+// Every call admitted by Allow must be followed by exactly one Record or Cancel.
+type Breaker struct {
+	mu       sync.Mutex
+	cfg      Config
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+	now      func() time.Time
+	logger   *logrus.Entry
+}
+
+// New creates a closed breaker, name identifies the guarded dependency in logs
+func New(name string, cfg Config) *Breaker {
+	if cfg.FailureThreshold < 1 {
+		cfg.FailureThreshold = 1
+	}
+
+	return &Breaker{
+		cfg:    cfg,
+		now:    time.Now,
+		logger: log.NewLogger("breaker").WithField("module", "breaker").WithField("breaker", name),
+	}
+}
+
+// Call is a call admitted by Allow, its outcome is reported with Record or Cancel
+type Call struct {
+	// probe is the single trial call of the half-open circuit
+	probe bool
+}
+
+// Allow reports whether a call may go through
+func (b *Breaker) Allow() (Call, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Closed:
+		return Call{}, true
+	case Open:
+		if b.now().Sub(b.openedAt) < b.cfg.OpenTimeout {
+			return Call{}, false
+		}
+
+		b.transition(HalfOpen)
+		b.probing = true
+
+		return Call{probe: true}, true
+	default:
+		if b.probing {
+			return Call{}, false
+		}
+
+		b.probing = true
+
+		return Call{probe: true}, true
+	}
+}
+
+// Record reports the outcome of a call admitted by Allow. Only the probe decides
+// whether a half-open circuit closes; other calls were admitted before the circuit
+// opened and prove nothing about recovery.
+func (b *Breaker) Record(call Call, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if call.probe {
+		b.probing = false
+
+		if b.state != HalfOpen {
+			return
+		}
+
+		if err == nil {
+			b.failures = 0
+			b.transition(Closed)
+
+			return
+		}
+
+		b.logger.WithField("err", err).Warn("dependency still unhealthy")
+		b.open()
+
+		return
+	}
+
+	if b.state != Closed {
+		return
+	}
+
+	if err == nil {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.cfg.FailureThreshold {
+		b.logger.WithField("err", err).Warn("dependency unhealthy")
+		b.open()
+	}
+}
+
+// Cancel reports a call admitted by Allow that ended without telling anything
+// about the dependency, e.g. because its caller gave up. A cancelled probe lets
+// the next call probe instead.
+func (b *Breaker) Cancel(call Call) {
+	if !call.probe {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// Trip opens the circuit, e.g. when the dependency is already down at startup
+func (b *Breaker) Trip() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != Open {
+		b.open()
+	}
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+func (b *Breaker) open() {
+	b.openedAt = b.now()
+	b.transition(Open)
+}
+
+func (b *Breaker) transition(to State) {
+	entry := b.logger.WithFields(logrus.Fields{
+		"from": b.state,
+		"to":   to,
+	})
+
+	b.state = to
+
+	if to == Closed {
+		entry.Info("circuit closed, dependency recovered")
+		return
+	}
+
+	entry.Warn("circuit state changed")
+}
@@ -0,0 +1,161 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var errDown = errors.New("connection refused")
+
+func newTestBreaker(threshold int) (*Breaker, *time.Time) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	b := New("test", Config{FailureThreshold: threshold, OpenTimeout: 30 * time.Second})
+	b.now = func() time.Time { return now }
+
+	return b, &now
+}
+
+func TestBreaker_OpensAfterConsecutiveFailures(t *testing.T) {
+	// Arrange
+	b, _ := newTestBreaker(3)
+
+	// Act
+	for range 2 {
+		call, _ := b.Allow()
+		b.Record(call, errDown)
+	}
+
+	call, _ := b.Allow()
+	b.Record(call, nil) // a success resets the count
+
+	for range 3 {
+		call, _ = b.Allow()
+		b.Record(call, errDown)
+	}
+
+	// Assert
+	if b.State() != Open {
+		t.Fatalf("expected open, got %v", b.State())
+	}
+
+	if _, ok := b.Allow(); ok {
+		t.Fatalf("expected open circuit to reject calls")
+	}
+}
+
+func TestBreaker_HalfOpenProbe(t *testing.T) {
+	// Arrange
+	b, now := newTestBreaker(1)
+	b.Trip()
+
+	*now = now.Add(30 * time.Second)
+
+	// Act & Assert: a single trial after the timeout
+	probe, ok := b.Allow()
+	if !ok {
+		t.Fatalf("expected trial call after open timeout")
+	}
+
+	if b.State() != HalfOpen {
+		t.Fatalf("expected half-open, got %v", b.State())
+	}
+
+	if _, ok = b.Allow(); ok {
+		t.Fatalf("expected only one trial call at a time")
+	}
+
+	// failed trial opens again
+	b.Record(probe, errDown)
+
+	if _, ok = b.Allow(); b.State() != Open || ok {
+		t.Fatalf("expected open after failed trial, got %v", b.State())
+	}
+
+	// successful trial closes
+	*now = now.Add(30 * time.Second)
+
+	probe, _ = b.Allow()
+	b.Record(probe, nil)
+
+	if b.State() != Closed {
+		t.Fatalf("expected closed after successful trial, got %v", b.State())
+	}
+}
+
+func TestBreaker_LateSuccessDoesNotClose(t *testing.T) {
+	// Arrange
+	b, _ := newTestBreaker(1)
+
+	late, _ := b.Allow() // admitted while closed, finishes after the circuit opened
+	call, _ := b.Allow()
+	b.Record(call, errDown)
+
+	// Act
+	b.Record(late, nil)
+
+	// Assert
+	if b.State() != Open {
+		t.Fatalf("expected open, got %v", b.State())
+	}
+}
+
+func TestBreaker_LateCallDoesNotEndProbe(t *testing.T) {
+	// Arrange
+	b, now := newTestBreaker(1)
+
+	late, _ := b.Allow() // admitted while closed, finishes during the probe
+	call, _ := b.Allow()
+	b.Record(call, errDown)
+
+	*now = now.Add(30 * time.Second)
+	probe, _ := b.Allow()
+
+	// Act
+	b.Record(late, nil)
+
+	// Assert
+	if b.State() != HalfOpen {
+		t.Fatalf("expected half-open until the probe finished, got %v", b.State())
+	}
+
+	if _, ok := b.Allow(); ok {
+		t.Fatalf("expected the probe to still be the only trial call")
+	}
+
+	b.Record(probe, nil)
+
+	if b.State() != Closed {
+		t.Fatalf("expected closed after successful probe, got %v", b.State())
+	}
+}
+
+func TestBreaker_CancelledProbe(t *testing.T) {
+	// Arrange
+	b, now := newTestBreaker(1)
+	b.Trip()
+
+	*now = now.Add(30 * time.Second)
+	probe, _ := b.Allow()
+
+	// Act
+	b.Cancel(probe)
+
+	// Assert
+	if b.State() != HalfOpen {
+		t.Fatalf("expected half-open after cancelled probe, got %v", b.State())
+	}
+
+	if _, ok := b.Allow(); !ok {
+		t.Fatalf("expected the next call to probe")
+	}
+}
+
+func TestState_MarshalText(t *testing.T) {
+	for state, name := range map[State]string{Closed: "closed", Open: "open", HalfOpen: "half-open"} {
+		text, _ := state.MarshalText()
+		if string(text) != name {
+			t.Fatalf("expected %s, got %s", name, text)
+		}
+	}
+}
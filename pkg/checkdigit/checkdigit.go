@@ -0,0 +1,132 @@
+// Package checkdigit computes and verifies the check digits of account numbers:
+// ISO 7064 mod 97-10 as used by IBANs, and the Luhn algorithm.
+package checkdigit
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidInput = errors.New("invalid characters for check digits")
+
+const (
+	minIBANLength      = 15
+	maxIBANLength      = 34
+	mod97              = 97
+	ibanCheckRemainder = 1
+	ibanCheckBase      = 98
+)
+
+// Mod97 is the remainder of the number s stands for modulo 97, letters count as 10 to 35
+func Mod97(s string) (int, error) {
+	remainder := 0
+
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			remainder = (remainder*10 + int(r-'0')) % mod97 //nolint:mnd // decimal digit
+		case r >= 'A' && r <= 'Z':
+			remainder = (remainder*100 + int(r-'A') + 10) % mod97 //nolint:mnd // two digits per letter
+		default:
+			return 0, fmt.Errorf("%w: %q", ErrInvalidInput, s)
+		}
+	}
+
+	return remainder, nil
+}
+
+// IBAN builds the IBAN of the country code and the basic bank account number
+func IBAN(country, bban string) (string, error) {
+	country = strings.ToUpper(country)
+	bban = strings.ToUpper(bban)
+
+	if len(country) != 2 || !letters(country) {
+		return "", fmt.Errorf("%w: country %q", ErrInvalidInput, country)
+	}
+
+	remainder, err := Mod97(bban + country + "00")
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s%02d%s", country, ibanCheckBase-remainder, bban), nil
+}
+
+// ValidIBAN checks the country code and the mod-97 check digits of an IBAN
+// without spaces
+func ValidIBAN(iban string) bool {
+	if len(iban) < minIBANLength || len(iban) > maxIBANLength {
+		return false
+	}
+
+	if !letters(iban[:2]) || !digits(iban[2:4]) {
+		return false
+	}
+
+	// the first four characters move to the end
+	remainder, err := Mod97(iban[4:] + iban[:4])
+
+	return err == nil && remainder == ibanCheckRemainder
+}
+
+// LuhnDigit is the check digit to append to the decimal number s
+func LuhnDigit(s string) (byte, error) {
+	if s == "" || !digits(s) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidInput, s)
+	}
+
+	// the digit appended takes the rightmost position, so the doubling starts
+	// at the last digit of s
+	return byte('0' + (10-luhnSum(s, true)%10)%10), nil //nolint:mnd // decimal
+}
+
+// ValidLuhn checks the Luhn check digit at the end of the decimal number s
+func ValidLuhn(s string) bool {
+	if len(s) < 2 || !digits(s) {
+		return false
+	}
+
+	return luhnSum(s, false)%10 == 0 //nolint:mnd // decimal
+}
+
+// luhnSum adds up the digits from the right, doubling every second one
+func luhnSum(s string, doubleFirst bool) int {
+	sum := 0
+	double := doubleFirst
+
+	for i := len(s) - 1; i >= 0; i-- {
+		d := int(s[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 { //nolint:mnd // the digits of a doubled digit add up to d - 9
+				d -= 9
+			}
+		}
+
+		sum += d
+		double = !double
+	}
+
+	return sum
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func letters(s string) bool {
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+
+	return true
+}
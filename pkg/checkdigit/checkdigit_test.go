@@ -0,0 +1,101 @@
+package checkdigit
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIBAN(t *testing.T) {
+	tests := []struct {
+		country, bban, want string
+	}{
+		{"DE", "370400440532013000", "DE89370400440532013000"},
+		{"GB", "NWBK60161331926819", "GB29NWBK60161331926819"},
+		{"fr", "20041010050500013M02606", "FR1420041010050500013M02606"},
+	}
+
+	for _, tt := range tests {
+		// Act
+		got, err := IBAN(tt.country, tt.bban)
+
+		// Assert
+		if err != nil {
+			t.Fatalf("IBAN(%q, %q): %v", tt.country, tt.bban, err)
+		}
+
+		if got != tt.want {
+			t.Errorf("IBAN(%q, %q) = %q, want %q", tt.country, tt.bban, got, tt.want)
+		}
+
+		if !ValidIBAN(got) {
+			t.Errorf("ValidIBAN(%q) = false", got)
+		}
+	}
+}
+
+func TestIBAN_InvalidInput(t *testing.T) {
+	for _, args := range [][2]string{{"D1", "370400440532013000"}, {"DE", "3704-0044"}} {
+		if _, err := IBAN(args[0], args[1]); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("IBAN(%q, %q) error = %v, want ErrInvalidInput", args[0], args[1], err)
+		}
+	}
+}
+
+func TestValidIBAN(t *testing.T) {
+	tests := map[string]bool{
+		"DE89370400440532013000": true,
+		"DE88370400440532013000": false, // check digits
+		"DE89370400440532013001": false, // account number
+		"D989370400440532013000": false, // country code
+		"DE89":                   false,
+		"de89370400440532013000": false,
+	}
+
+	for iban, want := range tests {
+		if got := ValidIBAN(iban); got != want {
+			t.Errorf("ValidIBAN(%q) = %v, want %v", iban, got, want)
+		}
+	}
+}
+
+func TestLuhn(t *testing.T) {
+	tests := map[string]byte{
+		"7992739871":      '3',
+		"453201511283036": '6',
+		"0":               '0',
+	}
+
+	for number, want := range tests {
+		// Act
+		got, err := LuhnDigit(number)
+
+		// Assert
+		if err != nil {
+			t.Fatalf("LuhnDigit(%q): %v", number, err)
+		}
+
+		if got != want {
+			t.Errorf("LuhnDigit(%q) = %c, want %c", number, got, want)
+		}
+
+		if !ValidLuhn(number + string(got)) {
+			t.Errorf("ValidLuhn(%q) = false", number+string(got))
+		}
+	}
+}
+
+func TestValidLuhn(t *testing.T) {
+	tests := map[string]bool{
+		"79927398713": true,
+		"79927398710": false,
+		"79927398731": false, // transposed digits
+		"7992739871a": false,
+		"7":           false,
+	}
+
+	for number, want := range tests {
+		if got := ValidLuhn(number); got != want {
+			t.Errorf("ValidLuhn(%q) = %v, want %v", number, got, want)
+		}
+	}
+}
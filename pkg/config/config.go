@@ -1,50 +1,89 @@
-package config
-
-import (
-	"flag"
-	"fmt"
-	"log"
-	"strings"
-
-	"github.com/fsnotify/fsnotify"
-	"github.com/spf13/viper"
-)
-
-var confPath string
-
-// Initialize inits viper app settings
-func Initialize() error {
-
-	flag.StringVar(&confPath, "c", "", "configuration file path")
-
-	if !flag.Parsed() {
-		flag.Parse()
-	}
-
-	viper.AutomaticEnv()
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-	viper.SetDefault("log_path", "/var/log")
-	viper.SetDefault("app_name", "main")
-
-	if confPath != "" {
-		viper.SetConfigFile(confPath)
-	} else {
-		viper.AddConfigPath(".")
-		viper.SetConfigName("config")
-	}
-
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
-	} else {
-		fmt.Println("Error reading config file:", viper.ConfigFileUsed())
-	}
-
-	viper.Debug()
-
-	viper.WatchConfig()
-	viper.OnConfigChange(func(e fsnotify.Event) {
-		log.Println("Config file changed:", e.Name)
-	})
-
-	return nil
-}
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/amelonpie/wallet-service/pkg/secret"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+var confPath string
+
+// Initialize inits viper app settings. A configuration file given with -c must
+// be readable; without -c a missing config file in the working directory leaves
+// the defaults and the environment.
+func Initialize() error {
+
+	flag.StringVar(&confPath, "c", "", "configuration file path")
+
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+
+	useEnv(viper.GetViper())
+	viper.SetDefault("log_path", "/var/log")
+	viper.SetDefault("app_name", "main")
+
+	if confPath != "" {
+		viper.SetConfigFile(confPath)
+	} else {
+		viper.AddConfigPath(".")
+		viper.SetConfigName("config")
+	}
+
+	err := viper.ReadInConfig()
+
+	switch {
+	case errors.As(err, &viper.ConfigFileNotFoundError{}):
+		fmt.Println("No config file found, using defaults and environment")
+	case err != nil:
+		return fmt.Errorf("failed to read config file %s: %w", viper.ConfigFileUsed(), err)
+	default:
+		fmt.Println("Using config file:", viper.ConfigFileUsed())
+	}
+
+	// secrets.dir and secrets.file are usually set as SECRETS_DIR and SECRETS_FILE
+	if err = secret.Initialize(secret.Config{
+		Dir:  viper.GetString("secrets.dir"),
+		File: viper.GetString("secrets.file"),
+	}); err != nil {
+		return fmt.Errorf("failed to initialize secrets: %w", err)
+	}
+
+	return nil
+}
+
+// useEnv lets environment variables like POSTGRESQL_ADDRESS override every key
+func useEnv(v *viper.Viper) {
+	v.AutomaticEnv()
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+}
+
+// Watch reads the config file into a private viper instance whenever it changes
+// and calls onChange with it. Only a change onChange accepts is read into the
+// global viper, a rejected file never reaches what the rest of the program reads.
+func Watch(onChange func(v *viper.Viper, name string) error) {
+	file := viper.ConfigFileUsed()
+	if file == "" {
+		return
+	}
+
+	watcher := viper.New()
+	useEnv(watcher)
+	watcher.SetConfigFile(file)
+
+	watcher.OnConfigChange(func(e fsnotify.Event) {
+		if err := onChange(watcher, e.Name); err != nil {
+			return
+		}
+
+		if err := viper.ReadInConfig(); err != nil {
+			fmt.Println("failed to read accepted config file:", err)
+		}
+	})
+	watcher.WatchConfig()
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var errNegative = errors.New("negative limit")
+
+// waitFor waits until onChange saw the limit, a write may raise several events
+func waitFor(t *testing.T, seen <-chan float64, limit float64) {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+
+	for {
+		select {
+		case got := <-seen:
+			if got == limit {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for a change to %v", limit)
+		}
+	}
+}
+
+func TestWatch_RejectedChangeKeepsGlobalConfig(t *testing.T) {
+	// Arrange
+	t.Cleanup(viper.Reset)
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("limits:\n  max_amount: 10\n")
+	viper.SetConfigFile(file)
+
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(chan float64, 100)
+
+	Watch(func(v *viper.Viper, _ string) error {
+		limit := v.GetFloat64("limits.max_amount")
+		seen <- limit
+
+		if limit < 0 {
+			return errNegative
+		}
+
+		return nil
+	})
+
+	// Act: the watcher handles changes one after another, once it saw a change
+	// it is done with the ones before
+	write("limits:\n  max_amount: -1\n")
+	waitFor(t, seen, -1)
+
+	rejected := viper.GetFloat64("limits.max_amount")
+
+	write("limits:\n  max_amount: 20\n")
+	waitFor(t, seen, 20)
+	write("limits:\n  max_amount: -2\n")
+	waitFor(t, seen, -2)
+
+	accepted := viper.GetFloat64("limits.max_amount")
+
+	// Assert
+	if rejected != 10 {
+		t.Errorf("expected the global config to keep 10 after the rejected change, got %v", rejected)
+	}
+
+	if accepted != 20 {
+		t.Errorf("expected the global config to take the accepted 20, got %v", accepted)
+	}
+}
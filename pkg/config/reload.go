@@ -0,0 +1,58 @@
+package config
+
+import (
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// Reloader holds the current value of settings that may change while the
+// program runs. Reload loads them again and hands them to the subscribers,
+// unless loading fails, in which case the current value stays.
+type Reloader[T any] struct {
+	mu          sync.Mutex
+	current     T
+	load        func(v *viper.Viper) (T, error)
+	subscribers []func(T)
+}
+
+// NewReloader starts from current, load is expected to validate what it reads
+func NewReloader[T any](current T, load func(v *viper.Viper) (T, error)) *Reloader[T] {
+	return &Reloader[T]{current: current, load: load}
+}
+
+// Current returns the settings last accepted
+func (r *Reloader[T]) Current() T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.current
+}
+
+// Subscribe calls fn with the current settings now and with every reload
+func (r *Reloader[T]) Subscribe(fn func(T)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.subscribers = append(r.subscribers, fn)
+	fn(r.current)
+}
+
+// Reload loads the settings from v and passes them to the subscribers in the
+// order they subscribed. A load error is returned and nothing changes.
+func (r *Reloader[T]) Reload(v *viper.Viper) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := r.load(v)
+	if err != nil {
+		return err
+	}
+
+	r.current = next
+	for _, fn := range r.subscribers {
+		fn(next)
+	}
+
+	return nil
+}
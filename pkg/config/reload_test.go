@@ -0,0 +1,42 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+var errInvalid = errors.New("invalid")
+
+func TestReloader(t *testing.T) {
+	// Arrange
+	next, nextErr := 2, error(nil)
+	r := NewReloader(1, func(*viper.Viper) (int, error) { return next, nextErr })
+
+	var seen []int
+	r.Subscribe(func(v int) { seen = append(seen, v) })
+
+	// Act
+	accepted := r.Reload(viper.New())
+
+	next, nextErr = 3, errInvalid
+	rejected := r.Reload(viper.New())
+
+	// Assert
+	if accepted != nil {
+		t.Errorf("expected the reload to be accepted, got %v", accepted)
+	}
+
+	if !errors.Is(rejected, errInvalid) {
+		t.Errorf("expected the load error, got %v", rejected)
+	}
+
+	if r.Current() != 2 {
+		t.Errorf("expected the last accepted value 2, got %d", r.Current())
+	}
+
+	if len(seen) != 2 || seen[0] != 1 || seen[1] != 2 {
+		t.Errorf("expected subscribers to see 1 then 2, got %v", seen)
+	}
+}
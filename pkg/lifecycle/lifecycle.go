@@ -0,0 +1,100 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/amelonpie/wallet-service/pkg/log"
+	"github.com/sirupsen/logrus"
+)
+
+type worker struct {
+	name   string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type closer struct {
+	name  string
+	close func() error
+}
+
+// Group runs the background workers of the application and stops them on
+// shutdown, in reverse order of starting, then closes the resources registered
+// with OnClose, also in reverse order. Resources the workers use are registered
+// before they start, so they are closed after every worker returned.
+type Group struct {
+	mu      sync.Mutex
+	workers []*worker
+	closers []closer
+	stopped bool
+	logger  *logrus.Entry
+}
+
+func New() *Group {
+	return &Group{logger: log.NewLogger("lifecycle").WithField("module", "lifecycle")}
+}
+
+// Go runs the worker in a goroutine until Stop cancels its context. Workers
+// started after Stop are not run.
+func (g *Group) Go(name string, run func(ctx context.Context)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.stopped {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &worker{name: name, cancel: cancel, done: make(chan struct{})}
+	g.workers = append(g.workers, w)
+
+	go func() {
+		defer close(w.done)
+
+		run(ctx)
+	}()
+}
+
+// OnClose registers a resource to close once the workers stopped
+func (g *Group) OnClose(name string, closeFn func() error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.closers = append(g.closers, closer{name: name, close: closeFn})
+}
+
+// Stop stops every worker and waits for it to return, then closes the resources.
+// A worker still running when ctx is done is left behind, the resources are
+// closed anyway. Stop returns the errors of both.
+func (g *Group) Stop(ctx context.Context) error {
+	g.mu.Lock()
+	g.stopped = true
+	workers, closers := g.workers, g.closers
+	g.workers, g.closers = nil, nil
+	g.mu.Unlock()
+
+	var errs []error
+
+	for i := len(workers) - 1; i >= 0; i-- {
+		w := workers[i]
+		w.cancel()
+
+		select {
+		case <-w.done:
+			g.logger.WithField("worker", w.name).Info("worker stopped")
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("worker %s did not stop: %w", w.name, ctx.Err()))
+		}
+	}
+
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i].close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close %s: %w", closers[i].name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
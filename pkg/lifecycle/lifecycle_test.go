@@ -0,0 +1,84 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errClosed = errors.New("already closed")
+
+func TestGroup_StopsInReverseOrder(t *testing.T) {
+	// Arrange
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		order = append(order, name)
+	}
+
+	g := New()
+	g.OnClose("postgres", func() error { record("postgres"); return nil })
+	g.OnClose("redis", func() error { record("redis"); return errClosed })
+
+	for _, name := range []string{"relay", "scheduler"} {
+		g.Go(name, func(ctx context.Context) {
+			<-ctx.Done()
+			record(name)
+		})
+	}
+
+	// Act
+	err := g.Stop(context.Background())
+
+	// Assert
+	if !errors.Is(err, errClosed) {
+		t.Errorf("expected the close error, got %v", err)
+	}
+
+	if want := []string{"scheduler", "relay", "redis", "postgres"}; !slices.Equal(order, want) {
+		t.Errorf("expected stop order %v, got %v", want, order)
+	}
+}
+
+func TestGroup_StopTimeout(t *testing.T) {
+	// Arrange
+	release := make(chan struct{})
+	defer close(release)
+
+	closed := false
+
+	g := New()
+	g.OnClose("postgres", func() error { closed = true; return nil })
+	g.Go("stuck", func(context.Context) { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// Act
+	err := g.Stop(ctx)
+
+	// Assert
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the worker to time out, got %v", err)
+	}
+
+	if !closed {
+		t.Errorf("expected resources to be closed after a timeout")
+	}
+
+	started := false
+	g.Go("late", func(context.Context) { started = true })
+
+	if started {
+		t.Errorf("expected no worker to start after Stop")
+	}
+}
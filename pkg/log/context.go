@@ -0,0 +1,63 @@
+package log
+
+import (
+	"context"
+	"maps"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Fields FromContext adds to every log line of a request
+const (
+	FieldRequestID = "request_id"
+	FieldUserID    = "user_id"
+	FieldOperation = "operation"
+)
+
+type fieldsKey struct{}
+
+// WithFields returns a copy of ctx whose log lines carry fields, in addition to
+// the fields ctx already carries
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	merged := make(logrus.Fields, len(fields))
+	if parent, ok := ctx.Value(fieldsKey{}).(logrus.Fields); ok {
+		maps.Copy(merged, parent)
+	}
+
+	maps.Copy(merged, fields)
+
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// WithRequestID returns a copy of ctx whose log lines carry the request id
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return WithFields(ctx, logrus.Fields{FieldRequestID: requestID})
+}
+
+// WithUserID returns a copy of ctx whose log lines carry the user id
+func WithUserID(ctx context.Context, userID int) context.Context {
+	return WithFields(ctx, logrus.Fields{FieldUserID: userID})
+}
+
+// WithOperation returns a copy of ctx whose log lines carry the operation
+func WithOperation(ctx context.Context, operation string) context.Context {
+	return WithFields(ctx, logrus.Fields{FieldOperation: operation})
+}
+
+// RequestID returns the request id of ctx, empty outside of a request
+func RequestID(ctx context.Context) string {
+	fields, _ := ctx.Value(fieldsKey{}).(logrus.Fields)
+	id, _ := fields[FieldRequestID].(string)
+
+	return id
+}
+
+// FromContext returns logger with the fields of ctx, and ctx for the trace id
+func FromContext(ctx context.Context, logger *logrus.Entry) *logrus.Entry {
+	entry := logger.WithContext(ctx)
+	if fields, ok := ctx.Value(fieldsKey{}).(logrus.Fields); ok {
+		entry = entry.WithFields(fields)
+	}
+
+	return entry
+}
@@ -0,0 +1,37 @@
+package log
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestFromContext(t *testing.T) {
+	logger := logrus.New().WithField("module", "test")
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	ctx = WithUserID(ctx, 42)
+	child := WithOperation(ctx, "Withdraw")
+
+	entry := FromContext(child, logger)
+
+	want := logrus.Fields{"module": "test", FieldRequestID: "req-1", FieldUserID: 42, FieldOperation: "Withdraw"}
+	for key, value := range want {
+		if entry.Data[key] != value {
+			t.Errorf("expected %s=%v, got %v", key, value, entry.Data[key])
+		}
+	}
+
+	if _, ok := FromContext(ctx, logger).Data[FieldOperation]; ok {
+		t.Errorf("expected the parent context to be left unchanged")
+	}
+
+	if RequestID(child) != "req-1" || RequestID(context.Background()) != "" {
+		t.Errorf("unexpected request ids %q, %q", RequestID(child), RequestID(context.Background()))
+	}
+
+	if len(FromContext(context.Background(), logger).Data) != 1 {
+		t.Errorf("expected no fields from an empty context")
+	}
+}
@@ -0,0 +1,107 @@
+package log
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// files are the rotating log files by path, every logger writing to a path
+// shares one
+var files = struct { //nolint:gochecknoglobals // shared by all loggers
+	sync.Mutex
+	cfg  RotateConfig
+	open map[string]*rotatingFile
+}{open: map[string]*rotatingFile{}}
+
+// setRotateConfig applies to the files opened from now on
+func setRotateConfig(cfg RotateConfig) {
+	files.Lock()
+	defer files.Unlock()
+
+	files.cfg = cfg
+}
+
+// fileFor returns the shared file of path, nil for no path
+func fileFor(path string) *rotatingFile {
+	if path == "" {
+		return nil
+	}
+
+	files.Lock()
+	defer files.Unlock()
+
+	f, ok := files.open[path]
+	if !ok {
+		f = newRotatingFile(path, files.cfg)
+		files.open[path] = f
+	}
+
+	return f
+}
+
+// fileHook writes every entry as JSON to the module file and to the main file,
+// warnings and errors also as text to the warn file, and errors to the error file
+type fileHook struct {
+	moduleFile, mainFile, warnFile, errorFile *rotatingFile
+}
+
+var (
+	jsonFormatter = &logrus.JSONFormatter{}                    //nolint:gochecknoglobals // stateless
+	textFormatter = &logrus.TextFormatter{DisableColors: true} //nolint:gochecknoglobals // stateless
+)
+
+func newFileHook(modulePath string) *fileHook {
+	// the shared paths are empty before Initialize, entries then go to the module file only
+	return &fileHook{
+		moduleFile: fileFor(modulePath),
+		mainFile:   fileFor(mainLogPath),
+		warnFile:   fileFor(warnLogPath),
+		errorFile:  fileFor(errorLogPath),
+	}
+}
+
+func (h *fileHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire formats each format once, whatever the number of files
+func (h *fileHook) Fire(entry *logrus.Entry) error {
+	line, err := jsonFormatter.Format(entry)
+	if err != nil {
+		return err //nolint:wrapcheck // logrus reports hook errors itself
+	}
+
+	if err = writeTo(line, h.moduleFile, h.mainFile); err != nil {
+		return err
+	}
+
+	if entry.Level > logrus.WarnLevel {
+		return nil
+	}
+
+	text, err := textFormatter.Format(entry)
+	if err != nil {
+		return err //nolint:wrapcheck // logrus reports hook errors itself
+	}
+
+	if err = writeTo(text, h.warnFile); err != nil || entry.Level > logrus.ErrorLevel {
+		return err
+	}
+
+	return writeTo(text, h.errorFile)
+}
+
+func writeTo(line []byte, targets ...*rotatingFile) error {
+	for _, f := range targets {
+		if f == nil {
+			continue
+		}
+
+		if _, err := f.Write(line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,132 +1,228 @@
-package log
-
-import (
-	"os"
-	"path"
-
-	"github.com/rifflock/lfshook"
-	"github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
-)
-
-var (
-	logBasePath  string
-	mainLogger   *logrus.Logger
-	mainLogPath  string
-	warnLogPath  string
-	errorLogPath string
-	logLevel     logrus.Level = logrus.DebugLevel
-)
-
-// Initialize init logger and returns main logger
-func Initialize() (*logrus.Logger, error) {
-	if mainLogger != nil {
-		return mainLogger, nil
-	}
-
-	logBasePath = viper.GetString("log_path")
-	logrus.Info("Setting logBasePath: ", logBasePath)
-
-	appName := viper.GetString("app_name")
-	mainLogPath = path.Join(logBasePath, appName+".log")
-	warnLogPath = path.Join(logBasePath, appName+"_warn.log")
-	errorLogPath = path.Join(logBasePath, appName+"_error.log")
-
-	if _, err := os.Stat(logBasePath); os.IsNotExist(err) {
-		if err := os.MkdirAll(logBasePath, 0755); err != nil {
-			logrus.WithField("err", err).Error("failed to create log directory")
-
-			return nil, err
-		}
-		logrus.Info("created log directory:", logBasePath)
-	}
-
-	mainLogger = logrus.New()
-	mainLogger.Out = os.Stdout
-	mainLogger.SetFormatter(&logrus.JSONFormatter{})
-	mainLogger.SetReportCaller(true)
-
-	var err error
-	logLevel, err = logrus.ParseLevel(viper.GetString("log_level"))
-	if err != nil {
-		logrus.WithField("err", err).Warn("invalid log level")
-		logLevel = logrus.InfoLevel
-	}
-	mainLogger.SetLevel(logLevel)
-
-	mainLogger.Hooks.Add(lfshook.NewHook(lfshook.PathMap{
-		logrus.DebugLevel: mainLogPath,
-		logrus.InfoLevel:  mainLogPath,
-		logrus.WarnLevel:  mainLogPath,
-		logrus.ErrorLevel: mainLogPath,
-	}, &logrus.JSONFormatter{}))
-	mainLogger.Hooks.Add(lfshook.NewHook(lfshook.PathMap{
-		logrus.WarnLevel:  warnLogPath,
-		logrus.ErrorLevel: warnLogPath,
-	}, &logrus.TextFormatter{}))
-	mainLogger.Hooks.Add(lfshook.NewHook(lfshook.PathMap{
-		logrus.ErrorLevel: errorLogPath,
-	}, &logrus.TextFormatter{}))
-
-	return mainLogger, nil
-}
-
-// GetLogBasePath returns log base path
-func GetLogBasePath() string {
-	return logBasePath
-}
-
-// MainLogger returns main logger
-func MainLogger() *logrus.Logger {
-	return mainLogger
-}
-
-// NewLogger creates new logger, logs hooked to main logger
-func NewLogger(filename string) *logrus.Logger {
-	logger := logrus.New()
-	logger.Out = os.Stdout
-	logger.SetFormatter(&logrus.JSONFormatter{})
-	logger.SetReportCaller(true)
-
-	logger.SetLevel(logLevel)
-
-	loggerPath := path.Join(logBasePath, viper.GetString("app_name")+"_"+filename+".log")
-	logger.Hooks.Add(lfshook.NewHook(lfshook.PathMap{
-		logrus.DebugLevel: loggerPath,
-		logrus.InfoLevel:  loggerPath,
-		logrus.WarnLevel:  loggerPath,
-		logrus.ErrorLevel: loggerPath,
-	}, &logrus.JSONFormatter{}))
-	logger.Hooks.Add(lfshook.NewHook(lfshook.PathMap{
-		logrus.DebugLevel: mainLogPath,
-		logrus.InfoLevel:  mainLogPath,
-		logrus.WarnLevel:  mainLogPath,
-		logrus.ErrorLevel: mainLogPath,
-	}, &logrus.JSONFormatter{}))
-	logger.Hooks.Add(lfshook.NewHook(lfshook.PathMap{
-		logrus.WarnLevel:  warnLogPath,
-		logrus.ErrorLevel: warnLogPath,
-	}, &logrus.TextFormatter{}))
-	logger.Hooks.Add(lfshook.NewHook(lfshook.PathMap{
-		logrus.ErrorLevel: errorLogPath,
-	}, &logrus.TextFormatter{}))
-
-	return logger
-}
-
-// NewSoloLogger creates solo file logger at logBasePath
-func NewSoloLogger(filename string) *logrus.Logger {
-	logger := logrus.New()
-	logger.SetFormatter(&logrus.JSONFormatter{})
-	logger.SetLevel(logrus.DebugLevel)
-
-	loggerPath := path.Join(logBasePath, viper.GetString("app_name")+"_"+filename+".log")
-	file, err := os.OpenFile(loggerPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	if err == nil {
-		logger.Out = file
-	} else {
-		logger.Info("Failed to log to file, using default stderr")
-	}
-
-	return logger
-}
+package log
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+var (
+	logBasePath  string
+	appName      string
+	mainLogger   *logrus.Logger
+	mainLogPath  string
+	warnLogPath  string
+	errorLogPath string
+
+	// registry holds the main logger under "" and one logger per NewLogger name,
+	// SetLevels changes them all
+	registry = struct { //nolint:gochecknoglobals // shared by all loggers
+		sync.Mutex
+		level   logrus.Level
+		modules map[string]logrus.Level
+		loggers map[string]*logrus.Logger
+	}{level: logrus.DebugLevel, loggers: map[string]*logrus.Logger{}}
+)
+
+// Config of the loggers
+type Config struct {
+	Path    string
+	AppName string
+	Level   logrus.Level
+	// ModuleLevels override Level for the loggers of NewLogger by name
+	ModuleLevels map[string]logrus.Level
+	// RedactFields are masked in every entry in addition to the default ones
+	RedactFields []string
+	Rotate       RotateConfig
+}
+
+// NewConfig reads the logging settings, it fails on invalid levels
+func NewConfig() (Config, error) {
+	viper.SetDefault("log_level", "info")
+	viper.SetDefault("log_rotate.max_size_mb", 100)
+	viper.SetDefault("log_rotate.interval", "24h")
+	viper.SetDefault("log_rotate.max_backups", 14)
+	viper.SetDefault("log_rotate.max_age", "336h")
+	viper.SetDefault("log_rotate.compress", true)
+
+	level, err := logrus.ParseLevel(viper.GetString("log_level"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid log_level: %w", err)
+	}
+
+	modules, err := ParseLevels(viper.GetStringMapString("log_levels"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	return Config{
+		Path:         viper.GetString("log_path"),
+		AppName:      viper.GetString("app_name"),
+		Level:        level,
+		ModuleLevels: modules,
+		RedactFields: viper.GetStringSlice("log_redact_fields"),
+		Rotate: RotateConfig{
+			MaxSize:    viper.GetInt64("log_rotate.max_size_mb") * megabyte,
+			Interval:   viper.GetDuration("log_rotate.interval"),
+			MaxBackups: viper.GetInt("log_rotate.max_backups"),
+			MaxAge:     viper.GetDuration("log_rotate.max_age"),
+			Compress:   viper.GetBool("log_rotate.compress"),
+		},
+	}, nil
+}
+
+// ParseLevels parses the levels of log_levels by module name
+func ParseLevels(levels map[string]string) (map[string]logrus.Level, error) {
+	parsed := make(map[string]logrus.Level, len(levels))
+
+	for module, value := range levels {
+		level, err := logrus.ParseLevel(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log_levels.%s: %w", module, err)
+		}
+
+		parsed[module] = level
+	}
+
+	return parsed, nil
+}
+
+// Initialize init logger and returns main logger
+func Initialize() (*logrus.Logger, error) {
+	if mainLogger != nil {
+		return mainLogger, nil
+	}
+
+	cfg, err := NewConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	logBasePath = cfg.Path
+	appName = cfg.AppName
+	logrus.Info("Setting logBasePath: ", logBasePath)
+
+	mainLogPath = path.Join(logBasePath, appName+".log")
+	warnLogPath = path.Join(logBasePath, appName+"_warn.log")
+	errorLogPath = path.Join(logBasePath, appName+"_error.log")
+
+	if _, err := os.Stat(logBasePath); os.IsNotExist(err) {
+		if err := os.MkdirAll(logBasePath, 0755); err != nil {
+			logrus.WithField("err", err).Error("failed to create log directory")
+
+			return nil, err
+		}
+		logrus.Info("created log directory:", logBasePath)
+	}
+
+	setRotateConfig(cfg.Rotate)
+	RedactFields(cfg.RedactFields...)
+	SetLevels(cfg.Level, cfg.ModuleLevels)
+
+	mainLogger = register("", "")
+
+	return mainLogger, nil
+}
+
+// GetLogBasePath returns log base path
+func GetLogBasePath() string {
+	return logBasePath
+}
+
+// MainLogger returns main logger
+func MainLogger() *logrus.Logger {
+	return mainLogger
+}
+
+// NewLogger returns the logger of a module, it writes to <app>_<filename>.log
+// and to the shared main, warn and error files. Loggers are created once per
+// filename and share the open files.
+func NewLogger(filename string) *logrus.Logger {
+	return register(filename, path.Join(logBasePath, appName+"_"+filename+".log"))
+}
+
+func register(name, modulePath string) *logrus.Logger {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if logger, ok := registry.loggers[name]; ok {
+		return logger
+	}
+
+	logger := logrus.New()
+	logger.Out = os.Stdout
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.SetReportCaller(true)
+	logger.SetLevel(levelOf(name))
+
+	// added first so the file hook writes the trace fields and redacted values
+	logger.Hooks.Add(traceHook{})
+	logger.Hooks.Add(redactHook{})
+	logger.Hooks.Add(newFileHook(modulePath))
+
+	registry.loggers[name] = logger
+
+	return logger
+}
+
+// levelOf returns the level of the logger named name, registry must be locked
+func levelOf(name string) logrus.Level {
+	if level, ok := registry.modules[name]; ok && name != "" {
+		return level
+	}
+
+	return registry.level
+}
+
+// SetLevels sets the level of the main logger and of the loggers of NewLogger,
+// modules overrides it for loggers by name
+func SetLevels(level logrus.Level, modules map[string]logrus.Level) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registry.level = level
+	registry.modules = modules
+
+	for name, logger := range registry.loggers {
+		logger.SetLevel(levelOf(name))
+	}
+}
+
+// Close waits for rotated files to be compressed and closes the log files.
+// It is called last on shutdown, a later entry opens its files again.
+func Close() error {
+	files.Lock()
+	defer files.Unlock()
+
+	var errs []error
+
+	for name, f := range files.open {
+		if err := f.close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close %s: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// NewSoloLogger creates solo file logger at logBasePath
+func NewSoloLogger(filename string) *logrus.Logger {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.SetLevel(logrus.DebugLevel)
+
+	loggerPath := path.Join(logBasePath, viper.GetString("app_name")+"_"+filename+".log")
+	file, err := os.OpenFile(loggerPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err == nil {
+		logger.Out = file
+	} else {
+		logger.Info("Failed to log to file, using default stderr")
+	}
+
+	return logger
+}
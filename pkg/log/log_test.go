@@ -0,0 +1,200 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSetLevels(t *testing.T) {
+	endpoint := NewLogger("level_endpoint")
+	wallet := NewLogger("level_wallet")
+	t.Cleanup(func() { SetLevels(logrus.DebugLevel, nil) })
+
+	SetLevels(logrus.WarnLevel, map[string]logrus.Level{"level_endpoint": logrus.DebugLevel})
+
+	if endpoint.GetLevel() != logrus.DebugLevel {
+		t.Errorf("expected the module level debug, got %v", endpoint.GetLevel())
+	}
+
+	if wallet.GetLevel() != logrus.WarnLevel {
+		t.Errorf("expected the default level warn, got %v", wallet.GetLevel())
+	}
+
+	if NewLogger("level_wallet") != wallet {
+		t.Errorf("expected one logger per name")
+	}
+
+	if NewLogger("level_new").GetLevel() != logrus.WarnLevel {
+		t.Errorf("expected a new logger to start at warn")
+	}
+}
+
+func TestParseLevels(t *testing.T) {
+	levels, err := ParseLevels(map[string]string{"endpoint": "warn"})
+	if err != nil || levels["endpoint"] != logrus.WarnLevel {
+		t.Errorf("unexpected levels %v, %v", levels, err)
+	}
+
+	if _, err = ParseLevels(map[string]string{"endpoint": "loud"}); err == nil || !strings.Contains(err.Error(), "log_levels.endpoint") {
+		t.Errorf("expected an error naming the module, got %v", err)
+	}
+}
+
+func TestFileHook_SharesFilesByLevel(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	saved := []string{mainLogPath, warnLogPath, errorLogPath}
+	mainLogPath = filepath.Join(dir, "app.log")
+	warnLogPath = filepath.Join(dir, "app_warn.log")
+	errorLogPath = filepath.Join(dir, "app_error.log")
+
+	t.Cleanup(func() { mainLogPath, warnLogPath, errorLogPath = saved[0], saved[1], saved[2] })
+
+	newLogger := func(module string) *logrus.Logger {
+		logger := logrus.New()
+		logger.Out = &strings.Builder{}
+		logger.Hooks.Add(newFileHook(filepath.Join(dir, "app_"+module+".log")))
+
+		return logger
+	}
+
+	endpoint, wallet := newLogger("endpoint"), newLogger("wallet")
+
+	// Act
+	endpoint.Info("request served")
+	wallet.Warn("cache degraded")
+	wallet.Error("deposit failed")
+
+	// Assert
+	want := map[string][]string{
+		"app.log":          {"request served", "cache degraded", "deposit failed"},
+		"app_endpoint.log": {"request served"},
+		"app_wallet.log":   {"cache degraded", "deposit failed"},
+		"app_warn.log":     {"cache degraded", "deposit failed"},
+		"app_error.log":    {"deposit failed"},
+	}
+
+	for name, messages := range want {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if lines := strings.Count(string(b), "\n"); lines != len(messages) {
+			t.Errorf("%s: expected %d lines, got %d:\n%s", name, len(messages), lines, b)
+		}
+
+		for _, msg := range messages {
+			if !strings.Contains(string(b), msg) {
+				t.Errorf("%s: expected %q", name, msg)
+			}
+		}
+	}
+
+	if fileFor(mainLogPath) != fileFor(mainLogPath) {
+		t.Errorf("expected loggers to share the open file")
+	}
+
+	if err := Close(); err != nil {
+		t.Errorf("failed to close log files: %v", err)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	logger := logrus.New()
+	logger.Hooks.Add(redactHook{})
+
+	var out strings.Builder
+	logger.Out = &out
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	Redact("pa55word")
+	Redact("pw") // too short to redact
+
+	logger.WithFields(logrus.Fields{
+		"dsn": "postgres://app:pa55word@db/bank",
+		"err": errors.New("auth failed for pa55word"),
+	}).Info("connecting with pa55word, pw")
+
+	if strings.Contains(out.String(), "pa55word") {
+		t.Errorf("expected the secret to be redacted, got %s", out.String())
+	}
+
+	if strings.Count(out.String(), "[REDACTED]") != 3 || !strings.Contains(out.String(), ", pw") {
+		t.Errorf("unexpected redaction %s", out.String())
+	}
+}
+
+func TestRedact_SensitiveFields(t *testing.T) {
+	logger := logrus.New()
+	logger.Hooks.Add(redactHook{})
+
+	var out strings.Builder
+	logger.Out = &out
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	RedactFields("Session")
+
+	logger.WithFields(logrus.Fields{
+		"email":      "jane.doe@example.com",
+		"to_account": "DE89370400440532013000",
+		"token":      "eyJhbGciOiJIUzI1NiJ9",
+		"session":    "s-123456",
+		"user_id":    42,
+	}).Info("transfer")
+
+	for _, want := range []string{
+		`"email":"j***@example.com"`,
+		`"to_account":"******************3000"`,
+		`"token":"[REDACTED]"`,
+		`"session":"[REDACTED]"`,
+		`"user_id":42`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %s in %s", want, out.String())
+		}
+	}
+}
+
+type dsn string
+
+func (d dsn) String() string { return string(d) }
+
+func TestRedact_RequestBody(t *testing.T) {
+	logger := logrus.New()
+	logger.Hooks.Add(redactHook{})
+
+	var out strings.Builder
+	logger.Out = &out
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	Redact("s3cr3t-key")
+
+	logger.WithFields(logrus.Fields{
+		"request_body": []byte(`{"amount":10,"to_account":"DE89370400440532013000","meta":{"token":"abc123"}}`),
+		"raw":          []byte("not json, key s3cr3t-key"),
+		"dsn":          dsn("postgres://app:s3cr3t-key@db/bank"),
+	}).Info("invalid request")
+
+	for _, leaked := range []string{"DE8937040044", "abc123", "s3cr3t-key"} {
+		if strings.Contains(out.String(), leaked) {
+			t.Errorf("expected %s to be redacted, got %s", leaked, out.String())
+		}
+	}
+
+	for _, want := range []string{
+		`"to_account\":\"******************3000\"`,
+		`\"token\":\"[REDACTED]\"`,
+		`"raw":"not json, key [REDACTED]"`,
+		`"dsn":"postgres://app:[REDACTED]@db/bank"`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %s in %s", want, out.String())
+		}
+	}
+}
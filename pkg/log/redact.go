@@ -0,0 +1,204 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	redacted = "[REDACTED]"
+	// shorter values would garble unrelated text, they are not worth redacting
+	minRedactLen = 4
+	// visibleDigits of an account number stay readable
+	visibleDigits = 4
+)
+
+var secrets struct { //nolint:gochecknoglobals // shared by all loggers
+	sync.RWMutex
+	replacer *strings.Replacer
+	values   []string
+}
+
+// Redact replaces value by [REDACTED] in the message and the string, error, raw
+// body and fmt.Stringer fields of every log entry from now on
+func Redact(value string) {
+	if len(value) < minRedactLen {
+		return
+	}
+
+	secrets.Lock()
+	defer secrets.Unlock()
+
+	for _, v := range secrets.values {
+		if v == value {
+			return
+		}
+	}
+
+	secrets.values = append(secrets.values, value)
+
+	pairs := make([]string, 0, 2*len(secrets.values)) //nolint:mnd // old, new pairs
+	for _, v := range secrets.values {
+		pairs = append(pairs, v, redacted)
+	}
+
+	secrets.replacer = strings.NewReplacer(pairs...)
+}
+
+// mask replaces the value of a sensitive field
+type mask func(value any) any
+
+// sensitive are the fields masked by name, whatever their value
+var sensitive = struct { //nolint:gochecknoglobals // shared by all loggers
+	sync.RWMutex
+	fields map[string]mask
+}{fields: map[string]mask{
+	"email":           maskEmail,
+	"password":        maskAll,
+	"secret":          maskAll,
+	"token":           maskAll,
+	"access_token":    maskAll,
+	"refresh_token":   maskAll,
+	"api_key":         maskAll,
+	"authorization":   maskAll,
+	"account_number":  maskLast4,
+	"to_account":      maskLast4,
+	"virtual_account": maskLast4,
+	"debtor_account":  maskLast4,
+	"iban":            maskLast4,
+	"card_number":     maskLast4,
+	// payout destinations hold the name and account of the beneficiary
+	"destination": maskAll,
+}}
+
+// RedactFields masks the fields of these names in every log entry from now on,
+// in addition to the default ones like email, token and account_number
+func RedactFields(names ...string) {
+	sensitive.Lock()
+	defer sensitive.Unlock()
+
+	for _, name := range names {
+		sensitive.fields[strings.ToLower(name)] = maskAll
+	}
+}
+
+func maskAll(any) any {
+	return redacted
+}
+
+// maskLast4 keeps the last four characters, enough to tell accounts apart
+func maskLast4(value any) any {
+	s := fmt.Sprint(value)
+	if len(s) <= visibleDigits {
+		return redacted
+	}
+
+	return strings.Repeat("*", len(s)-visibleDigits) + s[len(s)-visibleDigits:]
+}
+
+// maskEmail keeps the first character and the domain, j***@example.com
+func maskEmail(value any) any {
+	s, ok := value.(string)
+
+	at := strings.LastIndex(s, "@")
+	if !ok || at < 1 {
+		return redacted
+	}
+
+	return s[:1] + "***" + s[at:]
+}
+
+// maskFields masks the sensitive fields of a decoded JSON object and the objects in it.
+// The caller holds sensitive's lock.
+func maskFields(fields map[string]any) {
+	for key, value := range fields {
+		if m, ok := sensitive.fields[strings.ToLower(key)]; ok {
+			fields[key] = m(value)
+
+			continue
+		}
+
+		switch v := value.(type) {
+		case map[string]any:
+			maskFields(v)
+		case []any:
+			for _, item := range v {
+				if object, ok := item.(map[string]any); ok {
+					maskFields(object)
+				}
+			}
+		}
+	}
+}
+
+// maskBody logs a raw request body as text, with the sensitive fields of a JSON
+// body masked. The caller holds sensitive's lock.
+func maskBody(body []byte) string {
+	var fields map[string]any
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return string(body)
+	}
+
+	maskFields(fields)
+
+	masked, err := json.Marshal(fields)
+	if err != nil {
+		return redacted
+	}
+
+	return string(masked)
+}
+
+// redactHook is added before the file hooks, which format the entry it changed.
+// It masks sensitive fields by name, also inside raw JSON bodies, and secret values
+// wherever they appear.
+type redactHook struct{}
+
+func (redactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (redactHook) Fire(entry *logrus.Entry) error {
+	sensitive.RLock()
+	for key, value := range entry.Data {
+		if m, ok := sensitive.fields[strings.ToLower(key)]; ok {
+			entry.Data[key] = m(value)
+		} else if body, ok := value.([]byte); ok {
+			entry.Data[key] = maskBody(body)
+		}
+	}
+	sensitive.RUnlock()
+
+	secrets.RLock()
+	replacer := secrets.replacer
+	secrets.RUnlock()
+
+	if replacer == nil {
+		return nil
+	}
+
+	entry.Message = replacer.Replace(entry.Message)
+
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case string:
+			entry.Data[key] = replacer.Replace(v)
+		case error:
+			if msg := replacer.Replace(v.Error()); msg != v.Error() {
+				entry.Data[key] = errors.New(msg) //nolint:err113 // carries the redacted message only
+			}
+		case fmt.Stringer:
+			// values are only replaced by their text when it holds a secret
+			if text := replacer.Replace(v.String()); text != v.String() {
+				entry.Data[key] = text
+			}
+		}
+	}
+
+	return nil
+}
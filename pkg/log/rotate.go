@@ -0,0 +1,266 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	backupTimeFormat = "20060102T150405.000"
+	compressedExt    = ".gz"
+	megabyte         = 1 << 20
+)
+
+// RotateConfig of every log file
+type RotateConfig struct {
+	// MaxSize in bytes starts a new file before it would be exceeded, 0 for no limit
+	MaxSize int64
+	// Interval starts a new file on every multiple of it, like daily at midnight UTC
+	// for 24h, 0 to rotate by size only
+	Interval time.Duration
+	// MaxBackups is the number of rotated files kept, 0 to keep all
+	MaxBackups int
+	// MaxAge removes rotated files older than it, 0 to keep them
+	MaxAge time.Duration
+	// Compress gzips rotated files
+	Compress bool
+}
+
+// rotatingFile appends to path and moves it aside to path-<time>.log once it
+// gets too large or too old. Compression and removal of old files happen in the
+// background, one file at a time.
+type rotatingFile struct {
+	path string
+	cfg  RotateConfig
+	now  func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	rotateAt time.Time
+
+	// mill serializes compressing and pruning, wg lets close wait for them
+	mill sync.Mutex
+	wg   sync.WaitGroup
+}
+
+func newRotatingFile(path string, cfg RotateConfig) *rotatingFile {
+	return &rotatingFile{path: path, cfg: cfg, now: time.Now}
+}
+
+func (w *rotatingFile) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	tooLarge := w.cfg.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.cfg.MaxSize
+	tooOld := !w.rotateAt.IsZero() && !w.now().Before(w.rotateAt)
+
+	if tooLarge || tooOld {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+
+	return n, err //nolint:wrapcheck // io.Writer
+}
+
+// open continues an existing file, its age counts from now
+func (w *rotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0o755); err != nil { //nolint:mnd // rwxr-xr-x
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644) //nolint:mnd // rw-r--r--
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	w.file = file
+	w.size = info.Size()
+	w.rotateAt = time.Time{}
+
+	if w.cfg.Interval > 0 {
+		w.rotateAt = w.now().Truncate(w.cfg.Interval).Add(w.cfg.Interval)
+	}
+
+	return nil
+}
+
+func (w *rotatingFile) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+
+	w.file = nil
+
+	backup := w.backupName(w.now())
+	if err := os.Rename(w.path, backup); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	w.wg.Add(1)
+
+	go func() {
+		defer w.wg.Done()
+
+		w.mill.Lock()
+		defer w.mill.Unlock()
+
+		if w.cfg.Compress {
+			compress(backup)
+		}
+
+		w.prune()
+	}()
+
+	return nil
+}
+
+// backupName is app.log -> app-20060102T150405.000.log. A name taken by an
+// earlier backup, compressed or not, moves on to the next millisecond: the
+// rename would replace it, and names still sort like the rotations.
+func (w *rotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(w.path)
+
+	for {
+		name := strings.TrimSuffix(w.path, ext) + "-" + t.UTC().Format(backupTimeFormat) + ext
+		if !exists(name) && !exists(name+compressedExt) {
+			return name
+		}
+
+		t = t.Add(time.Millisecond)
+	}
+}
+
+func exists(name string) bool {
+	_, err := os.Lstat(name)
+
+	return !os.IsNotExist(err)
+}
+
+// backups returns the rotated files of w, oldest first
+func (w *rotatingFile) backups() []string {
+	ext := filepath.Ext(w.path)
+	prefix := filepath.Base(strings.TrimSuffix(w.path, ext)) + "-"
+
+	entries, err := os.ReadDir(filepath.Dir(w.path))
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), compressedExt)
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+
+		// app-20060102T150405.000.log, not app-endpoint.log
+		if _, err := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)); err == nil {
+			names = append(names, filepath.Join(filepath.Dir(w.path), e.Name()))
+		}
+	}
+
+	// the timestamp sorts like the name
+	slices.Sort(names)
+
+	return names
+}
+
+// prune removes the backups beyond MaxBackups and those older than MaxAge.
+// Failures leave files behind, which the next rotation retries.
+func (w *rotatingFile) prune() {
+	backups := w.backups()
+
+	for i, name := range backups {
+		remove := w.cfg.MaxBackups > 0 && i < len(backups)-w.cfg.MaxBackups
+
+		if w.cfg.MaxAge > 0 {
+			if info, err := os.Stat(name); err == nil && w.now().Sub(info.ModTime()) > w.cfg.MaxAge {
+				remove = true
+			}
+		}
+
+		if remove {
+			_ = os.Remove(name)
+		}
+	}
+}
+
+// compress replaces name by name.gz, it leaves name on failure
+func compress(name string) {
+	src, err := os.Open(name)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+compressedExt, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644) //nolint:mnd // rw-r--r--
+	if err != nil {
+		return
+	}
+
+	gz := gzip.NewWriter(dst)
+
+	_, err = io.Copy(gz, src)
+	if err = firstErr(err, gz.Close(), dst.Close()); err != nil {
+		_ = os.Remove(name + compressedExt)
+		return
+	}
+
+	_ = os.Remove(name)
+}
+
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// close waits for compression and closes the file, a later Write opens it again
+func (w *rotatingFile) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.wg.Wait()
+
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.file.Close()
+	w.file = nil
+
+	return err //nolint:wrapcheck // callers name the file
+}
@@ -0,0 +1,160 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clock is moved forward by the tests
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func newTestFile(t *testing.T, cfg RotateConfig) (*rotatingFile, *clock) {
+	t.Helper()
+
+	c := &clock{t: time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)}
+	w := newRotatingFile(filepath.Join(t.TempDir(), "app.log"), cfg)
+	w.now = c.now
+
+	t.Cleanup(func() { _ = w.close() })
+
+	return w, c
+}
+
+func write(t *testing.T, w *rotatingFile, line string) {
+	t.Helper()
+
+	if _, err := w.Write([]byte(line + "\n")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// settle waits for compression and pruning of the last rotation
+func settle(w *rotatingFile) {
+	w.wg.Wait()
+}
+
+func TestRotatingFile_BySize(t *testing.T) {
+	// Arrange
+	w, c := newTestFile(t, RotateConfig{MaxSize: 20, MaxBackups: 2})
+
+	// Act
+	for _, line := range []string{"first entry", "second entry", "third entry", "fourth entry"} {
+		write(t, w, line)
+		c.t = c.t.Add(time.Second)
+	}
+
+	settle(w)
+
+	// Assert
+	backups := w.backups()
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 backups kept, got %v", backups)
+	}
+
+	if b, _ := os.ReadFile(backups[0]); string(b) != "second entry\n" {
+		t.Errorf("expected the oldest kept backup to hold the second entry, got %q", b)
+	}
+
+	if b, _ := os.ReadFile(w.path); string(b) != "fourth entry\n" {
+		t.Errorf("expected the current file to hold the last entry, got %q", b)
+	}
+
+	if want := "app-20261019T100003.000.log"; filepath.Base(backups[1]) != want {
+		t.Errorf("expected backup %s, got %s", want, filepath.Base(backups[1]))
+	}
+}
+
+func TestRotatingFile_SameMillisecond(t *testing.T) {
+	// Arrange: the clock stands still, every entry fills a file
+	w, _ := newTestFile(t, RotateConfig{MaxSize: 10, Compress: true})
+
+	// Act
+	for _, line := range []string{"first entry", "second entry", "third entry"} {
+		write(t, w, line)
+		settle(w)
+	}
+
+	// Assert: no backup replaced another
+	backups := w.backups()
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 backups, got %v", backups)
+	}
+
+	if want := "app-20261019T100000.001.log.gz"; filepath.Base(backups[1]) != want {
+		t.Errorf("expected backup %s, got %s", want, filepath.Base(backups[1]))
+	}
+
+	for i, want := range []string{"first entry\n", "second entry\n"} {
+		f, err := os.Open(backups[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if b, _ := io.ReadAll(gz); string(b) != want {
+			t.Errorf("expected backup %d to hold %q, got %q", i, want, b)
+		}
+
+		f.Close()
+	}
+}
+
+func TestRotatingFile_ByTimeCompressed(t *testing.T) {
+	// Arrange
+	w, c := newTestFile(t, RotateConfig{Interval: time.Hour, MaxAge: 48 * time.Hour, Compress: true})
+	// file times are real, so is the clock
+	c.t = time.Now()
+
+	// Act
+	write(t, w, "first hour")
+	c.t = c.t.Add(time.Hour)
+	write(t, w, "second hour")
+	settle(w)
+
+	// Assert
+	backups := w.backups()
+	if len(backups) != 1 || !strings.HasSuffix(backups[0], ".log.gz") {
+		t.Fatalf("expected one compressed backup, got %v", backups)
+	}
+
+	f, err := os.Open(backups[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b, _ := io.ReadAll(gz); string(b) != "first hour\n" {
+		t.Errorf("expected the backup to hold the first hour, got %q", b)
+	}
+
+	// backups past MaxAge go with the next rotation
+	old := time.Now().Add(-72 * time.Hour)
+	if err = os.Chtimes(backups[0], old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	first := backups[0]
+	c.t = c.t.Add(time.Hour)
+	write(t, w, "third hour")
+	settle(w)
+
+	if backups = w.backups(); len(backups) != 1 || backups[0] == first {
+		t.Errorf("expected only the new backup, got %v", backups)
+	}
+}
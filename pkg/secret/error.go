@@ -0,0 +1,9 @@
+package secret
+
+import "errors"
+
+var (
+	ErrInvalidMasterKey = errors.New("master key must be 32 bytes, base64 encoded")
+	ErrMissingMasterKey = errors.New("secrets file given without SECRETS_MASTER_KEY")
+	ErrDecrypt          = errors.New("secrets file cannot be decrypted with this master key")
+)
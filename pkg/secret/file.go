@@ -0,0 +1,127 @@
+package secret
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// fileHeader starts every encrypted secrets file, it names the format version
+	fileHeader = "wallet-secrets:v1:"
+	keySize    = 32
+)
+
+// File holds the secrets of a decrypted secrets file. The file is a YAML
+// mapping of config keys to values, like `postgresql.password: ...`, sealed
+// with AES-256-GCM under the master key.
+type File map[string]String
+
+func (f File) Lookup(key string) (String, bool, error) {
+	value, ok := f[key]
+
+	return value, ok, nil
+}
+
+// OpenFile reads and decrypts the secrets file at path
+func OpenFile(path string, masterKey String) (File, error) {
+	sealed, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+
+	plain, err := Decrypt(masterKey, sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]string
+	if err = yaml.Unmarshal(plain, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse secrets file: %w", err)
+	}
+
+	file := make(File, len(values))
+	for key, value := range values {
+		file[key] = String(value)
+	}
+
+	return file, nil
+}
+
+// NewMasterKey returns a random master key, base64 encoded
+func NewMasterKey() (String, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate master key: %w", err)
+	}
+
+	return String(base64.StdEncoding.EncodeToString(key)), nil
+}
+
+// Encrypt seals plain under masterKey in the format OpenFile reads
+func Encrypt(masterKey String, plain []byte) ([]byte, error) {
+	aead, err := newAEAD(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, plain, []byte(fileHeader))
+
+	return []byte(fileHeader + base64.StdEncoding.EncodeToString(sealed) + "\n"), nil
+}
+
+// Decrypt opens what Encrypt sealed
+func Decrypt(masterKey String, sealed []byte) ([]byte, error) {
+	aead, err := newAEAD(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, ok := strings.CutPrefix(string(bytes.TrimSpace(sealed)), fileHeader)
+	if !ok {
+		return nil, fmt.Errorf("%w: not a secrets file", ErrDecrypt)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(raw) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: malformed content", ErrDecrypt)
+	}
+
+	plain, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], []byte(fileHeader))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return plain, nil
+}
+
+func newAEAD(masterKey String) (cipher.AEAD, error) { //nolint:ireturn // the standard library returns it
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(masterKey.Reveal()))
+	if err != nil || len(key) != keySize {
+		return nil, ErrInvalidMasterKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return aead, nil
+}
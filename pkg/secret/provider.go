@@ -0,0 +1,89 @@
+package secret
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/amelonpie/wallet-service/pkg/log"
+)
+
+// KeyMasterKey is the key of the master key of the secrets file. It is looked up
+// in the environment only, never in a config file or a secrets directory.
+const KeyMasterKey = "secrets.master_key"
+
+// Config of the provider Initialize sets up
+type Config struct {
+	// Dir is a mounted secrets directory, empty for none
+	Dir string
+	// File is an encrypted secrets file, empty for none
+	File string
+}
+
+// NewProvider chains the environment, the secrets directory and the secrets
+// file, in this order of precedence
+func NewProvider(cfg Config) (Provider, error) { //nolint:ireturn // stick to interface
+	chain := Chain{Env{}}
+
+	if cfg.Dir != "" {
+		chain = append(chain, Dir(cfg.Dir))
+	}
+
+	if cfg.File != "" {
+		masterKey, ok, err := Env{}.Lookup(KeyMasterKey)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			return nil, ErrMissingMasterKey
+		}
+
+		file, err := OpenFile(cfg.File, masterKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", cfg.File, err)
+		}
+
+		chain = append(chain, file)
+	}
+
+	return chain, nil
+}
+
+var current = struct { //nolint:gochecknoglobals // set up once with the config
+	sync.RWMutex
+	provider Provider
+}{provider: Env{}}
+
+// Initialize sets the provider Lookup uses, until then it is the environment
+func Initialize(cfg Config) error {
+	provider, err := NewProvider(cfg)
+	if err != nil {
+		return err
+	}
+
+	current.Lock()
+	defer current.Unlock()
+
+	current.provider = provider
+
+	return nil
+}
+
+// Lookup resolves key with the provider set by Initialize. A value found is
+// redacted from every log entry from then on.
+func Lookup(key string) (String, bool, error) {
+	current.RLock()
+	provider := current.provider
+	current.RUnlock()
+
+	value, ok, err := provider.Lookup(key)
+	if err != nil {
+		return "", false, err //nolint:wrapcheck // providers name the secret
+	}
+
+	if ok {
+		log.Redact(value.Reveal())
+	}
+
+	return value, ok, nil
+}
@@ -0,0 +1,96 @@
+// Package secret resolves passwords and keys from the environment, from files
+// mounted by the orchestrator and from an encrypted secrets file, so that they
+// need not be written into the config file.
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// String is a secret value. It prints, formats and marshals as [REDACTED], only
+// Reveal returns the value.
+type String string
+
+func (s String) String() string   { return redacted }
+func (s String) GoString() string { return redacted }
+
+// MarshalText keeps the value out of JSON, YAML and the log formatters
+func (s String) MarshalText() ([]byte, error) { return []byte(redacted), nil }
+
+// Reveal returns the value for the one place that needs it, e.g. a DSN
+func (s String) Reveal() string { return string(s) }
+
+// Provider looks secrets up by their config key, e.g. postgresql.password
+type Provider interface {
+	// Lookup returns ok false when the provider does not hold key
+	Lookup(key string) (value String, ok bool, err error)
+}
+
+// Env provides key as the environment variable POSTGRESQL_PASSWORD, or the
+// content of the file named by POSTGRESQL_PASSWORD_FILE, which takes precedence
+type Env struct{}
+
+func (Env) Lookup(key string) (String, bool, error) {
+	name := strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+
+	if path, ok := os.LookupEnv(name + "_FILE"); ok {
+		value, err := readFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read %s_FILE: %w", name, err)
+		}
+
+		return value, true, nil
+	}
+
+	value, ok := os.LookupEnv(name)
+
+	return String(value), ok, nil
+}
+
+// Dir provides key as the content of the file of the same name in a mounted
+// secrets directory, like /run/secrets/postgresql.password
+type Dir string
+
+func (d Dir) Lookup(key string) (String, bool, error) {
+	value, err := readFile(filepath.Join(string(d), key))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", false, nil
+	}
+
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read secret %s: %w", key, err)
+	}
+
+	return value, true, nil
+}
+
+// Chain asks its providers in order, the first that holds a key wins
+type Chain []Provider
+
+func (c Chain) Lookup(key string) (String, bool, error) {
+	for _, p := range c {
+		value, ok, err := p.Lookup(key)
+		if err != nil || ok {
+			return value, ok, err
+		}
+	}
+
+	return "", false, nil
+}
+
+// readFile reads a secret file, without the trailing newline editors and
+// `echo` leave
+func readFile(path string) (String, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err //nolint:wrapcheck // callers name the secret
+	}
+
+	return String(strings.TrimRight(string(b), "\r\n")), nil
+}
@@ -0,0 +1,136 @@
+package secret
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestString_IsRedacted(t *testing.T) {
+	s := String("hunter22")
+
+	cfg := struct {
+		Addr     string
+		Password String
+	}{"localhost", s}
+
+	out, _ := json.Marshal(cfg)
+
+	for _, dump := range []string{fmt.Sprint(s), fmt.Sprintf("%+v", cfg), fmt.Sprintf("%#v", cfg), string(out)} {
+		if strings.Contains(dump, "hunter22") {
+			t.Errorf("expected the secret to be redacted, got %s", dump)
+		}
+	}
+
+	if s.Reveal() != "hunter22" {
+		t.Errorf("expected Reveal to return the value, got %q", s.Reveal())
+	}
+}
+
+func TestChain_Precedence(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "redis.password"), "from-dir\n")
+	writeFile(t, filepath.Join(dir, "postgresql.password"), "from-dir\n")
+	writeFile(t, filepath.Join(dir, "pg-file"), "from-env-file\n")
+
+	t.Setenv("POSTGRESQL_PASSWORD", "from-env")
+	t.Setenv("POSTGRESQL_PASSWORD_FILE", filepath.Join(dir, "pg-file"))
+
+	chain := Chain{Env{}, Dir(dir), File{"webhook.secret": "from-file"}}
+
+	tests := map[string]String{
+		"postgresql.password": "from-env-file",
+		"redis.password":      "from-dir",
+		"webhook.secret":      "from-file",
+	}
+
+	for key, want := range tests {
+		// Act
+		got, ok, err := chain.Lookup(key)
+
+		// Assert
+		if err != nil || !ok || got != want {
+			t.Errorf("%s: expected %q, got %q, %v, %v", key, want.Reveal(), got.Reveal(), ok, err)
+		}
+	}
+
+	if _, ok, err := chain.Lookup("missing.password"); ok || err != nil {
+		t.Errorf("expected a missing secret to be not found, got %v, %v", ok, err)
+	}
+}
+
+func TestEnv_UnreadableFile(t *testing.T) {
+	t.Setenv("REDIS_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+
+	if _, _, err := (Env{}).Lookup("redis.password"); err == nil || !strings.Contains(err.Error(), "REDIS_PASSWORD_FILE") {
+		t.Errorf("expected an error naming the variable, got %v", err)
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	// Arrange
+	key, err := NewMasterKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := Encrypt(key, []byte("postgresql.password: s3cret\nredis.password: r3dis\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	writeFile(t, path, string(sealed))
+
+	otherKey, _ := NewMasterKey()
+
+	// Act
+	file, err := OpenFile(path, key)
+	_, wrongKeyErr := OpenFile(path, otherKey)
+	_, shortKeyErr := OpenFile(path, "c2hvcnQ=")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("expected the file to open, got %v", err)
+	}
+
+	if file["postgresql.password"] != "s3cret" || file["redis.password"] != "r3dis" {
+		t.Errorf("unexpected secrets %v", file)
+	}
+
+	if strings.Contains(string(sealed), "s3cret") {
+		t.Errorf("expected the file to be encrypted")
+	}
+
+	if !errors.Is(wrongKeyErr, ErrDecrypt) {
+		t.Errorf("expected ErrDecrypt for another key, got %v", wrongKeyErr)
+	}
+
+	if !errors.Is(shortKeyErr, ErrInvalidMasterKey) {
+		t.Errorf("expected ErrInvalidMasterKey, got %v", shortKeyErr)
+	}
+}
+
+func TestNewProvider_MissingMasterKey(t *testing.T) {
+	for _, name := range []string{"SECRETS_MASTER_KEY", "SECRETS_MASTER_KEY_FILE"} {
+		t.Setenv(name, "") // restored after the test
+		os.Unsetenv(name)
+	}
+
+	if _, err := NewProvider(Config{File: "secrets.enc"}); !errors.Is(err, ErrMissingMasterKey) {
+		t.Errorf("expected ErrMissingMasterKey, got %v", err)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}